@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/logging"
+)
+
+// batchResult 批量执行时一条命令的JSON输出
+type batchResult struct {
+	// 行号, -c 中为第几条命令
+	Line int `json:"line"`
+	// 命令
+	Command string `json:"command"`
+	// 命令是否成功, 参数错误、未知命令、执行出错与断言失败时为false
+	OK bool `json:"ok"`
+	// 命令失败时的第一个错误
+	Error string `json:"error,omitempty"`
+	// 命令与日志的输出
+	Output string `json:"output"`
+	// 执行后的虚拟时间
+	Clock string `json:"clock"`
+	// 执行后剩余的事件数
+	Pending int `json:"pending"`
+}
+
+// batchSummary 批量执行结束时的统计, 作为最后一行JSON输出
+type batchSummary struct {
+	// 执行的命令数
+	Commands int `json:"commands"`
+	// 失败的命令数, 包括失败的断言
+	Errors int `json:"errors"`
+	// 通过与失败的断言数
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	// 读取命令出错时的错误
+	Error string `json:"error,omitempty"`
+}
+
+// runBatch 批量执行命令, 不显示欢迎信息与提示符, 返回进程退出码
+// 命令中可以使用 expect, 断言失败与命令出错都使退出码为1
+// @param name string 命令来源, 用于断言的位置信息
+// @param r io.Reader 每行一条命令
+// @param jsonOutput bool 每条命令输出一行JSON
+// @param errExit bool 第一条命令失败后停止执行
+// @return int 0 所有命令成功且断言全部通过, 1 有命令失败或断言失败, 2 无法读取命令
+func runBatch(name string, r io.Reader, jsonOutput, errExit bool) int {
+	run := &scriptRun{path: name}
+	currentScript = run
+	defer func() { currentScript = nil }()
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	count, failed := 0, 0
+	err := ExecLines(r, func(num int, line string) bool {
+		run.line = num
+		count++
+		simMu.Lock()
+		defer simMu.Unlock()
+		commandErr = ""
+		var more bool
+		if jsonOutput {
+			output := captureOutput(func() { more = execLine(line) })
+			enc.Encode(batchResult{Line: num, Command: line, OK: commandErr == "", Error: commandErr,
+				Output: output, Clock: host.FormatClock(host.Clock), Pending: host.Pending()})
+		} else {
+			more = execLine(line)
+		}
+		if commandErr != "" {
+			failed++
+			return more && !errExit
+		}
+		return more
+	})
+	code := 0
+	switch {
+	case err != nil:
+		code = 2
+	case failed > 0 || run.failed > 0:
+		code = 1
+	}
+	if jsonOutput {
+		summary := batchSummary{Commands: count, Errors: failed, Passed: run.passed, Failed: run.failed}
+		if err != nil {
+			summary.Error = err.Error()
+		}
+		enc.Encode(map[string]batchSummary{"summary": summary})
+	} else if err != nil {
+		fmt.Printf("%s: %v\n", name, err)
+	}
+	return code
+}
+
+// openBatch 打开 -f 指定的命令文件, - 表示标准输入
+func openBatch(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// splitCommands 按 ; 拆分 -c 的命令, 双引号内的 ; 不拆分
+// @param s string 以 ; 分隔的命令
+// @return string 每行一条命令
+func splitCommands(s string) string {
+	var b strings.Builder
+	inQuotes := false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// captureOutput 执行 fn 并返回其间写到标准输出的内容, 输出到标准输出的日志也一并收集
+func captureOutput(fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		fn()
+		return ""
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	if logFile == nil {
+		logging.SetOutput(w, logging.Format())
+	}
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+	fn()
+	// log format 命令可能在 fn 中修改了格式, 恢复时沿用新的格式
+	os.Stdout = stdout
+	if logFile == nil {
+		logging.SetOutput(stdout, logging.Format())
+	}
+	w.Close()
+	<-done
+	r.Close()
+	return buf.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"osiweb-go/host"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := map[string]string{
+		"run":                        "run",
+		"host add h1; run":           "host add h1\n run",
+		`send h1 10.0.0.2 udp "a;b"`: `send h1 10.0.0.2 udp "a;b"`,
+		"a;;b":                       "a\n\nb",
+	}
+	for in, want := range tests {
+		if got := splitCommands(in); got != want {
+			t.Errorf("splitCommands(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRunBatchJSON(t *testing.T) {
+	resetSimulator(t)
+	commands := splitCommands("topology load examples/lab.yaml;run;expect arp h1 contains 192.168.1.1;expect clock >1h")
+	var code int
+	out := captureOutput(func() { code = runBatch("-c", strings.NewReader(commands), true, false) })
+	if code != 1 {
+		t.Errorf("exit code %d, want 1 for the failed assertion", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines of output, want 4 results and a summary:\n%s", len(lines), out)
+	}
+	for i, line := range lines[:4] {
+		var res batchResult
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("line %d: %v: %s", i+1, err, line)
+		}
+		if res.Line != i+1 {
+			t.Errorf("line %d reported as %d", i+1, res.Line)
+		}
+		if wantOK := i < 3; res.OK != wantOK || (res.Error == "") != wantOK {
+			t.Errorf("line %d: ok %v error %q, want ok %v", i+1, res.OK, res.Error, wantOK)
+		}
+		if i == 1 && (res.Clock == "" || res.Pending != 0) {
+			t.Errorf("after run: clock %q with %d events pending", res.Clock, res.Pending)
+		}
+		if i == 2 && !strings.Contains(res.Output, "断言通过") {
+			t.Errorf("passing expect printed %q", res.Output)
+		}
+	}
+	var summary map[string]batchSummary
+	if err := json.Unmarshal([]byte(lines[4]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if got := summary["summary"]; got.Commands != 4 || got.Errors != 1 || got.Passed != 1 || got.Failed != 1 || got.Error != "" {
+		t.Errorf("summary %+v, want 4 commands, 1 error, 1 passed, 1 failed", got)
+	}
+	if currentScript != nil {
+		t.Error("currentScript still set after the batch")
+	}
+}
+
+// 命令出错时退出码为1, errExit 时不再执行后面的命令
+func TestRunBatchErrors(t *testing.T) {
+	resetSimulator(t)
+	commands := splitCommands("nonsense;host add h1")
+	var code int
+	captureOutput(func() { code = runBatch("-c", strings.NewReader(commands), false, false) })
+	if code != 1 || host.FindHost("h1") == nil {
+		t.Errorf("exit code %d with h1 %v, want 1 and the second command run", code, host.FindHost("h1"))
+	}
+
+	resetSimulator(t)
+	captureOutput(func() { code = runBatch("-c", strings.NewReader(commands), false, true) })
+	if code != 1 || host.FindHost("h1") != nil {
+		t.Errorf("exit code %d with h1 %v, want 1 and the batch stopped", code, host.FindHost("h1"))
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+	"osiweb-go/pcap"
+)
+
+// captureSession 抓包会话
+type captureSession struct {
+	// 抓包回调编号, 0表示已停止
+	tapID int
+	// 会话名称: 设备名称或 link<编号>
+	name string
+	// 设备名称, 链路抓包时为空
+	device string
+	// 端口号, -1表示所有端口
+	port int
+	// 链路编号, 设备抓包时为0
+	linkID int
+	// 抓到的帧
+	frames []host.CapturedFrame
+	// 抓包文件
+	file *os.File
+	// 抓包文件写入器
+	writer pcap.Writer
+	// 接口名称到文件内接口编号
+	ifaces map[string]int
+}
+
+// captures 按会话名称索引的抓包会话
+var captures = make(map[string]*captureSession)
+
+// cmdCapture 抓包命令
+// @param args []string 子命令与参数
+func cmdCapture(args []string) {
+	if len(args) < 2 {
+		printUsage("capture")
+		return
+	}
+	switch args[0] {
+	case "start":
+		target, file, includeFCS := args[1:], "", false
+		if target[len(target)-1] == "fcs" {
+			target, includeFCS = target[:len(target)-1], true
+		}
+		if n := len(target); n == 2 && target[0] != "link" || n == 3 {
+			target, file = target[:n-1], target[n-1]
+		}
+		if len(target) == 0 {
+			printUsage("capture")
+			return
+		}
+		if _, err := startCapture(target, file, includeFCS); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	case "stop":
+		s := findCapture(args[1:])
+		if s == nil {
+			return
+		}
+		if err := s.stop(); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Printf(i18n.T("OK, 共抓到 %d 帧\n"), len(s.frames))
+	case "show":
+		target, index := args[1:], ""
+		if len(target) == 3 || (len(target) == 2 && target[0] != "link") {
+			target, index = target[:len(target)-1], target[len(target)-1]
+		}
+		s := findCapture(target)
+		if s == nil {
+			return
+		}
+		if index == "" {
+			showCapture(s)
+			return
+		}
+		n, err := strconv.Atoi(index)
+		if err != nil || n < 1 || n > len(s.frames) {
+			printError(fmt.Sprintf(i18n.T("帧编号应在 1-%d 之间"), len(s.frames)))
+			return
+		}
+		f := s.frames[n-1]
+		name, _, _ := s.interfaceOf(f)
+		fmt.Printf(i18n.T("帧 %d: %s %s %s, %d 字节\n"), n, host.FormatClock(f.Time), name, f.Direction, len(f.Data))
+		showDissection(f.Data, true)
+	case "save":
+		if len(args) < 3 {
+			printUsage("capture")
+			return
+		}
+		includeFCS := args[len(args)-1] == "fcs"
+		if includeFCS {
+			args = args[:len(args)-1]
+		}
+		s := findCapture(args[1 : len(args)-1])
+		if s == nil {
+			return
+		}
+		if err := s.save(args[len(args)-1], includeFCS); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Printf(i18n.T("OK, 已写入 %d 帧\n"), len(s.frames))
+	default:
+		printUsage("capture")
+	}
+}
+
+// startCapture 开始抓包
+// @param target <dev>[:port] 或 link <id>
+// @param file 抓包文件, 为空时只保存在内存
+// @param includeFCS 文件中是否保留FCS
+// @return *captureSession, error
+func startCapture(target []string, file string, includeFCS bool) (*captureSession, error) {
+	s, rest, err := parseCaptureTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf(i18n.T("多余的参数: %s"), strings.Join(rest, " "))
+	}
+	if old, ok := captures[s.name]; ok && old.tapID != 0 {
+		return nil, fmt.Errorf(i18n.T("已经在抓包: %s"), s.name)
+	}
+	if file != "" {
+		if err := s.openFile(file, includeFCS); err != nil {
+			return nil, err
+		}
+	}
+	s.tapID = host.AddTap(s.record)
+	captures[s.name] = s
+	return s, nil
+}
+
+// stop 停止抓包并关闭文件
+func (s *captureSession) stop() error {
+	if s.tapID == 0 {
+		return fmt.Errorf(i18n.T("没有进行中的抓包: %s"), s.name)
+	}
+	host.RemoveTap(s.tapID)
+	s.tapID = 0
+	s.closeFile()
+	return nil
+}
+
+// parseCaptureTarget 解析抓包对象: <dev>[:port] 或 link <id>
+// @return *captureSession 未启动的会话
+// @return []string 剩余参数
+func parseCaptureTarget(args []string) (*captureSession, []string, error) {
+	if args[0] == "link" {
+		if len(args) < 2 {
+			return nil, nil, errors.New(i18n.T("缺少链路编号"))
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil || host.FindLink(id) == nil {
+			return nil, nil, fmt.Errorf(i18n.T("链路不存在: %s"), args[1])
+		}
+		return &captureSession{name: fmt.Sprintf("link%d", id), port: -1, linkID: id}, args[2:], nil
+	}
+	dev, port, err := host.ParseEndpoint(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	name := dev.DeviceName()
+	return &captureSession{name: name, device: name, port: port}, args[1:], nil
+}
+
+// findCapture 按 <dev> 或 link <id> 查找抓包会话, 不存在时打印提示
+func findCapture(args []string) *captureSession {
+	name := ""
+	switch {
+	case len(args) == 1:
+		name = args[0]
+	case len(args) == 2 && args[0] == "link":
+		name = "link" + args[1]
+	default:
+		printUsage("capture")
+		return nil
+	}
+	s, ok := captures[name]
+	if !ok {
+		printError(i18n.T("没有抓包记录: "), name)
+		return nil
+	}
+	return s
+}
+
+// match 判断帧是否属于本会话; 链路抓包只记录发送方向, 避免同一帧记录两次
+func (s *captureSession) match(f host.CapturedFrame) bool {
+	if s.linkID != 0 {
+		return f.LinkID == s.linkID && f.Direction == host.DirectionTx
+	}
+	return f.Device == s.device && (s.port < 0 || f.Port == s.port)
+}
+
+// record 抓包回调
+func (s *captureSession) record(f host.CapturedFrame) {
+	if !s.match(f) {
+		return
+	}
+	s.frames = append(s.frames, f)
+	if s.writer == nil {
+		return
+	}
+	if err := s.writeFrame(s.writer, f); err != nil {
+		appLog.Error(i18n.T("写入抓包文件失败"), "capture", s.name, "err", err)
+		s.closeFile()
+	}
+}
+
+// openFile 创建抓包文件, 格式由扩展名决定
+func (s *captureSession) openFile(path string, includeFCS bool) error {
+	path, err := capturePath(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer, err := pcap.NewWriter(file, pcap.FormatFromName(path), includeFCS)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.writer, s.ifaces = file, writer, make(map[string]int)
+	return nil
+}
+
+// closeFile 关闭抓包文件
+func (s *captureSession) closeFile() {
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file, s.writer, s.ifaces = nil, nil, nil
+}
+
+// save 将已抓到的帧写入文件
+func (s *captureSession) save(path string, includeFCS bool) error {
+	path, err := capturePath(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer, err := pcap.NewWriter(file, pcap.FormatFromName(path), includeFCS)
+	if err != nil {
+		return err
+	}
+	saved := *s
+	saved.ifaces = make(map[string]int)
+	for _, f := range s.frames {
+		if err := saved.writeFrame(writer, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFrame 写入一帧, 首次出现的接口先登记接口描述
+func (s *captureSession) writeFrame(w pcap.Writer, f host.CapturedFrame) error {
+	name, description, dir := s.interfaceOf(f)
+	iface, ok := s.ifaces[name]
+	if !ok {
+		var err error
+		if iface, err = w.AddInterface(name, description); err != nil {
+			return err
+		}
+		s.ifaces[name] = iface
+	}
+	return w.WritePacket(f.Time, iface, dir, f.Data)
+}
+
+// interfaceOf 帧所属接口的名称、描述与方向
+func (s *captureSession) interfaceOf(f host.CapturedFrame) (string, string, pcap.Direction) {
+	if s.linkID != 0 {
+		description := ""
+		if l := host.FindLink(s.linkID); l != nil {
+			description = l.String()
+		}
+		return s.name, description, pcap.DirectionUnknown
+	}
+	name := fmt.Sprintf("%s:%d", f.Device, f.Port)
+	description := ""
+	if dev := host.FindDevice(f.Device); dev != nil && f.Port < len(dev.Ports()) {
+		name = f.Device + ":" + dev.PortName(f.Port)
+		if h, ok := dev.(*host.BaseHost); ok {
+			iface := h.Interfaces[f.Port]
+			description = fmt.Sprintf("%s %s/%d", level.FormatMAC(iface.MACAddress),
+				level.FormatIPv4(iface.IPv4Address), iface.PrefixLen)
+		}
+	}
+	dir := pcap.DirectionOutbound
+	if f.Direction == host.DirectionRx {
+		dir = pcap.DirectionInbound
+	}
+	return name, description, dir
+}
+
+// showCapture 逐行打印抓到的帧
+func showCapture(s *captureSession) {
+	if len(s.frames) == 0 {
+		fmt.Println("(empty)")
+		return
+	}
+	for i, f := range s.frames {
+		name, _, _ := s.interfaceOf(f)
+		fmt.Printf("%4d %s %-10s %s %s\n", i+1, host.FormatClock(f.Time), name, f.Direction,
+			level.Summarize(f.Data))
+	}
+}
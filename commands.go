@@ -2,25 +2,56 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 	"golang.org/x/term"
+
+	"osiweb-go/i18n"
 )
 
+// errInterrupted 用户按下 Ctrl+C
+var errInterrupted = errors.New("interrupted")
+
 // FindSimilarCommands 查找相似命令
-// @author xuyang
-// @datetime 2025-6-24 7:00
+// 按与命令名或别名的 Damerau–Levenshtein 距离排序, 包含关系的命令也算相似
 func FindSimilarCommands(input string) []Command {
-	var similar []Command
+	type candidate struct {
+		cmd  Command
+		dist int
+	}
+	var candidates []candidate
 	inputLower := strings.ToLower(input)
+	maxDist := 1
+	if utf8.RuneCountInString(inputLower) > 3 {
+		maxDist = 2
+	}
 	for _, cmd := range Commands {
-		cmdLower := strings.ToLower(cmd.Name)
-		if strings.Contains(cmdLower, inputLower) || strings.Contains(inputLower, cmdLower) {
-			similar = append(similar, cmd)
+		best := -1
+		for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+			d := editDistance(inputLower, name)
+			if d <= maxDist || strings.Contains(name, inputLower) || strings.Contains(inputLower, name) {
+				if best < 0 || d < best {
+					best = d
+				}
+			}
+		}
+		if best >= 0 {
+			candidates = append(candidates, candidate{cmd, best})
 		}
 	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.dist - b.dist })
+	var similar []Command
+	for _, c := range candidates {
+		similar = append(similar, c.cmd)
+	}
 	return similar
 }
 
@@ -31,12 +62,47 @@ func FindSimilarCommands(input string) []Command {
 func showSimilarCommands(input string) {
 	similar := FindSimilarCommands(input)
 	if len(similar) > 0 {
-		fmt.Println("您是否在查找:")
+		fmt.Println(i18n.T("您是否在查找:"))
 		for _, cmd := range similar {
-			fmt.Printf("  %-10s - %s\n", cmd.Name, cmd.Description)
+			name := cmd.Name
+			if len(cmd.Aliases) > 0 {
+				name += " (" + strings.Join(cmd.Aliases, ", ") + ")"
+			}
+			fmt.Printf("  %-14s - %s\n", name, i18n.T(cmd.Description))
 		}
 	} else {
-		fmt.Println("输入 'help' 以查看所有可用命令")
+		fmt.Println(i18n.T("输入 'help' 以查看所有可用命令"))
+	}
+}
+
+// printUsage 打印参数错误与命令用法
+// 能从当前命令行看出错在哪个参数时指出该参数, 并只显示对应子命令的用法
+// @param name string 命令名称
+func printUsage(name string) {
+	cmd := findCommand(name)
+	if cmd == nil {
+		printError(i18n.T("参数错误!"))
+		return
+	}
+	if fields := parseFields(commandLine); len(fields) > 0 && findCommand(fields[0]) == cmd {
+		if index, reason, usage := diagnoseArgs(cmd, fields[1:]); reason != "" {
+			printArgErrorLine(index, reason)
+			fmt.Printf(i18n.T("用法: %s\n"), usage)
+			return
+		}
+	}
+	printError(i18n.T("参数错误!"))
+	fmt.Printf(i18n.T("用法: %s\n"), cmd.Usage)
+}
+
+// printArgError 指出取值错误的参数
+// @param name string 命令名称
+// @param index int 出错的参数在参数列表中的位置, 不含命令名
+// @param reason string 错误原因
+func printArgError(name string, index int, reason string) {
+	printArgErrorLine(index+1, reason)
+	if cmd := findCommand(name); cmd != nil {
+		fmt.Printf(i18n.T("用法: %s\n"), usageFor(cmd, parseFields(commandLine)))
 	}
 }
 
@@ -83,7 +149,7 @@ func showWithPager(content string) error {
 	} else if _, err := exec.LookPath("more"); err == nil {
 		cmd = exec.Command("more")
 	} else {
-		return fmt.Errorf("未找到分页器")
+		return errors.New(i18n.T("未找到分页器"))
 	}
 	// 设置标准输入输出
 	cmd.Stdin = strings.NewReader(content)
@@ -93,35 +159,89 @@ func showWithPager(content string) error {
 	return cmd.Run()
 }
 
-// showHelp 显示帮助信息
+// showHelp 显示帮助信息, 指定命令时只显示该命令
 // @author xuyang
 // @datetime 2025-6-24 7:00
-func showHelp() {
+// @param args []string 可选的命令名称
+func showHelp(args []string) {
+	if len(args) > 1 {
+		printUsage("help")
+		return
+	}
+	if len(args) == 1 {
+		cmd := findCommand(args[0])
+		if cmd == nil {
+			printError(i18n.T("未知命令: "), args[0])
+			showSimilarCommands(args[0])
+			return
+		}
+		fmt.Print(formatCommandHelp(cmd))
+		return
+	}
 	// 构建帮助内容
 	var helpContent strings.Builder
-	helpContent.WriteString("GopherKV 命令参考手册\n")
+	helpContent.WriteString(i18n.T("OSIWeb-Go 命令参考手册\n"))
 	helpContent.WriteString("===============================================\n\n")
-	helpContent.WriteString("GopherKV 是一个轻量级的键值型内存数据库，支持以下命令：\n\n")
-	for _, cmd := range Commands {
-		helpContent.WriteString(fmt.Sprintf("命令: %s\n", cmd.Name))
-		helpContent.WriteString(fmt.Sprintf("描述: %s\n", cmd.Description))
-		helpContent.WriteString(fmt.Sprintf("用法: %s\n", cmd.Usage))
+	helpContent.WriteString(i18n.T("OSIWeb-Go 是一个计算机七层网络协议模拟器，支持以下命令：\n\n"))
+	for i := range Commands {
+		helpContent.WriteString(formatCommandHelp(&Commands[i]))
 		helpContent.WriteString("-----------------------------------------------\n")
 	}
-	helpContent.WriteString("更多信息请访问: https://github.com/xuyangpojo/gopher-kv\n")
-	// 尝试使用分页器显示
-	if err := showWithPager(helpContent.String()); err != nil {
+	helpContent.WriteString(i18n.T("输入 'help <命令>' 只查看一条命令\n"))
+	helpContent.WriteString(i18n.T("更多信息请访问: %s\n", "https://github.com/xuyangpojo/osiweb-go"))
+	// 输出不是终端时直接打印, 否则尝试使用分页器显示
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Println(helpContent.String())
+	} else if err := showWithPager(helpContent.String()); err != nil {
 		// 如果分页器失败，回退到直接打印
 		fmt.Println(helpContent.String())
 	}
 }
 
+// formatCommandHelp 一条命令的描述、用法、参数、示例与相关命令
+func formatCommandHelp(cmd *Command) string {
+	var b strings.Builder
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&b, i18n.T("命令: %s (别名: %s)\n"), cmd.Name, strings.Join(cmd.Aliases, ", "))
+	} else {
+		fmt.Fprintf(&b, i18n.T("命令: %s\n"), cmd.Name)
+	}
+	fmt.Fprintf(&b, i18n.T("描述: %s\n"), i18n.T(cmd.Description))
+	fmt.Fprintf(&b, i18n.T("用法: %s\n"), cmd.Usage)
+	if len(cmd.Args) > 0 {
+		b.WriteString(i18n.T("参数:\n"))
+		width := 0
+		for _, arg := range cmd.Args {
+			width = max(width, displayWidth(arg.Name))
+		}
+		for _, arg := range cmd.Args {
+			fmt.Fprintf(&b, "      %s%s  %s\n", arg.Name, strings.Repeat(" ", width-displayWidth(arg.Name)), i18n.T(arg.Description))
+		}
+	}
+	if len(cmd.Examples) > 0 {
+		b.WriteString(i18n.T("示例:\n"))
+		for _, ex := range cmd.Examples {
+			fmt.Fprintf(&b, "      %s\n", ex)
+		}
+	}
+	if len(cmd.Related) > 0 {
+		fmt.Fprintf(&b, i18n.T("相关: %s\n"), strings.Join(cmd.Related, ", "))
+	}
+	return b.String()
+}
+
 // InputHandler 输入处理器
 type InputHandler struct {
 	history     []string
 	historyPos  int
 	currentLine string
 	reader      *bufio.Reader
+	// 历史文件, 为空时只保存在内存中
+	historyFile string
+	// 最多保存的历史条数
+	historySize int
+	// 历史文件的行数, 超过上限两倍时整理文件
+	fileLines int
 }
 
 // NewInputHandler 创建新的输入处理器
@@ -133,24 +253,32 @@ func NewInputHandler() *InputHandler {
 	}
 }
 
-// ReadLine 读取一行输入，支持历史记录
+// ReadLine 读取一行输入，支持历史记录、补全与行编辑
+// 行内容按rune编辑, 光标按显示宽度移动, 中文等宽字符占两列
 func (h *InputHandler) ReadLine(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return h.readPlainLine()
+	}
 	fmt.Print(prompt)
 	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
 	if err != nil {
 		return "", err
 	}
 	defer term.Restore(int(os.Stdin.Fd()), oldState)
-	var line strings.Builder
+	var line []rune
 	cursorPos := 0
+	lastTab := false
 	for {
 		char, _, err := h.reader.ReadRune()
 		if err != nil {
 			return "", err
 		}
+		// 连续两次Tab时列出候选
+		doubleTab := char == '\t' && lastTab
+		lastTab = char == '\t'
 		switch char {
 		case '\r', '\n': // 回车键
-			result := line.String()
+			result := string(line)
 			if strings.TrimSpace(result) != "" {
 				h.addToHistory(result)
 			}
@@ -160,150 +288,496 @@ func (h *InputHandler) ReadLine(prompt string) (string, error) {
 			return result, nil
 		case 3: // Ctrl+C
 			fmt.Println("^C")
-			return "", fmt.Errorf("用户中断")
-		case 4: // Ctrl+D
-			fmt.Println("^D")
-			return "", fmt.Errorf("EOF")
-		case 127: // 退格键
+			return "", errInterrupted
+		case 4: // Ctrl+D 空行时退出, 否则删除光标处字符
+			if len(line) == 0 {
+				fmt.Println("^D")
+				return "", io.EOF
+			}
+			if cursorPos < len(line) {
+				line = slices.Delete(line, cursorPos, cursorPos+1)
+				h.redrawLine(prompt, line, cursorPos)
+			}
+		case '\t': // Tab键 补全
+			line, cursorPos = h.complete(prompt, line, cursorPos, doubleTab)
+		case 127, 8: // 退格键, Ctrl+H
 			if cursorPos > 0 {
-				lineStr := line.String()
-				line.Reset()
-				line.WriteString(lineStr[:cursorPos-1])
-				line.WriteString(lineStr[cursorPos:])
+				line = slices.Delete(line, cursorPos-1, cursorPos)
 				cursorPos--
-				h.redrawLine(prompt, line.String(), cursorPos)
+				h.redrawLine(prompt, line, cursorPos)
 			}
-
+		case 1: // Ctrl+A 行首
+			cursorPos = h.moveTo(line, cursorPos, 0)
+		case 5: // Ctrl+E 行尾
+			cursorPos = h.moveTo(line, cursorPos, len(line))
+		case 2: // Ctrl+B 左移
+			cursorPos = h.moveTo(line, cursorPos, max(cursorPos-1, 0))
+		case 6: // Ctrl+F 右移
+			cursorPos = h.moveTo(line, cursorPos, min(cursorPos+1, len(line)))
+		case 11: // Ctrl+K 删除到行尾
+			line = line[:cursorPos]
+			h.redrawLine(prompt, line, cursorPos)
+		case 21: // Ctrl+U 删除到行首
+			line = slices.Delete(line, 0, cursorPos)
+			cursorPos = 0
+			h.redrawLine(prompt, line, cursorPos)
+		case 23: // Ctrl+W 删除光标前的一个词
+			start := wordStart(line, cursorPos)
+			line = slices.Delete(line, start, cursorPos)
+			cursorPos = start
+			h.redrawLine(prompt, line, cursorPos)
+		case 18: // Ctrl+R 反向搜索历史
+			line, cursorPos = h.reverseSearch(prompt, line, cursorPos)
 		case 27: // ESC键
-			// 检查是否是方向键
-			nextChar, _, err := h.reader.ReadRune()
-			if err != nil {
-				continue
-			}
-			if nextChar == '[' {
-				arrowChar, _, err := h.reader.ReadRune()
-				if err != nil {
-					continue
-				}
-				switch arrowChar {
-				case 'A': // 上箭头
-					h.navigateHistory(1, prompt, &line, &cursorPos)
-				case 'B': // 下箭头
-					h.navigateHistory(-1, prompt, &line, &cursorPos)
-				case 'C': // 右箭头
-					if cursorPos < line.Len() {
-						cursorPos++
-						h.moveCursor(1)
-					}
-				case 'D': // 左箭头
-					if cursorPos > 0 {
-						cursorPos--
-						h.moveCursor(-1)
-					}
-				}
-			}
+			line, cursorPos = h.handleEscape(prompt, line, cursorPos)
 		default:
-			// 普通字符
-			if char >= 32 && char <= 126 { // 可打印字符
-				lineStr := line.String()
-				line.Reset()
-				line.WriteString(lineStr[:cursorPos])
-				line.WriteRune(char)
-				line.WriteString(lineStr[cursorPos:])
+			// 普通字符, 包括中文
+			if unicode.IsPrint(char) {
+				line = slices.Insert(line, cursorPos, char)
 				cursorPos++
 				// 重新显示当前行
-				h.redrawLine(prompt, line.String(), cursorPos)
+				h.redrawLine(prompt, line, cursorPos)
+			}
+		}
+	}
+}
+
+// readPlainLine 标准输入不是终端时按行读取, 不显示提示符也不处理编辑键
+func (h *InputHandler) readPlainLine() (string, error) {
+	line, err := h.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", io.EOF
+		}
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) != "" {
+		h.addToHistory(line)
+	}
+	return line, nil
+}
+
+// handleEscape 处理方向键、Home/End/Delete 与 Alt 组合键的转义序列
+// 支持 ESC [ 参数 终止符、ESC O 终止符 与 ESC b/f 三种形式
+func (h *InputHandler) handleEscape(prompt string, line []rune, cursorPos int) ([]rune, int) {
+	nextChar, _, err := h.reader.ReadRune()
+	if err != nil {
+		return line, cursorPos
+	}
+	var params string
+	final := nextChar
+	switch nextChar {
+	case '[':
+		// 参数由数字与分号组成, 以 0x40-0x7E 之间的字符结束
+		var b strings.Builder
+		for {
+			c, _, err := h.reader.ReadRune()
+			if err != nil {
+				return line, cursorPos
+			}
+			if c >= 0x40 && c <= 0x7e {
+				final = c
+				break
+			}
+			b.WriteRune(c)
+		}
+		params = b.String()
+	case 'O':
+		if final, _, err = h.reader.ReadRune(); err != nil {
+			return line, cursorPos
+		}
+	case 'b': // Alt+B 左移一个词
+		return line, h.moveTo(line, cursorPos, wordStart(line, cursorPos))
+	case 'f': // Alt+F 右移一个词
+		return line, h.moveTo(line, cursorPos, wordEnd(line, cursorPos))
+	default:
+		return line, cursorPos
+	}
+	// Ctrl/Alt+方向键的参数为 1;5 或 1;3, 按词移动
+	byWord := strings.HasSuffix(params, ";5") || strings.HasSuffix(params, ";3")
+	switch {
+	case final == 'A': // 上箭头
+		return h.navigateHistory(1, prompt, line, cursorPos)
+	case final == 'B': // 下箭头
+		return h.navigateHistory(-1, prompt, line, cursorPos)
+	case final == 'C' && byWord:
+		cursorPos = h.moveTo(line, cursorPos, wordEnd(line, cursorPos))
+	case final == 'D' && byWord:
+		cursorPos = h.moveTo(line, cursorPos, wordStart(line, cursorPos))
+	case final == 'C': // 右箭头
+		cursorPos = h.moveTo(line, cursorPos, min(cursorPos+1, len(line)))
+	case final == 'D': // 左箭头
+		cursorPos = h.moveTo(line, cursorPos, max(cursorPos-1, 0))
+	case final == 'H', final == '~' && (params == "1" || params == "7"): // Home
+		cursorPos = h.moveTo(line, cursorPos, 0)
+	case final == 'F', final == '~' && (params == "4" || params == "8"): // End
+		cursorPos = h.moveTo(line, cursorPos, len(line))
+	case final == '~' && params == "3": // Delete
+		if cursorPos < len(line) {
+			line = slices.Delete(line, cursorPos, cursorPos+1)
+			h.redrawLine(prompt, line, cursorPos)
+		}
+	}
+	return line, cursorPos
+}
+
+// reverseSearch 反向增量搜索历史, 与 bash 的 Ctrl+R 相同
+// 输入字符缩小范围, 再按 Ctrl+R 查找更早的匹配, Ctrl+G 取消;
+// 其他键(包括回车)接受当前匹配, 再交回 ReadLine 按普通按键处理
+func (h *InputHandler) reverseSearch(prompt string, line []rune, cursorPos int) ([]rune, int) {
+	var query []rune
+	// 当前匹配在 history 中的位置
+	idx := len(h.history)
+	match := string(line)
+	failed := false
+	find := func(from int) {
+		for i := min(from, len(h.history)-1); i >= 0; i-- {
+			if strings.Contains(h.history[i], string(query)) {
+				idx, match, failed = i, h.history[i], false
+				return
+			}
+		}
+		failed = true
+	}
+	for {
+		label := "(reverse-i-search)"
+		if failed {
+			label = "(failed reverse-i-search)"
+		}
+		fmt.Printf("\r\033[K%s`%s': %s", label, string(query), match)
+		char, _, err := h.reader.ReadRune()
+		if err != nil {
+			return line, cursorPos
+		}
+		switch {
+		case char == 18: // Ctrl+R 更早的匹配
+			if len(query) > 0 {
+				find(idx - 1)
 			}
+		case char == 7, char == 3: // Ctrl+G, Ctrl+C 取消, 恢复原来的输入
+			h.redrawLine(prompt, line, cursorPos)
+			return line, cursorPos
+		case char == 127, char == 8: // 退格键 从最近的历史重新搜索
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(h.history) - 1)
+			}
+		case unicode.IsPrint(char):
+			// 当前匹配仍可能包含新的查询串, 从当前位置开始找
+			query = append(query, char)
+			find(idx)
+		default:
+			line = []rune(match)
+			cursorPos = len(line)
+			h.redrawLine(prompt, line, cursorPos)
+			h.reader.UnreadRune()
+			return line, cursorPos
+		}
+	}
+}
+
+// wordStart 光标左侧词的起点, 先跳过空格
+func wordStart(line []rune, pos int) int {
+	for pos > 0 && line[pos-1] == ' ' {
+		pos--
+	}
+	for pos > 0 && line[pos-1] != ' ' {
+		pos--
+	}
+	return pos
+}
+
+// wordEnd 光标右侧词的终点, 先跳过空格
+func wordEnd(line []rune, pos int) int {
+	for pos < len(line) && line[pos] == ' ' {
+		pos++
+	}
+	for pos < len(line) && line[pos] != ' ' {
+		pos++
+	}
+	return pos
+}
+
+// complete 补全光标前的词, 只有一个候选时补全并加空格, 多个候选时补全公共前缀
+// 无法继续补全时响铃, 再按一次Tab列出候选, 没有候选则显示命令用法
+func (h *InputHandler) complete(prompt string, line []rune, cursorPos int, showList bool) ([]rune, int) {
+	prefix := string(line[:cursorPos])
+	word, candidates := completeLine(prefix)
+	common := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(common, ":") && !strings.HasSuffix(common, "=") && !strings.HasSuffix(common, "/") {
+		common += " "
+	}
+	if len(common) > len(word) {
+		insert := []rune(common[len(word):])
+		line = slices.Insert(line, cursorPos, insert...)
+		cursorPos += len(insert)
+		h.redrawLine(prompt, line, cursorPos)
+		return line, cursorPos
+	}
+	if !showList {
+		fmt.Print("\a")
+		return line, cursorPos
+	}
+	// 终端处于raw模式, 换行需要回车
+	if len(candidates) > 0 {
+		fmt.Print("\r\n" + formatCandidates(candidates) + "\r\n")
+	} else if hint := usageHint(prefix); hint != "" {
+		fmt.Print("\r\n" + strings.ReplaceAll(hint, "\n", "\r\n") + "\r\n")
+	} else {
+		return line, cursorPos
+	}
+	h.redrawLine(prompt, line, cursorPos)
+	return line, cursorPos
+}
+
+// commonPrefix 候选的最长公共前缀
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// formatCandidates 按终端宽度分列排列候选
+func formatCandidates(candidates []string) string {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	colWidth := 0
+	for _, c := range candidates {
+		colWidth = max(colWidth, displayWidth(c)+2)
+	}
+	cols := max(width/colWidth, 1)
+	var b strings.Builder
+	for i, c := range candidates {
+		if i > 0 && i%cols == 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(c)
+		if (i+1)%cols != 0 && i != len(candidates)-1 {
+			b.WriteString(strings.Repeat(" ", colWidth-displayWidth(c)))
 		}
 	}
+	return b.String()
 }
 
 // addToHistory 添加命令到历史记录
+// 相同的命令只保留最近的一条, 超过条数上限时丢弃最早的, 并追加到历史文件
 func (h *InputHandler) addToHistory(cmd string) {
-	// 避免重复添加相同的命令
-	if len(h.history) == 0 || h.history[len(h.history)-1] != cmd {
-		h.history = append(h.history, cmd)
-	}
 	h.historyPos = -1
+	if len(h.history) > 0 && h.history[len(h.history)-1] == cmd {
+		return
+	}
+	h.history = slices.DeleteFunc(h.history, func(c string) bool { return c == cmd })
+	h.history = append(h.history, cmd)
+	if h.historySize > 0 && len(h.history) > h.historySize {
+		h.history = slices.Delete(h.history, 0, len(h.history)-h.historySize)
+	}
+	if h.historyFile != "" {
+		err := appendHistory(h.historyFile, cmd)
+		if h.fileLines++; err == nil && h.fileLines > 2*h.historySize {
+			err = h.SaveHistoryToFile(h.historyFile)
+			h.fileLines = len(h.history)
+		}
+		if err != nil {
+			// 写不进历史文件不影响输入, 提示一次后只保存在内存中
+			fmt.Printf(i18n.T("\r\n保存命令历史失败: %v\r\n"), err)
+			h.historyFile = ""
+		}
+	}
+}
+
+// appendHistory 向历史文件追加一条命令
+func appendHistory(filename, cmd string) error {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(cmd + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadHistory 从历史文件加载命令历史, 之后的命令追加到该文件
+// 文件中重复的命令只保留最近的一条, 超过 size 条时只保留最近的, 整理后写回文件
+// @param filename string 历史文件, 不存在时创建
+// @param size int 最多保存的条数
+func (h *InputHandler) LoadHistory(filename string, size int) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	// 从后向前去重, 保留每条命令最近的位置
+	seen := make(map[string]bool)
+	var history []string
+	for i := len(lines) - 1; i >= 0 && len(history) < size; i-- {
+		line := strings.TrimRight(lines[i], "\r")
+		if strings.TrimSpace(line) == "" || seen[line] {
+			continue
+		}
+		seen[line] = true
+		history = append(history, line)
+	}
+	slices.Reverse(history)
+	h.history, h.historyPos, h.historySize = history, -1, size
+	if err := h.SaveHistoryToFile(filename); err != nil {
+		return err
+	}
+	h.historyFile, h.fileLines = filename, len(history)
+	return nil
 }
 
 // navigateHistory 导航历史记录
-func (h *InputHandler) navigateHistory(direction int, prompt string, line *strings.Builder, cursorPos *int) {
+func (h *InputHandler) navigateHistory(direction int, prompt string, line []rune, cursorPos int) ([]rune, int) {
 	if len(h.history) == 0 {
-		return
+		return line, cursorPos
 	}
 	newPos := h.historyPos + direction
 	if newPos >= -1 && newPos < len(h.history) {
 		h.historyPos = newPos
 		if h.historyPos == -1 {
 			// 回到当前输入
-			line.Reset()
-			line.WriteString(h.currentLine)
-			*cursorPos = line.Len()
+			line = []rune(h.currentLine)
 		} else {
 			// 显示历史记录
-			line.Reset()
-			line.WriteString(h.history[len(h.history)-1-h.historyPos])
-			*cursorPos = line.Len()
+			line = []rune(h.history[len(h.history)-1-h.historyPos])
 		}
-		h.redrawLine(prompt, line.String(), *cursorPos)
+		cursorPos = len(line)
+		h.redrawLine(prompt, line, cursorPos)
 	}
+	return line, cursorPos
 }
 
 // redrawLine 重新绘制当前行
-func (h *InputHandler) redrawLine(prompt, line string, cursorPos int) {
+func (h *InputHandler) redrawLine(prompt string, line []rune, cursorPos int) {
 	// 清除当前行并重新开始
 	fmt.Print("\r\033[K")
 	fmt.Print(prompt)
-	fmt.Print(line)
-	// 将光标移动到正确位置
+	fmt.Print(string(line))
+	// 将光标移动到正确位置, 按光标之后字符的显示宽度左移
 	if cursorPos < len(line) {
-		fmt.Printf("\033[%dD", len(line)-cursorPos)
+		fmt.Printf("\033[%dD", displayWidth(string(line[cursorPos:])))
+	}
+}
+
+// moveTo 把光标从 from 移到 to, 返回新位置
+func (h *InputHandler) moveTo(line []rune, from, to int) int {
+	if to > from {
+		h.moveCursor(displayWidth(string(line[from:to])))
+	} else if to < from {
+		h.moveCursor(-displayWidth(string(line[to:from])))
 	}
+	return to
 }
 
-// moveCursor 移动光标
+// moveCursor 按显示列数移动光标
 func (h *InputHandler) moveCursor(direction int) {
 	if direction > 0 {
 		fmt.Printf("\033[%dC", direction)
-	} else {
+	} else if direction < 0 {
 		fmt.Printf("\033[%dD", -direction)
 	}
 }
 
+// displayWidth 字符串在终端中占的列数
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth 字符在终端中占的列数: 组合字符与零宽字符为0, 东亚宽字符与全角字符为2
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r):
+		return 0
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, // 谚文字母
+		r >= 0x2e80 && r <= 0x303e, // 中日韩部首、标点
+		r >= 0x3041 && r <= 0x33ff, // 假名、注音、中日韩兼容字符
+		r >= 0x3400 && r <= 0x4dbf, // 中日韩统一表意文字扩展A
+		r >= 0x4e00 && r <= 0x9fff, // 中日韩统一表意文字
+		r >= 0xa000 && r <= 0xa4cf, // 彝文
+		r >= 0xac00 && r <= 0xd7a3, // 谚文音节
+		r >= 0xf900 && r <= 0xfaff, // 中日韩兼容表意文字
+		r >= 0xfe30 && r <= 0xfe4f, // 中日韩兼容标点
+		r >= 0xff00 && r <= 0xff60, // 全角字符
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // 表情符号
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd: // 扩展B及以后
+		return 2
+	}
+	return 1
+}
+
 // saveCurrentLine 保存当前输入行
 func (h *InputHandler) saveCurrentLine(line string) {
 	h.currentLine = line
 }
 
-// SaveHistoryToFile 将历史命令保存到文件
+// SaveHistoryToFile 将历史命令保存到文件, 先写临时文件再改名
 func (h *InputHandler) SaveHistoryToFile(filename string) error {
-	file, err := os.Create(filename)
+	file, err := os.CreateTemp(filepath.Dir(filename), ".history-*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(file.Name())
+	w := bufio.NewWriter(file)
 	for _, cmd := range h.history {
-		_, err := file.WriteString(cmd + "\n")
-		if err != nil {
-			return err
-		}
+		w.WriteString(cmd + "\n")
 	}
-	return nil
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
 }
 
-// LoadAndExecHistoryFromFile 从文件加载命令并执行
-func LoadAndExecHistoryFromFile(filename string, execFunc func(string)) error {
+// LoadAndExecHistoryFromFile 从文件加载命令并执行, 跳过空行与 # 开头的注释
+// execFunc 收到行号与命令, 返回false时停止执行
+func LoadAndExecHistoryFromFile(filename string, execFunc func(lineNum int, line string) bool) error {
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	scanner := bufio.NewScanner(file)
+	return ExecLines(file, execFunc)
+}
+
+// ExecLines 逐行读取命令并执行, 规则与 LoadAndExecHistoryFromFile 相同
+func ExecLines(r io.Reader, execFunc func(lineNum int, line string) bool) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			execFunc(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !execFunc(lineNum, line) {
+			break
 		}
 	}
 	return scanner.Err()
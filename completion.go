@@ -0,0 +1,329 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// argCompleter 补全一个参数, 返回以 word 开头的候选
+type argCompleter func(word string) []string
+
+// subcommands 各命令的子命令
+var subcommands = map[string][]string{
+	"host":     {"add", "listen", "close"},
+	"switch":   {"add"},
+	"router":   {"add"},
+	"link":     {"add", "set", "del"},
+	"ip":       {"set"},
+	"route":    {"add"},
+	"vlan":     {"access", "trunk", "sub"},
+	"stp":      {"enable", "disable", "priority", "cost", "port-priority", "edge"},
+	"show":     {"hosts", "links", "arp", "routes", "mac", "vlan", "spanning-tree", "tcp", "clock", "config"},
+	"topology": {"load", "check", "save"},
+	"snapshot": {"save", "load", "list", "del"},
+	"capture":  {"start", "stop", "show", "save"},
+	"phy":      {"encode", "frame"},
+	"pcap":     {"read", "replay"},
+	"log":      {"level", "trace", "format"},
+	"expect":   {"arp", "mac", "stp", "route", "tcp", "capture", "clock"},
+}
+
+// argCompleters 命令(或命令与子命令)之后各位置参数的补全方式
+// 为 nil 或超出列表的参数不补全
+var argCompleters = map[string][]argCompleter{
+	"host listen":        {completeHosts},
+	"host close":         {completeHosts, completeIPs},
+	"host add":           {nil, nil, completeIPs},
+	"link add":           {completeEndpoints, completeEndpoints, completeLinkParams, completeLinkParams, completeLinkParams, completeLinkParams, completeLinkParams},
+	"link set":           {completeLinks, completeLinkParams, completeLinkParams, completeLinkParams, completeLinkParams, completeLinkParams},
+	"link del":           {completeLinks},
+	"ip set":             {completeEndpoints},
+	"route add":          {completeHosts, nil, completeIPs},
+	"show arp":           {completeHosts},
+	"show routes":        {completeHosts},
+	"vlan access":        {completeEndpoints},
+	"vlan trunk":         {completeEndpoints, completeWords("native=", "allowed="), completeWords("native=", "allowed=")},
+	"vlan sub":           {completeEndpoints},
+	"show mac":           {completeSwitches},
+	"show vlan":          {completeSwitches},
+	"show spanning-tree": {completeSwitches},
+	"stp enable":         {completeSwitches, completeWords("stp", "rstp")},
+	"stp disable":        {completeSwitches},
+	"stp priority":       {completeSwitches},
+	"stp cost":           {completeEndpoints, completeWords("auto")},
+	"stp port-priority":  {completeEndpoints},
+	"stp edge":           {completeEndpoints, completeWords("on", "off")},
+	"show tcp":           {completeHosts},
+	"topology load":      {completeFiles},
+	"topology check":     {completeFiles},
+	"topology save":      {completeFiles},
+	"snapshot save":      {completeSnapshots},
+	"snapshot load":      {completeSnapshots},
+	"snapshot del":       {completeSnapshots},
+	"run-script":         {completeFiles},
+	"help":               {completeCommands},
+	"send":               {completeHosts, completeIPs, completeWords("icmp", "udp", "tcp")},
+	"capture start":      {completeCaptureTargets, completeFiles, completeWords("fcs")},
+	"capture stop":       {completeCaptureTargets},
+	"capture show":       {completeCaptureTargets},
+	"capture save":       {completeCaptureTargets, completeFiles, completeWords("fcs")},
+	"phy encode":         {completeWords("nrz", "nrzi", "manchester", "4b5b", "8b10b")},
+	"phy frame":          {completeCaptureTargets},
+	"pcap read":          {completeFiles, completeWords("detail")},
+	"pcap replay":        {completeFiles, completeEndpoints, completeWords("speed=")},
+	"log level":          {completeWords("l2", "l3", "l4", "app", "trace", "debug", "info", "warn", "error"), completeWords("trace", "debug", "info", "warn", "error")},
+	"log trace":          {completeDevices, completeWords("on", "off")},
+	"log format":         {completeWords("console", "text", "json")},
+	"expect arp":         {completeHosts, completeWords("contains", "lacks"), completeIPs},
+	"expect mac":         {completeSwitches, completeWords("contains", "lacks")},
+	"expect stp":         {completeEndpoints, completeWords("root", "designated", "alternate", "backup", "forwarding", "blocking", "learning", "listening")},
+	"expect route":       {completeHosts, completeIPs, completeWords("via", "direct", "none"), completeIPs},
+	"expect tcp":         {completeHosts, completeWords("LISTEN", host.TCPSynSent, host.TCPSynReceived, host.TCPEstablished, host.TCPFinWait1, host.TCPFinWait2, host.TCPCloseWait, host.TCPLastAck, host.TCPTimeWait, host.TCPClosed)},
+	"expect capture":     {completeCaptureTargets, completeWords("count"), completeFilters},
+}
+
+// completeLine 补全光标前的输入
+// @param prefix string 光标前的文本
+// @return string 正在补全的词
+// @return []string 候选, 已排序去重
+func completeLine(prefix string) (string, []string) {
+	fields := strings.Fields(prefix)
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(prefix, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	simMu.Lock()
+	defer simMu.Unlock()
+	var candidates []string
+	switch {
+	case len(fields) == 0:
+		candidates = completeCommands(word)
+	case strings.ToLower(fields[0]) == "craft":
+		candidates = completeCraft(fields[1:], word)
+	default:
+		candidates = completeArgs(strings.ToLower(fields[0]), fields[1:], word)
+	}
+	// 候选可能是共享的静态列表, 过滤到新切片中
+	var matched []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matched = append(matched, c)
+		}
+	}
+	slices.Sort(matched)
+	return word, slices.Compact(matched)
+}
+
+// usageHint 没有候选时提示当前命令的用法
+// @param prefix string 光标前的文本
+// @return string 用法, 未知命令时为空
+func usageHint(prefix string) string {
+	fields := strings.Fields(prefix)
+	if len(fields) == 0 {
+		return ""
+	}
+	for _, cmd := range Commands {
+		if cmd.Name == strings.ToLower(fields[0]) {
+			return i18n.T("用法: %s", cmd.Usage)
+		}
+	}
+	return ""
+}
+
+// completeArgs 补全子命令或参数
+func completeArgs(cmd string, args []string, word string) []string {
+	key := cmd
+	if subs, ok := subcommands[cmd]; ok {
+		if len(args) == 0 {
+			return subs
+		}
+		key, args = cmd+" "+args[0], args[1:]
+	}
+	// 抓包目标 link <id> 占两个参数
+	if len(args) > 0 && args[0] == "link" && (strings.HasPrefix(key, "capture ") || key == "expect capture") {
+		if len(args) == 1 {
+			return completeLinks(word)
+		}
+		args = args[1:]
+	}
+	completers := argCompleters[key]
+	if len(args) >= len(completers) || completers[len(args)] == nil {
+		return nil
+	}
+	return completers[len(args)](word)
+}
+
+// completeCraft 补全 craft 的协议层与字段名, / 之后为下一层
+func completeCraft(args []string, word string) []string {
+	layer := ""
+	for i, arg := range args {
+		switch {
+		case arg == "/":
+			layer = ""
+		case arg == "inject":
+			if i == len(args)-1 {
+				return completeEndpoints(word)
+			}
+			return nil
+		case layer == "":
+			layer = arg
+		}
+	}
+	var names []string
+	if layer == "" {
+		for _, l := range craftLayers {
+			names = append(names, l.Name)
+		}
+		return names
+	}
+	if l := findCraftLayer(layer); l != nil && !strings.Contains(word, "=") {
+		for _, f := range l.Fields {
+			names = append(names, f+"=")
+		}
+	}
+	return append(names, "/", "inject")
+}
+
+// completeCommands 命令名称
+func completeCommands(string) []string {
+	var names []string
+	for _, cmd := range Commands {
+		names = append(names, cmd.Name)
+	}
+	return names
+}
+
+// completeWords 固定候选
+func completeWords(words ...string) argCompleter {
+	return func(string) []string { return words }
+}
+
+// completeHosts 主机与路由器名称
+func completeHosts(string) []string {
+	var names []string
+	for _, h := range host.HostList {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+// completeSwitches 交换机名称
+func completeSwitches(string) []string {
+	var names []string
+	for _, sw := range host.SwitchList {
+		names = append(names, sw.Name)
+	}
+	return names
+}
+
+// completeDevices 全部设备名称
+func completeDevices(string) []string {
+	var names []string
+	for _, dev := range host.DeviceList {
+		names = append(names, dev.DeviceName())
+	}
+	return names
+}
+
+// completeEndpoints 设备名称, 输入了 : 之后补全该设备的端口名称
+func completeEndpoints(word string) []string {
+	name, _, hasPort := strings.Cut(word, ":")
+	if !hasPort {
+		names := completeDevices(word)
+		if slices.Contains(names, word) {
+			names = append(names, word+":")
+		}
+		return names
+	}
+	dev := host.FindDevice(name)
+	if dev == nil {
+		return nil
+	}
+	var ports []string
+	for i := range dev.Ports() {
+		ports = append(ports, name+":"+dev.PortName(i))
+	}
+	return ports
+}
+
+// completeCaptureTargets 抓包目标: 设备、端口或 link
+func completeCaptureTargets(word string) []string {
+	return append(completeEndpoints(word), "link")
+}
+
+// completeIPs 各主机接口上已配置的IPv4地址
+func completeIPs(string) []string {
+	var ips []string
+	for _, h := range host.HostList {
+		for _, iface := range h.Interfaces {
+			if iface.IPv4Address != [4]byte{} {
+				ips = append(ips, level.FormatIPv4(iface.IPv4Address))
+			}
+		}
+	}
+	return ips
+}
+
+// completeLinks 链路编号
+func completeLinks(string) []string {
+	var ids []string
+	for _, l := range host.LinkList {
+		ids = append(ids, strconv.Itoa(l.ID))
+	}
+	return ids
+}
+
+// completeLinkParams 链路参数名
+func completeLinkParams(string) []string {
+	return []string{"delay=", "bw=", "loss=", "ber=", "code="}
+}
+
+// completeFilters 抓包过滤条件中的协议名与字段名
+func completeFilters(string) []string {
+	names := slices.Collect(maps.Keys(filterFieldKinds))
+	return append(names, slices.Collect(maps.Keys(filterProtocols))...)
+}
+
+// completeSnapshots 数据目录中的快照名称
+func completeSnapshots(string) []string {
+	paths, _ := filepath.Glob(filepath.Join(appConfig.DataDir, "*"+snapshotExt))
+	var names []string
+	for _, p := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(p), snapshotExt))
+	}
+	return names
+}
+
+// completeFiles 文件路径, 目录以 / 结尾
+func completeFiles(word string) []string {
+	dir, base := filepath.Split(word)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		p := dir + e.Name()
+		if e.IsDir() {
+			p += "/"
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompleteLine(t *testing.T) {
+	resetSimulator(t)
+	if _, err := loadTopology("examples/lab.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prefix string
+		word   string
+		want   []string
+	}{
+		{"sho", "sho", []string{"show"}},
+		{"show ", "", []string{"arp", "clock", "config", "hosts", "links", "mac", "routes", "spanning-tree", "tcp", "vlan"}},
+		{"show a", "a", []string{"arp"}},
+		{"show arp ", "", []string{"h1", "h2", "h3", "r1"}},
+		{"show mac ", "", []string{"s1"}},
+		{"link set ", "", []string{"1", "2", "3", "4"}},
+		{"link set 1 b", "b", []string{"ber=", "bw="}},
+		{"link add h1", "h1", []string{"h1", "h1:"}},
+		{"link add r1:", "r1:", []string{"r1:eth0", "r1:eth1"}},
+		{"send h1 192.168.2", "192.168.2", []string{"192.168.2.1", "192.168.2.30"}},
+		{"send h1 192.168.2.30 ", "", []string{"icmp", "tcp", "udp"}},
+		{"capture start l", "l", []string{"link"}},
+		{"capture stop link ", "", []string{"1", "2", "3", "4"}},
+		{"expect capture link 1 ", "", []string{"count"}},
+		{"expect tcp h3 EST", "EST", []string{"ESTABLISHED"}},
+		{"log level l", "l", []string{"l2", "l3", "l4"}},
+		{"topology load examples/lab.y", "examples/lab.y", []string{"examples/lab.yaml"}},
+		{"topology load exam", "exam", []string{"examples/"}},
+		{"craft ", "", nil},
+		{"craft eth src=", "src=", nil},
+		{"craft eth / ", "", nil},
+		{"show arp h1 ", "", nil},
+		{"nonsense ", "", nil},
+	}
+	for _, tt := range tests {
+		word, got := completeLine(tt.prefix)
+		if word != tt.word {
+			t.Errorf("%q: completing %q, want %q", tt.prefix, word, tt.word)
+		}
+		if tt.want != nil && !slices.Equal(got, tt.want) {
+			t.Errorf("%q: candidates %q, want %q", tt.prefix, got, tt.want)
+		}
+		if !slices.IsSorted(got) {
+			t.Errorf("%q: candidates %q are not sorted", tt.prefix, got)
+		}
+	}
+
+	// craft 补全协议层名与字段名
+	if _, got := completeLine("craft "); !slices.Contains(got, "eth") || !slices.Contains(got, "ip") {
+		t.Errorf("craft layers %q, want eth and ip", got)
+	}
+	if _, got := completeLine("craft eth "); !slices.Contains(got, "dst=") || !slices.Contains(got, "/") {
+		t.Errorf("craft eth fields %q, want dst= and /", got)
+	}
+	if _, got := completeLine("craft eth inject "); !slices.Contains(got, "h1") {
+		t.Errorf("craft inject targets %q, want device names", got)
+	}
+	if _, got := completeLine("show arp h1 "); len(got) != 0 {
+		t.Errorf("candidates %q past the last argument", got)
+	}
+}
+
+func TestUsageHint(t *testing.T) {
+	if hint := usageHint("expect arp "); !strings.HasPrefix(hint, "用法: expect") {
+		t.Errorf("usageHint(expect) = %q", hint)
+	}
+	if hint := usageHint("nonsense"); hint != "" {
+		t.Errorf("usageHint(nonsense) = %q, want empty", hint)
+	}
+	if hint := usageHint(""); hint != "" {
+		t.Errorf("usageHint() = %q, want empty", hint)
+	}
+}
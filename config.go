@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+	"osiweb-go/logging"
+)
+
+// defaultConfigFile 未指定 -config 时读取的配置文件, 不存在时使用默认配置
+const defaultConfigFile = "config.json"
+
+// envPrefix 环境变量前缀, 如 OSIWEB_HTTP_PORT
+const envPrefix = "OSIWEB_"
+
+// Config 配置文件内容
+type Config struct {
+	// 数据目录
+	DataDir string `json:"data_dir"`
+	// 日志级别 trace/debug/info/warn/error, 适用于所有子系统
+	LogLevel string `json:"log_level"`
+	// 单独设置的子系统级别, 如 {"l2": "debug"}
+	LogLevels map[string]string `json:"log_levels"`
+	// 日志格式 console/text/json
+	LogFormat string `json:"log_format"`
+	// 日志文件, 为空时输出到标准输出
+	LogFile string `json:"log_file"`
+	// 开启 trace 的设备
+	TraceHosts []string `json:"trace_hosts"`
+	// 抓包文件目录, 相对路径的抓包文件放在这里
+	CaptureDir string `json:"capture_dir"`
+	// 启动时加载的拓扑文件, 为空时不加载
+	Topology string `json:"topology"`
+	// 保存在数据目录中的命令历史条数, 0表示不保存
+	HistorySize int `json:"history_size"`
+	// 界面语言 zh/en
+	Lang string `json:"lang"`
+	// 模拟器设置
+	Simulator SimulatorConfig `json:"simulator"`
+	// HTTP 服务设置
+	HTTP HTTPConfig `json:"http"`
+	// 无界面运行的场景脚本, 只能由 -script 指定, 执行完后退出
+	Script string `json:"-"`
+	// 批量执行的命令, 以 ; 分隔, 只能由 -c 指定
+	Commands string `json:"-"`
+	// 批量执行的命令文件, 只能由 -f 指定, - 表示标准输入
+	BatchFile string `json:"-"`
+	// 批量执行时每条命令输出一行JSON, 只能由 -json 指定
+	JSONOutput bool `json:"-"`
+	// 批量执行时第一条命令失败后停止, 只能由 -e 指定
+	ErrExit bool `json:"-"`
+}
+
+// SimulatorConfig 模拟器设置
+type SimulatorConfig struct {
+	// 随机数种子, 相同种子的模拟结果相同
+	Seed int64 `json:"seed"`
+	// 时间模式 virtual/realtime
+	TimeMode string `json:"time_mode"`
+	// 新接口的MTU
+	MTU int `json:"mtu"`
+	// 新链路的默认参数
+	Link LinkConfig `json:"link"`
+	// 自动分配的IPv4网段, 如 10.0.0.0/24
+	IPv4Pool string `json:"ipv4_pool"`
+	// 自动分配的MAC地址前3字节, 如 02:00:00
+	MACPrefix string `json:"mac_prefix"`
+}
+
+// LinkConfig 链路默认参数
+type LinkConfig struct {
+	// 传播时延
+	Delay Duration `json:"delay"`
+	// 带宽(bit/s), 0表示不计发送时延
+	Bandwidth Bandwidth `json:"bandwidth"`
+	// 丢包率 0~1
+	Loss float64 `json:"loss"`
+}
+
+// HTTPConfig HTTP 服务设置
+type HTTPConfig struct {
+	// 监听地址, 为空表示所有地址
+	Listen string `json:"listen"`
+	// 端口, 0表示不启动
+	Port int `json:"port"`
+}
+
+// Duration 配置中的时长, 写作 "1ms" 形式的字符串
+type Duration time.Duration
+
+// UnmarshalJSON 解析 "1ms" 形式的时长
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf(i18n.T("时长应写成字符串, 如 \"1ms\": %s"), data)
+	}
+	return d.Set(s)
+}
+
+// MarshalJSON 输出 "1ms" 形式的字符串
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Set 解析时长, 也用于环境变量与命令行
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf(i18n.T("时长格式错误: %s"), s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Bandwidth 配置中的带宽, 可以写数字或 "100M" 形式的字符串
+type Bandwidth int64
+
+// UnmarshalJSON 解析数字或带 K/M/G 后缀的字符串
+func (b *Bandwidth) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	return b.Set(s)
+}
+
+// MarshalJSON 输出 "100M" 形式的字符串, 0 输出 "inf"
+func (b Bandwidth) MarshalJSON() ([]byte, error) {
+	return json.Marshal(host.FormatBandwidth(int64(b)))
+}
+
+// Set 解析带宽, 也用于环境变量与命令行
+func (b *Bandwidth) Set(s string) error {
+	v, err := parseBandwidth(s)
+	if err != nil {
+		return err
+	}
+	*b = Bandwidth(v)
+	return nil
+}
+
+// appConfig 当前生效的配置
+var appConfig = defaultConfig()
+
+// defaultConfig 默认配置
+func defaultConfig() *Config {
+	return &Config{
+		DataDir:     "./data",
+		LogLevel:    "info",
+		LogLevels:   map[string]string{},
+		LogFormat:   logging.FormatConsole,
+		TraceHosts:  []string{},
+		CaptureDir:  ".",
+		HistorySize: 1000,
+		Lang:        i18n.ZH,
+		Simulator: SimulatorConfig{
+			Seed:     1,
+			TimeMode: host.TimeVirtual,
+			MTU:      1500,
+			Link: LinkConfig{
+				Delay:     Duration(time.Millisecond),
+				Bandwidth: 100_000_000,
+			},
+			IPv4Pool:  "10.0.0.0/24",
+			MACPrefix: "02:00:00",
+		},
+		HTTP: HTTPConfig{Listen: "127.0.0.1", Port: 8080},
+	}
+}
+
+// setting 可以由环境变量与命令行覆盖的配置项
+type setting struct {
+	// 命令行参数名, 环境变量为 OSIWEB_ 加大写参数名, - 换成 _
+	name string
+	// 说明
+	usage string
+	// 写入配置
+	set func(cfg *Config, value string) error
+}
+
+// settings 全部可覆盖的配置项
+var settings = []setting{
+	{"data-dir", "数据目录", func(c *Config, v string) error { c.DataDir = v; return nil }},
+	{"log-level", "日志级别 trace/debug/info/warn/error", func(c *Config, v string) error { c.LogLevel = v; return nil }},
+	{"log-levels", "子系统日志级别, 如 l2=debug,l4=trace", setLogLevels},
+	{"log-format", "日志格式 console/text/json", func(c *Config, v string) error { c.LogFormat = v; return nil }},
+	{"log-file", "日志文件, 为空时输出到标准输出", func(c *Config, v string) error { c.LogFile = v; return nil }},
+	{"trace-hosts", "开启 trace 的设备, 逗号分隔", func(c *Config, v string) error { c.TraceHosts = splitList(v); return nil }},
+	{"capture-dir", "抓包文件目录", func(c *Config, v string) error { c.CaptureDir = v; return nil }},
+	{"topology", "启动时加载的拓扑文件 (JSON/YAML)", func(c *Config, v string) error { c.Topology = v; return nil }},
+	{"history-size", "保存的命令历史条数, 0表示不保存", func(c *Config, v string) error { return setInt(&c.HistorySize, v) }},
+	{"lang", "界面语言 zh/en", func(c *Config, v string) error { c.Lang = v; return nil }},
+	{"seed", "随机数种子", func(c *Config, v string) error { return setInt64(&c.Simulator.Seed, v) }},
+	{"time-mode", "时间模式 virtual/realtime", func(c *Config, v string) error { c.Simulator.TimeMode = v; return nil }},
+	{"mtu", "新接口的MTU", func(c *Config, v string) error { return setInt(&c.Simulator.MTU, v) }},
+	{"link-delay", "新链路的传播时延", func(c *Config, v string) error { return c.Simulator.Link.Delay.Set(v) }},
+	{"link-bandwidth", "新链路的带宽, 支持 K/M/G 后缀", func(c *Config, v string) error { return c.Simulator.Link.Bandwidth.Set(v) }},
+	{"link-loss", "新链路的丢包率 0~1", func(c *Config, v string) error { return setFloat(&c.Simulator.Link.Loss, v) }},
+	{"ipv4-pool", "自动分配的IPv4网段", func(c *Config, v string) error { c.Simulator.IPv4Pool = v; return nil }},
+	{"mac-prefix", "自动分配的MAC地址前3字节", func(c *Config, v string) error { c.Simulator.MACPrefix = v; return nil }},
+	{"http-listen", "HTTP 监听地址", func(c *Config, v string) error { c.HTTP.Listen = v; return nil }},
+	{"http-port", "HTTP 端口, 0表示不启动", func(c *Config, v string) error { return setInt(&c.HTTP.Port, v) }},
+}
+
+// envName 配置项对应的环境变量名
+func (s setting) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+// setLogLevels 解析 l2=debug,l4=trace, 与配置文件中的设置合并
+func setLogLevels(c *Config, v string) error {
+	levels := make(map[string]string)
+	for k, l := range c.LogLevels {
+		levels[k] = l
+	}
+	for _, item := range splitList(v) {
+		sub, l, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf(i18n.T("应为 子系统=级别: %s"), item)
+		}
+		levels[sub] = l
+	}
+	c.LogLevels = levels
+	return nil
+}
+
+// splitList 逗号分隔的列表, 忽略空项
+func splitList(v string) []string {
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func setInt(p *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf(i18n.T("应为整数: %s"), v)
+	}
+	*p = n
+	return nil
+}
+
+func setInt64(p *int64, v string) error {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf(i18n.T("应为整数: %s"), v)
+	}
+	*p = n
+	return nil
+}
+
+func setFloat(p *float64, v string) error {
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf(i18n.T("应为数字: %s"), v)
+	}
+	*p = n
+	return nil
+}
+
+// helpLang 打印参数说明时配置尚未读取, 语言取自 -lang 参数或 OSIWEB_LANG 环境变量
+func helpLang(args []string) string {
+	lang := os.Getenv(envPrefix + "LANG")
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case name != "lang" || !strings.HasPrefix(arg, "-"):
+		case hasValue:
+			lang = value
+		case i+1 < len(args):
+			lang = args[i+1]
+		}
+	}
+	return lang
+}
+
+// printFlags 按所选语言打印命令行参数说明
+// @param envNames 参数对应的环境变量
+// @param lang 界面语言, 无效时使用当前语言
+func printFlags(fs *flag.FlagSet, envNames map[string]string, lang string) {
+	if slices.Contains(i18n.Langs, lang) {
+		prev := i18n.Lang()
+		i18n.SetLang(lang)
+		defer i18n.SetLang(prev)
+	}
+	out := fs.Output()
+	fmt.Fprintf(out, i18n.T("用法: %s [参数]\n"), fs.Name())
+	fs.VisitAll(func(f *flag.Flag) {
+		kind, usage := flag.UnquoteUsage(f)
+		usage = i18n.T(usage)
+		if env, ok := envNames[f.Name]; ok {
+			usage = i18n.T("%s (环境变量 %s)", usage, env)
+		}
+		if kind != "" {
+			kind = " " + kind
+		}
+		fmt.Fprintf(out, "  -%s%s\n    \t%s\n", f.Name, kind, usage)
+	})
+}
+
+// loadConfig 读取配置, 优先级从低到高: 默认值、配置文件、环境变量、命令行参数
+// @param args []string 命令行参数, 不含程序名
+// @return *Config, error 参数为 -h 时返回 flag.ErrHelp
+func loadConfig(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("osiweb-go", flag.ContinueOnError)
+	// 参数对应的环境变量, 打印说明时附上
+	envNames := map[string]string{"config": envPrefix + "CONFIG"}
+	path := fs.String("config", "", "配置文件, 默认为 "+defaultConfigFile)
+	script := fs.String("script", "", "无界面运行场景脚本, 断言全部通过时退出码为0, 否则为1")
+	commands := fs.String("c", "", "批量执行以 ; 分隔的命令后退出")
+	batchFile := fs.String("f", "", "批量执行文件中的命令后退出, - 表示标准输入")
+	jsonOutput := fs.Bool("json", false, "批量执行时每条命令输出一行JSON")
+	errExit := fs.Bool("e", false, "批量执行时第一条命令失败后停止")
+	// 先记下命令行的值, 读完配置文件与环境变量后再写入
+	flags := make(map[string]string)
+	for _, s := range settings {
+		name := s.name
+		envNames[name] = s.envName()
+		fs.Func(name, s.usage, func(v string) error {
+			if err := s.set(defaultConfig(), v); err != nil {
+				return err
+			}
+			flags[name] = v
+			return nil
+		})
+	}
+	fs.Usage = func() { printFlags(fs, envNames, helpLang(args)) }
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf(i18n.T("多余的参数: %s"), strings.Join(fs.Args(), " "))
+	}
+	modes := 0
+	for _, v := range []string{*script, *commands, *batchFile} {
+		if v != "" {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, errors.New(i18n.T("-script、-c 与 -f 只能指定一个"))
+	}
+
+	cfg := defaultConfig()
+	file := *path
+	if file == "" {
+		file = os.Getenv(envPrefix + "CONFIG")
+	}
+	if file != "" {
+		if err := cfg.readFile(file); err != nil {
+			return nil, err
+		}
+	} else if err := cfg.readFile(defaultConfigFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.envName()); ok {
+			if err := s.set(cfg, v); err != nil {
+				return nil, fmt.Errorf(i18n.T("环境变量 %s: %v"), s.envName(), err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flags[s.name]; ok {
+			s.set(cfg, v)
+		}
+	}
+	// 先切换语言, 配置错误按所选语言显示
+	if slices.Contains(i18n.Langs, cfg.Lang) {
+		i18n.SetLang(cfg.Lang)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	cfg.Script, cfg.Commands, cfg.BatchFile, cfg.JSONOutput = *script, *commands, *batchFile, *jsonOutput
+	cfg.ErrExit = *errExit
+	return cfg, nil
+}
+
+// readFile 用JSON配置文件覆盖当前配置, 未知字段视为错误
+func (cfg *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// validate 检查全部配置项, 一次报告所有错误
+func (cfg *Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, errors.New(i18n.T(format, args...)))
+		}
+	}
+	check(cfg.DataDir != "", "data_dir 不能为空")
+	check(cfg.CaptureDir != "", "capture_dir 不能为空")
+	check(cfg.HistorySize >= 0, "history_size 不能为负数: %d", cfg.HistorySize)
+	check(slices.Contains(i18n.Langs, cfg.Lang), "lang 应为 zh/en: %q", cfg.Lang)
+	_, err := logging.ParseLevel(cfg.LogLevel)
+	check(err == nil, "log_level 应为 trace/debug/info/warn/error: %q", cfg.LogLevel)
+	for _, sub := range slices.Sorted(maps.Keys(cfg.LogLevels)) {
+		l := cfg.LogLevels[sub]
+		check(slices.Contains(logging.Subsystems, sub), "log_levels 的子系统应为 l2/l3/l4/app: %q", sub)
+		_, err := logging.ParseLevel(l)
+		check(err == nil, "log_levels.%s 应为 trace/debug/info/warn/error: %q", sub, l)
+	}
+	switch cfg.LogFormat {
+	case logging.FormatConsole, logging.FormatText, logging.FormatJSON:
+	default:
+		check(false, "log_format 应为 console/text/json: %q", cfg.LogFormat)
+	}
+	sim := cfg.Simulator
+	check(sim.TimeMode == host.TimeVirtual || sim.TimeMode == host.TimeRealtime,
+		"simulator.time_mode 应为 virtual 或 realtime: %q", sim.TimeMode)
+	// 不超过以太网帧的最大载荷, IPv4要求至少68
+	check(sim.MTU >= 68 && sim.MTU <= level.MaxDataSize, "simulator.mtu 应在68~%d之间: %d", level.MaxDataSize, sim.MTU)
+	check(sim.Link.Delay >= 0, "simulator.link.delay 不能为负: %s", time.Duration(sim.Link.Delay))
+	check(sim.Link.Bandwidth >= 0, "simulator.link.bandwidth 不能为负: %d", sim.Link.Bandwidth)
+	check(sim.Link.Loss >= 0 && sim.Link.Loss <= 1, "simulator.link.loss 应在0~1之间: %v", sim.Link.Loss)
+	if _, _, err := cfg.addressPool(); err != nil {
+		errs = append(errs, err)
+	}
+	check(cfg.HTTP.Port >= 0 && cfg.HTTP.Port <= 65535, "http.port 应在0~65535之间: %d", cfg.HTTP.Port)
+	check(cfg.HTTP.Listen == "" || net.ParseIP(cfg.HTTP.Listen) != nil || cfg.HTTP.Listen == "localhost",
+		"http.listen 应为IP地址或 localhost: %q", cfg.HTTP.Listen)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s\n%w", i18n.T("配置错误:"), errors.Join(errs...))
+	}
+	return nil
+}
+
+// addressPool 解析地址池
+// @return [3]byte MAC地址前缀
+// @return *net.IPNet IPv4网段
+func (cfg *Config) addressPool() ([3]byte, *net.IPNet, error) {
+	var prefix [3]byte
+	parts := strings.Split(cfg.Simulator.MACPrefix, ":")
+	if len(parts) != 3 {
+		return prefix, nil, fmt.Errorf(i18n.T("simulator.mac_prefix 应为3个字节, 如 02:00:00: %q"), cfg.Simulator.MACPrefix)
+	}
+	for i, p := range parts {
+		b, err := strconv.ParseUint(p, 16, 8)
+		if err != nil {
+			return prefix, nil, fmt.Errorf(i18n.T("simulator.mac_prefix 格式错误: %q"), cfg.Simulator.MACPrefix)
+		}
+		prefix[i] = byte(b)
+	}
+	if prefix[0]&0x01 != 0 {
+		return prefix, nil, fmt.Errorf(i18n.T("simulator.mac_prefix 不能是组播地址: %q"), cfg.Simulator.MACPrefix)
+	}
+	_, pool, err := net.ParseCIDR(cfg.Simulator.IPv4Pool)
+	if err != nil || pool.IP.To4() == nil {
+		return prefix, nil, fmt.Errorf(i18n.T("simulator.ipv4_pool 应为IPv4网段, 如 10.0.0.0/24: %q"), cfg.Simulator.IPv4Pool)
+	}
+	if ones, _ := pool.Mask.Size(); ones < 8 || ones > 30 {
+		return prefix, nil, fmt.Errorf(i18n.T("simulator.ipv4_pool 前缀长度应在8~30之间: %q"), cfg.Simulator.IPv4Pool)
+	}
+	return prefix, pool, nil
+}
+
+// apply 把日志与模拟器设置写入各个包
+func (cfg *Config) apply() error {
+	if err := i18n.SetLang(cfg.Lang); err != nil {
+		return err
+	}
+	if err := cfg.applyLogging(); err != nil {
+		return err
+	}
+	sim := cfg.Simulator
+	host.Seed(sim.Seed)
+	host.TimeMode = sim.TimeMode
+	host.DefaultMTU = sim.MTU
+	host.DefaultLinkDelay = time.Duration(sim.Link.Delay)
+	host.DefaultLinkBandwidth = int64(sim.Link.Bandwidth)
+	host.DefaultLinkLoss = sim.Link.Loss
+	macPrefix, pool, err := cfg.addressPool()
+	if err != nil {
+		return err
+	}
+	ones, _ := pool.Mask.Size()
+	return host.SetAddressPool(macPrefix, [4]byte(pool.IP.To4()), ones)
+}
+
+// logFile 当前打开的日志文件
+var logFile *os.File
+
+// applyLogging 设置日志输出、各子系统级别与 trace 设备
+func (cfg *Config) applyLogging() error {
+	var w io.Writer = os.Stdout
+	if cfg.LogFile != "" {
+		file, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		if logFile != nil {
+			logFile.Close()
+		}
+		logFile, w = file, file
+	}
+	if err := logging.SetOutput(w, cfg.LogFormat); err != nil {
+		return err
+	}
+	l, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	logging.SetLevel("", l)
+	for sub, name := range cfg.LogLevels {
+		l, err := logging.ParseLevel(name)
+		if err != nil {
+			return err
+		}
+		if err := logging.SetLevel(sub, l); err != nil {
+			return err
+		}
+	}
+	for _, h := range cfg.TraceHosts {
+		logging.Trace(h, true)
+	}
+	return nil
+}
+
+// capturePath 抓包文件只能写在抓包目录中, 不能是绝对路径或包含 .., 需要时创建目录
+func capturePath(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf(i18n.T("抓包文件只能是抓包目录中的相对路径: %q"), name)
+	}
+	if err := os.MkdirAll(appConfig.CaptureDir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(appConfig.CaptureDir, name), nil
+}
+
+// findCaptureFile 读取抓包文件时先按原路径查找, 找不到再到抓包目录中查找
+func findCaptureFile(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	if _, err := os.Stat(name); err != nil {
+		if p := filepath.Join(appConfig.CaptureDir, name); p != name {
+			if _, err := os.Stat(p); err == nil {
+				return p
+			}
+		}
+	}
+	return name
+}
+
+// showConfig 打印当前配置
+func showConfig() {
+	data, _ := json.MarshalIndent(appConfig, "", "  ")
+	fmt.Println(string(data))
+}
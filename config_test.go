@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"osiweb-go/i18n"
+)
+
+// writeConfigFile 在临时目录中写入配置文件
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 优先级从低到高: 默认值、配置文件、环境变量、命令行参数
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"data_dir": "./file-data",
+		"simulator": {"seed": 5, "mtu": 1400, "link": {"delay": "2ms", "bandwidth": "1G"}},
+		"http": {"port": 9000}
+	}`)
+	t.Setenv("OSIWEB_SEED", "7")
+	t.Setenv("OSIWEB_MTU", "1300")
+	cfg, err := loadConfig([]string{"-config", path, "-mtu", "1200", "-link-loss", "0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := defaultConfig()
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"data_dir from the file", cfg.DataDir, "./file-data"},
+		{"seed from the environment", cfg.Simulator.Seed, int64(7)},
+		{"mtu from the command line", cfg.Simulator.MTU, 1200},
+		{"link.loss from the command line", cfg.Simulator.Link.Loss, 0.5},
+		{"link.delay from the file", time.Duration(cfg.Simulator.Link.Delay), 2 * time.Millisecond},
+		{"link.bandwidth from the file", cfg.Simulator.Link.Bandwidth, Bandwidth(1_000_000_000)},
+		{"http.port from the file", cfg.HTTP.Port, 9000},
+		{"capture_dir default", cfg.CaptureDir, def.CaptureDir},
+		{"ipv4_pool default", cfg.Simulator.IPv4Pool, def.Simulator.IPv4Pool},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// 环境变量指定配置文件
+	t.Setenv("OSIWEB_CONFIG", path)
+	cfg, err = loadConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir != "./file-data" || cfg.Simulator.MTU != 1300 {
+		t.Errorf("OSIWEB_CONFIG: data_dir %q mtu %d, want ./file-data 1300", cfg.DataDir, cfg.Simulator.MTU)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	good := writeConfigFile(t, `{}`)
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		// 错误信息中应出现的配置项
+		want []string
+	}{
+		{"unknown field", []string{"-config", writeConfigFile(t, `{"simulator": {"mtuu": 1500}}`)}, nil, []string{"mtuu"}},
+		{"missing file", []string{"-config", filepath.Join(t.TempDir(), "nope.json")}, nil, nil},
+		{"bad flag value", []string{"-config", good, "-mtu", "big"}, nil, nil},
+		{"bad environment value", []string{"-config", good}, map[string]string{"OSIWEB_SEED": "x"}, []string{"OSIWEB_SEED"}},
+		{"extra arguments", []string{"-config", good, "extra"}, nil, []string{"extra"}},
+		{"all errors at once", []string{"-config", writeConfigFile(t, `{
+			"log_level": "loud",
+			"simulator": {"mtu": 10, "time_mode": "fast", "link": {"loss": 2}, "ipv4_pool": "10.0.0.0/31", "mac_prefix": "01:00:00"},
+			"http": {"port": 70000, "listen": "example.com"}
+		}`)}, nil, []string{"log_level", "simulator.mtu", "simulator.time_mode", "simulator.link.loss",
+			"simulator.mac_prefix", "http.port", "http.listen"}},
+		{"jumbo mtu", []string{"-config", good, "-mtu", "9000"}, nil, []string{"simulator.mtu"}},
+		{"bad duration", []string{"-config", good, "-link-delay", "soon"}, nil, nil},
+		{"bad bandwidth", []string{"-config", writeConfigFile(t, `{"simulator": {"link": {"bandwidth": "fast"}}}`)}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := loadConfig(tt.args)
+			if err == nil {
+				t.Fatal("loadConfig succeeded")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %s", err, want)
+				}
+			}
+		})
+	}
+	if _, err := loadConfig([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: %v, want flag.ErrHelp", err)
+	}
+}
+
+func TestPrintFlags(t *testing.T) {
+	tests := []struct {
+		env  string
+		args []string
+		want string
+	}{
+		{"", []string{"-h"}, ""},
+		{"en", []string{"-h"}, "en"},
+		{"", []string{"-lang", "en", "-h"}, "en"},
+		{"en", []string{"--lang=zh", "-h"}, "zh"},
+	}
+	for _, tt := range tests {
+		t.Setenv("OSIWEB_LANG", tt.env)
+		if got := helpLang(tt.args); got != tt.want {
+			t.Errorf("helpLang(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	fs := flag.NewFlagSet("osiweb-go", flag.ContinueOnError)
+	var out strings.Builder
+	fs.SetOutput(&out)
+	fs.Int("mtu", 0, "新接口的MTU")
+	fs.Bool("e", false, "批量执行时第一条命令失败后停止")
+	printFlags(fs, map[string]string{"mtu": "OSIWEB_MTU"}, "en")
+	for _, want := range []string{
+		"Usage: osiweb-go [flags]",
+		"-e\n    \tstop at the first failed command in batch mode",
+		"-mtu int\n    \tMTU of new interfaces (environment variable OSIWEB_MTU)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("help output missing %q:\n%s", want, out.String())
+		}
+	}
+	if i18n.Lang() != i18n.ZH {
+		t.Errorf("language after printFlags = %s, want %s", i18n.Lang(), i18n.ZH)
+	}
+}
+
+func TestBandwidthSet(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Bandwidth
+	}{
+		{"0", 0},
+		{"64000", 64000},
+		{"10K", 10_000},
+		{"100M", 100_000_000},
+		{"1G", 1_000_000_000},
+	}
+	for _, tt := range tests {
+		var b Bandwidth
+		if err := b.Set(tt.in); err != nil || b != tt.want {
+			t.Errorf("Set(%q) = %d, %v, want %d", tt.in, b, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "fast", "-1M", "1T"} {
+		var b Bandwidth
+		if err := b.Set(in); err == nil {
+			t.Errorf("Set(%q) = %d, want an error", in, b)
+		}
+	}
+}
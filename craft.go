@@ -0,0 +1,574 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// craftSpec 一层协议的字段赋值, 如 arp op=1 tpa=10.0.0.2
+type craftSpec struct {
+	layer  string
+	fields map[string]string
+}
+
+// craftContext 构造过程中各层共享的地址信息
+type craftContext struct {
+	srcMAC [6]byte
+	srcIP  [4]byte
+	dstIP  [4]byte
+}
+
+// craftLayer 可构造的协议层
+type craftLayer struct {
+	// 层名称
+	Name string
+	// 可设置的字段
+	Fields []string
+	// 字段说明
+	Description string
+	// 根据字段与上层数据构造本层
+	build func(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error)
+}
+
+// craftLayers 支持的协议层, 自下而上
+var craftLayers = []craftLayer{
+	{
+		Name:        "eth",
+		Fields:      []string{"dst", "src", "type"},
+		Description: "Ethernet II: dst/src MAC, type 如 0x0806 (缺省按上层推断)",
+		build:       buildEth,
+	},
+	{
+		Name:        "vlan",
+		Fields:      []string{"id", "pcp", "dei", "type"},
+		Description: "802.1Q: id VLAN编号, pcp 优先级 0~7, dei 可丢弃 0/1, type 缺省按上层推断",
+		build:       buildVLAN,
+	},
+	{
+		Name:        "llc",
+		Fields:      []string{"dsap", "ssap", "ctrl"},
+		Description: "802.2 LLC: dsap/ssap 服务访问点如 0x42 (STP), ctrl 缺省 0x03 (UI); 下层类型字段为长度",
+		build:       buildLLC,
+	},
+	{
+		Name:        "snap",
+		Fields:      []string{"oui", "pid"},
+		Description: "LLC SNAP (AA-AA-03): oui 组织代码缺省0, pid 缺省按上层推断",
+		build:       buildSNAP,
+	},
+	{
+		Name:        "stp",
+		Fields:      []string{"type", "flags", "root", "cost", "bridge", "port"},
+		Description: "生成树BPDU: type config/rst/tcn 或数值, root/bridge 网桥标识如 32768/02:b0:00:00:00:01 (缺省为源MAC), port 缺省 0x8001; 下层应为 llc",
+		build:       buildSTP,
+	},
+	{
+		Name:        "arp",
+		Fields:      []string{"op", "sha", "spa", "tha", "tpa"},
+		Description: "ARP: op 1请求/2应答, sha/tha MAC, spa/tpa IPv4",
+		build:       buildARP,
+	},
+	{
+		Name:        "ip",
+		Fields:      []string{"src", "dst", "ttl", "proto", "id", "tos"},
+		Description: "IPv4: proto 缺省按上层推断",
+		build:       buildIPv4,
+	},
+	{
+		Name:        "icmp",
+		Fields:      []string{"type", "code", "id", "seq", "data"},
+		Description: "ICMP: type 8请求/0应答",
+		build:       buildICMP,
+	},
+	{
+		Name:        "udp",
+		Fields:      []string{"sport", "dport", "data"},
+		Description: "UDP",
+		build:       buildUDP,
+	},
+	{
+		Name:        "tcp",
+		Fields:      []string{"sport", "dport", "seq", "ack", "flags", "win", "data"},
+		Description: "TCP: flags 如 S、SA、SYN,ACK 或数值",
+		build:       buildTCP,
+	},
+	{
+		Name:        "raw",
+		Fields:      []string{"data", "hex"},
+		Description: "原始负载: data 文本或 hex 十六进制",
+		build:       buildRaw,
+	},
+}
+
+// craftLayerArgs 各协议层的字段说明, 列在 craft 命令的帮助中
+func craftLayerArgs() []Arg {
+	args := make([]Arg, len(craftLayers))
+	for i, l := range craftLayers {
+		args[i] = Arg{Name: l.Name, Description: l.Description}
+	}
+	return args
+}
+
+// findCraftLayer 按名称查找协议层
+func findCraftLayer(name string) *craftLayer {
+	for i := range craftLayers {
+		if craftLayers[i].Name == name {
+			return &craftLayers[i]
+		}
+	}
+	return nil
+}
+
+// cmdCraft 逐层构造一帧, 显示十六进制与解码结果, 可选注入主机端口
+// @param args []string 以 / 分隔的各层, 末尾可带 inject <dev>[:port]
+func cmdCraft(args []string) {
+	if len(args) == 0 {
+		printUsage("craft")
+		return
+	}
+	var injectTo string
+	if n := len(args); n >= 2 && args[n-2] == "inject" {
+		injectTo = args[n-1]
+		args = args[:n-2]
+	}
+	specs, err := parseCraftSpecs(args)
+	if err != nil {
+		printError(err)
+		return
+	}
+	var dev host.Device
+	port := 0
+	if injectTo != "" {
+		if dev, port, err = host.ParseEndpoint(injectTo); err != nil {
+			printError(err)
+			return
+		}
+		if port < 0 {
+			port = 0
+		}
+		if len(dev.Ports()) == 0 {
+			printError(fmt.Sprintf(i18n.T("%s 没有端口"), dev.DeviceName()))
+			return
+		}
+	}
+	frame, err := craftFrame(specs, dev, port)
+	if err != nil {
+		printError(err)
+		return
+	}
+	fmt.Println(level.Summarize(frame))
+	showDissection(frame, true)
+	if dev != nil {
+		select {
+		case dev.Ports()[port] <- frame:
+			fmt.Printf(i18n.T("已注入 %s:%s, 输入 run 或 step 推进模拟\n"), dev.DeviceName(), dev.PortName(port))
+		default:
+			fmt.Println(i18n.T("端口发送队列已满"))
+		}
+	}
+}
+
+// parseCraftSpecs 解析 "eth dst=... / arp op=1" 形式的参数
+func parseCraftSpecs(args []string) ([]craftSpec, error) {
+	var specs []craftSpec
+	expectLayer := true
+	for _, tok := range args {
+		if tok == "/" {
+			if expectLayer {
+				return nil, errors.New(i18n.T("'/' 前缺少协议层"))
+			}
+			expectLayer = true
+			continue
+		}
+		if expectLayer {
+			layer := findCraftLayer(strings.ToLower(tok))
+			if layer == nil {
+				return nil, fmt.Errorf(i18n.T("未知协议层: %s"), tok)
+			}
+			specs = append(specs, craftSpec{layer: layer.Name, fields: make(map[string]string)})
+			expectLayer = false
+			continue
+		}
+		key, value, ok := strings.Cut(tok, "=")
+		if !ok {
+			return nil, fmt.Errorf(i18n.T("字段格式应为 name=value: %s"), tok)
+		}
+		spec := specs[len(specs)-1]
+		if !containsString(findCraftLayer(spec.layer).Fields, key) {
+			return nil, fmt.Errorf(i18n.T("%s 没有字段 %s, 可用字段: %s"), spec.layer, key,
+				strings.Join(findCraftLayer(spec.layer).Fields, " "))
+		}
+		spec.fields[key] = value
+	}
+	if expectLayer {
+		return nil, errors.New(i18n.T("'/' 后缺少协议层"))
+	}
+	return specs, nil
+}
+
+// craftFrame 自上而下逐层封装, 最底层不是 eth 时自动补一层
+func craftFrame(specs []craftSpec, dev host.Device, port int) ([]byte, error) {
+	if specs[0].layer != "eth" {
+		specs = append([]craftSpec{{layer: "eth", fields: map[string]string{}}}, specs...)
+	}
+	ctx := &craftContext{dstIP: [4]byte{255, 255, 255, 255}}
+	if h, ok := dev.(*host.BaseHost); ok {
+		ctx.srcMAC = h.Interfaces[port].MACAddress
+		ctx.srcIP = h.Interfaces[port].IPv4Address
+	}
+	// 先解析下层地址, 上层的默认值与校验和依赖它们
+	for _, spec := range specs {
+		var err error
+		switch spec.layer {
+		case "eth":
+			err = parseField(spec, "src", &ctx.srcMAC)
+		case "ip":
+			if err = parseField(spec, "src", &ctx.srcIP); err == nil {
+				err = parseField(spec, "dst", &ctx.dstIP)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// BPDU 没有类型字段可用, 只能封装在LLC中, 由LLC下层写入802.3长度
+	for i, spec := range specs {
+		if spec.layer == "stp" && specs[i-1].layer != "llc" {
+			return nil, fmt.Errorf(i18n.T("stp 下层应为 llc, 而不是 %s"), specs[i-1].layer)
+		}
+	}
+	var payload []byte
+	upper := ""
+	for i := len(specs) - 1; i >= 0; i-- {
+		var err error
+		payload, err = findCraftLayer(specs[i].layer).build(ctx, specs[i], payload, upper)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", specs[i].layer, err)
+		}
+		upper = specs[i].layer
+	}
+	return payload, nil
+}
+
+// parseField 按目标类型解析字段, 字段未设置时保持默认值
+func parseField(spec craftSpec, name string, out any) error {
+	value, ok := spec.fields[name]
+	if !ok {
+		return nil
+	}
+	var err error
+	switch p := out.(type) {
+	case *[6]byte:
+		*p, err = level.ParseMAC(value)
+	case *[4]byte:
+		*p, err = level.ParseIPv4(value)
+	case *uint8:
+		var v uint64
+		v, err = strconv.ParseUint(value, 0, 8)
+		*p = uint8(v)
+	case *uint16:
+		var v uint64
+		v, err = strconv.ParseUint(value, 0, 16)
+		*p = uint16(v)
+	case *uint32:
+		var v uint64
+		v, err = strconv.ParseUint(value, 0, 32)
+		*p = uint32(v)
+	case *[]byte:
+		*p = []byte(value)
+	}
+	if err != nil {
+		return fmt.Errorf(i18n.T("字段 %s=%s 无效"), name, value)
+	}
+	return nil
+}
+
+// buildEth 构造以太网帧, 类型缺省按上层推断
+func buildEth(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	dst := level.BroadcastMAC
+	if err := parseField(spec, "dst", &dst); err != nil {
+		return nil, err
+	}
+	etherType := upperEtherType(upper, payload)
+	if err := parseField(spec, "type", &etherType); err != nil {
+		return nil, err
+	}
+	frame := level.NewEthernet2(dst, ctx.srcMAC, "", payload)
+	frame.SetEtherType(etherType)
+	return frame.Serialize(), nil
+}
+
+// upperEtherType 按上层推断类型字段, QinQ 的外层标签可用 eth type=0x88a8 指定; 上层为LLC时是802.3长度字段
+func upperEtherType(upper string, payload []byte) uint16 {
+	switch upper {
+	case "llc", "snap":
+		return uint16(len(payload))
+	case "vlan":
+		return level.EtherTypeVLAN
+	case "arp":
+		return level.EtherTypeARP
+	case "ip":
+		return level.EtherTypeIPv4
+	}
+	return 0
+}
+
+// buildVLAN 构造 802.1Q 标签, 输出标签控制信息与内层类型, TPID 由下一层的类型字段给出
+func buildVLAN(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	id, pcp, dei := uint16(1), uint8(0), uint8(0)
+	etherType := upperEtherType(upper, payload)
+	for _, err := range []error{
+		parseField(spec, "id", &id), parseField(spec, "pcp", &pcp),
+		parseField(spec, "dei", &dei), parseField(spec, "type", &etherType),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if id > 0x0FFF || pcp > 7 || dei > 1 {
+		return nil, errors.New(i18n.T("id 应为0~4095, pcp 应为0~7, dei 应为0或1"))
+	}
+	tag := level.NewVLANTag(level.EtherTypeVLAN, pcp, dei == 1, id)
+	return append([]byte{byte(tag.TCI >> 8), byte(tag.TCI), byte(etherType >> 8), byte(etherType)}, payload...), nil
+}
+
+// buildLLC 构造LLC头部, 控制字段低2位不是11时为2字节
+func buildLLC(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	dsap, ssap, ctrl := uint8(level.SAPNull), uint8(level.SAPNull), uint16(level.LLCControlUI)
+	if upper == "stp" {
+		dsap, ssap = level.SAPSTP, level.SAPSTP
+	}
+	for _, err := range []error{
+		parseField(spec, "dsap", &dsap), parseField(spec, "ssap", &ssap), parseField(spec, "ctrl", &ctrl),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return level.NewLLC(dsap, ssap, ctrl, payload).Serialize(), nil
+}
+
+// buildSNAP 构造LLC与SNAP头部, 协议标识缺省按上层推断
+func buildSNAP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	oui := uint32(0)
+	pid := upperEtherType(upper, payload)
+	if err := parseField(spec, "oui", &oui); err != nil {
+		return nil, err
+	}
+	if err := parseField(spec, "pid", &pid); err != nil {
+		return nil, err
+	}
+	if oui > 0xFFFFFF {
+		return nil, errors.New(i18n.T("oui 应为3字节"))
+	}
+	return level.NewSNAP([3]byte{byte(oui >> 16), byte(oui >> 8), byte(oui)}, pid, payload).Serialize(), nil
+}
+
+// buildSTP 构造BPDU, 计时器取默认值, 根桥与网桥缺省为注入端口的MAC
+func buildSTP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	self := level.NewBridgeID(host.DefaultBridgePriority, ctx.srcMAC)
+	bpdu := &level.BPDU{
+		RootID:       self,
+		BridgeID:     self,
+		PortID:       0x8001,
+		MaxAge:       host.DefaultMaxAge,
+		HelloTime:    host.DefaultHelloTime,
+		ForwardDelay: host.DefaultForwardDelay,
+	}
+	switch t := spec.fields["type"]; t {
+	case "", "config":
+	case "rst":
+		bpdu.Version, bpdu.Type = level.RSTPVersion, level.BPDUTypeRST
+	case "tcn":
+		bpdu.Type = level.BPDUTypeTCN
+	default:
+		if err := parseField(spec, "type", &bpdu.Type); err != nil {
+			return nil, err
+		}
+		if bpdu.Type == level.BPDUTypeRST {
+			bpdu.Version = level.RSTPVersion
+		}
+	}
+	for _, err := range []error{
+		parseField(spec, "flags", &bpdu.Flags), parseField(spec, "cost", &bpdu.RootPathCost),
+		parseField(spec, "port", &bpdu.PortID),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	ids := []*level.BridgeID{&bpdu.RootID, &bpdu.BridgeID}
+	for i, name := range []string{"root", "bridge"} {
+		if value, ok := spec.fields[name]; ok {
+			var err error
+			if *ids[i], err = level.ParseBridgeID(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return append(bpdu.Serialize(), payload...), nil
+}
+
+// buildARP 构造ARP报文, 发送方地址缺省取注入端口
+func buildARP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	op := uint16(1)
+	sha, spa := ctx.srcMAC, ctx.srcIP
+	var tha [6]byte
+	var tpa [4]byte
+	for _, err := range []error{
+		parseField(spec, "op", &op), parseField(spec, "sha", &sha), parseField(spec, "spa", &spa),
+		parseField(spec, "tha", &tha), parseField(spec, "tpa", &tpa),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(level.NewARPPacket(op, sha, spa, tha, tpa).Serialize(), payload...), nil
+}
+
+// buildIPv4 构造IPv4报文, 协议号缺省按上层推断
+func buildIPv4(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	proto := uint8(0)
+	switch upper {
+	case "icmp":
+		proto = level.IPProtocolICMP
+	case "tcp":
+		proto = level.IPProtocolTCP
+	case "udp":
+		proto = level.IPProtocolUDP
+	}
+	ip := level.NewIPv4Packet(ctx.srcIP, ctx.dstIP, proto, payload)
+	for _, err := range []error{
+		parseField(spec, "ttl", &ip.TTL), parseField(spec, "proto", &ip.Protocol),
+		parseField(spec, "id", &ip.Identification), parseField(spec, "tos", &ip.TOS),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ip.Serialize(), nil
+}
+
+// buildICMP 构造ICMP报文, 缺省为回显请求
+func buildICMP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	icmp := level.NewICMPPacket(level.ICMPTypeEchoRequest, 0, 1, 1, payload)
+	for _, err := range []error{
+		parseField(spec, "type", &icmp.Type), parseField(spec, "code", &icmp.Code),
+		parseField(spec, "id", &icmp.Identifier), parseField(spec, "seq", &icmp.Sequence),
+		parseField(spec, "data", &icmp.Data),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return icmp.Serialize(), nil
+}
+
+// buildUDP 构造UDP报文
+func buildUDP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	sport, dport := uint16(49152), uint16(53)
+	if err := parseField(spec, "data", &payload); err != nil {
+		return nil, err
+	}
+	if err := parseField(spec, "sport", &sport); err != nil {
+		return nil, err
+	}
+	if err := parseField(spec, "dport", &dport); err != nil {
+		return nil, err
+	}
+	return level.NewUDPPacket(sport, dport, payload).Serialize(ctx.srcIP, ctx.dstIP), nil
+}
+
+// buildTCP 构造TCP报文, 缺省为SYN
+func buildTCP(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	sport, dport, window := uint16(49152), uint16(80), uint16(65535)
+	var seq, ack uint32
+	for _, err := range []error{
+		parseField(spec, "sport", &sport), parseField(spec, "dport", &dport),
+		parseField(spec, "seq", &seq), parseField(spec, "ack", &ack),
+		parseField(spec, "win", &window), parseField(spec, "data", &payload),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	flags := uint16(level.TCPFlagSYN)
+	if s, ok := spec.fields["flags"]; ok {
+		var err error
+		if flags, err = parseTCPFlags(s); err != nil {
+			return nil, err
+		}
+	}
+	return level.NewTCPPacket(sport, dport, seq, ack, flags, window, payload).Serialize(ctx.srcIP, ctx.dstIP), nil
+}
+
+// buildRaw 构造原始负载
+func buildRaw(ctx *craftContext, spec craftSpec, payload []byte, upper string) ([]byte, error) {
+	data := []byte(spec.fields["data"])
+	if s, ok := spec.fields["hex"]; ok {
+		b, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("字段 hex=%s 无效"), s)
+		}
+		data = append(data, b...)
+	}
+	return append(data, payload...), nil
+}
+
+// parseTCPFlags 解析 TCP 标志: 数值、缩写串(SA)或名称列表(SYN,ACK)
+func parseTCPFlags(s string) (uint16, error) {
+	if v, err := strconv.ParseUint(s, 0, 9); err == nil {
+		return uint16(v), nil
+	}
+	byName := map[string]uint16{
+		"FIN": level.TCPFlagFIN, "SYN": level.TCPFlagSYN, "RST": level.TCPFlagRST,
+		"PSH": level.TCPFlagPSH, "ACK": level.TCPFlagACK, "URG": level.TCPFlagURG,
+	}
+	var flags uint16
+	if strings.Contains(s, ",") || byName[strings.ToUpper(s)] != 0 {
+		for _, name := range strings.Split(strings.ToUpper(s), ",") {
+			bit, ok := byName[strings.TrimSpace(name)]
+			if !ok {
+				return 0, fmt.Errorf(i18n.T("未知TCP标志: %s"), name)
+			}
+			flags |= bit
+		}
+		return flags, nil
+	}
+	for _, c := range strings.ToUpper(s) {
+		switch c {
+		case 'F':
+			flags |= level.TCPFlagFIN
+		case 'S':
+			flags |= level.TCPFlagSYN
+		case 'R':
+			flags |= level.TCPFlagRST
+		case 'P':
+			flags |= level.TCPFlagPSH
+		case 'A':
+			flags |= level.TCPFlagACK
+		case 'U':
+			flags |= level.TCPFlagURG
+		default:
+			return 0, fmt.Errorf(i18n.T("未知TCP标志: %c"), c)
+		}
+	}
+	return flags, nil
+}
+
+// containsString 判断切片是否包含字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// BPDU 必须封装在LLC中, 以太网类型字段为802.3长度
+func TestCraftSTPNeedsLLC(t *testing.T) {
+	for _, line := range []string{"stp", "eth / stp", "eth / vlan id=10 / stp", "eth / snap / stp"} {
+		specs, err := parseCraftSpecs(strings.Fields(line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := craftFrame(specs, nil, 0); err == nil || !strings.Contains(err.Error(), "llc") {
+			t.Errorf("craft %s: %v, want an llc error", line, err)
+		}
+	}
+	specs, err := parseCraftSpecs(strings.Fields("eth / llc / stp type=tcn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := craftFrame(specs, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eth, err := level.Deserialize(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LLC 3字节加 TCN BPDU 4字节
+	if got := eth.EtherType(); got != 7 {
+		t.Errorf("length field = %d, want 7", got)
+	}
+}
+
+// craft 的帮助列出各协议层的说明, 并随语言翻译
+func TestCraftHelpLayers(t *testing.T) {
+	if err := i18n.SetLang(i18n.EN); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { i18n.SetLang(i18n.ZH) })
+	help := formatCommandHelp(findCommand("craft"))
+	for _, want := range []string{"ICMP: type 8 request/0 reply", "needs an llc layer below"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("help craft missing %q:\n%s", want, help)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// colorEnabled 标准输出为终端时才输出颜色
+var colorEnabled = term.IsTerminal(int(os.Stdout.Fd()))
+
+// fieldColors 字段轮流使用的颜色
+var fieldColors = []string{
+	"\033[31m", "\033[32m", "\033[33m", "\033[34m", "\033[35m", "\033[36m",
+	"\033[91m", "\033[92m", "\033[93m", "\033[94m", "\033[95m", "\033[96m",
+}
+
+// colorReset 恢复默认颜色
+const colorReset = "\033[0m"
+
+// paint 用第c种颜色输出文本, c<0 或未启用颜色时原样返回
+func paint(c int, s string) string {
+	if !colorEnabled || c < 0 {
+		return s
+	}
+	return fieldColors[c%len(fieldColors)] + s + colorReset
+}
+
+// showDissection 打印字段树与带颜色标注的十六进制, 每个字段与其字节同色
+// @param frame []byte 以太网帧
+// @param hasFCS bool 帧尾是否带CRC
+func showDissection(frame []byte, hasFCS bool) {
+	var layers []level.Layer
+	if hasFCS {
+		layers = level.Dissect(frame)
+	} else {
+		layers = level.DissectWithoutFCS(frame)
+	}
+	// owner 每个字节所属字段的颜色, -1表示不属于任何字段
+	owner := make([]int, len(frame))
+	for i := range owner {
+		owner[i] = -1
+	}
+	// starts 字段起始字节, 用于在十六进制右侧标注
+	var starts []fieldStart
+	color := 0
+	for _, layer := range layers {
+		title := i18n.Field(layer.Name)
+		if layer.Summary != "" {
+			title += ", " + layer.Summary
+		}
+		fmt.Printf("%s  [%d-%d]\n", title, layer.Offset, layer.Offset+layer.Length-1)
+		for _, f := range layer.Fields {
+			printField(frame, f, f, color, 1)
+			for i := f.Offset; i < f.Offset+f.Length; i++ {
+				owner[i] = color
+			}
+			starts = append(starts, fieldStart{offset: f.Offset, label: paint(color, i18n.Field(f.Name))})
+			color++
+		}
+	}
+	sort.SliceStable(starts, func(i, j int) bool { return starts[i].offset < starts[j].offset })
+	labels := make(map[int][]string)
+	for _, s := range starts {
+		labels[s.offset/16] = append(labels[s.offset/16], s.label)
+	}
+	fmt.Println()
+	fmt.Print(annotatedHexDump(frame, owner, labels))
+	showFindings(level.ValidateFrame(frame, hasFCS))
+}
+
+// showFindings 打印各层的诊断结果, 错误为红色, 警告为黄色
+func showFindings(findings level.Findings) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println(i18n.T("诊断:"))
+	for _, f := range findings {
+		color := -1
+		switch f.Severity {
+		case level.SeverityError:
+			color = 0
+		case level.SeverityWarning:
+			color = 2
+		}
+		fmt.Println("    " + paint(color, f.String()))
+	}
+}
+
+// fieldStart 字段的起始字节与着色后的名称
+type fieldStart struct {
+	offset int
+	label  string
+}
+
+// printField 打印一个字段及其子字段, 非字节对齐的字段前面显示位图
+func printField(frame []byte, f, top level.Field, color, depth int) {
+	prefix := ""
+	if !f.Aligned() {
+		prefix = bitPattern(frame, top, f) + " = "
+	}
+	fmt.Printf("%s%s%s: %s\n", strings.Repeat("    ", depth), prefix, paint(color, i18n.Field(f.Name)), f.Display)
+	for _, child := range f.Children {
+		printField(frame, child, top, color, depth+1)
+	}
+}
+
+// bitPattern 在顶层字段覆盖的字节内画出字段所占的位, 如 .... ..1.
+func bitPattern(frame []byte, top, f level.Field) string {
+	var b strings.Builder
+	first := top.Offset * 8
+	for i := first; i < first+top.Length*8; i++ {
+		if i > first && (i-first)%4 == 0 {
+			b.WriteByte(' ')
+		}
+		switch {
+		case i < f.Bit || i >= f.Bit+f.Bits:
+			b.WriteByte('.')
+		case frame[i/8]>>(7-i%8)&1 == 1:
+			b.WriteByte('1')
+		default:
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// annotatedHexDump 每行16字节的十六进制与ASCII, 字节按所属字段着色, 行尾列出在该行开始的字段
+func annotatedHexDump(data []byte, owner []int, labels map[int][]string) string {
+	var b strings.Builder
+	for off := 0; off < len(data); off += 16 {
+		end := min(off+16, len(data))
+		fmt.Fprintf(&b, "%04x  ", off)
+		for i := off; i < off+16; i++ {
+			if i < end {
+				b.WriteString(paint(owner[i], fmt.Sprintf("%02x", data[i])) + " ")
+			} else {
+				b.WriteString("   ")
+			}
+			if i == off+7 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(" |")
+		for i := off; i < end; i++ {
+			c := data[i]
+			if c < 32 || c > 126 {
+				c = '.'
+			}
+			b.WriteString(paint(owner[i], string(c)))
+		}
+		b.WriteString("|")
+		if names := labels[off/16]; len(names) > 0 {
+			b.WriteString("  " + strings.Join(names, " "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+)
+
+// eventBuffer 每个客户端缓冲的事件数, 客户端读得太慢时丢弃新事件
+const eventBuffer = 1024
+
+// eventHeartbeat 没有事件时发送心跳的间隔, 防止代理断开空闲连接
+const eventHeartbeat = 15 * time.Second
+
+// wsGUID RFC 6455 握手使用的固定GUID
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWSPayload 客户端消息的最大长度
+const maxWSPayload = 64 * 1024
+
+// const WebSocket 操作码
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// jsonEvent 推送给客户端的事件
+type jsonEvent struct {
+	Seq       uint64         `json:"seq"`
+	Time      int64          `json:"time"`
+	Kind      string         `json:"kind"`
+	Device    string         `json:"device"`
+	Peer      string         `json:"peer,omitempty"`
+	Link      int            `json:"link,omitempty"`
+	Layer     int            `json:"layer"`
+	Protocols []string       `json:"protocols"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// eventJSON 转换总线事件
+func eventJSON(ev host.BusEvent) jsonEvent {
+	protocols := ev.Protocols
+	if protocols == nil {
+		protocols = []string{}
+	}
+	return jsonEvent{ev.Seq, int64(ev.Time), ev.Kind, ev.Device, ev.Peer, ev.LinkID,
+		ev.Layer, protocols, ev.Message, ev.Data}
+}
+
+// marshalEvent 编码事件, 不转义 < > &, 方便直接查看
+func marshalEvent(ev host.BusEvent) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(eventJSON(ev))
+	return bytes.TrimRight(b.Bytes(), "\n")
+}
+
+// eventFilter 客户端的事件过滤条件, 为空的条件不过滤
+type eventFilter struct {
+	// 设备名称, 匹配发布设备或帧的接收设备
+	hosts map[string]bool
+	// OSI层 1-7
+	layers map[int]bool
+	// 协议名称, 匹配协议栈中任意一层
+	protocols map[string]bool
+	// 事件类型
+	kinds map[string]bool
+}
+
+// filterRequest 过滤条件的文本形式, 各项为逗号分隔的列表
+type filterRequest struct {
+	Host  string `json:"host"`
+	Layer string `json:"layer"`
+	Proto string `json:"proto"`
+	Kind  string `json:"kind"`
+}
+
+// filterFromQuery 从URL参数 host, layer, proto, kind 读取过滤条件
+func filterFromQuery(q url.Values) (eventFilter, error) {
+	return parseEventFilter(filterRequest{q.Get("host"), q.Get("layer"), q.Get("proto"), q.Get("kind")})
+}
+
+// parseEventFilter 解析过滤条件, 层可以写成 3 或 L3
+// @param req filterRequest 文本形式的条件
+// @return eventFilter, error
+func parseEventFilter(req filterRequest) (eventFilter, error) {
+	var f eventFilter
+	f.hosts = splitSet(req.Host)
+	f.protocols = splitSet(strings.ToLower(req.Proto))
+	f.kinds = splitSet(req.Kind)
+	for kind := range f.kinds {
+		switch kind {
+		case host.BusFrameTx, host.BusFrameDrop, host.BusARPUpdate, host.BusTCPState, host.BusRouteLookup, host.BusSTPState:
+		default:
+			return f, fmt.Errorf(i18n.T("未知的事件类型: %s"), kind)
+		}
+	}
+	for layer := range splitSet(strings.ToLower(req.Layer)) {
+		n, err := strconv.Atoi(strings.TrimPrefix(layer, "l"))
+		if err != nil || n < 1 || n > 7 {
+			return f, fmt.Errorf(i18n.T("层应为 1-7 或 L1-L7: %s"), layer)
+		}
+		if f.layers == nil {
+			f.layers = make(map[int]bool)
+		}
+		f.layers[n] = true
+	}
+	return f, nil
+}
+
+// splitSet 逗号分隔的列表转为集合, 空串返回nil
+func splitSet(s string) map[string]bool {
+	var set map[string]bool
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			if set == nil {
+				set = make(map[string]bool)
+			}
+			set[item] = true
+		}
+	}
+	return set
+}
+
+// match 判断事件是否满足全部条件
+func (f eventFilter) match(ev host.BusEvent) bool {
+	if f.hosts != nil && !f.hosts[ev.Device] && !f.hosts[ev.Peer] {
+		return false
+	}
+	if f.layers != nil && !f.layers[ev.Layer] {
+		return false
+	}
+	if f.kinds != nil && !f.kinds[ev.Kind] {
+		return false
+	}
+	if f.protocols != nil {
+		for _, p := range ev.Protocols {
+			if f.protocols[p] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// eventClient 事件总线的一个订阅者, 模拟器通过缓冲信道把事件交给连接所在的goroutine
+type eventClient struct {
+	// 订阅编号
+	id int
+	// 待发送的事件
+	events chan host.BusEvent
+	// 缓冲已满时丢弃的事件数
+	dropped atomic.Int64
+}
+
+// subscribeEvents 订阅事件总线; 模拟器持有锁时发布事件, 所以这里绝不阻塞
+func subscribeEvents() *eventClient {
+	c := &eventClient{events: make(chan host.BusEvent, eventBuffer)}
+	c.id = host.Subscribe(func(ev host.BusEvent) {
+		select {
+		case c.events <- ev:
+		default:
+			c.dropped.Add(1)
+		}
+	})
+	return c
+}
+
+// close 取消订阅
+func (c *eventClient) close() {
+	host.Unsubscribe(c.id)
+}
+
+// handleEvents GET /api/events?host=&layer=&proto=&kind= 以 Server-Sent Events 推送模拟器事件
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterFromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	flusher := http.NewResponseController(w)
+	client := subscribeEvents()
+	defer client.close()
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, i18n.T(": 已连接\n\n"))
+	if flusher.Flush() != nil {
+		return
+	}
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-client.events:
+			if !filter.match(ev) {
+				continue
+			}
+			data := marshalEvent(ev)
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Kind, data); err != nil {
+				return
+			}
+			// 一次 run 会产生大量事件, 取完缓冲再刷新
+			if len(client.events) == 0 {
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			if n := client.dropped.Swap(0); n > 0 {
+				fmt.Fprintf(w, i18n.T(": 客户端过慢, 丢弃了 %d 个事件\n\n"), n)
+			} else {
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// wsFrame 一个WebSocket帧
+type wsFrame struct {
+	opcode  byte
+	payload []byte
+}
+
+// handleEventsWebSocket GET /api/events/ws 以 WebSocket 推送模拟器事件
+// URL参数与SSE相同; 客户端可以随时发送 {"host","layer","proto","kind"} 更换过滤条件
+func handleEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	filter, err := filterFromQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// WebSocket 不受浏览器同源策略限制, 需要自己检查 Origin
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, fmt.Errorf(i18n.T("不接受跨站请求: %s"), r.Header.Get("Origin")))
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		writeError(w, http.StatusBadRequest, errors.New(i18n.T("需要 WebSocket 握手")))
+		return
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf(i18n.T("连接不支持 WebSocket: %v"), err))
+		return
+	}
+	defer conn.Close()
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if rw.Flush() != nil {
+		return
+	}
+
+	client := subscribeEvents()
+	defer client.close()
+	filters := make(chan eventFilter, 1)
+	replies := make(chan wsFrame, 4)
+	done := make(chan struct{})
+	go readWebSocket(rw.Reader, filters, replies, done)
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var out wsFrame
+		select {
+		case <-done:
+			// 回复客户端的关闭帧
+			select {
+			case f := <-replies:
+				writeWebSocket(conn, f)
+			default:
+			}
+			return
+		case f := <-replies:
+			out = f
+		case filter = <-filters:
+			continue
+		case ev := <-client.events:
+			if !filter.match(ev) {
+				continue
+			}
+			out = wsFrame{wsText, marshalEvent(ev)}
+		case <-heartbeat.C:
+			out = wsFrame{wsPing, nil}
+		}
+		if writeWebSocket(conn, out) != nil {
+			return
+		}
+	}
+}
+
+// readWebSocket 读取客户端消息: 文本消息为新的过滤条件, ping 回复 pong, close 结束连接
+// 客户端消息都很短, 不支持分片
+func readWebSocket(r *bufio.Reader, filters chan eventFilter, replies chan<- wsFrame, done chan<- struct{}) {
+	defer close(done)
+	// 发送端已退出时不能阻塞, 回复放不下就丢弃
+	reply := func(f wsFrame) {
+		select {
+		case replies <- f:
+		default:
+		}
+	}
+	for {
+		f, err := readWebSocketFrame(r)
+		if err != nil {
+			return
+		}
+		switch f.opcode {
+		case wsText:
+			var req filterRequest
+			if err := json.Unmarshal(f.payload, &req); err != nil {
+				reply(errorFrame(fmt.Errorf(i18n.T("过滤条件不是合法的JSON: %v"), err)))
+				continue
+			}
+			filter, err := parseEventFilter(req)
+			if err != nil {
+				reply(errorFrame(err))
+				continue
+			}
+			// 只保留最新的条件
+			select {
+			case <-filters:
+			default:
+			}
+			filters <- filter
+		case wsPing:
+			reply(wsFrame{wsPong, f.payload})
+		case wsClose:
+			reply(wsFrame{wsClose, f.payload})
+			return
+		}
+	}
+}
+
+// errorFrame 以 {"error": ...} 文本消息报告错误
+func errorFrame(err error) wsFrame {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return wsFrame{wsText, data}
+}
+
+// readWebSocketFrame 读取一帧并去掉掩码
+func readWebSocketFrame(r *bufio.Reader) (wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return wsFrame{}, err
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxWSPayload {
+		return wsFrame{}, fmt.Errorf(i18n.T("WebSocket 消息过长: %d 字节"), length)
+	}
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return wsFrame{}, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return wsFrame{}, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return wsFrame{head[0] & 0x0f, payload}, nil
+}
+
+// writeWebSocket 写出一个不分片、不加掩码的服务端帧
+func writeWebSocket(conn net.Conn, f wsFrame) error {
+	head := []byte{0x80 | f.opcode}
+	switch n := len(f.payload); {
+	case n < 126:
+		head = append(head, byte(n))
+	case n <= 0xffff:
+		head = append(head, 126)
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head = append(head, 127)
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := conn.Write(append(head, f.payload...))
+	return err
+}
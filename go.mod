@@ -1,3 +1,7 @@
 module osiweb-go
 
 go 1.24.2
+
+require golang.org/x/term v0.32.0
+
+require golang.org/x/sys v0.33.0 // indirect
@@ -1,5 +1,13 @@
 package main
 
+// Arg 命令参数说明
+type Arg struct {
+	// 参数写法, 与用法中的一致
+	Name string
+	// 说明
+	Description string
+}
+
 // Command 命令结构
 // @author xuyang
 // @datetime 2025-6-24 7:00
@@ -7,6 +15,14 @@ type Command struct {
 	Name        string
 	Description string
 	Usage       string
+	// 别名, 与命令名等效
+	Aliases []string
+	// 参数说明
+	Args []Arg
+	// 示例
+	Examples []string
+	// 相关命令
+	Related []string
 }
 
 // Commands 命令列表
@@ -14,58 +30,354 @@ type Command struct {
 // @datetime 2025-6-24 7:00
 var Commands = []Command{
 	{
-		Name:        "set",
-		Description: "设置键值对",
-		Usage:       "set \"key\" \"value\"",
+		Name:        "host",
+		Description: "添加主机, 监听或关闭TCP端口",
+		Usage: "host add <name> [ip/prefix] [gateway]\n" +
+			"      host listen <name> <port>\n" +
+			"      host close <name> <ip> <port>",
+		Args: []Arg{
+			{"<name>", "主机名称, 不能与已有设备重名"},
+			{"[ip/prefix]", "eth0 的IPv4地址与前缀长度, 省略时从 ipv4_pool 自动分配"},
+			{"[gateway]", "默认网关"},
+			{"<port>", "TCP端口 0~65535"},
+			{"<ip>", "close 时为连接对端的IPv4地址"},
+		},
+		Examples: []string{
+			"host add h1 10.0.0.1/24 10.0.0.254",
+			"host listen h2 80",
+			"host close h1 10.0.0.2 80",
+		},
+		Related: []string{"switch", "router", "link", "show"},
+	},
+	{
+		Name:        "switch",
+		Description: "添加二层交换机",
+		Usage:       "switch add <name> [ports]",
+		Args: []Arg{
+			{"<name>", "交换机名称"},
+			{"[ports]", "端口数, 默认8"},
+		},
+		Examples: []string{"switch add s1", "switch add s2 24"},
+		Related:  []string{"link", "vlan", "show"},
+	},
+	{
+		Name:        "router",
+		Description: "添加路由器, 接口在连接链路时创建",
+		Usage:       "router add <name>",
+		Args:        []Arg{{"<name>", "路由器名称"}},
+		Examples:    []string{"router add r1", "link add r1 s1", "ip set r1:eth0 10.0.0.254/24"},
+		Related:     []string{"link", "ip", "route"},
+	},
+	{
+		Name:        "link",
+		Description: "添加、修改或删除链路",
+		Usage: "link add <dev>[:port] <dev>[:port] [delay=1ms] [bw=100M] [loss=0] [ber=0] [code=auto]\n" +
+			"      link set <id> [delay=1ms] [bw=100M] [loss=0] [ber=0] [code=auto]\n" +
+			"      link del <id>",
+		Args: []Arg{
+			{"<dev>[:port]", "设备与端口, 端口可写名称或编号, 省略时使用第一个空闲端口"},
+			{"<id>", "链路编号, 见 show links"},
+			{"delay=", "传播时延, 如 2ms"},
+			{"bw=", "带宽, 支持 K/M/G 后缀, inf 表示不计发送时延"},
+			{"loss=", "丢包率 0~1"},
+			{"ber=", "误码率 0~1, 按线路编码后的码元翻转, 出错的帧被物理层或CRC检查丢弃"},
+			{"code=", "线路编码 auto/nrz/nrzi/manchester/4b5b/8b10b, auto 按带宽选择"},
+		},
+		Examples: []string{
+			"link add h1 s1",
+			"link add h2:eth0 s1:eth3 delay=2ms bw=10M",
+			"link set 1 loss=0.1",
+			"link set 1 ber=1e-4 code=manchester",
+			"link del 2",
+		},
+		Related: []string{"show", "capture", "phy"},
+	},
+	{
+		Name:        "ip",
+		Description: "设置接口IPv4地址",
+		Usage:       "ip set <dev>:<port> <ip/prefix>",
+		Args: []Arg{
+			{"<dev>:<port>", "主机或路由器的接口, 如 r1:eth1"},
+			{"<ip/prefix>", "IPv4地址与前缀长度, 如 192.168.1.1/24"},
+		},
+		Examples: []string{"ip set r1:eth1 192.168.2.1/24"},
+		Related:  []string{"route", "show"},
+	},
+	{
+		Name:        "route",
+		Description: "添加静态路由",
+		Usage:       "route add <dev> <prefix/len|default> <next-hop>",
+		Args: []Arg{
+			{"<dev>", "主机或路由器"},
+			{"<prefix/len|default>", "目的网段, default 表示 0.0.0.0/0"},
+			{"<next-hop>", "下一跳IPv4地址, 必须在直连网段内"},
+		},
+		Examples: []string{"route add r1 10.1.0.0/16 192.168.2.2", "route add h1 default 10.0.0.254"},
+		Related:  []string{"ip", "show"},
+	},
+	{
+		Name:        "vlan",
+		Description: "设置交换机端口的VLAN模式, 或在主机、路由器的接口上创建VLAN子接口(单臂路由)",
+		Usage: "vlan access <switch>:<port> <vid>\n" +
+			"      vlan trunk <switch>:<port> [native=<vid>] [allowed=<vid,...>]\n" +
+			"      vlan sub <dev>:<port> <vid> [ip/prefix]",
+		Args: []Arg{
+			{"<vid>", "VLAN编号 1~4094"},
+			{"access", "接入端口, 属于一个VLAN, 收发不带标签的帧"},
+			{"trunk", "干道端口, 本征VLAN以外的帧带 802.1Q 标签"},
+			{"native=", "干道端口的本征VLAN, 默认1"},
+			{"allowed=", "干道端口允许的VLAN, 如 10,20,30-35, 默认全部"},
+			{"sub", "创建子接口 <port>.<vid>, 收发带该VLAN标签的帧"},
+		},
+		Examples: []string{
+			"vlan access s1:eth1 10",
+			"vlan trunk s1:eth0 allowed=10,20",
+			"vlan sub r1:eth0 10 10.0.10.1/24",
+		},
+		Related: []string{"switch", "ip", "show"},
+	},
+	{
+		Name:        "stp",
+		Description: "在交换机上启用 802.1D 生成树或快速生成树, 设置网桥优先级、端口开销、端口优先级与边缘端口",
+		Usage: "stp enable <switch> [stp|rstp]\n" +
+			"      stp disable <switch>\n" +
+			"      stp priority <switch> <n>\n" +
+			"      stp cost <switch>:<port> <n|auto>\n" +
+			"      stp port-priority <switch>:<port> <n>\n" +
+			"      stp edge <switch>:<port> on|off",
+		Args: []Arg{
+			{"[stp|rstp]", "stp 为 802.1D, 端口经侦听、学习各15秒后转发; rstp 为 802.1w, 用提议/同意快速转发; 默认 stp"},
+			{"priority", "网桥优先级 0~61440, 4096的倍数, 默认32768, 越小越优先成为根桥"},
+			{"cost", "端口路径开销, auto 按链路带宽计算"},
+			{"port-priority", "端口优先级 0~240, 16的倍数, 默认128"},
+			{"edge", "边缘端口, 连接主机时立即转发, 收到BPDU后自动取消"},
+		},
+		Examples: []string{
+			"stp enable s1 rstp",
+			"stp priority s1 4096",
+			"stp cost s2:eth1 100",
+			"stp edge s1:eth3 on",
+			"show spanning-tree s1",
+		},
+		Related: []string{"switch", "show", "expect"},
+	},
+	{
+		Name:        "show",
+		Description: "查看主机、链路、ARP缓存、路由表、MAC地址表、端口VLAN、生成树、TCP连接或当前配置",
+		Usage:       "show hosts|links|arp [dev]|routes <dev>|mac [switch]|vlan [switch]|spanning-tree [switch]|tcp [host]|clock|config",
+		Args: []Arg{
+			{"[dev]", "只看一台设备, 省略时显示全部"},
+			{"[switch]", "只看一台交换机"},
+			{"[host]", "只看一台主机"},
+		},
+		Examples: []string{"show hosts", "show arp h1", "show routes r1", "show mac s1", "show vlan s1", "show spanning-tree s1", "show tcp"},
+		Related:  []string{"capture", "log"},
+	},
+	{
+		Name:        "topology",
+		Description: "从JSON/YAML文件加载拓扑, 校验拓扑文件, 或把当前拓扑保存到文件",
+		Usage: "topology load <file>\n" +
+			"      topology check <file>\n" +
+			"      topology save <file>",
+		Args: []Arg{{"<file>", "拓扑文件, 扩展名为 .yaml/.yml 时按YAML读写, 否则为JSON"}},
+		Examples: []string{
+			"topology check examples/lab.yaml",
+			"topology load examples/lab.yaml",
+			"topology save mylab.json",
+		},
+		Related: []string{"snapshot", "run-script"},
+	},
+	{
+		Name:        "snapshot",
+		Description: "把完整的模拟状态保存到数据目录, 或从快照恢复",
+		Usage: "snapshot save [name]\n" +
+			"      snapshot load [name]\n" +
+			"      snapshot list\n" +
+			"      snapshot del [name]",
+		Args:     []Arg{{"[name]", "快照名称, 默认为 default, 不能包含路径"}},
+		Examples: []string{"snapshot save lesson1", "snapshot list", "snapshot load lesson1"},
+		Related:  []string{"topology"},
+	},
+	{
+		Name:        "send",
+		Description: "从主机发送ICMP回显请求、UDP数据报或TCP数据",
+		Usage:       "send <host> <dst-ip> [icmp|udp|tcp] [port] [data]",
+		Aliases:     []string{"ping"},
+		Args: []Arg{
+			{"<host>", "发送的主机"},
+			{"<dst-ip>", "目的IPv4地址"},
+			{"[icmp|udp|tcp]", "协议, 默认 icmp"},
+			{"[port]", "udp/tcp 的目的端口"},
+			{"[data]", "数据, 含空格时加双引号"},
+		},
+		Examples: []string{
+			"send h1 10.0.0.2",
+			"ping h1 10.0.0.2",
+			"send h1 10.0.0.2 udp 53 hello",
+			"send h1 10.0.0.2 tcp 80 \"GET /\"",
+		},
+		Related: []string{"run", "step", "capture"},
+	},
+	{
+		Name:        "craft",
+		Description: "逐层构造一帧, 显示十六进制与解码结果, 可注入设备端口",
+		Usage: "craft <layer> [field=value ...] [/ <layer> ...] [inject <dev>[:port]]\n" +
+			"      layer: eth(dst src type) vlan(id pcp dei type) arp(op sha spa tha tpa)\n" +
+			"             llc(dsap ssap ctrl) snap(oui pid) stp(type flags root cost bridge port)\n" +
+			"             ip(src dst ttl proto id tos)\n" +
+			"             icmp(type code id seq data) udp(sport dport data)\n" +
+			"             tcp(sport dport seq ack flags win data) raw(data hex)",
+		Args: append([]Arg{
+			{"<layer>", "协议层 eth/vlan/llc/snap/stp/arp/ip/icmp/udp/tcp/raw, 自下而上用 / 分隔"},
+			{"field=value", "字段取值, 未给出的字段按上下层推断"},
+			{"inject <dev>[:port]", "从设备端口发出构造的帧"},
+		}, craftLayerArgs()...),
+		Examples: []string{
+			"craft eth dst=ff:ff:ff:ff:ff:ff / arp op=1 tpa=10.0.0.2 inject h1",
+			"craft eth / ip dst=10.0.0.2 ttl=1 / icmp type=8",
+			"craft eth / ip / tcp dport=80 flags=S",
+			"craft eth / vlan id=10 pcp=5 / ip dst=10.0.10.1 / icmp type=8",
+			"craft eth dst=01:80:c2:00:00:00 / llc dsap=0x42 ssap=0x42 / raw hex=0000000000",
+			"craft eth dst=01:80:c2:00:00:00 / llc / stp type=rst flags=0x3c root=4096/02:b0:00:00:00:01 cost=19",
+		},
+		Related: []string{"capture", "send"},
 	},
 	{
-		Name:        "get",
-		Description: "获取键对应的值",
-		Usage:       "get \"key\"",
+		Name:        "capture",
+		Description: "在设备、端口或链路上抓包, 可写入 pcap/pcapng 文件; show 指定帧号时逐字段解码",
+		Usage: "capture start <dev>[:port]|link <id> [file.pcap|file.pcapng] [fcs]\n" +
+			"      capture stop <dev>|link <id>\n" +
+			"      capture show <dev>|link <id> [n]\n" +
+			"      capture save <dev>|link <id> <file.pcap|file.pcapng> [fcs]",
+		Args: []Arg{
+			{"<dev>[:port]", "设备或设备的一个端口"},
+			{"link <id>", "链路"},
+			{"[file]", "同时写入的抓包文件, 位于 capture_dir 中"},
+			{"[fcs]", "文件中的帧带上以太网FCS"},
+			{"[n]", "帧编号, 逐字段解码该帧"},
+		},
+		Examples: []string{
+			"capture start h1",
+			"capture start link 1 l1.pcapng",
+			"capture show h1 3",
+			"capture save h1 h1.pcap",
+		},
+		Related: []string{"pcap", "craft", "expect", "phy"},
 	},
 	{
-		Name:        "setnx",
-		Description: "仅当键不存在时设置值",
-		Usage:       "setnx \"key\" \"value\"",
+		Name:        "phy",
+		Description: "物理层: 显示线路编码的波形, 翻转电平模拟误码, 观察物理层编码检查与接收方FCS检查能否发现",
+		Usage: "phy encode <code> <hex> [errors=i,j] [ber=x] [from=n] [bits=48] [svg=file]\n" +
+			"      phy frame <dev>|link <id> <n> [code=auto] [errors=i,j] [ber=x] [from=n] [bits=48] [svg=file]",
+		Args: []Arg{
+			{"<code>", "线路编码 nrz/nrzi/manchester/4b5b/8b10b"},
+			{"<hex>", "要编码的数据, 十六进制"},
+			{"<dev>|link <id> <n>", "抓包会话与帧编号, 帧加上前导码与定界符后发送"},
+			{"code=", "线路编码, 默认使用帧所在链路的编码"},
+			{"errors=", "要翻转的电平下标, 曼彻斯特编码每个比特两个电平"},
+			{"ber=", "按误码率随机翻转电平"},
+			{"from=", "从第几个线路比特开始显示, 帧默认从帧起始定界符之前开始"},
+			{"bits=", "显示的线路比特数"},
+			{"svg=", "同时把波形写入SVG文件, 位于 capture_dir 中"},
+		},
+		Examples: []string{
+			"phy encode manchester a5",
+			"phy encode 8b10b bc errors=3",
+			"phy frame h1 1 code=4b5b",
+			"phy frame h1 1 code=nrz errors=200 svg=frame.svg",
+		},
+		Related: []string{"capture", "link"},
 	},
 	{
-		Name:        "setxx",
-		Description: "仅当键存在时设置值",
-		Usage:       "setxx \"key\" \"value\"",
+		Name:        "pcap",
+		Description: "读取 pcap/pcapng 文件并解码, 或按原始时间间隔回放到设备端口",
+		Usage: "pcap read <file> [detail] [count]\n" +
+			"      pcap replay <file> <dev>[:port] [speed=1] [count]",
+		Args: []Arg{
+			{"<file>", "pcap 或 pcapng 文件"},
+			{"[detail]", "逐字段解码"},
+			{"[count]", "最多处理的帧数"},
+			{"[speed=1]", "回放速度倍数"},
+		},
+		Examples: []string{"pcap read h1.pcapng detail 5", "pcap replay h1.pcapng h2 speed=2"},
+		Related:  []string{"capture"},
 	},
 	{
-		Name:        "del",
-		Description: "删除键值对",
-		Usage:       "del \"key\"",
+		Name:        "log",
+		Description: "查看或修改日志格式、各层日志级别, 为单个设备开启逐帧trace",
+		Usage: "log\n" +
+			"      log level [l2|l3|l4|app] trace|debug|info|warn|error\n" +
+			"      log trace <dev> [on|off]\n" +
+			"      log format console|text|json",
+		Args: []Arg{
+			{"[l2|l3|l4|app]", "子系统, 省略时设置全部"},
+			{"<dev>", "开启或关闭 trace 的设备"},
+		},
+		Examples: []string{"log level l2 debug", "log trace h1", "log format json"},
+		Related:  []string{"show"},
 	},
 	{
-		Name:        "keys",
-		Description: "获取所有键",
-		Usage:       "keys",
+		Name:        "run",
+		Description: "运行模拟直到没有事件, 或推进指定的虚拟时间",
+		Usage:       "run [duration]",
+		Args:        []Arg{{"[duration]", "虚拟时间, 如 10ms、1s"}},
+		Examples:    []string{"run", "run 5ms"},
+		Related:     []string{"step", "send"},
 	},
 	{
-		Name:        "kvs",
-		Description: "获取所有键值对",
-		Usage:       "kvs",
+		Name:        "step",
+		Description: "处理下一个(或n个)事件",
+		Usage:       "step [n]",
+		Args:        []Arg{{"[n]", "事件数, 默认1"}},
+		Examples:    []string{"step", "step 5"},
+		Related:     []string{"run"},
 	},
 	{
-		Name:        "settime",
-		Description: "设置键的过期时间(毫秒)",
-		Usage:       "settime \"key\" milliseconds",
+		Name:        "run-script",
+		Description: "逐行执行场景脚本, 统计 expect 断言的结果",
+		Usage:       "run-script <file>",
+		Args:        []Arg{{"<file>", "脚本文件, 每行一条命令, # 开头为注释"}},
+		Examples:    []string{"run-script examples/lab.osi"},
+		Related:     []string{"expect", "topology"},
 	},
 	{
-		Name:        "getlasttime",
-		Description: "获取键的剩余生存时间（毫秒）",
-		Usage:       "getlasttime \"key\"",
+		Name:        "expect",
+		Description: "断言ARP缓存、MAC地址表、生成树、路由、TCP状态、抓包帧数或虚拟时间, 用于场景脚本自动评分",
+		Usage: "expect arp <host> contains|lacks <ip>\n" +
+			"      expect mac <switch> contains|lacks <mac> [vlan]\n" +
+			"      expect stp <switch> root\n" +
+			"      expect stp <switch>:<port> <role|state>\n" +
+			"      expect route <host> <dst-ip> [via] <next-hop>|direct|none\n" +
+			"      expect tcp <host> <state> [n|>=n|<n]\n" +
+			"      expect capture <dev>|link <id> count <filter> <n|>=n|<n>\n" +
+			"      expect clock <op><duration>",
+		Args: []Arg{
+			{"<state>", "TCP状态, 如 ESTABLISHED, LISTEN 为监听中的端口"},
+			{"<role|state>", "生成树端口角色 root/designated/alternate/backup/disabled 或状态 blocking/listening/learning/forwarding"},
+			{"<filter>", "all、协议名或 协议.字段 比较, 多个条件用 && 连接"},
+			{"<op><duration>", "比较符与虚拟时间, 如 <100ms"},
+		},
+		Examples: []string{
+			"expect arp h1 contains 10.0.0.2",
+			"expect stp s1 root",
+			"expect stp s3:eth0 alternate",
+			"expect route h1 192.168.2.30 via 192.168.1.1",
+			"expect tcp h3 ESTABLISHED 1",
+			"expect capture h3 count tcp.flags==SYN 1",
+			"expect clock <100ms",
+		},
+		Related: []string{"run-script", "capture"},
 	},
 	{
 		Name:        "help",
-		Description: "显示帮助信息",
-		Usage:       "help",
+		Description: "显示帮助信息, 指定命令时显示该命令的参数、示例与相关命令",
+		Usage:       "help [command]",
+		Examples:    []string{"help", "help link"},
 	},
 	{
 		Name:        "quit",
 		Description: "退出程序",
 		Usage:       "quit",
+		Aliases:     []string{"exit"},
 	},
 }
@@ -0,0 +1,206 @@
+package host
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// ARPEntry ARP缓存条目
+type ARPEntry struct {
+	// MAC地址
+	MAC [6]byte
+	// 学到该条目的端口
+	Port int
+	// 更新时刻(虚拟时间)
+	Updated time.Duration
+}
+
+// ARP 解析的重试, 见 RFC 1122 §2.3.2.1 与 §2.3.2.2
+const (
+	// ARPRetryInterval 没有应答时重发ARP请求的间隔
+	ARPRetryInterval = time.Second
+	// ARPMaxAttempts 放弃解析前最多发出的ARP请求数
+	ARPMaxAttempts = 3
+	// ARPQueueLimit 每个下一跳最多等待的报文数, 超出时丢弃最早的报文
+	ARPQueueLimit = 3
+)
+
+// pendingPacket 等待ARP解析的IP报文
+type pendingPacket struct {
+	port   int
+	packet []byte
+}
+
+// arpRequest 正在解析的下一跳
+type arpRequest struct {
+	// 发出ARP请求的端口
+	port int
+	// 等待解析的IP报文
+	packets []pendingPacket
+	// 已发出的ARP请求数
+	attempts int
+	// 下次重发的时刻
+	retryAt time.Duration
+}
+
+// HandleFrame 处理从端口收到的以太网帧
+// @param port 端口号
+// @param frame 以太网帧
+func (host *BaseHost) HandleFrame(port int, frame []byte) {
+	eth, err := level.Deserialize(frame)
+	var badCRC *level.ErrBadChecksum
+	switch {
+	case errors.As(err, &badCRC):
+		logf(logL2, host.Name, "%s 收到CRC错误的帧, 丢弃", host.PortName(port))
+		publishFrameDrop(host.Name, linkID(host, port), 2, frame, i18n.T("CRC错误"))
+		return
+	case err != nil:
+		logf(logL2, host.Name, "%s 收到无法解析的帧(%d 字节), 丢弃: %v", host.PortName(port), len(frame), err)
+		publishFrameDrop(host.Name, linkID(host, port), 2, frame, i18n.T("无法解析的帧"))
+		return
+	}
+	// 带标签的帧交给对应VLAN的子接口
+	if vlan := eth.VLANID(); vlan != 0 {
+		sub := host.subinterface(port, vlan)
+		if sub < 0 {
+			debugf(logL2, host.Name, "%s 没有VLAN %d 的子接口, 丢弃", host.PortName(port), vlan)
+			return
+		}
+		port = sub
+	}
+	if !host.ownsMAC(port, eth.DMacAddress) {
+		return
+	}
+	switch eth.EtherType() {
+	case level.EtherTypeARP:
+		host.handleARP(port, eth.DataPackage)
+	case level.EtherTypeIPv4:
+		host.handleIPv4(port, eth.DataPackage)
+	}
+}
+
+// sendFrame 封装以太网帧并从端口发出, 子接口的帧加上VLAN标签后从父接口发出
+func (host *BaseHost) sendFrame(port int, dst [6]byte, protocolType string, payload []byte) {
+	iface := host.Interfaces[port]
+	frame := level.NewEthernet2(dst, iface.MACAddress, protocolType, payload)
+	if iface.VLAN != 0 {
+		frame.PushTag(level.NewVLANTag(level.EtherTypeVLAN, 0, false, iface.VLAN))
+		port = iface.Parent
+	}
+	transmit(host, port, frame.Serialize())
+}
+
+// sendIPv4Via 经下一跳发送IP报文, 下一跳MAC未知时先发ARP请求
+func (host *BaseHost) sendIPv4Via(port int, nextHop [4]byte, packet []byte) {
+	if entry, ok := host.ARPTable[nextHop]; ok {
+		host.sendFrame(port, entry.MAC, "IP", packet)
+		return
+	}
+	req := host.pending[nextHop]
+	if req == nil {
+		req = &arpRequest{port: port}
+		host.pending[nextHop] = req
+		host.retryARP(nextHop, req)
+	}
+	if len(req.packets) >= ARPQueueLimit {
+		// 保留最新的报文, 丢弃最早的
+		logf(logL3, host.Name, "等待 %s 的报文超过 %d 个, 丢弃最早的报文", level.FormatIPv4(nextHop), ARPQueueLimit)
+		if ip, err := level.DeserializeIPv4Packet(req.packets[0].packet); err == nil {
+			publishPacketDrop(host.Name, ip, i18n.T("ARP队列已满"))
+		}
+		req.packets = slices.Delete(req.packets, 0, 1)
+	}
+	req.packets = append(req.packets, pendingPacket{port: port, packet: packet})
+}
+
+// retryARP 发出一次ARP请求并安排重发
+func (host *BaseHost) retryARP(nextHop [4]byte, req *arpRequest) {
+	req.attempts++
+	req.retryAt = Clock + ARPRetryInterval
+	host.sendARPRequest(req.port, nextHop)
+	Schedule(&Event{At: req.retryAt, Kind: EventRetry, Device: host.Name})
+}
+
+// handleTimer 重发到期的ARP请求, 请求次数用完时放弃解析
+func (host *BaseHost) handleTimer(at time.Duration) {
+	var due [][4]byte
+	for nextHop, req := range host.pending {
+		if req.retryAt == at {
+			due = append(due, nextHop)
+		}
+	}
+	slices.SortFunc(due, func(a, b [4]byte) int { return slices.Compare(a[:], b[:]) })
+	for _, nextHop := range due {
+		req := host.pending[nextHop]
+		if req.attempts < ARPMaxAttempts {
+			host.retryARP(nextHop, req)
+			continue
+		}
+		host.arpFailed(nextHop, req)
+	}
+}
+
+// arpFailed 放弃解析下一跳, 丢弃等待的报文, 转发的报文向源主机回送主机不可达
+func (host *BaseHost) arpFailed(nextHop [4]byte, req *arpRequest) {
+	delete(host.pending, nextHop)
+	logf(logL2, host.Name, "%d 次ARP请求没有应答, %s 不可达, 丢弃 %d 个报文",
+		req.attempts, level.FormatIPv4(nextHop), len(req.packets))
+	for _, p := range req.packets {
+		ip, err := level.DeserializeIPv4Packet(p.packet)
+		if err != nil {
+			continue
+		}
+		publishPacketDrop(host.Name, ip, i18n.T("ARP解析失败"))
+		if !host.HasAddress(ip.SourceIP) {
+			host.sendICMPError(ip, level.ICMPTypeUnreachable, 1)
+		}
+	}
+}
+
+// sendARPRequest 广播ARP请求
+func (host *BaseHost) sendARPRequest(port int, target [4]byte) {
+	iface := host.Interfaces[port]
+	arp := level.NewARPPacket(1, iface.MACAddress, iface.IPv4Address, [6]byte{}, target)
+	logf(logL2, host.Name, "ARP 请求: 谁是 %s? 请告诉 %s",
+		level.FormatIPv4(target), level.FormatIPv4(iface.IPv4Address))
+	host.sendFrame(port, level.BroadcastMAC, "ARP", arp.Serialize())
+}
+
+// handleARP 处理ARP报文, 按 RFC 826 的合并规则更新缓存
+func (host *BaseHost) handleARP(port int, payload []byte) {
+	arp, err := level.DeserializeARPPacket(payload)
+	if err != nil || !arp.Validate().Valid() {
+		return
+	}
+	iface := host.Interfaces[port]
+	_, known := host.ARPTable[arp.SenderIP]
+	forMe := iface.IPv4Address != ([4]byte{}) && arp.TargetIP == iface.IPv4Address
+	if known || forMe {
+		host.learnARP(port, arp.SenderIP, arp.SenderMAC)
+	}
+	if forMe && arp.Operation == 1 {
+		reply := level.NewARPPacket(2, iface.MACAddress, iface.IPv4Address, arp.SenderMAC, arp.SenderIP)
+		logf(logL2, host.Name, "ARP 应答: %s 在 %s",
+			level.FormatIPv4(iface.IPv4Address), level.FormatMAC(iface.MACAddress))
+		host.sendFrame(port, arp.SenderMAC, "ARP", reply.Serialize())
+	}
+}
+
+// learnARP 写入ARP缓存并发出等待该地址的报文
+func (host *BaseHost) learnARP(port int, ip [4]byte, mac [6]byte) {
+	host.ARPTable[ip] = ARPEntry{MAC: mac, Port: port, Updated: Clock}
+	publishARPUpdate(host, port, ip, mac)
+	req := host.pending[ip]
+	if req == nil {
+		return
+	}
+	delete(host.pending, ip)
+	cancelEvent(host.Name, EventRetry, req.retryAt)
+	for _, p := range req.packets {
+		host.sendFrame(p.port, mac, "IP", p.packet)
+	}
+}
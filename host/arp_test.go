@@ -0,0 +1,65 @@
+package host
+
+import (
+	"testing"
+	"time"
+)
+
+func TestARPRetry(t *testing.T) {
+	resetSimulator(t)
+	h1, h2, link := connectHosts(t)
+	target := h2.Interfaces[0].IPv4Address
+	link.Loss = 1
+	if err := h1.Ping(target); err != nil {
+		t.Fatal(err)
+	}
+	start := Clock
+	Run(0)
+	if len(h1.pending) != 0 {
+		t.Fatalf("pending = %d next hops after giving up, want 0", len(h1.pending))
+	}
+	if got, want := Clock-start, ARPMaxAttempts*ARPRetryInterval; got != want {
+		t.Errorf("gave up after %v, want %v", got, want)
+	}
+
+	// 链路恢复后重新解析
+	link.Loss = 0
+	if err := h1.Ping(target); err != nil {
+		t.Fatal(err)
+	}
+	start = Clock
+	if n := Run(0); n == 0 {
+		t.Fatal("no events after the link recovered")
+	}
+	if _, ok := h1.ARPTable[target]; !ok {
+		t.Errorf("h1 did not learn %v", target)
+	}
+	if Pending() != 0 {
+		t.Errorf("%d events left, want the retry timer cancelled", Pending())
+	}
+	if Clock-start > 100*time.Millisecond {
+		t.Errorf("run took %v, want the stale retry timer not to advance the clock", Clock-start)
+	}
+}
+
+func TestARPQueueLimit(t *testing.T) {
+	resetSimulator(t)
+	h1, h2, link := connectHosts(t)
+	target := h2.Interfaces[0].IPv4Address
+	link.Loss = 1
+	for range ARPQueueLimit + 2 {
+		if err := h1.Ping(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := h1.pending[target]
+	if req == nil {
+		t.Fatal("no ARP request pending")
+	}
+	if len(req.packets) != ARPQueueLimit {
+		t.Errorf("queued %d packets, want %d", len(req.packets), ARPQueueLimit)
+	}
+	if req.attempts != 1 {
+		t.Errorf("sent %d ARP requests, want 1", req.attempts)
+	}
+}
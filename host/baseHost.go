@@ -1,54 +1,254 @@
 package host
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
 
-// BaseHost 基本主机-端系统
-// @author xuyang
-// @datetime 2025/6/27 8:00
-type BaseHost struct {
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// Interface 网络接口
+type Interface struct {
+	// 接口名称 如 eth0
+	Name string
 	// MAC地址
 	MACAddress [6]byte
 	// IPv4地址
 	IPv4Address [4]byte
-	// 通信端口
+	// 前缀长度
+	PrefixLen int
+	// 最大传输单元, IP报文不能超过该长度
+	MTU int
+	// 子接口的VLAN编号, 物理接口为0
+	VLAN uint16
+	// 子接口所属物理接口的端口号, 物理接口不使用
+	Parent int
+}
+
+// BaseHost 基本主机-端系统
+// @author xuyang
+// @datetime 2025/6/27 8:00
+type BaseHost struct {
+	// 主机名称
+	Name string
+	// 网络接口, 与NetChannel一一对应, 包括VLAN子接口
+	Interfaces []*Interface
+	// 通信端口, 写入的帧由模拟器从对应接口发出; 子接口的帧经父接口发出, 不使用自己的信道
 	NetChannel []chan []byte
+	// 是否转发不属于自己的IP报文(路由器)
+	Forwarding bool
+	// ARP缓存
+	ARPTable map[[4]byte]ARPEntry
+	// 静态路由
+	Routes []Route
+	// 监听中的TCP端口
+	Listening map[uint16]bool
+	// TCP连接
+	TCPConns map[string]*TCPConn
+	// 等待ARP解析的IP报文
+	pending map[[4]byte]*arpRequest
+	// IP 标识计数
+	nextID uint16
+	// ICMP 回显序号计数
+	pingSeq uint16
 }
 
 // Print 打印主机信息
 // @author xuyang
 // @datetime 2025/6/27 8:00
 func (host *BaseHost) print() {
-	fmt.Printf("MAC地址: %02X:%02X:%02X:%02X:%02X:%02X\n",
-		host.MACAddress[0], host.MACAddress[1], host.MACAddress[2],
-		host.MACAddress[3], host.MACAddress[4], host.MACAddress[5])
-	fmt.Printf("IPv4地址: %02d,%02d,%02d,%02d\n",
-		host.IPv4Address[0], host.IPv4Address[1],
-		host.IPv4Address[2], host.IPv4Address[3])
-}
-
-// 全局主机列表
-var HostList []BaseHost
-
-// 全局广播地址 0.0.0.0
-var Forcast chan []byte
-
-// 创建主机
-func NewHost() *BaseHost {
-	newMacAddress := generateMacAddress()
-	newIPv4Address := generateIPv4Address()
-	host := &BaseHost{
-		MACAddress:  newMacAddress,
-		IPv4Address: newIPv4Address,
-		NetChannel:  append(make([]chan []byte, 0), Forcast),
-	}
-	HostList = append(HostList, *host)
+	for _, iface := range host.Interfaces {
+		fmt.Printf(i18n.T("%s MAC地址: %s\n"), iface.Name, level.FormatMAC(iface.MACAddress))
+		fmt.Printf(i18n.T("%s IPv4地址: %s/%d\n"), iface.Name,
+			level.FormatIPv4(iface.IPv4Address), iface.PrefixLen)
+	}
+}
+
+// 全局主机列表(含路由器)
+var HostList []*BaseHost
+
+// NewHost 创建主机, 自带一个接口 eth0
+// @param name 主机名称
+// @return *BaseHost
+func NewHost(name string) *BaseHost {
+	host := newBaseHost(name)
+	host.AddPort()
+	host.Interfaces[0].IPv4Address = generateIPv4Address()
+	host.Interfaces[0].PrefixLen = ipPoolPrefixLen
+	HostList = append(HostList, host)
+	DeviceList = append(DeviceList, host)
 	return host
 }
 
+// newBaseHost 创建没有接口的主机
+func newBaseHost(name string) *BaseHost {
+	return &BaseHost{
+		Name:       name,
+		ARPTable:   make(map[[4]byte]ARPEntry),
+		Listening:  make(map[uint16]bool),
+		TCPConns:   make(map[string]*TCPConn),
+		pending:    make(map[[4]byte]*arpRequest),
+		Interfaces: make([]*Interface, 0),
+		NetChannel: make([]chan []byte, 0),
+	}
+}
+
+// DeviceName 设备名称
+func (host *BaseHost) DeviceName() string {
+	return host.Name
+}
+
+// Ports 通信端口
+func (host *BaseHost) Ports() []chan []byte {
+	return host.NetChannel
+}
+
+// PortName 端口名称
+func (host *BaseHost) PortName(port int) string {
+	return host.Interfaces[port].Name
+}
+
+// AddPort 新增一个没有IP地址的物理接口, 按物理接口的个数命名
+// @return int 新接口的端口号
+func (host *BaseHost) AddPort() int {
+	port := len(host.Interfaces)
+	physical := 0
+	for _, iface := range host.Interfaces {
+		if iface.VLAN == 0 {
+			physical++
+		}
+	}
+	host.Interfaces = append(host.Interfaces, &Interface{
+		Name:       fmt.Sprintf("eth%d", physical),
+		MACAddress: generateMacAddress(),
+		MTU:        DefaultMTU,
+	})
+	host.NetChannel = append(host.NetChannel, make(chan []byte, ChannelSize))
+	return port
+}
+
+// AddSubinterface 在物理接口上新增VLAN子接口, 如 eth0.10, 收发的帧带 802.1Q 标签
+// 子接口与父接口共用MAC地址, 路由器借此在一条干道链路上为多个VLAN转发(单臂路由)
+// @param parent 物理接口的端口号
+// @param vlan VLAN编号 1~4094
+// @return int 子接口的端口号
+// @return error 父接口不存在或是子接口, VLAN编号无效或已有该VLAN的子接口
+func (host *BaseHost) AddSubinterface(parent int, vlan uint16) (int, error) {
+	if parent < 0 || parent >= len(host.Interfaces) {
+		return 0, fmt.Errorf(i18n.T("%s 没有端口 %d"), host.Name, parent)
+	}
+	p := host.Interfaces[parent]
+	if p.VLAN != 0 {
+		return 0, fmt.Errorf(i18n.T("%s 是子接口, 不能再划分子接口"), p.Name)
+	}
+	if err := checkVLAN(vlan); err != nil {
+		return 0, err
+	}
+	if sub := host.subinterface(parent, vlan); sub >= 0 {
+		return 0, fmt.Errorf(i18n.T("子接口已存在: %s"), host.Interfaces[sub].Name)
+	}
+	port := len(host.Interfaces)
+	host.Interfaces = append(host.Interfaces, &Interface{
+		Name:       fmt.Sprintf("%s.%d", p.Name, vlan),
+		MACAddress: p.MACAddress,
+		MTU:        p.MTU,
+		VLAN:       vlan,
+		Parent:     parent,
+	})
+	host.NetChannel = append(host.NetChannel, make(chan []byte, ChannelSize))
+	return port, nil
+}
+
+// subinterface 查找物理接口上该VLAN的子接口, 没有时返回-1
+func (host *BaseHost) subinterface(parent int, vlan uint16) int {
+	for i, iface := range host.Interfaces {
+		if iface.VLAN == vlan && iface.Parent == parent {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetAddress 设置接口IPv4地址
+// @param port 端口号
+// @param ip IPv4地址
+// @param prefixLen 前缀长度
+func (host *BaseHost) SetAddress(port int, ip [4]byte, prefixLen int) error {
+	if port < 0 || port >= len(host.Interfaces) {
+		return fmt.Errorf(i18n.T("%s 没有端口 %d"), host.Name, port)
+	}
+	host.Interfaces[port].IPv4Address = ip
+	host.Interfaces[port].PrefixLen = prefixLen
+	return nil
+}
+
+// SetGateway 设置默认网关
+// @param gateway 网关地址
+func (host *BaseHost) SetGateway(gateway [4]byte) {
+	host.AddRoute([4]byte{}, 0, gateway)
+}
+
+// HasAddress 判断地址是否属于本机
+func (host *BaseHost) HasAddress(ip [4]byte) bool {
+	for _, iface := range host.Interfaces {
+		if iface.IPv4Address == ip && ip != ([4]byte{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsMAC 判断MAC地址是否属于指定接口或为广播
+func (host *BaseHost) ownsMAC(port int, mac [6]byte) bool {
+	return mac == level.BroadcastMAC || mac == host.Interfaces[port].MACAddress
+}
+
+// DefaultMTU 新接口的MTU
+var DefaultMTU = 1500
+
+var macCounter uint32
+var ipCounter uint32
+
+// 地址池: 自动分配的MAC地址前3字节与IPv4网段
+var (
+	// 02 开头为本地管理地址
+	macPoolPrefix   = [3]byte{0x02, 0, 0}
+	ipPoolPrefix    = [4]byte{10, 0, 0, 0}
+	ipPoolPrefixLen = 24
+)
+
+// SetAddressPool 设置自动分配地址使用的地址池
+// @param macPrefix MAC地址前3字节, 必须是单播地址
+// @param prefix IPv4网段
+// @param prefixLen 前缀长度, 8~30
+func SetAddressPool(macPrefix [3]byte, prefix [4]byte, prefixLen int) error {
+	if macPrefix[0]&0x01 != 0 {
+		return fmt.Errorf(i18n.T("MAC地址前缀不能是组播地址: %s"), level.FormatMAC([6]byte{macPrefix[0], macPrefix[1], macPrefix[2]}))
+	}
+	if prefixLen < 8 || prefixLen > 30 {
+		return fmt.Errorf(i18n.T("地址池前缀长度应在8~30之间: %d"), prefixLen)
+	}
+	mask := level.PrefixMask(prefixLen)
+	for i := range prefix {
+		prefix[i] &= mask[i]
+	}
+	macPoolPrefix, ipPoolPrefix, ipPoolPrefixLen = macPrefix, prefix, prefixLen
+	return nil
+}
+
 func generateMacAddress() [6]byte {
-	return [6]byte{0, 0, 0, 0, 0, 0}
+	macCounter++
+	return [6]byte{macPoolPrefix[0], macPoolPrefix[1], macPoolPrefix[2],
+		byte(macCounter >> 16), byte(macCounter >> 8), byte(macCounter)}
 }
 
+// generateIPv4Address 依次分配地址池中的主机地址, 用完后从头开始
 func generateIPv4Address() [4]byte {
-	return [4]byte{0, 0, 0, 0}
+	ipCounter++
+	size := uint32(1)<<(32-ipPoolPrefixLen) - 2
+	n := binary.BigEndian.Uint32(ipPoolPrefix[:]) + (ipCounter-1)%size + 1
+	var ip [4]byte
+	binary.BigEndian.PutUint32(ip[:], n)
+	return ip
 }
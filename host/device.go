@@ -0,0 +1,100 @@
+package host
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// ChannelSize 每个端口通信信道的缓冲帧数
+const ChannelSize = 256
+
+// Device 网络设备(主机、路由器、交换机)
+type Device interface {
+	// DeviceName 设备名称
+	DeviceName() string
+	// Ports 通信端口, 每个端口一个信道
+	Ports() []chan []byte
+	// PortName 端口名称
+	PortName(port int) string
+	// AddPort 新增端口
+	AddPort() int
+	// HandleFrame 处理从端口收到的以太网帧
+	HandleFrame(port int, frame []byte)
+}
+
+// 全局设备列表
+var DeviceList []Device
+
+// FindDevice 按名称查找设备
+// @param name 设备名称
+// @return Device 未找到返回nil
+func FindDevice(name string) Device {
+	for _, dev := range DeviceList {
+		if dev.DeviceName() == name {
+			return dev
+		}
+	}
+	return nil
+}
+
+// FindHost 按名称查找主机或路由器
+// @param name 主机名称
+// @return *BaseHost 未找到返回nil
+func FindHost(name string) *BaseHost {
+	for _, h := range HostList {
+		if h.Name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+// ParsePort 解析端口名称或端口号, 如 eth1 或 1
+// @param dev 设备
+// @param name 端口名称
+// @return int, error
+func ParsePort(dev Device, name string) (int, error) {
+	for i := range dev.Ports() {
+		if dev.PortName(i) == name {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < len(dev.Ports()) {
+		return n, nil
+	}
+	return 0, fmt.Errorf(i18n.T("%s 没有端口 %s"), dev.DeviceName(), name)
+}
+
+// ParseEndpoint 解析 设备[:端口] 形式的端点
+// @param s 端点字符串, 端口缺省时返回-1
+// @return Device, int, error
+func ParseEndpoint(s string) (Device, int, error) {
+	name, portName, hasPort := strings.Cut(s, ":")
+	dev := FindDevice(name)
+	if dev == nil {
+		return nil, 0, fmt.Errorf(i18n.T("设备不存在: %s"), name)
+	}
+	if !hasPort {
+		return dev, -1, nil
+	}
+	port, err := ParsePort(dev, portName)
+	if err != nil {
+		return nil, 0, err
+	}
+	return dev, port, nil
+}
+
+// transmit 将帧写入设备端口信道
+// @return bool 信道已满时丢弃并返回false
+func transmit(dev Device, port int, frame []byte) bool {
+	select {
+	case dev.Ports()[port] <- frame:
+		return true
+	default:
+		logf(logL2, dev.DeviceName(), "端口 %s 发送队列已满, 丢弃帧", dev.PortName(port))
+		return false
+	}
+}
@@ -0,0 +1,263 @@
+package host
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// const 总线事件类型
+const (
+	// BusFrameTx 帧发上链路
+	BusFrameTx = "frame_tx"
+	// BusFrameDrop 帧被丢弃
+	BusFrameDrop = "frame_drop"
+	// BusARPUpdate ARP缓存更新
+	BusARPUpdate = "arp_update"
+	// BusTCPState TCP连接状态变化
+	BusTCPState = "tcp_state"
+	// BusRouteLookup 查路由
+	BusRouteLookup = "route_lookup"
+	// BusSTPState 生成树端口状态变化
+	BusSTPState = "stp_state"
+)
+
+// BusEvent 设备发布到事件总线的事件
+type BusEvent struct {
+	// 递增序号
+	Seq uint64
+	// 虚拟时刻
+	Time time.Duration
+	// 事件类型 BusFrameTx 等
+	Kind string
+	// 发布事件的设备
+	Device string
+	// 帧的接收设备, 其他事件为空
+	Peer string
+	// 链路编号, 与链路无关时为0
+	LinkID int
+	// 事件所在的OSI层
+	Layer int
+	// 涉及的协议, 帧事件为从外到内的协议栈
+	Protocols []string
+	// 可读描述
+	Message string
+	// 附加字段
+	Data map[string]any
+}
+
+// BusFunc 事件订阅回调, 在模拟器内同步调用, 不能阻塞
+type BusFunc func(ev BusEvent)
+
+var (
+	busMu          sync.Mutex
+	busSubscribers = make(map[int]BusFunc)
+	busCounter     int
+	busSeq         uint64
+)
+
+// Subscribe 订阅事件总线, 可以在其他goroutine中调用
+// @param fn 回调
+// @return int 订阅编号
+func Subscribe(fn BusFunc) int {
+	busMu.Lock()
+	defer busMu.Unlock()
+	busCounter++
+	busSubscribers[busCounter] = fn
+	return busCounter
+}
+
+// Unsubscribe 取消订阅
+func Unsubscribe(id int) {
+	busMu.Lock()
+	defer busMu.Unlock()
+	delete(busSubscribers, id)
+}
+
+// hasSubscribers 没有订阅者时不必构造事件
+func hasSubscribers() bool {
+	busMu.Lock()
+	defer busMu.Unlock()
+	return len(busSubscribers) > 0
+}
+
+// publish 按订阅顺序把事件交给所有订阅者
+func publish(ev BusEvent) {
+	busMu.Lock()
+	defer busMu.Unlock()
+	busSeq++
+	ev.Seq = busSeq
+	ev.Time = Clock
+	ids := make([]int, 0, len(busSubscribers))
+	for id := range busSubscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		busSubscribers[id](ev)
+	}
+}
+
+// publishFrameTx 发布帧发上链路事件
+// @param arrive 预计到达对端的时刻
+func publishFrameTx(dev Device, port int, link *Link, to Endpoint, frame []byte, arrive time.Duration) {
+	if !hasSubscribers() {
+		return
+	}
+	publish(BusEvent{
+		Kind:      BusFrameTx,
+		Device:    dev.DeviceName(),
+		Peer:      to.Device.DeviceName(),
+		LinkID:    link.ID,
+		Layer:     2,
+		Protocols: level.Protocols(frame),
+		Message:   level.Summarize(frame),
+		Data: map[string]any{
+			"port":   dev.PortName(port),
+			"size":   len(frame),
+			"arrive": int64(arrive),
+		},
+	})
+}
+
+// publishFrameDrop 发布丢帧事件
+// @param layer 丢弃发生的层: 1链路丢失, 2帧错误, 3IP层丢弃
+// @param reason 丢弃原因
+func publishFrameDrop(dev string, linkID, layer int, frame []byte, reason string) {
+	if !hasSubscribers() {
+		return
+	}
+	publish(BusEvent{
+		Kind:      BusFrameDrop,
+		Device:    dev,
+		LinkID:    linkID,
+		Layer:     layer,
+		Protocols: level.Protocols(frame),
+		Message:   reason,
+		Data:      map[string]any{"size": len(frame), "reason": reason},
+	})
+}
+
+// publishPacketDrop 发布IP层丢弃事件, 报文不含以太网头
+func publishPacketDrop(dev string, ip *level.IPv4Packet, reason string) {
+	if !hasSubscribers() {
+		return
+	}
+	protocols := []string{"ipv4"}
+	switch ip.Protocol {
+	case level.IPProtocolICMP:
+		protocols = append(protocols, "icmp")
+	case level.IPProtocolTCP:
+		protocols = append(protocols, "tcp")
+	case level.IPProtocolUDP:
+		protocols = append(protocols, "udp")
+	}
+	publish(BusEvent{
+		Kind:      BusFrameDrop,
+		Device:    dev,
+		Layer:     3,
+		Protocols: protocols,
+		Message:   reason,
+		Data: map[string]any{
+			"src":    level.FormatIPv4(ip.SourceIP),
+			"dst":    level.FormatIPv4(ip.DestIP),
+			"reason": reason,
+		},
+	})
+}
+
+// publishARPUpdate 发布ARP缓存更新事件
+func publishARPUpdate(host *BaseHost, port int, ip [4]byte, mac [6]byte) {
+	if !hasSubscribers() {
+		return
+	}
+	publish(BusEvent{
+		Kind:      BusARPUpdate,
+		Device:    host.Name,
+		Layer:     2,
+		Protocols: []string{"arp"},
+		Message:   i18n.T("ARP 缓存 %s -> %s", level.FormatIPv4(ip), level.FormatMAC(mac)),
+		Data: map[string]any{
+			"ip":   level.FormatIPv4(ip),
+			"mac":  level.FormatMAC(mac),
+			"port": host.PortName(port),
+		},
+	})
+}
+
+// publishTCPState 发布TCP状态变化事件
+func publishTCPState(host *BaseHost, conn *TCPConn, from, to string) {
+	if !hasSubscribers() {
+		return
+	}
+	publish(BusEvent{
+		Kind:      BusTCPState,
+		Device:    host.Name,
+		Layer:     4,
+		Protocols: []string{"tcp"},
+		Message:   fmt.Sprintf("TCP %s %s -> %s", conn, from, to),
+		Data: map[string]any{
+			"local_port": conn.LocalPort,
+			"remote":     fmt.Sprintf("%s:%d", level.FormatIPv4(conn.RemoteIP), conn.RemotePort),
+			"from":       from,
+			"to":         to,
+		},
+	})
+}
+
+// publishRouteLookup 发布查路由事件
+func publishRouteLookup(host *BaseHost, dst [4]byte, port int, nextHop [4]byte, ok bool) {
+	if !hasSubscribers() {
+		return
+	}
+	data := map[string]any{"dst": level.FormatIPv4(dst), "found": ok}
+	message := i18n.T("查路由 %s: 没有路由", level.FormatIPv4(dst))
+	if ok {
+		data["port"] = host.PortName(port)
+		data["next_hop"] = level.FormatIPv4(nextHop)
+		message = i18n.T("查路由 %s: %s 下一跳 %s", level.FormatIPv4(dst),
+			host.PortName(port), level.FormatIPv4(nextHop))
+	}
+	publish(BusEvent{
+		Kind:      BusRouteLookup,
+		Device:    host.Name,
+		Layer:     3,
+		Protocols: []string{"ipv4"},
+		Message:   message,
+		Data:      data,
+	})
+}
+
+// publishSTPState 发布生成树端口状态变化事件
+func publishSTPState(sw *Switch, port int) {
+	if !hasSubscribers() {
+		return
+	}
+	p := sw.STP.Ports[port]
+	publish(BusEvent{
+		Kind:      BusSTPState,
+		Device:    sw.Name,
+		LinkID:    linkID(sw, port),
+		Layer:     2,
+		Protocols: []string{"stp"},
+		Message:   i18n.T("生成树 %s: %s %s", sw.PortName(port), p.Role, p.State),
+		Data: map[string]any{
+			"port":  sw.PortName(port),
+			"role":  p.Role.Name(),
+			"state": p.State.Name(),
+			"root":  sw.STP.RootID.String(),
+		},
+	})
+}
+
+// linkID 端口所连链路的编号, 未连接为0
+func linkID(dev Device, port int) int {
+	if l := LinkAt(dev, port); l != nil {
+		return l.ID
+	}
+	return 0
+}
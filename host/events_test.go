@@ -0,0 +1,36 @@
+package host
+
+import (
+	"slices"
+	"testing"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// 事件消息随界面语言变化
+func TestEventMessagesTranslated(t *testing.T) {
+	if err := i18n.SetLang("en"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { i18n.SetLang("zh") })
+	resetSimulator(t)
+	h1, h2, _ := connectHosts(t)
+	var messages []string
+	id := Subscribe(func(ev BusEvent) { messages = append(messages, ev.Message) })
+	defer Unsubscribe(id)
+	if err := h1.Ping(h2.Interfaces[0].IPv4Address); err != nil {
+		t.Fatal(err)
+	}
+	Run(0)
+	// 没有网关, 发往其他网段的报文在查路由时失败
+	h1.Ping([4]byte{192, 0, 2, 1})
+	for _, want := range []string{
+		"ARP cache 10.0.0.2 -> " + level.FormatMAC(h2.Interfaces[0].MACAddress),
+		"route lookup 192.0.2.1: no route",
+	} {
+		if !slices.Contains(messages, want) {
+			t.Errorf("no event %q in %q", want, messages)
+		}
+	}
+}
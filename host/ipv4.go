@@ -0,0 +1,233 @@
+package host
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// Route 静态路由
+type Route struct {
+	// 目的网络
+	Prefix [4]byte
+	// 前缀长度
+	PrefixLen int
+	// 下一跳, 全0表示直连
+	NextHop [4]byte
+}
+
+// AddRoute 添加静态路由
+// @param prefix 目的网络
+// @param prefixLen 前缀长度
+// @param nextHop 下一跳
+func (host *BaseHost) AddRoute(prefix [4]byte, prefixLen int, nextHop [4]byte) {
+	mask := level.PrefixMask(prefixLen)
+	for i := range prefix {
+		prefix[i] &= mask[i]
+	}
+	for i, r := range host.Routes {
+		if r.Prefix == prefix && r.PrefixLen == prefixLen {
+			host.Routes[i].NextHop = nextHop
+			return
+		}
+	}
+	host.Routes = append(host.Routes, Route{Prefix: prefix, PrefixLen: prefixLen, NextHop: nextHop})
+}
+
+// LookupRoute 最长前缀匹配查找路由
+// @param dst 目的地址
+// @return port 出端口
+// @return nextHop 下一跳地址
+// @return ok 是否找到
+func (host *BaseHost) LookupRoute(dst [4]byte) (port int, nextHop [4]byte, ok bool) {
+	best := -1
+	for i, iface := range host.Interfaces {
+		if iface.IPv4Address == ([4]byte{}) {
+			continue
+		}
+		if level.SameSubnet(dst, iface.IPv4Address, iface.PrefixLen) && iface.PrefixLen > best {
+			best, port, nextHop, ok = iface.PrefixLen, i, dst, true
+		}
+	}
+	for _, r := range host.Routes {
+		if r.PrefixLen <= best || !level.SameSubnet(dst, r.Prefix, r.PrefixLen) {
+			continue
+		}
+		// 下一跳必须直连
+		for i, iface := range host.Interfaces {
+			if iface.IPv4Address != ([4]byte{}) && level.SameSubnet(r.NextHop, iface.IPv4Address, iface.PrefixLen) {
+				best, port, nextHop, ok = r.PrefixLen, i, r.NextHop, true
+				break
+			}
+		}
+	}
+	return port, nextHop, ok
+}
+
+// routeTo 查路由, 记录日志并发布查路由事件
+func (host *BaseHost) routeTo(dst [4]byte) (int, [4]byte, bool) {
+	port, nextHop, ok := host.LookupRoute(dst)
+	if ok {
+		debugf(logL3, host.Name, "查路由 %s: %s 下一跳 %s", level.FormatIPv4(dst),
+			host.PortName(port), level.FormatIPv4(nextHop))
+	} else {
+		debugf(logL3, host.Name, "查路由 %s: 没有路由", level.FormatIPv4(dst))
+	}
+	publishRouteLookup(host, dst, port, nextHop, ok)
+	return port, nextHop, ok
+}
+
+// sendIPv4 查路由并发送IP报文
+// @param dst 目的地址
+// @param protocol 上层协议号
+// @param payload 根据源地址生成上层数据(TCP/UDP校验和需要源地址)
+func (host *BaseHost) sendIPv4(dst [4]byte, protocol uint8, payload func(src [4]byte) []byte) error {
+	port, nextHop, ok := host.routeTo(dst)
+	if !ok {
+		return fmt.Errorf(i18n.T("%s 没有到 %s 的路由"), host.Name, level.FormatIPv4(dst))
+	}
+	iface := host.Interfaces[port]
+	packet := level.NewIPv4Packet(iface.IPv4Address, dst, protocol, payload(iface.IPv4Address))
+	if int(packet.TotalLength) > iface.MTU {
+		// 模拟器不实现分片
+		return fmt.Errorf(i18n.T("%s 报文长度 %d 超过 %s 的MTU %d"), host.Name, packet.TotalLength, iface.Name, iface.MTU)
+	}
+	host.nextID++
+	packet.Identification = host.nextID
+	host.sendIPv4Via(port, nextHop, packet.Serialize())
+	return nil
+}
+
+// handleIPv4 处理IP报文: 本机接收或转发
+func (host *BaseHost) handleIPv4(port int, payload []byte) {
+	ip, err := level.DeserializeIPv4Packet(payload)
+	if err != nil || !ip.Validate().Valid() {
+		return
+	}
+	if host.HasAddress(ip.DestIP) {
+		host.deliverLocal(ip)
+		return
+	}
+	if host.Forwarding {
+		host.forward(port, ip)
+	}
+}
+
+// forward 转发IP报文
+func (host *BaseHost) forward(inPort int, ip *level.IPv4Packet) {
+	if ip.TTL <= 1 {
+		logf(logL3, host.Name, "%s -> %s TTL耗尽, 丢弃", level.FormatIPv4(ip.SourceIP), level.FormatIPv4(ip.DestIP))
+		publishPacketDrop(host.Name, ip, i18n.T("TTL耗尽"))
+		host.sendICMPError(ip, level.ICMPTypeTimeExceeded, 0)
+		return
+	}
+	port, nextHop, ok := host.routeTo(ip.DestIP)
+	if !ok {
+		logf(logL3, host.Name, "没有到 %s 的路由, 丢弃", level.FormatIPv4(ip.DestIP))
+		publishPacketDrop(host.Name, ip, i18n.T("没有路由"))
+		host.sendICMPError(ip, level.ICMPTypeUnreachable, 0)
+		return
+	}
+	if int(ip.TotalLength) > host.Interfaces[port].MTU {
+		logf(logL3, host.Name, "%s -> %s 报文长度 %d 超过 %s 的MTU, 丢弃", level.FormatIPv4(ip.SourceIP),
+			level.FormatIPv4(ip.DestIP), ip.TotalLength, host.PortName(port))
+		publishPacketDrop(host.Name, ip, i18n.T("超过MTU"))
+		// 模拟器不实现分片, 回复 fragmentation needed
+		host.sendICMPError(ip, level.ICMPTypeUnreachable, 4)
+		return
+	}
+	ip.TTL--
+	logf(logL3, host.Name, "转发 %s -> %s: %s => %s 下一跳 %s", level.FormatIPv4(ip.SourceIP),
+		level.FormatIPv4(ip.DestIP), host.PortName(inPort), host.PortName(port), level.FormatIPv4(nextHop))
+	host.sendIPv4Via(port, nextHop, ip.Serialize())
+}
+
+// deliverLocal 交给本机上层协议
+func (host *BaseHost) deliverLocal(ip *level.IPv4Packet) {
+	switch ip.Protocol {
+	case level.IPProtocolICMP:
+		host.handleICMP(ip)
+	case level.IPProtocolUDP:
+		udp, err := level.DeserializeUDPPacket(ip.Data)
+		if err != nil {
+			return
+		}
+		logf(logL4, host.Name, "收到 UDP %s:%d -> :%d %q", level.FormatIPv4(ip.SourceIP),
+			udp.SourcePort, udp.DestPort, udp.Data)
+	case level.IPProtocolTCP:
+		host.handleTCP(ip)
+	}
+}
+
+// Ping 发送ICMP回显请求, 数据部分携带发送时刻用于计算往返时延
+// @param dst 目的地址
+// @return error 没有路由时返回错误
+func (host *BaseHost) Ping(dst [4]byte) error {
+	host.pingSeq++
+	seq := host.pingSeq
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(Clock))
+	return host.sendIPv4(dst, level.IPProtocolICMP, func(src [4]byte) []byte {
+		return level.NewICMPPacket(level.ICMPTypeEchoRequest, 0, 1, seq, data).Serialize()
+	})
+}
+
+// SendUDP 发送UDP数据报
+// @param dst 目的地址
+// @param srcPort 源端口
+// @param dstPort 目的端口
+// @param data 数据
+func (host *BaseHost) SendUDP(dst [4]byte, srcPort, dstPort uint16, data []byte) error {
+	return host.sendIPv4(dst, level.IPProtocolUDP, func(src [4]byte) []byte {
+		return level.NewUDPPacket(srcPort, dstPort, data).Serialize(src, dst)
+	})
+}
+
+// handleICMP 处理ICMP报文
+func (host *BaseHost) handleICMP(ip *level.IPv4Packet) {
+	icmp, err := level.DeserializeICMPPacket(ip.Data)
+	if err != nil {
+		return
+	}
+	src := level.FormatIPv4(ip.SourceIP)
+	switch icmp.Type {
+	case level.ICMPTypeEchoRequest:
+		logf(logL3, host.Name, "收到来自 %s 的 ICMP 回显请求 seq=%d", src, icmp.Sequence)
+		reply := level.NewICMPPacket(level.ICMPTypeEchoReply, 0, icmp.Identifier, icmp.Sequence, icmp.Data)
+		host.sendIPv4(ip.SourceIP, level.IPProtocolICMP, func(src [4]byte) []byte {
+			return reply.Serialize()
+		})
+	case level.ICMPTypeEchoReply:
+		rtt := ""
+		if len(icmp.Data) >= 8 {
+			sent := time.Duration(binary.BigEndian.Uint64(icmp.Data[:8]))
+			rtt = " time=" + (Clock - sent).String()
+		}
+		logf(logL3, host.Name, "来自 %s 的回复: seq=%d ttl=%d%s", src, icmp.Sequence, ip.TTL, rtt)
+	default:
+		logf(logL3, host.Name, "收到来自 %s 的 ICMP %s", src, level.ICMPTypeName(icmp.Type))
+	}
+}
+
+// sendICMPError 回送ICMP差错报文, 数据为原报文头部加8字节
+func (host *BaseHost) sendICMPError(orig *level.IPv4Packet, typ, code uint8) {
+	if orig.Protocol == level.IPProtocolICMP {
+		// 不对ICMP差错报文再产生差错报文, 避免循环
+		icmp, err := level.DeserializeICMPPacket(orig.Data)
+		if err != nil || (icmp.Type != level.ICMPTypeEchoRequest && icmp.Type != level.ICMPTypeEchoReply) {
+			return
+		}
+	}
+	raw := orig.Serialize()
+	n := int(orig.VersionIHL&0x0F)*4 + 8
+	if n > len(raw) {
+		n = len(raw)
+	}
+	data := raw[:n]
+	host.sendIPv4(orig.SourceIP, level.IPProtocolICMP, func(src [4]byte) []byte {
+		return level.NewICMPPacket(typ, code, 0, 0, data).Serialize()
+	})
+}
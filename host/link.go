@@ -0,0 +1,197 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// Endpoint 链路端点
+type Endpoint struct {
+	// 设备
+	Device Device
+	// 端口号
+	Port int
+}
+
+// String 返回 设备:端口 形式的名称
+func (e Endpoint) String() string {
+	return e.Device.DeviceName() + ":" + e.Device.PortName(e.Port)
+}
+
+// Link 点到点链路
+type Link struct {
+	// 链路编号
+	ID int
+	// 两端端点
+	A, B Endpoint
+	// 传播时延
+	Delay time.Duration
+	// 带宽(bit/s), 0表示不计发送时延
+	Bandwidth int64
+	// 丢包率 0~1
+	Loss float64
+	// 误码率 0~1, 每个线路码元被翻转的概率
+	BER float64
+	// 线路编码, LineAuto 按带宽选择
+	LineCode level.LineCode
+	// 两个方向上链路空闲的时刻, 用于计算排队
+	busyUntil [2]time.Duration
+}
+
+// 默认链路参数
+var (
+	DefaultLinkDelay     = time.Millisecond
+	DefaultLinkBandwidth = int64(100_000_000)
+	DefaultLinkLoss      = 0.0
+)
+
+// 全局链路列表
+var LinkList []*Link
+
+var linkCounter int
+
+// Connect 用链路连接两个设备端口
+// @param a 端点A
+// @param b 端点B
+// @return *Link, error
+func Connect(a, b Endpoint) (*Link, error) {
+	if a.Device == b.Device && a.Port == b.Port {
+		return nil, errors.New(i18n.T("不能将端口连接到自身"))
+	}
+	for _, ep := range []Endpoint{a, b} {
+		if isSubinterface(ep.Device, ep.Port) {
+			return nil, fmt.Errorf(i18n.T("%s 是子接口, 链路应连接其父接口"), ep)
+		}
+		if l := LinkAt(ep.Device, ep.Port); l != nil {
+			return nil, fmt.Errorf(i18n.T("端口 %s 已连接到链路 %d"), ep, l.ID)
+		}
+	}
+	linkCounter++
+	link := &Link{
+		ID:        linkCounter,
+		A:         a,
+		B:         b,
+		Delay:     DefaultLinkDelay,
+		Bandwidth: DefaultLinkBandwidth,
+		Loss:      DefaultLinkLoss,
+	}
+	LinkList = append(LinkList, link)
+	return link, nil
+}
+
+// Disconnect 删除链路
+// @param id 链路编号
+func Disconnect(id int) error {
+	for i, l := range LinkList {
+		if l.ID == id {
+			LinkList = append(LinkList[:i], LinkList[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf(i18n.T("链路不存在: %d"), id)
+}
+
+// FindLink 按编号查找链路
+func FindLink(id int) *Link {
+	for _, l := range LinkList {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// LinkAt 查找连接在设备端口上的链路
+// @return *Link 未连接返回nil
+func LinkAt(dev Device, port int) *Link {
+	for _, l := range LinkList {
+		if (l.A.Device == dev && l.A.Port == port) || (l.B.Device == dev && l.B.Port == port) {
+			return l
+		}
+	}
+	return nil
+}
+
+// FreePort 返回设备上第一个未连接链路的端口, 没有则新增端口
+func FreePort(dev Device) int {
+	for i := range dev.Ports() {
+		if LinkAt(dev, i) == nil && !isSubinterface(dev, i) {
+			return i
+		}
+	}
+	return dev.AddPort()
+}
+
+// isSubinterface 端口是否为主机的VLAN子接口, 子接口不能单独连接链路
+func isSubinterface(dev Device, port int) bool {
+	h, ok := dev.(*BaseHost)
+	return ok && port >= 0 && port < len(h.Interfaces) && h.Interfaces[port].VLAN != 0
+}
+
+// peer 返回链路另一端及方向下标
+func (l *Link) peer(dev Device, port int) (Endpoint, int) {
+	if l.A.Device == dev && l.A.Port == port {
+		return l.B, 0
+	}
+	return l.A, 1
+}
+
+// Code 链路实际使用的线路编码, 未指定时按带宽选择
+func (l *Link) Code() level.LineCode {
+	if l.LineCode == level.LineAuto {
+		return level.LineCodeFor(l.Bandwidth)
+	}
+	return l.LineCode
+}
+
+// String 链路描述
+func (l *Link) String() string {
+	s := fmt.Sprintf("%d: %s <-> %s delay=%s bw=%s loss=%g",
+		l.ID, l.A, l.B, l.Delay, FormatBandwidth(l.Bandwidth), l.Loss)
+	if l.BER > 0 || l.LineCode != level.LineAuto {
+		s += fmt.Sprintf(" ber=%g code=%s", l.BER, l.Code())
+	}
+	return s
+}
+
+// lineErrors 在线路编码后的信号上按误码率翻转码元, 返回接收方物理层还原的帧
+// 物理层发现编码错误或找不到帧起始定界符时丢弃该帧, 其余错误留给接收方的CRC检查
+// @param dev 发送设备
+// @param frame 以太网帧
+// @return []byte 接收到的帧
+// @return bool 物理层丢弃时为false
+func (l *Link) lineErrors(dev Device, frame []byte) ([]byte, bool) {
+	sig := level.EncodeFrame(l.Code(), frame)
+	n := sig.InjectErrors(l.BER, rng)
+	if n == 0 {
+		return frame, true
+	}
+	received, err := sig.DecodeFrame()
+	if err != nil {
+		logf(logL2, dev.DeviceName(), "链路 %d 上的帧出现 %d 个误码, 物理层丢弃: %v", l.ID, n, err)
+		publishFrameDrop(dev.DeviceName(), l.ID, 1, frame, err.Error())
+		return nil, false
+	}
+	debugf(logL2, dev.DeviceName(), "链路 %d 上的帧出现 %d 个误码", l.ID, n)
+	return received, true
+}
+
+// FormatBandwidth 格式化带宽
+func FormatBandwidth(bw int64) string {
+	switch {
+	case bw == 0:
+		return "inf"
+	case bw%1_000_000_000 == 0:
+		return fmt.Sprintf("%dG", bw/1_000_000_000)
+	case bw%1_000_000 == 0:
+		return fmt.Sprintf("%dM", bw/1_000_000)
+	case bw%1_000 == 0:
+		return fmt.Sprintf("%dK", bw/1_000)
+	default:
+		return fmt.Sprintf("%d", bw)
+	}
+}
@@ -0,0 +1,12 @@
+package host
+
+// NewRouter 创建路由器, 接口在连接链路时按需添加
+// @param name 路由器名称
+// @return *BaseHost 开启转发的主机
+func NewRouter(name string) *BaseHost {
+	router := newBaseHost(name)
+	router.Forwarding = true
+	HostList = append(HostList, router)
+	DeviceList = append(DeviceList, router)
+	return router
+}
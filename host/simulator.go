@@ -0,0 +1,397 @@
+package host
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+	"osiweb-go/logging"
+)
+
+// Clock 虚拟时钟, 从0开始, 只在处理事件时前进
+var Clock time.Duration
+
+// MaxEventsPerRun 单次 Run 最多处理的事件数, 防止广播风暴时卡死
+var MaxEventsPerRun = 100000
+
+// rng 丢包等随机行为使用的随机数发生器, 固定种子保证可重现
+var rng, rngSource = newRand(1)
+
+// countingSource 记录取数次数, 恢复快照时用相同种子重放到同一位置
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+// newRand 创建计数的随机数发生器
+func newRand(seed int64) (*rand.Rand, *countingSource) {
+	src := &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	return rand.New(src), src
+}
+
+// const 时间模式
+const (
+	// TimeVirtual 尽快处理事件, 虚拟时间与真实时间无关
+	TimeVirtual = "virtual"
+	// TimeRealtime Run 按真实时间的节奏处理事件, 方便观察动画
+	TimeRealtime = "realtime"
+)
+
+// TimeMode 当前时间模式
+var TimeMode = TimeVirtual
+
+// Seed 设置随机数种子
+func Seed(seed int64) {
+	rng, rngSource = newRand(seed)
+}
+
+// const 事件类型
+const (
+	// EventDeliver 帧到达设备端口
+	EventDeliver = iota
+	// EventInject 帧从设备端口发出
+	EventInject
+	// EventTimer 设备的定时器到期, 如生成树的每秒计时
+	EventTimer
+	// EventRetry 设备的重试定时器到期, 如ARP请求的重发, 与周期定时器不同, Run 会等待它
+	EventRetry
+)
+
+// Event 模拟器事件
+type Event struct {
+	// 触发时刻
+	At time.Duration
+	// 序号, 同一时刻按加入顺序处理
+	Seq uint64
+	// 事件类型
+	Kind int
+	// 目标设备名称
+	Device string
+	// 目标端口
+	Port int
+	// 所经链路编号, 注入事件为0
+	LinkID int
+	// 以太网帧, 定时器事件为空
+	Frame []byte
+}
+
+// eventHeap 按时间排序的事件队列
+type eventHeap []*Event
+
+func (q eventHeap) Len() int { return len(q) }
+func (q eventHeap) Less(i, j int) bool {
+	if q[i].At != q[j].At {
+		return q[i].At < q[j].At
+	}
+	return q[i].Seq < q[j].Seq
+}
+func (q eventHeap) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *eventHeap) Push(x any)   { *q = append(*q, x.(*Event)) }
+func (q *eventHeap) Pop() any {
+	old := *q
+	ev := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return ev
+}
+
+var eventQueue eventHeap
+var eventSeq uint64
+
+// Schedule 加入事件
+// @param ev 事件, Seq 由模拟器分配
+func Schedule(ev *Event) {
+	eventSeq++
+	ev.Seq = eventSeq
+	heap.Push(&eventQueue, ev)
+}
+
+// InjectAt 在指定虚拟时刻从设备端口发出一帧
+// @param at 虚拟时刻
+// @param dev 设备
+// @param port 端口号
+// @param frame 以太网帧
+func InjectAt(at time.Duration, dev Device, port int, frame []byte) {
+	Schedule(&Event{At: at, Kind: EventInject, Device: dev.DeviceName(), Port: port, Frame: frame})
+}
+
+// timerDevice 处理定时器事件的设备
+type timerDevice interface {
+	// handleTimer at 为事件安排的时刻, 与设备记录的下次定时不同时是已作废的定时器
+	handleTimer(at time.Duration)
+}
+
+// onlyTimers 待处理的事件是否都是定时器, 此时网络上已没有帧
+func onlyTimers() bool {
+	for _, ev := range eventQueue {
+		if ev.Kind != EventTimer {
+			return false
+		}
+	}
+	return true
+}
+
+// cancelEvent 取消设备的一个定时器事件
+func cancelEvent(device string, kind int, at time.Duration) {
+	for i, ev := range eventQueue {
+		if ev.Device == device && ev.Kind == kind && ev.At == at {
+			heap.Remove(&eventQueue, i)
+			return
+		}
+	}
+}
+
+// Pending 返回尚未处理的事件数
+func Pending() int {
+	pump()
+	return eventQueue.Len()
+}
+
+// Step 处理下一个事件
+// @return bool 没有待处理事件时返回false
+func Step() bool {
+	pump()
+	if eventQueue.Len() == 0 {
+		return false
+	}
+	ev := heap.Pop(&eventQueue).(*Event)
+	if ev.At > Clock {
+		Clock = ev.At
+	}
+	dispatch(ev)
+	pump()
+	return true
+}
+
+// Run 连续处理事件
+// @param limit 最多推进的虚拟时间, 0表示直到没有帧, 只剩定时器时停止
+// @return int 处理的事件数
+func Run(limit time.Duration) int {
+	deadline := Clock + limit
+	count := 0
+	startClock, startWall := Clock, time.Now()
+	for count < MaxEventsPerRun {
+		pump()
+		if eventQueue.Len() == 0 || (limit == 0 && onlyTimers()) {
+			break
+		}
+		if limit > 0 && eventQueue[0].At > deadline {
+			break
+		}
+		if TimeMode == TimeRealtime {
+			time.Sleep(time.Until(startWall.Add(eventQueue[0].At - startClock)))
+		}
+		Step()
+		count++
+	}
+	if limit > 0 && Clock < deadline {
+		Clock = deadline
+	}
+	return count
+}
+
+// dispatch 执行事件
+func dispatch(ev *Event) {
+	dev := FindDevice(ev.Device)
+	if t, ok := dev.(timerDevice); ok && (ev.Kind == EventTimer || ev.Kind == EventRetry) {
+		t.handleTimer(ev.At)
+		return
+	}
+	if dev == nil || ev.Port >= len(dev.Ports()) {
+		return
+	}
+	switch ev.Kind {
+	case EventDeliver:
+		if LinkAt(dev, ev.Port) == nil || LinkAt(dev, ev.Port).ID != ev.LinkID {
+			// 链路在传输途中被删除
+			publishFrameDrop(ev.Device, ev.LinkID, 1, ev.Frame, i18n.T("链路 %d 已删除", ev.LinkID))
+			return
+		}
+		capture(CapturedFrame{Time: Clock, Device: ev.Device, Port: ev.Port,
+			LinkID: ev.LinkID, Direction: DirectionRx, Data: ev.Frame})
+		tracef(logL2, ev.Device, "%s 收到 %s", dev.PortName(ev.Port), frameSummary(ev.Frame))
+		dev.HandleFrame(ev.Port, ev.Frame)
+	case EventInject:
+		transmit(dev, ev.Port, ev.Frame)
+	}
+}
+
+// pump 取出所有设备端口信道中的帧并放上链路
+func pump() {
+	for _, dev := range DeviceList {
+		for port, ch := range dev.Ports() {
+		drain:
+			for {
+				select {
+				case frame := <-ch:
+					sendOnLink(dev, port, frame)
+				default:
+					break drain
+				}
+			}
+		}
+	}
+}
+
+// sendOnLink 计算发送与传播时延, 安排帧到达对端
+func sendOnLink(dev Device, port int, frame []byte) {
+	link := LinkAt(dev, port)
+	linkID := 0
+	if link != nil {
+		linkID = link.ID
+	}
+	capture(CapturedFrame{Time: Clock, Device: dev.DeviceName(), Port: port,
+		LinkID: linkID, Direction: DirectionTx, Data: frame})
+	if link == nil {
+		tracef(logL2, dev.DeviceName(), "%s 未连接链路, 丢弃 %s", dev.PortName(port), frameSummary(frame))
+		return
+	}
+	tracef(logL2, dev.DeviceName(), "%s 发送 %s", dev.PortName(port), frameSummary(frame))
+	to, dir := link.peer(dev, port)
+	start := Clock
+	if link.busyUntil[dir] > start {
+		start = link.busyUntil[dir]
+	}
+	var txTime time.Duration
+	if link.Bandwidth > 0 {
+		txTime = time.Duration(int64(len(frame)) * 8 * int64(time.Second) / link.Bandwidth)
+	}
+	link.busyUntil[dir] = start + txTime
+	publishFrameTx(dev, port, link, to, frame, start+txTime+link.Delay)
+	if link.Loss > 0 && rng.Float64() < link.Loss {
+		logf(logL2, dev.DeviceName(), "链路 %d 丢失了一帧", link.ID)
+		publishFrameDrop(dev.DeviceName(), link.ID, 1, frame, i18n.T("链路 %d 丢失了一帧", link.ID))
+		return
+	}
+	if link.BER > 0 {
+		var ok bool
+		if frame, ok = link.lineErrors(dev, frame); !ok {
+			return
+		}
+	}
+	Schedule(&Event{
+		At:     start + txTime + link.Delay,
+		Kind:   EventDeliver,
+		Device: to.Device.DeviceName(),
+		Port:   to.Port,
+		LinkID: link.ID,
+		Frame:  frame,
+	})
+}
+
+// const 抓包方向
+const (
+	DirectionTx = "tx"
+	DirectionRx = "rx"
+)
+
+// CapturedFrame 抓到的帧
+type CapturedFrame struct {
+	// 虚拟时刻
+	Time time.Duration
+	// 设备名称
+	Device string
+	// 端口号
+	Port int
+	// 链路编号, 未连接为0
+	LinkID int
+	// 方向 tx/rx
+	Direction string
+	// 以太网帧
+	Data []byte
+}
+
+// TapFunc 抓包回调
+type TapFunc func(f CapturedFrame)
+
+var taps = make(map[int]TapFunc)
+var tapCounter int
+
+// AddTap 注册抓包回调, 所有设备收发的帧都会交给回调
+// @return int 回调编号
+func AddTap(fn TapFunc) int {
+	tapCounter++
+	taps[tapCounter] = fn
+	return tapCounter
+}
+
+// RemoveTap 注销抓包回调
+func RemoveTap(id int) {
+	delete(taps, id)
+}
+
+// capture 将帧交给所有抓包回调
+func capture(f CapturedFrame) {
+	ids := make([]int, 0, len(taps))
+	for id := range taps {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		taps[id](f)
+	}
+}
+
+// FormatClock 格式化虚拟时刻
+func FormatClock(d time.Duration) string {
+	return fmt.Sprintf("%.6fs", d.Seconds())
+}
+
+// 各子系统的日志
+var (
+	logL2 = logging.Logger(logging.L2)
+	logL3 = logging.Logger(logging.L3)
+	logL4 = logging.Logger(logging.L4)
+)
+
+// logf 以 info 级别打印设备日志
+func logf(log *slog.Logger, dev string, format string, args ...any) {
+	logAt(log, slog.LevelInfo, dev, format, args...)
+}
+
+// debugf 以 debug 级别打印设备日志
+func debugf(log *slog.Logger, dev string, format string, args ...any) {
+	logAt(log, slog.LevelDebug, dev, format, args...)
+}
+
+// tracef 逐帧日志, 只对开启 trace 的设备或级别为 trace 的子系统输出
+func tracef(log *slog.Logger, dev string, format string, args ...any) {
+	logAt(log, logging.LevelTrace, dev, format, args...)
+}
+
+// logAt 记录带虚拟时刻与设备名称的日志, 级别不够时不格式化消息
+func logAt(log *slog.Logger, l slog.Level, dev string, format string, args ...any) {
+	ctx := context.Background()
+	if !log.Enabled(ctx, l) {
+		return
+	}
+	log.Log(ctx, l, i18n.T(format, args...), logging.KeyClock, Clock, logging.KeyHost, dev)
+}
+
+// frameSummary 帧摘要, 作为日志参数时才调用 level.Summarize
+type frameSummary []byte
+
+func (f frameSummary) String() string {
+	return level.Summarize(f)
+}
@@ -0,0 +1,310 @@
+package host
+
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// Snapshot 模拟器的完整状态, 字段均可由 encoding/gob 编码
+type Snapshot struct {
+	// 虚拟时钟
+	Clock time.Duration
+	// 设备名称, 按 DeviceList 的顺序
+	Devices []string
+	// 主机与路由器
+	Hosts []HostState
+	// 交换机
+	Switches []SwitchState
+	// 链路
+	Links []LinkState
+	// 待处理的事件, 包括链路上传输中的帧
+	Events []Event
+	// 事件序号计数
+	EventSeq uint64
+	// 链路编号计数
+	LinkCounter int
+	// 自动分配地址计数
+	MACCounter, IPCounter uint32
+	// 最近分配的TCP临时端口
+	EphemeralPort uint16
+	// 随机数种子与已取数次数
+	RandSeed  int64
+	RandDraws uint64
+}
+
+// HostState 主机或路由器的状态
+type HostState struct {
+	// 主机名称
+	Name string
+	// 是否转发
+	Forwarding bool
+	// 网络接口
+	Interfaces []Interface
+	// ARP缓存
+	ARPTable map[[4]byte]ARPEntry
+	// 静态路由
+	Routes []Route
+	// 监听中的TCP端口
+	Listening []uint16
+	// TCP连接
+	TCPConns []TCPConnState
+	// 等待ARP解析的IP报文
+	Pending []PendingState
+	// IP 标识与 ICMP 回显序号计数
+	NextID, PingSeq uint16
+}
+
+// TCPConnState TCP连接及连接建立前排队的数据
+type TCPConnState struct {
+	Conn   TCPConn
+	Queued []byte
+}
+
+// PendingState 等待ARP解析的IP报文
+type PendingState struct {
+	// 下一跳
+	NextHop [4]byte
+	// 出端口
+	Port int
+	// IP报文
+	Packet []byte
+	// 已发出的ARP请求数与下次重发的时刻, 同一下一跳的报文相同
+	Attempts int
+	RetryAt  time.Duration
+}
+
+// SwitchState 交换机的状态
+type SwitchState struct {
+	// 交换机名称
+	Name string
+	// 端口数
+	Ports int
+	// 端口的VLAN设置
+	PortConfig []SwitchPort
+	// MAC地址表
+	MACTable map[MACKey]MACEntry
+	// 网桥地址
+	MACAddress [6]byte
+	// 生成树协议
+	STP STP
+}
+
+// LinkState 链路的状态
+type LinkState struct {
+	// 链路编号
+	ID int
+	// 两端的设备名称与端口号
+	DeviceA, DeviceB string
+	PortA, PortB     int
+	// 传播时延
+	Delay time.Duration
+	// 带宽
+	Bandwidth int64
+	// 丢包率
+	Loss float64
+	// 误码率
+	BER float64
+	// 线路编码
+	LineCode level.LineCode
+	// 两个方向上链路空闲的时刻
+	BusyUntil [2]time.Duration
+}
+
+// TakeSnapshot 记录当前状态, 端口信道中的帧先放上链路
+// @return *Snapshot
+func TakeSnapshot() *Snapshot {
+	pump()
+	s := &Snapshot{
+		Clock:         Clock,
+		EventSeq:      eventSeq,
+		LinkCounter:   linkCounter,
+		MACCounter:    macCounter,
+		IPCounter:     ipCounter,
+		EphemeralPort: ephemeralPort,
+		RandSeed:      rngSource.seed,
+		RandDraws:     rngSource.draws,
+	}
+	for _, dev := range DeviceList {
+		s.Devices = append(s.Devices, dev.DeviceName())
+	}
+	for _, h := range HostList {
+		hs := HostState{
+			Name:       h.Name,
+			Forwarding: h.Forwarding,
+			ARPTable:   make(map[[4]byte]ARPEntry, len(h.ARPTable)),
+			Routes:     slices.Clone(h.Routes),
+			NextID:     h.nextID,
+			PingSeq:    h.pingSeq,
+		}
+		for _, iface := range h.Interfaces {
+			hs.Interfaces = append(hs.Interfaces, *iface)
+		}
+		for ip, entry := range h.ARPTable {
+			hs.ARPTable[ip] = entry
+		}
+		for port, on := range h.Listening {
+			if on {
+				hs.Listening = append(hs.Listening, port)
+			}
+		}
+		slices.Sort(hs.Listening)
+		for _, conn := range h.TCPConns {
+			hs.TCPConns = append(hs.TCPConns, TCPConnState{Conn: *conn, Queued: slices.Clone(conn.queued)})
+		}
+		slices.SortFunc(hs.TCPConns, func(a, b TCPConnState) int {
+			return cmp.Compare(a.Conn.String(), b.Conn.String())
+		})
+		for nextHop, req := range h.pending {
+			for _, p := range req.packets {
+				hs.Pending = append(hs.Pending, PendingState{NextHop: nextHop, Port: p.port, Packet: p.packet,
+					Attempts: req.attempts, RetryAt: req.retryAt})
+			}
+		}
+		s.Hosts = append(s.Hosts, hs)
+	}
+	for _, sw := range SwitchList {
+		ss := SwitchState{Name: sw.Name, Ports: len(sw.NetChannel), MACTable: make(map[MACKey]MACEntry, len(sw.MACTable)),
+			MACAddress: sw.MACAddress, STP: sw.STP.clone()}
+		for _, cfg := range sw.PortConfig {
+			cfg.Allowed = slices.Clone(cfg.Allowed)
+			ss.PortConfig = append(ss.PortConfig, cfg)
+		}
+		for key, entry := range sw.MACTable {
+			ss.MACTable[key] = entry
+		}
+		s.Switches = append(s.Switches, ss)
+	}
+	for _, l := range LinkList {
+		s.Links = append(s.Links, LinkState{
+			ID:        l.ID,
+			DeviceA:   l.A.Device.DeviceName(),
+			PortA:     l.A.Port,
+			DeviceB:   l.B.Device.DeviceName(),
+			PortB:     l.B.Port,
+			Delay:     l.Delay,
+			Bandwidth: l.Bandwidth,
+			Loss:      l.Loss,
+			BER:       l.BER,
+			LineCode:  l.LineCode,
+			BusyUntil: l.busyUntil,
+		})
+	}
+	for _, ev := range eventQueue {
+		s.Events = append(s.Events, *ev)
+	}
+	slices.SortFunc(s.Events, func(a, b Event) int {
+		return cmp.Or(cmp.Compare(a.At, b.At), cmp.Compare(a.Seq, b.Seq))
+	})
+	return s
+}
+
+// RestoreSnapshot 用快照替换当前的全部设备、链路与事件
+// 快照先完整检查一遍, 有错误时不修改当前状态
+// @param s 快照
+func RestoreSnapshot(s *Snapshot) error {
+	devices := make(map[string]Device)
+	var hosts []*BaseHost
+	var switches []*Switch
+	for _, hs := range s.Hosts {
+		h := newBaseHost(hs.Name)
+		h.Forwarding = hs.Forwarding
+		// 不经 AddPort 分配地址, 检查失败时MAC地址计数不变
+		for _, iface := range hs.Interfaces {
+			h.Interfaces = append(h.Interfaces, &iface)
+			h.NetChannel = append(h.NetChannel, make(chan []byte, ChannelSize))
+		}
+		for ip, entry := range hs.ARPTable {
+			h.ARPTable[ip] = entry
+		}
+		h.Routes = slices.Clone(hs.Routes)
+		for _, port := range hs.Listening {
+			h.Listening[port] = true
+		}
+		for _, cs := range hs.TCPConns {
+			conn := cs.Conn
+			conn.queued = cs.Queued
+			h.TCPConns[connKey(conn.LocalPort, conn.RemoteIP, conn.RemotePort)] = &conn
+		}
+		for _, p := range hs.Pending {
+			req := h.pending[p.NextHop]
+			if req == nil {
+				req = &arpRequest{port: p.Port, attempts: p.Attempts, retryAt: p.RetryAt}
+				h.pending[p.NextHop] = req
+			}
+			req.packets = append(req.packets, pendingPacket{port: p.Port, packet: p.Packet})
+		}
+		h.nextID, h.pingSeq = hs.NextID, hs.PingSeq
+		hosts = append(hosts, h)
+		devices[h.Name] = h
+	}
+	for _, ss := range s.Switches {
+		sw := &Switch{Name: ss.Name, NetChannel: make([]chan []byte, 0), MACTable: make(map[MACKey]MACEntry),
+			MACAddress: ss.MACAddress, STP: newSTP()}
+		for range ss.Ports {
+			sw.AddPort()
+		}
+		copy(sw.PortConfig, ss.PortConfig)
+		// 没有生成树状态的旧快照保持默认值
+		if len(ss.STP.Ports) == ss.Ports {
+			sw.STP = ss.STP.clone()
+		}
+		for key, entry := range ss.MACTable {
+			sw.MACTable[key] = entry
+		}
+		switches = append(switches, sw)
+		devices[sw.Name] = sw
+	}
+	deviceList := make([]Device, 0, len(s.Devices))
+	for _, name := range s.Devices {
+		dev, ok := devices[name]
+		if !ok {
+			return fmt.Errorf(i18n.T("快照中没有设备 %s 的状态"), name)
+		}
+		deviceList = append(deviceList, dev)
+	}
+	if len(deviceList) != len(devices) {
+		return errors.New(i18n.T("快照中的设备列表不完整"))
+	}
+	endpoint := func(name string, port int) (Endpoint, error) {
+		dev, ok := devices[name]
+		if !ok || port < 0 || port >= len(dev.Ports()) {
+			return Endpoint{}, fmt.Errorf(i18n.T("快照中的链路端点不存在: %s:%d"), name, port)
+		}
+		return Endpoint{Device: dev, Port: port}, nil
+	}
+	var links []*Link
+	for _, ls := range s.Links {
+		a, err := endpoint(ls.DeviceA, ls.PortA)
+		if err != nil {
+			return err
+		}
+		b, err := endpoint(ls.DeviceB, ls.PortB)
+		if err != nil {
+			return err
+		}
+		links = append(links, &Link{ID: ls.ID, A: a, B: b, Delay: ls.Delay,
+			Bandwidth: ls.Bandwidth, Loss: ls.Loss, BER: ls.BER, LineCode: ls.LineCode, busyUntil: ls.BusyUntil})
+	}
+	queue := make(eventHeap, 0, len(s.Events))
+	for _, ev := range s.Events {
+		queue = append(queue, &ev)
+	}
+	heap.Init(&queue)
+
+	HostList, SwitchList, DeviceList, LinkList = hosts, switches, deviceList, links
+	eventQueue, eventSeq, Clock = queue, s.EventSeq, s.Clock
+	linkCounter, macCounter, ipCounter, ephemeralPort = s.LinkCounter, s.MACCounter, s.IPCounter, s.EphemeralPort
+	rng, rngSource = newRand(s.RandSeed)
+	for range s.RandDraws {
+		rngSource.src.Uint64()
+	}
+	rngSource.draws = s.RandDraws
+	return nil
+}
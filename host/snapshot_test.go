@@ -0,0 +1,124 @@
+package host
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// resetSimulator 清空全部设备、链路与事件
+func resetSimulator(t *testing.T) {
+	t.Helper()
+	if err := RestoreSnapshot(&Snapshot{RandSeed: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// connect 连接两个端点, 端口为负时使用第一个空闲端口
+func connect(t *testing.T, a Device, pa int, b Device, pb int) *Link {
+	t.Helper()
+	if pa < 0 {
+		pa = FreePort(a)
+	}
+	if pb < 0 {
+		pb = FreePort(b)
+	}
+	link, err := Connect(Endpoint{Device: a, Port: pa}, Endpoint{Device: b, Port: pb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+// connectHosts 创建直连的两台主机
+func connectHosts(t *testing.T) (*BaseHost, *BaseHost, *Link) {
+	t.Helper()
+	h1, h2 := NewHost("h1"), NewHost("h2")
+	return h1, h2, connect(t, h1, 0, h2, 0)
+}
+
+// 有错误的快照不修改当前的设备、链路、计数与时钟
+func TestRestoreSnapshotInvalid(t *testing.T) {
+	resetSimulator(t)
+	h1, h2, _ := connectHosts(t)
+	sw := NewSwitch("s1", 2)
+	connect(t, sw, 0, NewRouter("r1"), -1)
+	if err := h1.Ping(h2.Interfaces[0].IPv4Address); err != nil {
+		t.Fatal(err)
+	}
+	Run(time.Millisecond)
+
+	tests := map[string]func(s *Snapshot){
+		"unknown device": func(s *Snapshot) { s.Devices = append(s.Devices, "nope") },
+		"missing device": func(s *Snapshot) { s.Devices = s.Devices[1:] },
+		"bad endpoint":   func(s *Snapshot) { s.Links[0].PortB = 9 },
+		"unknown endpoint": func(s *Snapshot) {
+			s.Links[len(s.Links)-1].DeviceA = "nope"
+		},
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := TakeSnapshot()
+			bad := TakeSnapshot()
+			// 改大计数, 误写入时可以看出
+			bad.MACCounter, bad.IPCounter, bad.LinkCounter = 1000, 1000, 1000
+			bad.Clock += time.Hour
+			modify(bad)
+			if err := RestoreSnapshot(bad); err == nil {
+				t.Fatal("RestoreSnapshot accepted a malformed snapshot")
+			}
+			after := TakeSnapshot()
+			if after.MACCounter != before.MACCounter || after.IPCounter != before.IPCounter ||
+				after.LinkCounter != before.LinkCounter || after.EventSeq != before.EventSeq {
+				t.Errorf("counters mac %d ip %d link %d seq %d, want %d %d %d %d",
+					after.MACCounter, after.IPCounter, after.LinkCounter, after.EventSeq,
+					before.MACCounter, before.IPCounter, before.LinkCounter, before.EventSeq)
+			}
+			if after.Clock != before.Clock || len(after.Events) != len(before.Events) {
+				t.Errorf("clock %v with %d events, want %v with %d", after.Clock, len(after.Events), before.Clock, len(before.Events))
+			}
+			if !slices.Equal(after.Devices, before.Devices) || len(after.Links) != len(before.Links) {
+				t.Errorf("devices %v links %d, want %v %d", after.Devices, len(after.Links), before.Devices, len(before.Links))
+			}
+			// 之后新建的设备继续使用原来的地址序列
+			mac := macCounter
+			NewHost("h9")
+			if macCounter != mac+1 || mac != before.MACCounter {
+				t.Errorf("next MAC counter %d after %d, want %d", macCounter, mac, before.MACCounter+1)
+			}
+			if err := RestoreSnapshot(before); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// 恢复快照得到相同的设备地址与待处理事件
+func TestRestoreSnapshotRoundTrip(t *testing.T) {
+	resetSimulator(t)
+	h1, h2, _ := connectHosts(t)
+	if err := h1.Ping(h2.Interfaces[0].IPv4Address); err != nil {
+		t.Fatal(err)
+	}
+	Run(time.Millisecond)
+	s := TakeSnapshot()
+	mac := macCounter
+	if err := RestoreSnapshot(s); err != nil {
+		t.Fatal(err)
+	}
+	if macCounter != mac {
+		t.Errorf("MAC counter %d after restore, want %d", macCounter, mac)
+	}
+	restored := HostList[0]
+	if restored.Interfaces[0].MACAddress != h1.Interfaces[0].MACAddress || len(restored.Ports()) != len(h1.Ports()) {
+		t.Errorf("h1 restored with MAC %v and %d ports, want %v and %d",
+			restored.Interfaces[0].MACAddress, len(restored.Ports()), h1.Interfaces[0].MACAddress, len(h1.Ports()))
+	}
+	if got := TakeSnapshot(); len(got.Events) != len(s.Events) || got.Clock != s.Clock {
+		t.Errorf("%d events at %v, want %d at %v", len(got.Events), got.Clock, len(s.Events), s.Clock)
+	}
+	Run(0)
+	if _, ok := restored.ARPTable[h2.Interfaces[0].IPv4Address]; !ok {
+		t.Error("restored h1 did not finish resolving h2")
+	}
+}
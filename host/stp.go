@@ -0,0 +1,788 @@
+package host
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// STPMode 交换机运行的生成树协议
+type STPMode int
+
+// const 生成树协议
+const (
+	// STPOff 不运行生成树, 所有端口都转发
+	STPOff STPMode = iota
+	// STPClassic IEEE 802.1D 生成树, 端口经侦听、学习两个转发延迟后转发
+	STPClassic
+	// STPRapid IEEE 802.1w 快速生成树, 点到点链路上用提议/同意立即转发
+	STPRapid
+)
+
+// String 协议名称 off/stp/rstp
+func (m STPMode) String() string {
+	switch m {
+	case STPClassic:
+		return "stp"
+	case STPRapid:
+		return "rstp"
+	default:
+		return "off"
+	}
+}
+
+// ParseSTPMode 解析 stp 或 rstp
+func ParseSTPMode(s string) (STPMode, bool) {
+	switch s {
+	case "stp":
+		return STPClassic, true
+	case "rstp":
+		return STPRapid, true
+	}
+	return STPOff, false
+}
+
+// PortRole 生成树端口角色
+type PortRole int
+
+// const 端口角色
+const (
+	// RoleDisabled 未连接链路
+	RoleDisabled PortRole = iota
+	// RoleRoot 根端口, 到根桥开销最小的端口
+	RoleRoot
+	// RoleDesignated 指定端口, 所在链路上到根桥最近的端口
+	RoleDesignated
+	// RoleAlternate 替代端口, 另一条到根桥的路径, 阻塞
+	RoleAlternate
+	// RoleBackup 备份端口, 与本交换机的另一个端口在同一链路上, 阻塞
+	RoleBackup
+)
+
+// String 与交换机 show spanning-tree 相同的缩写
+func (r PortRole) String() string {
+	return [...]string{"Disa", "Root", "Desg", "Altn", "Back"}[r]
+}
+
+// Name 角色全称 disabled/root/designated/alternate/backup
+func (r PortRole) Name() string {
+	return [...]string{"disabled", "root", "designated", "alternate", "backup"}[r]
+}
+
+// PortState 生成树端口状态
+type PortState int
+
+// const 端口状态
+const (
+	// StateDisabled 未连接链路
+	StateDisabled PortState = iota
+	// StateBlocking 阻塞, 只收BPDU; 快速生成树中称为丢弃(discarding)
+	StateBlocking
+	// StateListening 侦听, 只收发BPDU, 仅802.1D
+	StateListening
+	// StateLearning 学习, 学习MAC地址但不转发
+	StateLearning
+	// StateForwarding 转发
+	StateForwarding
+)
+
+// String 与交换机 show spanning-tree 相同的缩写
+func (s PortState) String() string {
+	return [...]string{"DIS", "BLK", "LIS", "LRN", "FWD"}[s]
+}
+
+// Name 状态全称 disabled/blocking/listening/learning/forwarding
+func (s PortState) Name() string {
+	return [...]string{"disabled", "blocking", "listening", "learning", "forwarding"}[s]
+}
+
+// 生成树参数的默认值, 与 IEEE 802.1D 的建议值相同
+const (
+	DefaultBridgePriority = 32768
+	DefaultPortPriority   = 128
+	DefaultHelloTime      = 2 * time.Second
+	DefaultMaxAge         = 20 * time.Second
+	DefaultForwardDelay   = 15 * time.Second
+)
+
+// PriorityVector 生成树优先级向量, 逐项比较, 小者为优
+type PriorityVector struct {
+	// 根桥
+	Root level.BridgeID
+	// 到根桥的开销
+	Cost uint32
+	// 指定网桥
+	Bridge level.BridgeID
+	// 指定端口
+	Port uint16
+}
+
+// compare 小于0表示 v 优于 o
+func (v PriorityVector) compare(o PriorityVector) int {
+	return cmp.Or(cmp.Compare(v.Root, o.Root), cmp.Compare(v.Cost, o.Cost),
+		cmp.Compare(v.Bridge, o.Bridge), cmp.Compare(v.Port, o.Port))
+}
+
+// STPPort 端口的生成树设置与状态
+type STPPort struct {
+	// 角色
+	Role PortRole
+	// 状态
+	State PortState
+	// 端口优先级, 0~240 且为16的倍数
+	Priority uint8
+	// 路径开销, 0表示按链路带宽计算
+	Cost uint32
+	// 边缘端口(portfast), 连接主机, 立即转发, 收到BPDU后取消
+	Edge bool
+	// 端口上收到的最好的消息
+	Info PriorityVector
+	// 是否有消息
+	HasInfo bool
+	// 消息中的消息年龄
+	InfoAge time.Duration
+	// 消息的老化时刻
+	InfoExpires time.Duration
+	// 侦听、学习或等待同意结束的时刻, 0表示没有
+	StateTimer time.Duration
+	// 快速生成树的指定端口已发出提议, 等待同意
+	Proposing bool
+	// 对端是802.1D网桥, 快速生成树在该端口上改发配置BPDU
+	Legacy bool
+	// 下一个配置BPDU带拓扑变化确认标志
+	SendTCAck bool
+}
+
+// STP 交换机的生成树协议状态, 整台交换机一个实例, BPDU不带VLAN标签
+type STP struct {
+	// 协议
+	Mode STPMode
+	// 网桥优先级, 0~61440 且为4096的倍数
+	Priority uint16
+	// 计时器
+	HelloTime, MaxAge, ForwardDelay time.Duration
+	// 根桥
+	RootID level.BridgeID
+	// 到根桥的开销
+	RootCost uint32
+	// 根端口, 本交换机为根桥时为-1
+	RootPort int
+	// 各端口, 与NetChannel一一对应
+	Ports []STPPort
+	// 下次每秒计时的时刻, 与之不同的定时器事件已作废
+	NextTick time.Duration
+	// 下次发送Hello的时刻
+	HelloDue time.Duration
+	// 在此之前发出的BPDU带拓扑变化标志
+	TCUntil time.Duration
+	// 802.1D 非根桥: 根端口最近收到的配置BPDU带拓扑变化标志
+	RootTC bool
+	// 802.1D 非根桥: 拓扑变化通知尚未得到确认
+	TCNPending bool
+	// 拓扑变化次数与最近一次的时刻
+	TopologyChanges int
+	LastChange      time.Duration
+}
+
+// newSTP 未启用的生成树, 参数为默认值
+func newSTP() STP {
+	return STP{
+		Priority:     DefaultBridgePriority,
+		HelloTime:    DefaultHelloTime,
+		MaxAge:       DefaultMaxAge,
+		ForwardDelay: DefaultForwardDelay,
+		RootPort:     -1,
+	}
+}
+
+// clone 复制端口切片, 供快照使用
+func (s STP) clone() STP {
+	s.Ports = append([]STPPort(nil), s.Ports...)
+	return s
+}
+
+// bridgeMAC 第n台交换机的网桥地址, 与主机的地址池分开分配
+func bridgeMAC(n int) [6]byte {
+	return [6]byte{0x02, 0xb0, 0, 0, byte(n >> 8), byte(n)}
+}
+
+// BridgeID 本交换机的网桥标识
+func (sw *Switch) BridgeID() level.BridgeID {
+	return level.NewBridgeID(sw.STP.Priority, sw.MACAddress)
+}
+
+// IsRoot 本交换机是否为根桥
+func (sw *Switch) IsRoot() bool {
+	return sw.STP.RootID == sw.BridgeID()
+}
+
+// PortID 端口标识, 高4位为优先级, 低12位为端口编号(从1开始)
+func (sw *Switch) PortID(port int) uint16 {
+	return uint16(sw.STP.Ports[port].Priority)<<8 | uint16(port+1)&0x0FFF
+}
+
+// PortCost 端口的路径开销, 未设置时按链路带宽计算
+func (sw *Switch) PortCost(port int) uint32 {
+	if c := sw.STP.Ports[port].Cost; c != 0 {
+		return c
+	}
+	bw := int64(0)
+	if l := LinkAt(sw, port); l != nil {
+		bw = l.Bandwidth
+	}
+	return DefaultPathCost(bw, sw.STP.Mode == STPRapid)
+}
+
+// DefaultPathCost 按带宽得出的默认路径开销
+// 802.1D 使用16位开销(10M为100, 100M为19, 1G为4), 快速生成树使用32位开销(20T/带宽)
+// @param bw 带宽(bit/s), 0表示不限
+// @param long 是否使用32位开销
+// @return uint32 路径开销
+func DefaultPathCost(bw int64, long bool) uint32 {
+	switch {
+	case bw <= 0:
+		return 1
+	case long:
+		return uint32(min(max(20_000_000_000_000/bw, 1), 200_000_000))
+	case bw >= 10_000_000_000:
+		return 2
+	case bw >= 1_000_000_000:
+		return 4
+	case bw >= 100_000_000:
+		return 19
+	case bw >= 10_000_000:
+		return 100
+	default:
+		return 250
+	}
+}
+
+// EnableSTP 启用或关闭生成树协议, 启用时所有端口从阻塞开始重新计算
+// @param mode 协议, STPOff 表示关闭
+func (sw *Switch) EnableSTP(mode STPMode) {
+	stp := &sw.STP
+	stp.Mode = mode
+	if mode == STPOff {
+		// 作废已安排的计时
+		stp.NextTick = -1
+		logf(logL2, sw.Name, "关闭生成树协议")
+		return
+	}
+	stp.RootID, stp.RootCost, stp.RootPort = sw.BridgeID(), 0, -1
+	stp.TCUntil, stp.RootTC, stp.TCNPending = 0, false, false
+	for p := range stp.Ports {
+		port := &stp.Ports[p]
+		port.Role, port.State, port.HasInfo = RoleDisabled, StateDisabled, false
+		port.StateTimer, port.Proposing, port.Legacy, port.SendTCAck = 0, false, false, false
+	}
+	logf(logL2, sw.Name, "启用生成树协议 %s, 网桥 %s", mode, sw.BridgeID())
+	stp.HelloDue = Clock
+	sw.scheduleTick(Clock)
+}
+
+// SetBridgePriority 设置网桥优先级并重新选举
+// @param priority 0~61440, 4096的倍数
+func (sw *Switch) SetBridgePriority(priority int) error {
+	if priority < 0 || priority > 61440 || priority%4096 != 0 {
+		return fmt.Errorf(i18n.T("网桥优先级应为0~61440之间4096的倍数: %d"), priority)
+	}
+	sw.STP.Priority = uint16(priority)
+	if sw.STP.RootPort < 0 {
+		sw.STP.RootID = sw.BridgeID()
+	}
+	sw.stpChanged()
+	return nil
+}
+
+// SetPortPriority 设置端口优先级
+// @param port 端口号
+// @param priority 0~240, 16的倍数
+func (sw *Switch) SetPortPriority(port, priority int) error {
+	if priority < 0 || priority > 240 || priority%16 != 0 {
+		return fmt.Errorf(i18n.T("端口优先级应为0~240之间16的倍数: %d"), priority)
+	}
+	if port < 0 || port >= len(sw.STP.Ports) {
+		return fmt.Errorf(i18n.T("%s 没有端口 %d"), sw.Name, port)
+	}
+	sw.STP.Ports[port].Priority = uint8(priority)
+	sw.stpChanged()
+	return nil
+}
+
+// SetPortCost 设置端口路径开销
+// @param port 端口号
+// @param cost 路径开销, 0表示按链路带宽计算
+func (sw *Switch) SetPortCost(port int, cost uint32) error {
+	if port < 0 || port >= len(sw.STP.Ports) {
+		return fmt.Errorf(i18n.T("%s 没有端口 %d"), sw.Name, port)
+	}
+	sw.STP.Ports[port].Cost = cost
+	sw.stpChanged()
+	return nil
+}
+
+// SetEdge 设置边缘端口, 边缘端口不经侦听、学习直接转发, 也不引起拓扑变化
+// @param port 端口号
+// @param edge 是否为边缘端口
+func (sw *Switch) SetEdge(port int, edge bool) error {
+	if port < 0 || port >= len(sw.STP.Ports) {
+		return fmt.Errorf(i18n.T("%s 没有端口 %d"), sw.Name, port)
+	}
+	sw.STP.Ports[port].Edge = edge
+	sw.stpChanged()
+	return nil
+}
+
+// stpChanged 参数改变后立即重新计算角色并通告
+func (sw *Switch) stpChanged() {
+	if sw.STP.Mode == STPOff {
+		return
+	}
+	sw.selectRoles()
+	sw.sendBPDUs(-1)
+}
+
+// scheduleTick 安排下一次每秒计时
+func (sw *Switch) scheduleTick(at time.Duration) {
+	sw.STP.NextTick = at
+	Schedule(&Event{At: at, Kind: EventTimer, Device: sw.Name})
+}
+
+// handleTimer 每秒计时: 老化消息、推进端口状态、重新计算角色、定时发送BPDU
+func (sw *Switch) handleTimer(at time.Duration) {
+	stp := &sw.STP
+	if stp.Mode == STPOff || at != stp.NextTick {
+		return
+	}
+	for p := range stp.Ports {
+		port := &stp.Ports[p]
+		if port.HasInfo && (Clock >= port.InfoExpires || LinkAt(sw, p) == nil) {
+			port.HasInfo = false
+			logf(logL2, sw.Name, "%s 上的生成树消息已老化", sw.PortName(p))
+		}
+	}
+	root := stp.RootID
+	sw.selectRoles()
+	if stp.RootID != root {
+		// 根端口的消息老化后立即通告新的根桥
+		stp.HelloDue = Clock
+	}
+	for p := range stp.Ports {
+		if t := stp.Ports[p].StateTimer; t != 0 && Clock >= t {
+			sw.advanceState(p)
+		}
+	}
+	if Clock >= stp.HelloDue {
+		stp.HelloDue = Clock + stp.HelloTime
+		// 802.1D 只有根桥定时发送, 其他网桥收到根端口的BPDU后转发
+		if stp.Mode == STPRapid || sw.IsRoot() {
+			sw.sendBPDUs(-1)
+		}
+		if stp.Mode == STPClassic && stp.TCNPending {
+			sw.sendTCN()
+		}
+	}
+	// 802.1D 拓扑变化期间MAC地址按转发延迟老化
+	if stp.Mode == STPClassic && sw.tcActive() {
+		maps.DeleteFunc(sw.MACTable, func(_ MACKey, e MACEntry) bool { return Clock-e.Updated > stp.ForwardDelay })
+	}
+	sw.scheduleTick(at + time.Second)
+}
+
+// selectRoles 选出根桥与根端口, 再确定各端口的角色
+func (sw *Switch) selectRoles() {
+	stp := &sw.STP
+	own := sw.BridgeID()
+	best := PriorityVector{Root: own, Bridge: own}
+	root := -1
+	for p, port := range stp.Ports {
+		// 从自己另一个端口绕回的BPDU不能作为到根桥的路径
+		if !port.HasInfo || LinkAt(sw, p) == nil || port.Info.Bridge == own {
+			continue
+		}
+		v := port.Info
+		v.Cost += sw.PortCost(p)
+		if c := v.compare(best); c < 0 || (c == 0 && root >= 0 && sw.PortID(p) < sw.PortID(root)) {
+			best, root = v, p
+		}
+	}
+	if best.Root != stp.RootID {
+		if best.Root == own {
+			logf(logL2, sw.Name, "本交换机成为根桥")
+		} else {
+			logf(logL2, sw.Name, "根桥为 %s, 根端口 %s, 开销 %d", best.Root, sw.PortName(root), best.Cost)
+		}
+	}
+	stp.RootID, stp.RootCost, stp.RootPort = best.Root, best.Cost, root
+	for p := range stp.Ports {
+		sw.setRole(p, sw.portRole(p))
+	}
+}
+
+// portRole 按优先级向量确定端口角色
+func (sw *Switch) portRole(p int) PortRole {
+	stp := &sw.STP
+	if LinkAt(sw, p) == nil {
+		return RoleDisabled
+	}
+	if p == stp.RootPort {
+		return RoleRoot
+	}
+	port := stp.Ports[p]
+	designated := PriorityVector{Root: stp.RootID, Cost: stp.RootCost, Bridge: sw.BridgeID(), Port: sw.PortID(p)}
+	switch {
+	case !port.HasInfo || designated.compare(port.Info) <= 0:
+		return RoleDesignated
+	case port.Info.Bridge == sw.BridgeID():
+		return RoleBackup
+	default:
+		return RoleAlternate
+	}
+}
+
+// setRole 设置端口角色并开始相应的状态转换
+func (sw *Switch) setRole(p int, role PortRole) {
+	stp := &sw.STP
+	port := &stp.Ports[p]
+	if port.Role != role {
+		debugf(logL2, sw.Name, "%s 角色 %s -> %s", sw.PortName(p), port.Role, role)
+		port.Role = role
+	}
+	switch role {
+	case RoleDisabled:
+		if port.State == StateForwarding && !port.Edge && stp.Mode == STPClassic {
+			sw.topologyChange(p)
+		}
+		port.StateTimer, port.Proposing, port.HasInfo = 0, false, false
+		sw.setState(p, StateDisabled)
+	case RoleAlternate, RoleBackup:
+		// 802.1D 中转发端口被阻塞也是拓扑变化
+		if port.State == StateForwarding && stp.Mode == STPClassic {
+			sw.topologyChange(p)
+		}
+		port.StateTimer, port.Proposing = 0, false
+		sw.setState(p, StateBlocking)
+	default:
+		if role == RoleDesignated {
+			// 指定端口上的消息就是本交换机发出的, 不再保存对端的次优消息
+			port.HasInfo = false
+		}
+		if stp.Mode == STPRapid && role == RoleRoot && !port.Edge && port.State != StateForwarding {
+			// 旧的根端口已同时变为替代端口, 新的根端口可以立即转发
+			port.StateTimer, port.Proposing = 0, false
+			sw.setState(p, StateForwarding)
+			sw.topologyChange(p)
+			return
+		}
+		if port.State != StateDisabled && (port.State != StateBlocking || port.StateTimer != 0) {
+			return
+		}
+		switch {
+		case port.Edge:
+			sw.setState(p, StateForwarding)
+		case stp.Mode == STPRapid:
+			// 先提议, 收到同意立即转发, 否则经两个转发延迟
+			sw.setState(p, StateBlocking)
+			port.Proposing = !port.Legacy
+			port.StateTimer = Clock + stp.ForwardDelay
+		default:
+			sw.setState(p, StateListening)
+			port.StateTimer = Clock + stp.ForwardDelay
+		}
+	}
+}
+
+// advanceState 转发延迟到期: 侦听或丢弃进入学习, 学习进入转发
+func (sw *Switch) advanceState(p int) {
+	port := &sw.STP.Ports[p]
+	switch port.State {
+	case StateBlocking, StateListening:
+		sw.setState(p, StateLearning)
+		port.StateTimer = Clock + sw.STP.ForwardDelay
+	case StateLearning:
+		port.StateTimer, port.Proposing = 0, false
+		sw.setState(p, StateForwarding)
+		if !port.Edge {
+			sw.topologyChange(p)
+		}
+	}
+}
+
+// setState 改变端口状态, 记录日志并发布事件
+func (sw *Switch) setState(p int, state PortState) {
+	port := &sw.STP.Ports[p]
+	if port.State == state {
+		return
+	}
+	port.State = state
+	logf(logL2, sw.Name, "生成树 %s: %s %s", sw.PortName(p), port.Role, state)
+	publishSTPState(sw, p)
+}
+
+// topologyChange 本交换机检测到拓扑变化
+// 802.1D: 根桥在 最大老化时间+转发延迟 内发出带TC标志的BPDU, 其他网桥向根桥发送TCN直到确认
+// 快速生成树: 清除其他非边缘端口学到的MAC地址, 并在两个Hello时间内从根端口与指定端口发出TC标志
+func (sw *Switch) topologyChange(p int) {
+	stp := &sw.STP
+	stp.TopologyChanges++
+	stp.LastChange = Clock
+	logf(logL2, sw.Name, "拓扑变化: %s", sw.PortName(p))
+	switch {
+	case stp.Mode == STPRapid:
+		sw.flushMAC(p)
+		stp.TCUntil = Clock + 2*stp.HelloTime
+		sw.sendBPDUs(p)
+	case sw.IsRoot():
+		stp.TCUntil = Clock + stp.MaxAge + stp.ForwardDelay
+	default:
+		stp.TCNPending = true
+		sw.sendTCN()
+	}
+}
+
+// tcActive 发出的BPDU是否带TC标志
+func (sw *Switch) tcActive() bool {
+	stp := &sw.STP
+	if stp.Mode == STPClassic && !sw.IsRoot() {
+		return stp.RootTC
+	}
+	return stp.TCUntil > Clock
+}
+
+// flushMAC 清除除 except 以外的非边缘端口学到的MAC地址
+func (sw *Switch) flushMAC(except int) {
+	maps.DeleteFunc(sw.MACTable, func(_ MACKey, e MACEntry) bool {
+		return e.Port != except && !sw.STP.Ports[e.Port].Edge
+	})
+}
+
+// handleBPDU 处理收到的BPDU, 更新端口消息并重新计算
+// @param p 入端口
+// @param frame 目的地址为网桥组地址的帧
+func (sw *Switch) handleBPDU(p int, frame []byte) {
+	stp := &sw.STP
+	eth, err := level.Deserialize(frame)
+	if err != nil {
+		debugf(logL2, sw.Name, "丢弃BPDU: %v", err)
+		return
+	}
+	llc, err := level.DeserializeLLC(eth.DataPackage)
+	if err != nil || !eth.IsIEEE8023() || llc.DSAP != level.SAPSTP {
+		debugf(logL2, sw.Name, "丢弃发往网桥组地址的非BPDU帧")
+		return
+	}
+	bpdu, err := level.DeserializeBPDU(llc.Data)
+	if err != nil {
+		debugf(logL2, sw.Name, "丢弃BPDU: %v", err)
+		return
+	}
+	port := &stp.Ports[p]
+	if port.Edge {
+		port.Edge = false
+		logf(logL2, sw.Name, "%s 收到BPDU, 不再作为边缘端口", sw.PortName(p))
+	}
+	if bpdu.Type == level.BPDUTypeTCN {
+		sw.handleTCN(p)
+		return
+	}
+	if stp.Mode == STPRapid && bpdu.Type == level.BPDUTypeConfig && !port.Legacy {
+		port.Legacy = true
+		logf(logL2, sw.Name, "%s 的对端运行802.1D, 改发配置BPDU", sw.PortName(p))
+	}
+	if bpdu.MessageAge >= bpdu.MaxAge {
+		debugf(logL2, sw.Name, "BPDU 的消息年龄已达最大老化时间, 丢弃")
+		return
+	}
+	msg := PriorityVector{Root: bpdu.RootID, Cost: bpdu.RootPathCost, Bridge: bpdu.BridgeID, Port: bpdu.PortID}
+	designated := PriorityVector{Root: stp.RootID, Cost: stp.RootCost, Bridge: sw.BridgeID(), Port: sw.PortID(p)}
+	sameSender := port.HasInfo && msg.Bridge == port.Info.Bridge && msg.Port == port.Info.Port
+	if !sameSender && (msg.compare(designated) >= 0 || (port.HasInfo && msg.compare(port.Info) > 0)) {
+		// 次优的消息不保存; 对端自认为指定端口时立即回应自己的BPDU
+		if stp.Mode == STPRapid && bpdu.Type == level.BPDUTypeRST {
+			sw.handleRST(p, bpdu.Flags)
+		}
+		role := bpdu.Flags & level.BPDUFlagRoleMask
+		if port.Role == RoleDesignated && (bpdu.Type == level.BPDUTypeConfig || role == level.BPDURoleDesignated) {
+			sw.sendBPDU(p, 0)
+		}
+		return
+	}
+	before := PriorityVector{Root: stp.RootID, Cost: stp.RootCost, Port: uint16(stp.RootPort + 1)}
+	port.Info, port.HasInfo, port.InfoAge = msg, true, bpdu.MessageAge
+	port.InfoExpires = Clock + bpdu.MaxAge - bpdu.MessageAge
+	if stp.Mode == STPRapid && !port.Legacy {
+		port.InfoExpires = Clock + 3*stp.HelloTime
+	}
+	sw.selectRoles()
+	after := PriorityVector{Root: stp.RootID, Cost: stp.RootCost, Port: uint16(stp.RootPort + 1)}
+	if stp.Mode == STPClassic {
+		if p == stp.RootPort {
+			if bpdu.Flags&level.BPDUFlagTCAck != 0 {
+				stp.TCNPending = false
+			}
+			stp.RootTC = bpdu.Flags&level.BPDUFlagTC != 0
+			// 收到根端口的配置BPDU后从指定端口转发
+			sw.sendBPDUs(-1)
+		} else if port.Role == RoleDesignated {
+			sw.sendBPDU(p, 0)
+		}
+		return
+	}
+	sw.handleRST(p, bpdu.Flags)
+	if before != after {
+		sw.sendBPDUs(-1)
+	} else if port.Role == RoleDesignated {
+		// 对端的消息变差后本端口成为指定端口, 立即通告
+		sw.sendBPDU(p, 0)
+	}
+}
+
+// handleTCN 802.1D 指定端口收到拓扑变化通知: 确认, 并继续报告给根桥
+func (sw *Switch) handleTCN(p int) {
+	stp := &sw.STP
+	if stp.Ports[p].Role != RoleDesignated {
+		return
+	}
+	logf(logL2, sw.Name, "%s 收到拓扑变化通知", sw.PortName(p))
+	if sw.IsRoot() {
+		// 拓扑变化期间收到的通知只延长期限, 不重复计数
+		if stp.TCUntil <= Clock {
+			stp.TopologyChanges++
+		}
+		stp.LastChange = Clock
+		stp.TCUntil = Clock + stp.MaxAge + stp.ForwardDelay
+	} else {
+		stp.TCNPending = true
+		sw.sendTCN()
+	}
+	stp.Ports[p].SendTCAck = true
+	sw.sendBPDU(p, 0)
+}
+
+// handleRST 快速生成树的标志: 拓扑变化、提议与同意
+func (sw *Switch) handleRST(p int, flags uint8) {
+	stp := &sw.STP
+	port := &stp.Ports[p]
+	if flags&level.BPDUFlagTC != 0 {
+		sw.flushMAC(p)
+		if stp.TCUntil <= Clock {
+			stp.TCUntil = Clock + 2*stp.HelloTime
+			sw.sendBPDUs(p)
+		}
+	}
+	if flags&level.BPDUFlagAgreement != 0 && port.Role == RoleDesignated && port.State != StateForwarding {
+		debugf(logL2, sw.Name, "%s 收到同意", sw.PortName(p))
+		port.StateTimer, port.Proposing = 0, false
+		sw.setState(p, StateForwarding)
+		sw.topologyChange(p)
+	}
+	if flags&level.BPDUFlagProposal == 0 || port.Role == RoleDesignated || port.Role == RoleDisabled {
+		return
+	}
+	if port.Role == RoleRoot {
+		// 同步: 其他非边缘指定端口先丢弃, 再向下游提议, 保证同意后不会成环
+		for q := range stp.Ports {
+			other := &stp.Ports[q]
+			if q != p && other.Role == RoleDesignated && !other.Edge && other.State != StateBlocking {
+				other.Proposing, other.StateTimer = !other.Legacy, Clock+stp.ForwardDelay
+				sw.setState(q, StateBlocking)
+			}
+		}
+		sw.sendBPDUs(p)
+	}
+	debugf(logL2, sw.Name, "%s 收到提议, 回应同意", sw.PortName(p))
+	sw.sendBPDU(p, level.BPDUFlagAgreement)
+}
+
+// sendBPDUs 从指定端口发送BPDU, 快速生成树拓扑变化期间根端口也发送
+// @param except 不发送的端口, -1表示没有
+func (sw *Switch) sendBPDUs(except int) {
+	stp := &sw.STP
+	for p, port := range stp.Ports {
+		if p == except || LinkAt(sw, p) == nil {
+			continue
+		}
+		if port.Role == RoleDesignated || (port.Role == RoleRoot && stp.Mode == STPRapid && sw.tcActive()) {
+			sw.sendBPDU(p, 0)
+		}
+	}
+}
+
+// sendBPDU 从端口发送配置BPDU或RST BPDU
+// @param p 端口
+// @param flags 附加的标志, 如同意
+func (sw *Switch) sendBPDU(p int, flags uint8) {
+	stp := &sw.STP
+	port := &stp.Ports[p]
+	bpdu := &level.BPDU{
+		RootID:       stp.RootID,
+		RootPathCost: stp.RootCost,
+		BridgeID:     sw.BridgeID(),
+		PortID:       sw.PortID(p),
+		MaxAge:       stp.MaxAge,
+		HelloTime:    stp.HelloTime,
+		ForwardDelay: stp.ForwardDelay,
+	}
+	if stp.RootPort >= 0 {
+		bpdu.MessageAge = stp.Ports[stp.RootPort].InfoAge + time.Second
+	}
+	if sw.tcActive() {
+		flags |= level.BPDUFlagTC
+	}
+	if stp.Mode == STPRapid && !port.Legacy {
+		bpdu.Version, bpdu.Type = level.RSTPVersion, level.BPDUTypeRST
+		switch port.Role {
+		case RoleRoot:
+			flags |= level.BPDURoleRoot
+		case RoleDesignated:
+			flags |= level.BPDURoleDesignated
+			if port.Proposing && port.State != StateForwarding {
+				flags |= level.BPDUFlagProposal
+			}
+		default:
+			flags |= level.BPDURoleAlternate
+		}
+		if port.State == StateLearning || port.State == StateForwarding {
+			flags |= level.BPDUFlagLearning
+		}
+		if port.State == StateForwarding {
+			flags |= level.BPDUFlagForward
+		}
+	} else {
+		flags &= level.BPDUFlagTC
+		if port.SendTCAck {
+			flags |= level.BPDUFlagTCAck
+			port.SendTCAck = false
+		}
+	}
+	bpdu.Flags = flags
+	sw.transmitBPDU(p, bpdu)
+}
+
+// sendTCN 802.1D 从根端口发送拓扑变化通知
+func (sw *Switch) sendTCN() {
+	if sw.STP.RootPort < 0 {
+		return
+	}
+	sw.transmitBPDU(sw.STP.RootPort, &level.BPDU{Type: level.BPDUTypeTCN})
+}
+
+// transmitBPDU 用LLC封装BPDU, 从端口发往网桥组地址, 不带VLAN标签
+func (sw *Switch) transmitBPDU(p int, bpdu *level.BPDU) {
+	llc := level.NewLLC(level.SAPSTP, level.SAPSTP, level.LLCControlUI, bpdu.Serialize())
+	transmit(sw, p, level.NewIEEE8023(level.STPMulticast, sw.MACAddress, llc).Serialize())
+}
+
+// stpForwarding 端口是否转发数据帧, 未启用生成树时总是转发
+func (sw *Switch) stpForwarding(p int) bool {
+	return sw.STP.Mode == STPOff || sw.STP.Ports[p].State == StateForwarding
+}
+
+// stpLearning 端口是否学习MAC地址
+func (sw *Switch) stpLearning(p int) bool {
+	return sw.stpForwarding(p) || sw.STP.Ports[p].State == StateLearning
+}
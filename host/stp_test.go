@@ -0,0 +1,98 @@
+package host
+
+import (
+	"testing"
+	"time"
+)
+
+// blockedPorts 连接了链路、处于阻塞(丢弃)状态的端口
+func blockedPorts(switches ...*Switch) []string {
+	var blocked []string
+	for _, sw := range switches {
+		for p, port := range sw.STP.Ports {
+			if LinkAt(sw, p) != nil && port.State == StateBlocking {
+				blocked = append(blocked, sw.Name+":"+sw.PortName(p))
+			}
+		}
+	}
+	return blocked
+}
+
+// checkRoot 所有交换机都以 root 为根桥
+func checkRoot(t *testing.T, root *Switch, switches ...*Switch) {
+	t.Helper()
+	if !root.IsRoot() {
+		t.Errorf("%s is not the root, root is %v", root.Name, root.STP.RootID)
+	}
+	for _, sw := range switches {
+		if sw.STP.RootID != root.BridgeID() {
+			t.Errorf("%s root = %v, want %v", sw.Name, sw.STP.RootID, root.BridgeID())
+		}
+	}
+}
+
+func TestSpanningTreeTriangle(t *testing.T) {
+	for _, mode := range []STPMode{STPClassic, STPRapid} {
+		t.Run(mode.String(), func(t *testing.T) {
+			resetSimulator(t)
+			s1, s2, s3 := NewSwitch("s1", 3), NewSwitch("s2", 3), NewSwitch("s3", 3)
+			l12 := connect(t, s1, 0, s2, 0)
+			connect(t, s2, 1, s3, 0)
+			connect(t, s3, 1, s1, 1)
+			for _, sw := range []*Switch{s1, s2, s3} {
+				sw.EnableSTP(mode)
+			}
+			if err := s1.SetBridgePriority(4096); err != nil {
+				t.Fatal(err)
+			}
+			// 802.1D 经侦听与学习两个转发延迟后转发
+			Run(2*DefaultForwardDelay + 5*time.Second)
+
+			checkRoot(t, s1, s2, s3)
+			for _, p := range []int{0, 1} {
+				if s1.STP.Ports[p].Role != RoleDesignated || s1.STP.Ports[p].State != StateForwarding {
+					t.Errorf("s1:%s is %v %v, want a forwarding designated port",
+						s1.PortName(p), s1.STP.Ports[p].Role, s1.STP.Ports[p].State)
+				}
+			}
+			if s2.STP.RootPort != 0 || s3.STP.RootPort != 1 {
+				t.Errorf("root ports s2:%d s3:%d, want the ports facing s1", s2.STP.RootPort, s3.STP.RootPort)
+			}
+			blocked := blockedPorts(s1, s2, s3)
+			if len(blocked) != 1 {
+				t.Fatalf("blocked ports %v, want exactly one", blocked)
+			}
+			// s2 与 s3 优先级相同, MAC地址大的 s3 在两者之间的链路上阻塞
+			if blocked[0] != "s3:"+s3.PortName(0) {
+				t.Errorf("blocked port %s, want s3:%s", blocked[0], s3.PortName(0))
+			}
+			if s3.STP.Ports[0].Role != RoleAlternate {
+				t.Errorf("s3:%s role %v, want alternate", s3.PortName(0), s3.STP.Ports[0].Role)
+			}
+
+			// 删除 s1-s2 的链路后, s2 经 s3 到达根桥, 原来阻塞的端口转发
+			changes := s3.STP.TopologyChanges
+			if err := Disconnect(l12.ID); err != nil {
+				t.Fatal(err)
+			}
+			Run(DefaultMaxAge + 2*DefaultForwardDelay + 5*time.Second)
+
+			checkRoot(t, s1, s2, s3)
+			if blocked := blockedPorts(s1, s2, s3); len(blocked) != 0 {
+				t.Errorf("blocked ports %v after the loop was broken, want none", blocked)
+			}
+			if s2.STP.RootPort != 1 {
+				t.Errorf("s2 root port %d, want 1 towards s3", s2.STP.RootPort)
+			}
+			if port := s3.STP.Ports[0]; port.Role != RoleDesignated || port.State != StateForwarding {
+				t.Errorf("s3:%s is %v %v, want a forwarding designated port", s3.PortName(0), port.Role, port.State)
+			}
+			if s2.STP.Ports[0].Role != RoleDisabled {
+				t.Errorf("s2:%s role %v after link del, want disabled", s2.PortName(0), s2.STP.Ports[0].Role)
+			}
+			if s3.STP.TopologyChanges == changes {
+				t.Error("s3 saw no topology change")
+			}
+		})
+	}
+}
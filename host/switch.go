@@ -0,0 +1,286 @@
+package host
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// MACEntry 交换机MAC地址表条目
+type MACEntry struct {
+	// 端口号
+	Port int
+	// 学习时刻(虚拟时间)
+	Updated time.Duration
+}
+
+// MACKey 交换机MAC地址表的键, 每个VLAN各有一张表
+type MACKey struct {
+	// VLAN编号
+	VLAN uint16
+	// MAC地址
+	MAC [6]byte
+}
+
+// PortMode 交换机端口模式
+type PortMode int
+
+// const 交换机端口模式
+const (
+	// PortAccess 接入端口, 属于一个VLAN, 收发不带标签的帧
+	PortAccess PortMode = iota
+	// PortTrunk 干道端口, 承载多个VLAN, 本征VLAN以外的帧带 802.1Q 标签
+	PortTrunk
+)
+
+// String 模式名称
+func (m PortMode) String() string {
+	if m == PortTrunk {
+		return "trunk"
+	}
+	return "access"
+}
+
+// DefaultVLAN 端口默认所属的VLAN
+const DefaultVLAN = 1
+
+// SwitchPort 交换机端口的VLAN设置
+type SwitchPort struct {
+	// 端口模式
+	Mode PortMode
+	// 接入端口所属的VLAN, 干道端口的本征VLAN
+	VLAN uint16
+	// 干道端口允许通过的VLAN, 为空表示全部允许
+	Allowed []uint16
+}
+
+// Carries 端口是否承载该VLAN
+func (p SwitchPort) Carries(vlan uint16) bool {
+	if p.Mode == PortAccess {
+		return p.VLAN == vlan
+	}
+	return len(p.Allowed) == 0 || slices.Contains(p.Allowed, vlan)
+}
+
+// String 如 access 10 或 trunk native 1 allowed 10,20
+func (p SwitchPort) String() string {
+	if p.Mode == PortAccess {
+		return fmt.Sprintf("access %d", p.VLAN)
+	}
+	allowed := "all"
+	if len(p.Allowed) > 0 {
+		allowed = FormatVLANList(p.Allowed)
+	}
+	return fmt.Sprintf("trunk native %d allowed %s", p.VLAN, allowed)
+}
+
+// FormatVLANList 以逗号分隔的VLAN编号
+func FormatVLANList(vlans []uint16) string {
+	parts := make([]string, len(vlans))
+	for i, v := range vlans {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// checkVLAN 检查VLAN编号是否可用
+func checkVLAN(vlan uint16) error {
+	if vlan < 1 || vlan > level.MaxVLAN {
+		return fmt.Errorf(i18n.T("VLAN编号应在1~%d之间: %d"), level.MaxVLAN, vlan)
+	}
+	return nil
+}
+
+// Switch 二层交换机, 按VLAN自学习MAC地址并转发
+type Switch struct {
+	// 交换机名称
+	Name string
+	// 通信端口
+	NetChannel []chan []byte
+	// 端口的VLAN设置, 与NetChannel一一对应
+	PortConfig []SwitchPort
+	// MAC地址表, 按VLAN划分
+	MACTable map[MACKey]MACEntry
+	// 网桥地址, 用作BPDU的源地址与网桥标识
+	MACAddress [6]byte
+	// 生成树协议
+	STP STP
+}
+
+// 全局交换机列表
+var SwitchList []*Switch
+
+// NewSwitch 创建交换机
+// @param name 交换机名称
+// @param ports 初始端口数
+// @return *Switch
+func NewSwitch(name string, ports int) *Switch {
+	sw := &Switch{
+		Name:       name,
+		NetChannel: make([]chan []byte, 0),
+		MACTable:   make(map[MACKey]MACEntry),
+		MACAddress: bridgeMAC(len(SwitchList) + 1),
+		STP:        newSTP(),
+	}
+	for i := 0; i < ports; i++ {
+		sw.AddPort()
+	}
+	SwitchList = append(SwitchList, sw)
+	DeviceList = append(DeviceList, sw)
+	return sw
+}
+
+// DeviceName 设备名称
+func (sw *Switch) DeviceName() string {
+	return sw.Name
+}
+
+// Ports 通信端口
+func (sw *Switch) Ports() []chan []byte {
+	return sw.NetChannel
+}
+
+// PortName 端口名称
+func (sw *Switch) PortName(port int) string {
+	return fmt.Sprintf("eth%d", port)
+}
+
+// AddPort 新增端口, 新端口是默认VLAN的接入端口
+func (sw *Switch) AddPort() int {
+	sw.NetChannel = append(sw.NetChannel, make(chan []byte, ChannelSize))
+	sw.PortConfig = append(sw.PortConfig, SwitchPort{Mode: PortAccess, VLAN: DefaultVLAN})
+	sw.STP.Ports = append(sw.STP.Ports, STPPort{Priority: DefaultPortPriority})
+	return len(sw.NetChannel) - 1
+}
+
+// SetAccess 将端口设为接入端口
+// @param port 端口号
+// @param vlan 所属的VLAN
+func (sw *Switch) SetAccess(port int, vlan uint16) error {
+	if err := checkVLAN(vlan); err != nil {
+		return err
+	}
+	return sw.setPort(port, SwitchPort{Mode: PortAccess, VLAN: vlan})
+}
+
+// SetTrunk 将端口设为干道端口
+// @param port 端口号
+// @param native 本征VLAN, 该VLAN的帧不带标签
+// @param allowed 允许通过的VLAN, 为空表示全部允许
+func (sw *Switch) SetTrunk(port int, native uint16, allowed []uint16) error {
+	for _, vlan := range append([]uint16{native}, allowed...) {
+		if err := checkVLAN(vlan); err != nil {
+			return err
+		}
+	}
+	allowed = slices.Compact(slices.Sorted(slices.Values(allowed)))
+	return sw.setPort(port, SwitchPort{Mode: PortTrunk, VLAN: native, Allowed: allowed})
+}
+
+// setPort 修改端口设置, 并清除从该端口学到的MAC地址
+func (sw *Switch) setPort(port int, cfg SwitchPort) error {
+	if port < 0 || port >= len(sw.PortConfig) {
+		return fmt.Errorf(i18n.T("%s 没有端口 %d"), sw.Name, port)
+	}
+	sw.PortConfig[port] = cfg
+	maps.DeleteFunc(sw.MACTable, func(_ MACKey, e MACEntry) bool { return e.Port == port })
+	logf(logL2, sw.Name, "%s 设为 %s", sw.PortName(port), cfg)
+	return nil
+}
+
+// HandleFrame 确定帧所属的VLAN, 在该VLAN中学习源MAC, 按目的MAC转发, 未知或广播时在VLAN内泛洪
+// 接入端口收到的帧属于端口的VLAN; 干道端口按最外层标签, 不带标签时属于本征VLAN
+// 启用生成树时BPDU交给生成树处理, 数据帧只在转发状态的端口间转发
+// @param port 入端口
+// @param frame 以太网帧
+func (sw *Switch) HandleFrame(port int, frame []byte) {
+	if len(frame) < level.EthernetHeaderSize {
+		return
+	}
+	// 存储转发: CRC错误的帧既不学习也不转发
+	var badCRC *level.ErrBadChecksum
+	if _, err := level.Deserialize(frame); errors.As(err, &badCRC) {
+		logf(logL2, sw.Name, "%s 收到CRC错误的帧, 丢弃", sw.PortName(port))
+		publishFrameDrop(sw.Name, linkID(sw, port), 2, frame, i18n.T("CRC错误"))
+		return
+	}
+	var dst, src [6]byte
+	copy(dst[:], frame[0:6])
+	copy(src[:], frame[6:12])
+	if sw.STP.Mode != STPOff {
+		if dst == level.STPMulticast {
+			sw.handleBPDU(port, frame)
+			return
+		}
+		if !sw.stpLearning(port) {
+			debugf(logL2, sw.Name, "%s 处于 %s 状态, 丢弃", sw.PortName(port), sw.STP.Ports[port].State)
+			return
+		}
+	}
+	cfg := sw.PortConfig[port]
+	vlan := cfg.VLAN
+	// 只带优先级的帧(VID为0)属于端口的VLAN
+	if tags, _, _ := level.FrameTags(frame); len(tags) > 0 && tags[0].VID() != 0 {
+		vlan = tags[0].VID()
+		if cfg.Mode == PortAccess && vlan != cfg.VLAN {
+			debugf(logL2, sw.Name, "接入端口 %s 收到VLAN %d 的帧, 丢弃", sw.PortName(port), vlan)
+			return
+		}
+	}
+	if !cfg.Carries(vlan) {
+		debugf(logL2, sw.Name, "%s 不允许VLAN %d, 丢弃", sw.PortName(port), vlan)
+		return
+	}
+	if src[0]&0x01 == 0 {
+		key := MACKey{VLAN: vlan, MAC: src}
+		if old, ok := sw.MACTable[key]; !ok || old.Port != port {
+			logf(logL2, sw.Name, "学习 %s 在 %s (VLAN %d)", level.FormatMAC(src), sw.PortName(port), vlan)
+		}
+		sw.MACTable[key] = MACEntry{Port: port, Updated: Clock}
+	}
+	if !sw.stpForwarding(port) {
+		return
+	}
+	if entry, ok := sw.MACTable[MACKey{VLAN: vlan, MAC: dst}]; ok && dst[0]&0x01 == 0 {
+		if entry.Port != port && sw.PortConfig[entry.Port].Carries(vlan) && sw.stpForwarding(entry.Port) {
+			debugf(logL2, sw.Name, "%s 转发到 %s", level.FormatMAC(dst), sw.PortName(entry.Port))
+			sw.forward(entry.Port, vlan, frame)
+		}
+		return
+	}
+	debugf(logL2, sw.Name, "%s 未知或为广播, 在VLAN %d 内泛洪", level.FormatMAC(dst), vlan)
+	for p := range sw.NetChannel {
+		if p != port && LinkAt(sw, p) != nil && sw.PortConfig[p].Carries(vlan) && sw.stpForwarding(p) {
+			sw.forward(p, vlan, frame)
+		}
+	}
+}
+
+// forward 按出端口的模式加上或去掉标签后发出, 标签不变时原样转发
+// 改写标签需要重新计算CRC, CRC错误的帧此时丢弃
+func (sw *Switch) forward(port int, vlan uint16, frame []byte) {
+	cfg := sw.PortConfig[port]
+	tagged := cfg.Mode == PortTrunk && vlan != cfg.VLAN
+	tags, _, _ := level.FrameTags(frame)
+	if (tagged && len(tags) > 0 && tags[0].VID() == vlan) || (!tagged && len(tags) == 0) {
+		transmit(sw, port, frame)
+		return
+	}
+	eth, err := level.Deserialize(frame)
+	if err != nil {
+		debugf(logL2, sw.Name, "%s 无法改写标签, 丢弃: %v", sw.PortName(port), err)
+		return
+	}
+	old, _ := eth.PopTag()
+	if tagged {
+		eth.PushTag(level.NewVLANTag(level.EtherTypeVLAN, old.PCP(), old.DEI(), vlan))
+	}
+	transmit(sw, port, eth.Serialize())
+}
@@ -0,0 +1,268 @@
+package host
+
+import (
+	"fmt"
+
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// const TCP连接状态
+const (
+	TCPClosed      = "CLOSED"
+	TCPSynSent     = "SYN_SENT"
+	TCPSynReceived = "SYN_RECEIVED"
+	TCPEstablished = "ESTABLISHED"
+	TCPFinWait1    = "FIN_WAIT_1"
+	TCPFinWait2    = "FIN_WAIT_2"
+	TCPCloseWait   = "CLOSE_WAIT"
+	TCPLastAck     = "LAST_ACK"
+	TCPTimeWait    = "TIME_WAIT"
+)
+
+// TCPConn TCP连接控制块
+type TCPConn struct {
+	// 本地端口
+	LocalPort uint16
+	// 对端地址
+	RemoteIP [4]byte
+	// 对端端口
+	RemotePort uint16
+	// 连接状态
+	State string
+	// 下一个发送序号 SND.NXT
+	SendNext uint32
+	// 下一个期望接收序号 RCV.NXT
+	RecvNext uint32
+	// 连接建立前等待发送的数据
+	queued []byte
+}
+
+// String 连接描述
+func (conn *TCPConn) String() string {
+	return fmt.Sprintf(":%d <-> %s:%d", conn.LocalPort, level.FormatIPv4(conn.RemoteIP), conn.RemotePort)
+}
+
+var ephemeralPort uint16 = 49151
+
+// connKey TCP连接表的键
+func connKey(localPort uint16, remoteIP [4]byte, remotePort uint16) string {
+	return fmt.Sprintf("%d-%s:%d", localPort, level.FormatIPv4(remoteIP), remotePort)
+}
+
+// Listen 在端口上监听TCP连接
+// @param port 端口
+func (host *BaseHost) Listen(port uint16) {
+	host.Listening[port] = true
+}
+
+// SendTCP 向对端发送数据, 没有已建立的连接时先发起三次握手
+// @param dst 目的地址
+// @param dstPort 目的端口
+// @param data 数据, 可为空
+func (host *BaseHost) SendTCP(dst [4]byte, dstPort uint16, data []byte) error {
+	if _, _, ok := host.LookupRoute(dst); !ok {
+		return fmt.Errorf(i18n.T("%s 没有到 %s 的路由"), host.Name, level.FormatIPv4(dst))
+	}
+	for _, conn := range host.TCPConns {
+		if conn.RemoteIP != dst || conn.RemotePort != dstPort {
+			continue
+		}
+		switch conn.State {
+		case TCPEstablished, TCPCloseWait:
+			if len(data) > 0 {
+				host.sendSegment(conn, level.TCPFlagPSH|level.TCPFlagACK, data)
+				conn.SendNext += uint32(len(data))
+			}
+			return nil
+		case TCPSynSent, TCPSynReceived:
+			conn.queued = append(conn.queued, data...)
+			return nil
+		}
+	}
+	ephemeralPort++
+	if ephemeralPort == 0 {
+		ephemeralPort = 49152
+	}
+	conn := &TCPConn{
+		LocalPort:  ephemeralPort,
+		RemoteIP:   dst,
+		RemotePort: dstPort,
+		State:      TCPClosed,
+		SendNext:   rng.Uint32(),
+		queued:     data,
+	}
+	host.TCPConns[connKey(conn.LocalPort, dst, dstPort)] = conn
+	host.sendSegment(conn, level.TCPFlagSYN, nil)
+	conn.SendNext++
+	host.setTCPState(conn, TCPSynSent)
+	return nil
+}
+
+// CloseTCP 主动关闭到对端的连接
+// @param dst 目的地址
+// @param dstPort 目的端口
+func (host *BaseHost) CloseTCP(dst [4]byte, dstPort uint16) error {
+	for _, conn := range host.TCPConns {
+		if conn.RemoteIP != dst || conn.RemotePort != dstPort {
+			continue
+		}
+		switch conn.State {
+		case TCPEstablished:
+			host.sendSegment(conn, level.TCPFlagFIN|level.TCPFlagACK, nil)
+			conn.SendNext++
+			host.setTCPState(conn, TCPFinWait1)
+			return nil
+		case TCPCloseWait:
+			host.sendSegment(conn, level.TCPFlagFIN|level.TCPFlagACK, nil)
+			conn.SendNext++
+			host.setTCPState(conn, TCPLastAck)
+			return nil
+		}
+	}
+	return fmt.Errorf(i18n.T("%s 没有到 %s:%d 的已建立连接"), host.Name, level.FormatIPv4(dst), dstPort)
+}
+
+// sendSegment 按连接状态发送TCP报文段
+func (host *BaseHost) sendSegment(conn *TCPConn, flags uint16, data []byte) {
+	ack := uint32(0)
+	if flags&level.TCPFlagACK != 0 {
+		ack = conn.RecvNext
+	}
+	seg := level.NewTCPPacket(conn.LocalPort, conn.RemotePort, conn.SendNext, ack, flags, 65535, data)
+	host.sendIPv4(conn.RemoteIP, level.IPProtocolTCP, func(src [4]byte) []byte {
+		return seg.Serialize(src, conn.RemoteIP)
+	})
+}
+
+// setTCPState 切换连接状态
+func (host *BaseHost) setTCPState(conn *TCPConn, state string) {
+	logf(logL4, host.Name, "TCP %s %s -> %s", conn, conn.State, state)
+	publishTCPState(host, conn, conn.State, state)
+	conn.State = state
+	if state == TCPClosed {
+		delete(host.TCPConns, connKey(conn.LocalPort, conn.RemoteIP, conn.RemotePort))
+	}
+}
+
+// handleTCP 处理TCP报文段
+func (host *BaseHost) handleTCP(ip *level.IPv4Packet) {
+	seg, err := level.DeserializeTCPPacket(ip.Data)
+	if err != nil {
+		return
+	}
+	flags := seg.Flags()
+	tracef(logL4, host.Name, "TCP %s:%d -> :%d [%s] seq=%d ack=%d len=%d", level.FormatIPv4(ip.SourceIP),
+		seg.SourcePort, seg.DestPort, level.TCPFlagNames(flags), seg.SeqNum, seg.AckNum, len(seg.Data))
+	conn := host.TCPConns[connKey(seg.DestPort, ip.SourceIP, seg.SourcePort)]
+	if conn == nil || (conn.State == TCPTimeWait && flags&level.TCPFlagSYN != 0) {
+		host.handleTCPNoConn(ip, seg)
+		return
+	}
+	if flags&level.TCPFlagRST != 0 {
+		host.setTCPState(conn, TCPClosed)
+		return
+	}
+	ackOK := flags&level.TCPFlagACK != 0 && seg.AckNum == conn.SendNext
+	switch conn.State {
+	case TCPSynSent:
+		if flags&level.TCPFlagSYN != 0 && ackOK {
+			conn.RecvNext = seg.SeqNum + 1
+			host.sendSegment(conn, level.TCPFlagACK, nil)
+			host.setTCPState(conn, TCPEstablished)
+			host.flushQueued(conn)
+		}
+	case TCPSynReceived:
+		if ackOK {
+			host.setTCPState(conn, TCPEstablished)
+			host.receiveData(conn, seg)
+		}
+	case TCPEstablished:
+		host.receiveData(conn, seg)
+	case TCPFinWait1:
+		if flags&level.TCPFlagFIN != 0 {
+			conn.RecvNext = seg.SeqNum + uint32(len(seg.Data)) + 1
+			host.sendSegment(conn, level.TCPFlagACK, nil)
+			host.setTCPState(conn, TCPTimeWait)
+		} else if ackOK {
+			host.setTCPState(conn, TCPFinWait2)
+		}
+	case TCPFinWait2:
+		if flags&level.TCPFlagFIN != 0 {
+			conn.RecvNext = seg.SeqNum + uint32(len(seg.Data)) + 1
+			host.sendSegment(conn, level.TCPFlagACK, nil)
+			host.setTCPState(conn, TCPTimeWait)
+		}
+	case TCPLastAck:
+		if ackOK {
+			host.setTCPState(conn, TCPClosed)
+		}
+	}
+}
+
+// handleTCPNoConn 处理不属于任何连接的报文段: 监听端口上接受SYN, 否则回复RST
+func (host *BaseHost) handleTCPNoConn(ip *level.IPv4Packet, seg *level.TCPPacket) {
+	flags := seg.Flags()
+	if flags&level.TCPFlagRST != 0 {
+		return
+	}
+	if flags&level.TCPFlagSYN != 0 && flags&level.TCPFlagACK == 0 && host.Listening[seg.DestPort] {
+		conn := &TCPConn{
+			LocalPort:  seg.DestPort,
+			RemoteIP:   ip.SourceIP,
+			RemotePort: seg.SourcePort,
+			State:      TCPClosed,
+			SendNext:   rng.Uint32(),
+			RecvNext:   seg.SeqNum + 1,
+		}
+		host.TCPConns[connKey(conn.LocalPort, conn.RemoteIP, conn.RemotePort)] = conn
+		host.sendSegment(conn, level.TCPFlagSYN|level.TCPFlagACK, nil)
+		conn.SendNext++
+		host.setTCPState(conn, TCPSynReceived)
+		return
+	}
+	// RFC 793: 对不存在的连接回复RST
+	rst := &TCPConn{LocalPort: seg.DestPort, RemoteIP: ip.SourceIP, RemotePort: seg.SourcePort}
+	resetFlags := uint16(level.TCPFlagRST)
+	if flags&level.TCPFlagACK != 0 {
+		rst.SendNext = seg.AckNum
+	} else {
+		rst.RecvNext = seg.SeqNum + uint32(len(seg.Data))
+		if flags&level.TCPFlagSYN != 0 {
+			rst.RecvNext++
+		}
+		resetFlags |= level.TCPFlagACK
+	}
+	logf(logL4, host.Name, "TCP 端口 %d 未监听, 回复 RST", seg.DestPort)
+	host.sendSegment(rst, resetFlags, nil)
+}
+
+// receiveData 已建立连接上接收数据与FIN
+func (host *BaseHost) receiveData(conn *TCPConn, seg *level.TCPPacket) {
+	if len(seg.Data) > 0 && seg.SeqNum == conn.RecvNext {
+		conn.RecvNext += uint32(len(seg.Data))
+		logf(logL4, host.Name, "TCP %s 收到数据 %q", conn, seg.Data)
+		if seg.Flags()&level.TCPFlagFIN == 0 {
+			host.sendSegment(conn, level.TCPFlagACK, nil)
+		}
+	}
+	if seg.Flags()&level.TCPFlagFIN != 0 {
+		conn.RecvNext++
+		host.sendSegment(conn, level.TCPFlagACK, nil)
+		host.setTCPState(conn, TCPCloseWait)
+		// 模拟应用立即关闭
+		host.sendSegment(conn, level.TCPFlagFIN|level.TCPFlagACK, nil)
+		conn.SendNext++
+		host.setTCPState(conn, TCPLastAck)
+	}
+}
+
+// flushQueued 连接建立后发送排队的数据
+func (host *BaseHost) flushQueued(conn *TCPConn) {
+	if len(conn.queued) == 0 {
+		return
+	}
+	host.sendSegment(conn, level.TCPFlagPSH|level.TCPFlagACK, conn.queued)
+	conn.SendNext += uint32(len(conn.queued))
+	conn.queued = nil
+}
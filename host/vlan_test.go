@@ -0,0 +1,129 @@
+package host
+
+import (
+	"testing"
+	"time"
+
+	"osiweb-go/level"
+)
+
+// newTestHost 创建指定地址与网关的主机
+func newTestHost(name, address, gateway string) *BaseHost {
+	h := NewHost(name)
+	ip, prefixLen, _ := level.ParseIPv4Prefix(address)
+	h.SetAddress(0, ip, prefixLen)
+	gw, _ := level.ParseIPv4(gateway)
+	h.SetGateway(gw)
+	return h
+}
+
+// tapICMP 记录设备收到的 ICMP 报文类型
+func tapICMP(t *testing.T) map[string][]uint8 {
+	received := make(map[string][]uint8)
+	id := AddTap(func(f CapturedFrame) {
+		if f.Direction != DirectionRx {
+			return
+		}
+		eth, err := level.Deserialize(f.Data)
+		if err != nil || eth.EtherType() != level.EtherTypeIPv4 {
+			return
+		}
+		ip, err := level.DeserializeIPv4Packet(eth.DataPackage)
+		if err != nil || ip.Protocol != level.IPProtocolICMP {
+			return
+		}
+		if icmp, err := level.DeserializeICMPPacket(ip.Data); err == nil {
+			received[f.Device] = append(received[f.Device], icmp.Type)
+		}
+	})
+	t.Cleanup(func() { RemoveTap(id) })
+	return received
+}
+
+// newVLANLab s1 的 eth0 为连接路由器 r1 的干道, h1 与 h3 在 VLAN 10, h2 在 VLAN 20,
+// h4 的地址与 h1 同网段, 但接在 VLAN 20
+func newVLANLab(t *testing.T) map[string]*BaseHost {
+	t.Helper()
+	resetSimulator(t)
+	sw := NewSwitch("s1", 5)
+	r1 := NewRouter("r1")
+	hosts := map[string]*BaseHost{
+		"h1": newTestHost("h1", "10.0.10.2/24", "10.0.10.1"),
+		"h2": newTestHost("h2", "10.0.20.2/24", "10.0.20.1"),
+		"h3": newTestHost("h3", "10.0.10.3/24", "10.0.10.1"),
+		"h4": newTestHost("h4", "10.0.10.4/24", "10.0.10.1"),
+		"r1": r1,
+	}
+	connect(t, r1, -1, sw, 0)
+	for i, name := range []string{"h1", "h2", "h3", "h4"} {
+		connect(t, hosts[name], 0, sw, i+1)
+	}
+	if err := sw.SetTrunk(0, DefaultVLAN, []uint16{10, 20}); err != nil {
+		t.Fatal(err)
+	}
+	for port, vlan := range map[int]uint16{1: 10, 2: 20, 3: 10, 4: 20} {
+		if err := sw.SetAccess(port, vlan); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for vlan, address := range map[uint16]string{10: "10.0.10.1/24", 20: "10.0.20.1/24"} {
+		sub, err := r1.AddSubinterface(0, vlan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip, prefixLen, _ := level.ParseIPv4Prefix(address)
+		r1.SetAddress(sub, ip, prefixLen)
+	}
+	return hosts
+}
+
+func TestVLANIsolation(t *testing.T) {
+	hosts := newVLANLab(t)
+	received := tapICMP(t)
+	h1 := hosts["h1"]
+	for _, name := range []string{"h3", "h4"} {
+		if err := h1.Ping(hosts[name].Interfaces[0].IPv4Address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Run(10 * time.Second)
+	if _, ok := h1.ARPTable[hosts["h3"].Interfaces[0].IPv4Address]; !ok {
+		t.Error("h1 did not resolve h3 in the same VLAN")
+	}
+	if len(received["h3"]) == 0 {
+		t.Error("h3 in the same VLAN got no echo request")
+	}
+	if _, ok := h1.ARPTable[hosts["h4"].Interfaces[0].IPv4Address]; ok {
+		t.Error("h1 resolved h4 in another VLAN")
+	}
+	if len(received["h4"]) != 0 || len(hosts["h4"].ARPTable) != 0 {
+		t.Errorf("h4 in VLAN 20 heard VLAN 10: icmp %v, arp %v", received["h4"], hosts["h4"].ARPTable)
+	}
+}
+
+func TestRouterOnAStick(t *testing.T) {
+	hosts := newVLANLab(t)
+	received := tapICMP(t)
+	if err := hosts["h1"].Ping(hosts["h2"].Interfaces[0].IPv4Address); err != nil {
+		t.Fatal(err)
+	}
+	Run(time.Second)
+	if got := received["h2"]; len(got) != 1 || got[0] != level.ICMPTypeEchoRequest {
+		t.Errorf("h2 received ICMP %v, want one echo request", got)
+	}
+	if got := received["h1"]; len(got) != 1 || got[0] != level.ICMPTypeEchoReply {
+		t.Errorf("h1 received ICMP %v, want one echo reply", got)
+	}
+	// 路由器在两个子接口上分别解析两台主机
+	r1 := hosts["r1"]
+	for _, name := range []string{"h1", "h2"} {
+		entry, ok := r1.ARPTable[hosts[name].Interfaces[0].IPv4Address]
+		if !ok {
+			t.Errorf("r1 did not resolve %s", name)
+			continue
+		}
+		if vlan := r1.Interfaces[entry.Port].VLAN; vlan == 0 {
+			t.Errorf("r1 learned %s on %s, want a subinterface", name, r1.PortName(entry.Port))
+		}
+	}
+}
@@ -1 +1,596 @@
-package main
\ No newline at end of file
+package main
+
+import (
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// webFiles 浏览器页面
+//
+//go:embed web
+var webFiles embed.FS
+
+// maxWebFrames 为动画保留的最近发送帧数
+const maxWebFrames = 1000
+
+// webFrame 链路上发送的一帧, 供页面绘制动画
+type webFrame struct {
+	// 递增序号, 页面据此增量拉取
+	Seq int `json:"seq"`
+	// 发出时刻(纳秒)
+	Time int64 `json:"time"`
+	// 预计到达对端的时刻(纳秒)
+	Arrive int64 `json:"arrive"`
+	// 链路编号
+	Link int `json:"link"`
+	// 发送设备
+	From string `json:"from"`
+	// 接收设备
+	To string `json:"to"`
+	// 单行摘要
+	Summary string `json:"summary"`
+}
+
+// webFrames 最近发送的帧, 受 simMu 保护
+var webFrames []webFrame
+
+// webFrameSeq 最后一帧的序号
+var webFrameSeq int
+
+// startHTTPServer 在后台启动HTTP服务: REST接口与浏览器页面
+// @param listen string 监听地址, 为空表示所有地址
+// @param port int 监听端口
+func startHTTPServer(listen string, port int) {
+	addr := net.JoinHostPort(listen, strconv.Itoa(port))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		appLog.Error(i18n.T("HTTP 服务启动失败"), "addr", addr, "err", err)
+		return
+	}
+	host.AddTap(recordWebFrame)
+	if listen == "" {
+		listen = "localhost"
+	}
+	fmt.Printf(i18n.T("Web 界面: http://%s/\n"), net.JoinHostPort(listen, strconv.Itoa(port)))
+	appLog.Debug(i18n.T("HTTP 服务已启动"), "addr", ln.Addr().String())
+	go http.Serve(ln, logRequests(checkRequest(newHTTPHandler())))
+}
+
+// newHTTPHandler 注册所有路由
+func newHTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	static, _ := fs.Sub(webFiles, "web")
+	mux.Handle("GET /", http.FileServerFS(static))
+	mux.HandleFunc("GET /api/topology", locked(handleTopology))
+	mux.HandleFunc("GET /api/hosts", locked(handleHosts))
+	mux.HandleFunc("GET /api/hosts/{name}", locked(handleHost))
+	mux.HandleFunc("POST /api/hosts", locked(handleAddHost))
+	mux.HandleFunc("GET /api/links", locked(handleLinks))
+	mux.HandleFunc("POST /api/links", locked(handleAddLink))
+	mux.HandleFunc("POST /api/send", locked(handleSend))
+	mux.HandleFunc("POST /api/run", locked(handleRun))
+	mux.HandleFunc("GET /api/frames", locked(handleFrames))
+	// 事件流长时间占用连接, 不持有模拟器锁
+	mux.HandleFunc("GET /api/events", handleEvents)
+	mux.HandleFunc("GET /api/events/ws", handleEventsWebSocket)
+	mux.HandleFunc("GET /api/captures", locked(handleCaptures))
+	mux.HandleFunc("POST /api/captures", locked(handleStartCapture))
+	mux.HandleFunc("GET /api/captures/{name}", locked(handleCapture))
+	mux.HandleFunc("GET /api/captures/{name}/{index}/signal", locked(handleCaptureSignal))
+	mux.HandleFunc("DELETE /api/captures/{name}", locked(handleStopCapture))
+	return mux
+}
+
+// statusRecorder 记下响应状态码, 用于请求日志
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap 事件流需要访问底层连接的 Flush 与 Hijack
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// logRequests 以 debug 级别记录每个请求
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		appLog.Debug(i18n.T("HTTP 请求"), "method", r.Method, "path", r.URL.Path, "status", rec.status,
+			"duration", time.Since(start))
+	})
+}
+
+// locked 处理请求期间持有模拟器锁
+func locked(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		simMu.Lock()
+		defer simMu.Unlock()
+		fn(w, r)
+	}
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 输出 {"error": ...}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// sameOrigin 浏览器发来的请求必须来自本页面, 没有 Origin 的请求不是跨站请求
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// checkRequest 修改状态的请求必须是同源的JSON请求, 其他网站的页面无法借用户的浏览器操作模拟器
+func checkRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !sameOrigin(r) {
+			writeError(w, http.StatusForbidden, fmt.Errorf(i18n.T("不接受跨站请求: %s"), r.Header.Get("Origin")))
+			return
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New(i18n.T("请求的 Content-Type 应为 application/json")))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readJSON 解析请求体
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(i18n.T("请求体不是合法的JSON: %v"), err))
+		return false
+	}
+	return true
+}
+
+// recordWebFrame 抓包回调: 记录链路上发出的帧
+func recordWebFrame(f host.CapturedFrame) {
+	if f.Direction != host.DirectionTx || f.LinkID == 0 {
+		return
+	}
+	link := host.FindLink(f.LinkID)
+	if link == nil {
+		return
+	}
+	to := link.B
+	if link.B.Device.DeviceName() == f.Device && link.B.Port == f.Port {
+		to = link.A
+	}
+	arrive := f.Time + link.Delay
+	if link.Bandwidth > 0 {
+		arrive += time.Duration(int64(len(f.Data)) * 8 * int64(time.Second) / link.Bandwidth)
+	}
+	webFrameSeq++
+	webFrames = append(webFrames, webFrame{
+		Seq:     webFrameSeq,
+		Time:    int64(f.Time),
+		Arrive:  int64(arrive),
+		Link:    f.LinkID,
+		From:    f.Device,
+		To:      to.Device.DeviceName(),
+		Summary: level.Summarize(f.Data),
+	})
+	if len(webFrames) > maxWebFrames {
+		webFrames = webFrames[len(webFrames)-maxWebFrames:]
+	}
+}
+
+// jsonInterface 接口
+type jsonInterface struct {
+	Name   string `json:"name"`
+	MAC    string `json:"mac,omitempty"`
+	IP     string `json:"ip,omitempty"`
+	Prefix int    `json:"prefix,omitempty"`
+	Link   int    `json:"link,omitempty"`
+}
+
+// jsonDevice 设备
+type jsonDevice struct {
+	Name       string          `json:"name"`
+	Kind       string          `json:"kind"`
+	Interfaces []jsonInterface `json:"interfaces"`
+}
+
+// jsonEndpoint 链路端点
+type jsonEndpoint struct {
+	Device string `json:"device"`
+	Port   string `json:"port"`
+}
+
+// jsonLink 链路
+type jsonLink struct {
+	ID        int          `json:"id"`
+	A         jsonEndpoint `json:"a"`
+	B         jsonEndpoint `json:"b"`
+	Delay     int64        `json:"delay"`
+	Bandwidth int64        `json:"bandwidth"`
+	Loss      float64      `json:"loss"`
+	BER       float64      `json:"ber"`
+	Code      string       `json:"code"`
+}
+
+// deviceJSON 设备及其接口
+func deviceJSON(dev host.Device) jsonDevice {
+	d := jsonDevice{Name: dev.DeviceName(), Interfaces: []jsonInterface{}}
+	switch v := dev.(type) {
+	case *host.BaseHost:
+		d.Kind = "host"
+		if v.Forwarding {
+			d.Kind = "router"
+		}
+		for _, iface := range v.Interfaces {
+			d.Interfaces = append(d.Interfaces, jsonInterface{
+				Name:   iface.Name,
+				MAC:    level.FormatMAC(iface.MACAddress),
+				IP:     level.FormatIPv4(iface.IPv4Address),
+				Prefix: iface.PrefixLen,
+			})
+		}
+	case *host.Switch:
+		d.Kind = "switch"
+		for i := range v.NetChannel {
+			d.Interfaces = append(d.Interfaces, jsonInterface{Name: v.PortName(i)})
+		}
+	}
+	for i := range d.Interfaces {
+		if l := host.LinkAt(dev, i); l != nil {
+			d.Interfaces[i].Link = l.ID
+		}
+	}
+	return d
+}
+
+// linkJSON 链路
+func linkJSON(l *host.Link) jsonLink {
+	return jsonLink{
+		ID:        l.ID,
+		A:         jsonEndpoint{l.A.Device.DeviceName(), l.A.Device.PortName(l.A.Port)},
+		B:         jsonEndpoint{l.B.Device.DeviceName(), l.B.Device.PortName(l.B.Port)},
+		Delay:     int64(l.Delay),
+		Bandwidth: l.Bandwidth,
+		Loss:      l.Loss,
+		BER:       l.BER,
+		Code:      l.Code().String(),
+	}
+}
+
+// handleTopology GET /api/topology 全部设备与链路
+func handleTopology(w http.ResponseWriter, r *http.Request) {
+	devices := []jsonDevice{}
+	for _, dev := range host.DeviceList {
+		devices = append(devices, deviceJSON(dev))
+	}
+	links := []jsonLink{}
+	for _, l := range host.LinkList {
+		links = append(links, linkJSON(l))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"clock":   int64(host.Clock),
+		"pending": host.Pending(),
+		"devices": devices,
+		"links":   links,
+	})
+}
+
+// handleHosts GET /api/hosts 主机与路由器列表
+func handleHosts(w http.ResponseWriter, r *http.Request) {
+	hosts := []jsonDevice{}
+	for _, h := range host.HostList {
+		hosts = append(hosts, deviceJSON(h))
+	}
+	writeJSON(w, http.StatusOK, hosts)
+}
+
+// handleHost GET /api/hosts/{name} 主机详情: 接口、ARP缓存、路由表与TCP连接
+func handleHost(w http.ResponseWriter, r *http.Request) {
+	h := host.FindHost(r.PathValue("name"))
+	if h == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf(i18n.T("主机不存在: %s"), r.PathValue("name")))
+		return
+	}
+	type arpJSON struct {
+		IP      string `json:"ip"`
+		MAC     string `json:"mac"`
+		Port    string `json:"port"`
+		Updated int64  `json:"updated"`
+	}
+	type routeJSON struct {
+		Prefix  string `json:"prefix"`
+		NextHop string `json:"next_hop"`
+	}
+	type tcpJSON struct {
+		LocalPort uint16 `json:"local_port"`
+		Remote    string `json:"remote"`
+		State     string `json:"state"`
+	}
+	arp := []arpJSON{}
+	for ip, e := range h.ARPTable {
+		arp = append(arp, arpJSON{level.FormatIPv4(ip), level.FormatMAC(e.MAC), h.PortName(e.Port), int64(e.Updated)})
+	}
+	sort.Slice(arp, func(i, j int) bool { return arp[i].IP < arp[j].IP })
+	routes := []routeJSON{}
+	for _, rt := range h.Routes {
+		routes = append(routes, routeJSON{
+			fmt.Sprintf("%s/%d", level.FormatIPv4(rt.Prefix), rt.PrefixLen), level.FormatIPv4(rt.NextHop)})
+	}
+	listening := []int{}
+	for p := range h.Listening {
+		listening = append(listening, int(p))
+	}
+	sort.Ints(listening)
+	conns := []tcpJSON{}
+	for _, c := range h.TCPConns {
+		conns = append(conns, tcpJSON{c.LocalPort,
+			fmt.Sprintf("%s:%d", level.FormatIPv4(c.RemoteIP), c.RemotePort), c.State})
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].LocalPort < conns[j].LocalPort })
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device":    deviceJSON(h),
+		"arp":       arp,
+		"routes":    routes,
+		"listening": listening,
+		"tcp":       conns,
+	})
+}
+
+// handleAddHost POST /api/hosts {"name", "address", "gateway"}
+func handleAddHost(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+		Gateway string `json:"gateway"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New(i18n.T("缺少主机名称")))
+		return
+	}
+	h, err := addHost(req.Name, req.Address, req.Gateway)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, deviceJSON(h))
+}
+
+// handleLinks GET /api/links
+func handleLinks(w http.ResponseWriter, r *http.Request) {
+	links := []jsonLink{}
+	for _, l := range host.LinkList {
+		links = append(links, linkJSON(l))
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+// handleAddLink POST /api/links {"a": "h1", "b": "s1:eth2", "delay": "2ms", "bw": "10M", "loss": "0.1", "ber": "1e-5", "code": "4b5b"}
+func handleAddLink(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		A     string `json:"a"`
+		B     string `json:"b"`
+		Delay string `json:"delay"`
+		BW    string `json:"bw"`
+		Loss  string `json:"loss"`
+		BER   string `json:"ber"`
+		Code  string `json:"code"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	// 按固定顺序生成参数, 出错时报告的总是同一个参数
+	var params []string
+	for _, kv := range [][2]string{{"delay", req.Delay}, {"bw", req.BW}, {"loss", req.Loss}, {"ber", req.BER}, {"code", req.Code}} {
+		if kv[1] != "" {
+			params = append(params, kv[0]+"="+kv[1])
+		}
+	}
+	link, err := addLink(req.A, req.B, params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, linkJSON(link))
+}
+
+// handleSend POST /api/send {"host", "dst", "proto", "port", "data"}
+func handleSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Host  string `json:"host"`
+		Dst   string `json:"dst"`
+		Proto string `json:"proto"`
+		Port  uint16 `json:"port"`
+		Data  string `json:"data"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Proto == "" {
+		req.Proto = "icmp"
+	}
+	if err := sendPacket(req.Host, req.Dst, strings.ToLower(req.Proto), req.Port, req.Data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"pending": host.Pending()})
+}
+
+// handleRun POST /api/run {"duration": "10ms"}, 省略时运行到没有事件
+func handleRun(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &req) {
+		return
+	}
+	var limit time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(i18n.T("时长格式错误: %s"), req.Duration))
+			return
+		}
+		limit = d
+	}
+	n := host.Run(limit)
+	writeJSON(w, http.StatusOK, map[string]any{"events": n, "clock": int64(host.Clock), "pending": host.Pending()})
+}
+
+// handleFrames GET /api/frames?since=<seq> 链路上发送的帧, 用于动画
+func handleFrames(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	frames := []webFrame{}
+	for _, f := range webFrames {
+		if f.Seq > since {
+			frames = append(frames, f)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"seq": webFrameSeq, "frames": frames})
+}
+
+// handleCaptures GET /api/captures 抓包会话列表
+func handleCaptures(w http.ResponseWriter, r *http.Request) {
+	type sessionJSON struct {
+		Name   string `json:"name"`
+		Active bool   `json:"active"`
+		Frames int    `json:"frames"`
+	}
+	list := []sessionJSON{}
+	for _, s := range captures {
+		list = append(list, sessionJSON{s.name, s.tapID != 0, len(s.frames)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleStartCapture POST /api/captures {"target": "h1" | "h1:eth0" | "link 1", "file", "fcs"}
+func handleStartCapture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Target string `json:"target"`
+		File   string `json:"file"`
+		FCS    bool   `json:"fcs"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	target := strings.Fields(req.Target)
+	if len(target) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New(i18n.T("缺少抓包对象")))
+		return
+	}
+	s, err := startCapture(target, req.File, req.FCS)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"name": s.name})
+}
+
+// handleCapture GET /api/captures/{name}?since=<n> 抓到的帧, 含摘要与十六进制
+func handleCapture(w http.ResponseWriter, r *http.Request) {
+	s, ok := captures[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(i18n.T("没有抓包记录: %s"), r.PathValue("name")))
+		return
+	}
+	type frameJSON struct {
+		Index     int    `json:"index"`
+		Time      int64  `json:"time"`
+		Interface string `json:"interface"`
+		Direction string `json:"direction"`
+		Summary   string `json:"summary"`
+		Hex       string `json:"hex"`
+	}
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	frames := []frameJSON{}
+	for i := max(since, 0); i < len(s.frames); i++ {
+		f := s.frames[i]
+		name, _, _ := s.interfaceOf(f)
+		frames = append(frames, frameJSON{i + 1, int64(f.Time), name, f.Direction,
+			level.Summarize(f.Data), hex.EncodeToString(f.Data)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": s.name, "active": s.tapID != 0, "frames": frames})
+}
+
+// handleCaptureSignal GET /api/captures/{name}/{index}/signal?code=4b5b&from=0&bits=64 帧在线路上的波形(SVG)
+func handleCaptureSignal(w http.ResponseWriter, r *http.Request) {
+	s, ok := captures[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(i18n.T("没有抓包记录: %s"), r.PathValue("name")))
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || n < 1 || n > len(s.frames) {
+		writeError(w, http.StatusNotFound, fmt.Errorf(i18n.T("帧编号应在 1-%d 之间: %s"), len(s.frames), r.PathValue("index")))
+		return
+	}
+	f := s.frames[n-1]
+	query := r.URL.Query()
+	code := frameLineCode(f)
+	if v := query.Get("code"); v != "" {
+		if code, err = level.ParseLineCode(v); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	sig := level.EncodeFrame(code, f.Data)
+	from, _ := strconv.Atoi(query.Get("from"))
+	bits, _ := strconv.Atoi(query.Get("bits"))
+	w.Header().Set("Content-Type", "image/svg+xml")
+	io.WriteString(w, sig.SVG(from, bits))
+}
+
+// handleStopCapture DELETE /api/captures/{name}
+func handleStopCapture(w http.ResponseWriter, r *http.Request) {
+	s, ok := captures[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf(i18n.T("没有抓包记录: %s"), r.PathValue("name")))
+		return
+	}
+	if err := s.stop(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": s.name, "frames": len(s.frames)})
+}
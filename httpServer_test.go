@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 修改状态的请求必须是同源的JSON请求, WebSocket 握手检查 Origin
+func TestCheckRequest(t *testing.T) {
+	resetSimulator(t)
+	handler := checkRequest(newHTTPHandler())
+	tests := []struct {
+		name, method, path, contentType, origin string
+		want                                    int
+	}{
+		{"json", "POST", "/api/run", "application/json", "", http.StatusOK},
+		{"json with charset", "POST", "/api/run", "application/json; charset=utf-8", "", http.StatusOK},
+		{"same origin", "POST", "/api/run", "application/json", "http://example.test", http.StatusOK},
+		{"form post", "POST", "/api/run", "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"text post", "POST", "/api/run", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"no content type", "DELETE", "/api/captures/h1", "", "", http.StatusUnsupportedMediaType},
+		{"cross origin", "POST", "/api/run", "application/json", "http://evil.test", http.StatusForbidden},
+		{"get", "GET", "/api/hosts", "", "http://evil.test", http.StatusOK},
+		{"websocket cross origin", "GET", "/api/events/ws", "", "http://evil.test", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "http://example.test"+tt.path, strings.NewReader("{}"))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+// 抓包文件不能写到抓包目录之外
+func TestStartCaptureFile(t *testing.T) {
+	resetSimulator(t)
+	dir := t.TempDir()
+	saved := appConfig.CaptureDir
+	appConfig.CaptureDir = dir
+	t.Cleanup(func() { appConfig.CaptureDir = saved })
+	execLine("host add h1")
+	handler := checkRequest(newHTTPHandler())
+	for _, file := range []string{"/tmp/h1.pcap", "../h1.pcap", "sub/../../h1.pcap"} {
+		r := httptest.NewRequest("POST", "/api/captures", strings.NewReader(`{"target": "h1", "file": "`+file+`"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("file %q: status %d, want %d", file, w.Code, http.StatusBadRequest)
+		}
+	}
+	path, err := capturePath("h1.pcapng")
+	if err != nil || path != filepath.Join(dir, "h1.pcapng") {
+		t.Errorf("capturePath(h1.pcapng) = %q, %v, want it inside %s", path, err, dir)
+	}
+}
+
+// 多个参数都错误时总是报告同一个参数
+func TestAddLinkParamOrder(t *testing.T) {
+	resetSimulator(t)
+	execLine("host add h1")
+	execLine("host add h2")
+	handler := checkRequest(newHTTPHandler())
+	body := `{"a": "h1", "b": "h2", "code": "x", "ber": "2", "loss": "2", "bw": "x", "delay": "x"}`
+	for i := 0; i < 20; i++ {
+		r := httptest.NewRequest("POST", "/api/links", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "时延格式错误") {
+			t.Fatalf("status %d: %s, want a delay error", w.Code, w.Body)
+		}
+	}
+}
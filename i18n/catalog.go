@@ -0,0 +1,670 @@
+package i18n
+
+// catalog 中文原文到英文的翻译, 键为代码中的中文文字或格式串
+// Chinese source text to English, keyed by the Chinese text or format in the code
+var catalog = map[string]string{
+	// 命令行 REPL
+	"您是否在查找:":             "Did you mean:",
+	"输入 'help' 以查看所有可用命令": "Type 'help' to see all available commands",
+	"参数错误!":               "Invalid arguments!",
+	"用法: %s\n":            "Usage: %s\n",
+	"未找到分页器":              "no pager found",
+	"未知命令: ":              "Unknown command:",
+	"OSIWeb-Go 命令参考手册\n":  "OSIWeb-Go Command Reference\n",
+	"OSIWeb-Go 是一个计算机七层网络协议模拟器，支持以下命令：\n\n": "OSIWeb-Go is a seven-layer network protocol simulator. The following commands are supported:\n\n",
+	"输入 'help <命令>' 只查看一条命令\n":              "Type 'help <command>' to see a single command\n",
+	"更多信息请访问: %s\n":                         "For more information visit: %s\n",
+	"命令: %s (别名: %s)\n":                     "Command: %s (aliases: %s)\n",
+	"命令: %s\n":                              "Command: %s\n",
+	"描述: %s\n":                              "Description: %s\n",
+	"参数:\n":                                 "Arguments:\n",
+	"示例:\n":                                 "Examples:\n",
+	"相关: %s\n":                              "See also: %s\n",
+	"\r\n保存命令历史失败: %v\r\n":                  "\r\nFailed to save command history: %v\r\n",
+	"用法: %s":                                "Usage: %s",
+	"读取配置失败: %v\n":                          "Failed to load configuration: %v\n",
+	"加载拓扑失败: %v\n":                          "Failed to load topology: %v\n",
+	"欢迎使用 OSIWeb-Go !":                      "Welcome to OSIWeb-Go!",
+	"读取命令历史失败: %v\n":                        "Failed to read command history: %v\n",
+	"再见! :D":                                "Bye! :D",
+	"输入错误: %v\n":                            "Input error: %v\n",
+	"缺少子命令, 应为 %s":                          "missing subcommand, expected %s",
+	"未知的子命令 %q":                             "unknown subcommand %q",
+	", 您是否想输入 %s?":                          ", did you mean %s?",
+	"缺少参数 %s":                               "missing argument %s",
+	"多余的参数 %q":                              "unexpected argument %q",
+	"参数错误: %s\n":                            "Invalid argument: %s\n",
+
+	// 命令帮助 Command help
+	"添加主机, 监听或关闭TCP端口":                       "Add a host, listen on or close a TCP port",
+	"主机名称, 不能与已有设备重名":                        "host name, must not clash with an existing device",
+	"eth0 的IPv4地址与前缀长度, 省略时从 ipv4_pool 自动分配": "IPv4 address and prefix length of eth0, allocated from ipv4_pool when omitted",
+	"默认网关":                "default gateway",
+	"TCP端口 0~65535":       "TCP port 0-65535",
+	"close 时为连接对端的IPv4地址": "for close, the IPv4 address of the connection's peer",
+	"添加二层交换机":             "Add a layer 2 switch",
+	"交换机名称":               "switch name",
+	"端口数, 默认8":            "number of ports, default 8",
+	"添加路由器, 接口在连接链路时创建":   "Add a router; interfaces are created when links are connected",
+	"路由器名称":               "router name",
+	"添加、修改或删除链路":          "Add, modify or delete links",
+	"设备与端口, 端口可写名称或编号, 省略时使用第一个空闲端口": "device and port; the port may be a name or number, the first free port is used when omitted",
+	"链路编号, 见 show links":            "link number, see show links",
+	"传播时延, 如 2ms":                   "propagation delay, e.g. 2ms",
+	"带宽, 支持 K/M/G 后缀, inf 表示不计发送时延": "bandwidth with optional K/M/G suffix, inf for no transmission delay",
+	"丢包率 0~1":                       "loss rate 0-1",
+	"设置接口IPv4地址":                    "Set an interface's IPv4 address",
+	"主机或路由器的接口, 如 r1:eth1":          "host or router interface, e.g. r1:eth1",
+	"IPv4地址与前缀长度, 如 192.168.1.1/24": "IPv4 address and prefix length, e.g. 192.168.1.1/24",
+	"添加静态路由":                        "Add a static route",
+	"主机或路由器":                        "host or router",
+	"目的网段, default 表示 0.0.0.0/0":    "destination network, default means 0.0.0.0/0",
+	"下一跳IPv4地址, 必须在直连网段内":           "next-hop IPv4 address, must be on a directly connected network",
+	"设置交换机端口的VLAN模式, 或在主机、路由器的接口上创建VLAN子接口(单臂路由)": "Set a switch port's VLAN mode, or create a VLAN subinterface on a host or router interface (router on a stick)",
+	"VLAN编号 1~4094": "VLAN ID 1-4094",
+	"接入端口, 属于一个VLAN, 收发不带标签的帧":                       "access port: belongs to one VLAN, sends and receives untagged frames",
+	"干道端口, 本征VLAN以外的帧带 802.1Q 标签":                    "trunk port: frames outside the native VLAN carry an 802.1Q tag",
+	"干道端口的本征VLAN, 默认1":                               "native VLAN of a trunk port, default 1",
+	"干道端口允许的VLAN, 如 10,20,30-35, 默认全部":               "VLANs allowed on a trunk port, e.g. 10,20,30-35, all by default",
+	"创建子接口 <port>.<vid>, 收发带该VLAN标签的帧":               "create subinterface <port>.<vid>, which sends and receives frames tagged with that VLAN",
+	"查看主机、链路、ARP缓存、路由表、MAC地址表、端口VLAN、生成树、TCP连接或当前配置": "Show hosts, links, ARP caches, routing tables, MAC address tables, port VLANs, spanning tree, TCP connections or the current configuration",
+	"只看一台设备, 省略时显示全部":                                "show only one device, all devices when omitted",
+	"只看一台交换机":                                        "show only one switch",
+	"只看一台主机":                                         "show only one host",
+	"从JSON/YAML文件加载拓扑, 校验拓扑文件, 或把当前拓扑保存到文件":          "Load a topology from a JSON/YAML file, validate a topology file, or save the current topology",
+	"拓扑文件, 扩展名为 .yaml/.yml 时按YAML读写, 否则为JSON":        "topology file, YAML when the extension is .yaml/.yml, JSON otherwise",
+	"把完整的模拟状态保存到数据目录, 或从快照恢复":                        "Save the complete simulation state to the data directory, or restore a snapshot",
+	"快照名称, 默认为 default, 不能包含路径":                      "snapshot name, default is default, must not contain a path",
+	"从主机发送ICMP回显请求、UDP数据报或TCP数据":                     "Send an ICMP echo request, UDP datagram or TCP data from a host",
+	"发送的主机":         "sending host",
+	"目的IPv4地址":      "destination IPv4 address",
+	"协议, 默认 icmp":   "protocol, default icmp",
+	"udp/tcp 的目的端口": "destination port for udp/tcp",
+	"数据, 含空格时加双引号":  "data, quoted when it contains spaces",
+	"逐层构造一帧, 显示十六进制与解码结果, 可注入设备端口":                                                                                    "Build a frame layer by layer, show its hex dump and dissection, optionally inject it at a device port",
+	"协议层 eth/vlan/llc/snap/stp/arp/ip/icmp/udp/tcp/raw, 自下而上用 / 分隔":                                                   "layer eth/vlan/llc/snap/stp/arp/ip/icmp/udp/tcp/raw, bottom up, separated by /",
+	"字段取值, 未给出的字段按上下层推断":                                                                                              "field value; omitted fields are inferred from adjacent layers",
+	"从设备端口发出构造的帧":                                                                                                     "send the built frame out of a device port",
+	"Ethernet II: dst/src MAC, type 如 0x0806 (缺省按上层推断)":                                                               "Ethernet II: dst/src MAC, type such as 0x0806 (inferred from the upper layer by default)",
+	"802.1Q: id VLAN编号, pcp 优先级 0~7, dei 可丢弃 0/1, type 缺省按上层推断":                                                       "802.1Q: id VLAN ID, pcp priority 0~7, dei drop eligible 0/1, type inferred from the upper layer by default",
+	"802.2 LLC: dsap/ssap 服务访问点如 0x42 (STP), ctrl 缺省 0x03 (UI); 下层类型字段为长度":                                            "802.2 LLC: dsap/ssap service access points such as 0x42 (STP), ctrl defaults to 0x03 (UI); the lower type field holds the length",
+	"LLC SNAP (AA-AA-03): oui 组织代码缺省0, pid 缺省按上层推断":                                                                   "LLC SNAP (AA-AA-03): oui organization code defaults to 0, pid inferred from the upper layer by default",
+	"生成树BPDU: type config/rst/tcn 或数值, root/bridge 网桥标识如 32768/02:b0:00:00:00:01 (缺省为源MAC), port 缺省 0x8001; 下层应为 llc": "spanning tree BPDU: type config/rst/tcn or a number, root/bridge bridge IDs such as 32768/02:b0:00:00:00:01 (source MAC by default), port defaults to 0x8001; needs an llc layer below",
+	"ARP: op 1请求/2应答, sha/tha MAC, spa/tpa IPv4":                                                                      "ARP: op 1 request/2 reply, sha/tha MAC, spa/tpa IPv4",
+	"IPv4: proto 缺省按上层推断":                                                                                             "IPv4: proto inferred from the upper layer by default",
+	"ICMP: type 8请求/0应答":                                                                                              "ICMP: type 8 request/0 reply",
+	"TCP: flags 如 S、SA、SYN,ACK 或数值":                                                                                   "TCP: flags such as S, SA, SYN,ACK or a number",
+	"原始负载: data 文本或 hex 十六进制":                                                                                         "raw payload: data as text or hex in hexadecimal",
+	"在设备、端口或链路上抓包, 可写入 pcap/pcapng 文件; show 指定帧号时逐字段解码":                                                               "Capture on a device, port or link, optionally writing a pcap/pcapng file; show with a frame number dissects it field by field",
+	"设备或设备的一个端口":                                                                                                      "a device or one of its ports",
+	"链路":                                                                                                              "link",
+	"同时写入的抓包文件, 位于 capture_dir 中":                                                                                     "capture file written alongside, inside capture_dir",
+	"文件中的帧带上以太网FCS":                                                                                                   "include the Ethernet FCS in frames written to the file",
+	"帧编号, 逐字段解码该帧":                                                                                                    "frame number, dissect that frame field by field",
+	"读取 pcap/pcapng 文件并解码, 或按原始时间间隔回放到设备端口":                                                                           "Read and decode a pcap/pcapng file, or replay it to a device port with the original timing",
+	"pcap 或 pcapng 文件":                                                                                                "pcap or pcapng file",
+	"逐字段解码":                                                                                                           "dissect field by field",
+	"最多处理的帧数":                                                                                                         "maximum number of frames to process",
+	"回放速度倍数":                                                                                                          "replay speed factor",
+	"查看或修改日志格式、各层日志级别, 为单个设备开启逐帧trace":                                                                                "Show or change the log format and per-layer log levels, enable per-frame trace for a device",
+	"子系统, 省略时设置全部":                                                                                                    "subsystem, all subsystems when omitted",
+	"开启或关闭 trace 的设备":                                                                                                 "device to enable or disable trace for",
+	"运行模拟直到没有事件, 或推进指定的虚拟时间":                                                                                          "Run the simulation until no events remain, or advance the virtual clock",
+	"虚拟时间, 如 10ms、1s":                                                                                                 "virtual time, e.g. 10ms, 1s",
+	"处理下一个(或n个)事件":                                                                                                    "Process the next event (or n events)",
+	"事件数, 默认1":                                                                                                        "number of events, default 1",
+	"逐行执行场景脚本, 统计 expect 断言的结果":                                                                                       "Run a scenario script line by line and tally its expect assertions",
+	"脚本文件, 每行一条命令, # 开头为注释":                                                                                           "script file, one command per line, lines starting with # are comments",
+	"断言ARP缓存、MAC地址表、生成树、路由、TCP状态、抓包帧数或虚拟时间, 用于场景脚本自动评分":                                            "Assert on ARP caches, MAC tables, spanning tree, routes, TCP states, capture counts or the virtual clock, for grading scenario scripts",
+	"TCP状态, 如 ESTABLISHED, LISTEN 为监听中的端口":                                                         "TCP state such as ESTABLISHED; LISTEN means a listening port",
+	"生成树端口角色 root/designated/alternate/backup/disabled 或状态 blocking/listening/learning/forwarding": "spanning tree port role root/designated/alternate/backup/disabled or state blocking/listening/learning/forwarding",
+	"all、协议名或 协议.字段 比较, 多个条件用 && 连接":                                                               "all, a protocol name or a protocol.field comparison, conditions joined by &&",
+	"比较符与虚拟时间, 如 <100ms":                                                                           "comparison operator and virtual time, e.g. <100ms",
+	"显示帮助信息, 指定命令时显示该命令的参数、示例与相关命令":                                                                "Show help; with a command, show its arguments, examples and related commands",
+	"退出程序": "Exit the program",
+
+	"在交换机上启用 802.1D 生成树或快速生成树, 设置网桥优先级、端口开销、端口优先级与边缘端口":                "Enable 802.1D or rapid spanning tree on a switch and set the bridge priority, port costs, port priorities and edge ports",
+	"stp 为 802.1D, 端口经侦听、学习各15秒后转发; rstp 为 802.1w, 用提议/同意快速转发; 默认 stp": "stp is 802.1D, ports forward after 15 s each of listening and learning; rstp is 802.1w, forwarding quickly via proposal/agreement; default stp",
+	"网桥优先级 0~61440, 4096的倍数, 默认32768, 越小越优先成为根桥":                       "bridge priority 0-61440 in multiples of 4096, default 32768; lower wins the root election",
+	"端口路径开销, auto 按链路带宽计算":                                             "port path cost, auto derives it from the link bandwidth",
+	"端口优先级 0~240, 16的倍数, 默认128":                                        "port priority 0-240 in multiples of 16, default 128",
+	"边缘端口, 连接主机时立即转发, 收到BPDU后自动取消":                                     "edge port for hosts, forwards immediately and is cleared when a BPDU arrives",
+
+	"物理层: 显示线路编码的波形, 翻转电平模拟误码, 观察物理层编码检查与接收方FCS检查能否发现":     "Physical layer: show line code waveforms, flip levels to simulate bit errors, and see whether the PHY code check or the receiver's FCS check catches them",
+	"误码率 0~1, 按线路编码后的码元翻转, 出错的帧被物理层或CRC检查丢弃":               "bit error rate 0-1, applied to line-coded symbols; damaged frames are dropped by the PHY or the CRC check",
+	"线路编码 auto/nrz/nrzi/manchester/4b5b/8b10b, auto 按带宽选择": "line code auto/nrz/nrzi/manchester/4b5b/8b10b, auto picks one from the bandwidth",
+	"线路编码 nrz/nrzi/manchester/4b5b/8b10b":                  "line code nrz/nrzi/manchester/4b5b/8b10b",
+	"要编码的数据, 十六进制":                                         "data to encode, in hex",
+	"抓包会话与帧编号, 帧加上前导码与定界符后发送":                              "capture session and frame number; the frame is sent with its preamble and delimiters",
+	"线路编码, 默认使用帧所在链路的编码":                                   "line code, defaults to the code of the frame's link",
+	"要翻转的电平下标, 曼彻斯特编码每个比特两个电平":                             "indexes of levels to flip; Manchester has two levels per bit",
+	"按误码率随机翻转电平":                                           "flip levels at random with this error rate",
+	"从第几个线路比特开始显示, 帧默认从帧起始定界符之前开始":                         "first line bit to show; for frames the default starts just before the start frame delimiter",
+	"显示的线路比特数":                                             "number of line bits to show",
+	"同时把波形写入SVG文件, 位于 capture_dir 中":                       "also write the waveform to an SVG file inside capture_dir",
+
+	// 配置 Configuration
+	"时长应写成字符串, 如 \"1ms\": %s":                          "duration must be a string such as \"1ms\": %s",
+	"时长格式错误: %s":                                       "invalid duration: %s",
+	"应为 子系统=级别: %s":                                    "expected subsystem=level: %s",
+	"应为整数: %s":                                         "expected an integer: %s",
+	"应为数字: %s":                                         "expected a number: %s",
+	"-script、-c 与 -f 只能指定一个":                           "only one of -script, -c and -f may be given",
+	"环境变量 %s: %v":                                      "environment variable %s: %v",
+	"data_dir 不能为空":                                    "data_dir must not be empty",
+	"capture_dir 不能为空":                                 "capture_dir must not be empty",
+	"history_size 不能为负数: %d":                           "history_size must not be negative: %d",
+	"lang 应为 zh/en: %q":                                "lang must be zh/en: %q",
+	"log_level 应为 trace/debug/info/warn/error: %q":     "log_level must be trace/debug/info/warn/error: %q",
+	"log_levels 的子系统应为 l2/l3/l4/app: %q":               "log_levels subsystem must be l2/l3/l4/app: %q",
+	"log_levels.%s 应为 trace/debug/info/warn/error: %q": "log_levels.%s must be trace/debug/info/warn/error: %q",
+	"log_format 应为 console/text/json: %q":              "log_format must be console/text/json: %q",
+	"simulator.mtu 应在68~%d之间: %d":                      "simulator.mtu must be between 68 and %d: %d",
+	"simulator.link.delay 不能为负: %s":                    "simulator.link.delay must not be negative: %s",
+	"simulator.link.bandwidth 不能为负: %d":                "simulator.link.bandwidth must not be negative: %d",
+	"simulator.link.loss 应在0~1之间: %v":                  "simulator.link.loss must be between 0 and 1: %v",
+	"http.port 应在0~65535之间: %d":                        "http.port must be between 0 and 65535: %d",
+	"配置错误:":                                            "invalid configuration:",
+	"simulator.mac_prefix 应为3个字节, 如 02:00:00: %q":      "simulator.mac_prefix must be 3 bytes such as 02:00:00: %q",
+	"simulator.mac_prefix 格式错误: %q":                    "simulator.mac_prefix is malformed: %q",
+	"simulator.mac_prefix 不能是组播地址: %q":                 "simulator.mac_prefix must not be a multicast address: %q",
+	"simulator.ipv4_pool 应为IPv4网段, 如 10.0.0.0/24: %q":  "simulator.ipv4_pool must be an IPv4 network such as 10.0.0.0/24: %q",
+	"simulator.ipv4_pool 前缀长度应在8~30之间: %q":             "simulator.ipv4_pool prefix length must be between 8 and 30: %q",
+	"http.listen 应为IP地址或 localhost: %q":                "http.listen must be an IP address or localhost: %q",
+	"simulator.time_mode 应为 virtual 或 realtime: %q":    "simulator.time_mode must be virtual or realtime: %q",
+	"用法: %s [参数]\n":                                    "Usage: %s [flags]\n",
+	"%s (环境变量 %s)":                                     "%s (environment variable %s)",
+	"配置文件, 默认为 config.json":                            "configuration file, defaults to config.json",
+	"HTTP 监听地址":                                        "HTTP listen address",
+	"HTTP 端口, 0表示不启动":                                  "HTTP port, 0 disables the server",
+	"保存的命令历史条数, 0表示不保存":                                "number of history commands to keep, 0 disables history",
+	"启动时加载的拓扑文件 (JSON/YAML)":                           "topology file loaded at startup (JSON/YAML)",
+	"子系统日志级别, 如 l2=debug,l4=trace":                     "per-subsystem log levels such as l2=debug,l4=trace",
+	"开启 trace 的设备, 逗号分隔":                               "comma-separated devices with trace enabled",
+	"批量执行以 ; 分隔的命令后退出":                                 "run commands separated by ; and exit",
+	"批量执行文件中的命令后退出, - 表示标准输入":                          "run commands from a file and exit, - for standard input",
+	"批量执行时每条命令输出一行JSON":                                "print one JSON line per command in batch mode",
+	"批量执行时第一条命令失败后停止":                                  "stop at the first failed command in batch mode",
+	"抓包文件目录":                                           "capture file directory",
+	"数据目录":                                             "data directory",
+	"新接口的MTU":                                          "MTU of new interfaces",
+	"新链路的丢包率 0~1":                                      "loss rate of new links, 0~1",
+	"新链路的传播时延":                                         "propagation delay of new links",
+	"新链路的带宽, 支持 K/M/G 后缀":                              "bandwidth of new links, K/M/G suffixes allowed",
+	"无界面运行场景脚本, 断言全部通过时退出码为0, 否则为1":                    "run a scenario script headless, exit code 0 if all assertions pass, otherwise 1",
+	"日志文件, 为空时输出到标准输出":                                 "log file, standard output when empty",
+	"日志格式 console/text/json":                           "log format console/text/json",
+	"日志级别 trace/debug/info/warn/error":                 "log level trace/debug/info/warn/error",
+	"时间模式 virtual/realtime":                            "time mode virtual/realtime",
+	"界面语言 zh/en":                                       "interface language zh/en",
+	"自动分配的IPv4网段":                                      "IPv4 network for automatic addressing",
+	"自动分配的MAC地址前3字节":                                   "first 3 bytes of automatically assigned MAC addresses",
+	"随机数种子":                                            "random seed",
+
+	// 网络命令 Network commands
+	"应为 on 或 off":                       "must be on or off",
+	"设备不存在: ":                           "No such device:",
+	"格式: %s\n":                          "Format: %s\n",
+	"trace: (无)":                        "trace: (none)",
+	"端口应为0~65535的整数":                    "port must be an integer 0-65535",
+	"设备已存在: %s":                         "device already exists: %s",
+	"设备已存在: ":                           "Device already exists:",
+	"端口数应为非负整数":                         "port count must be a non-negative integer",
+	"链路参数格式错误: %s":                      "invalid link parameter: %s",
+	"时延格式错误: %s":                        "invalid delay: %s",
+	"丢包率应在0~1之间: %s":                    "loss rate must be between 0 and 1: %s",
+	"未知链路参数: %s":                        "unknown link parameter: %s",
+	"带宽格式错误: %s":                        "invalid bandwidth: %s",
+	"只能为主机或路由器的指定接口设置地址":                "Addresses can only be set on a host or router interface",
+	"(没有链路)":                            "(no links)",
+	"虚拟时间 %s, 待处理事件 %d\n":               "Virtual time %s, %d pending events\n",
+	"(没有设备)":                            "(no devices)",
+	"%s (switch, %d 端口)\n":              "%s (switch, %d ports)\n",
+	" -- 链路%d -- %s":                    " -- link %d -- %s",
+	"%s ARP缓存:\n":                       "%s ARP cache:\n",
+	"%s 路由表:\n":                         "%s routing table:\n",
+	"  C %s/%d 直连 %s\n":                 "  C %s/%d directly connected %s\n",
+	"  S %s/%d 下一跳 %s\n":                "  S %s/%d via %s\n",
+	"%s MAC地址表:\n":                      "%s MAC address table:\n",
+	"%s 端口VLAN:\n":                      "%s port VLANs:\n",
+	"需要指定端口, 如 s1:eth1":                 "a port is required, e.g. s1:eth1",
+	"未知参数: %s":                          "unknown parameter: %s",
+	"VLAN编号应在1~%d之间: %s":                "VLAN ID must be between 1 and %d: %s",
+	"VLAN范围的起点大于终点: %s":                 "VLAN range start is greater than its end: %s",
+	"id 应为0~4095, pcp 应为0~7, dei 应为0或1": "id must be 0-4095, pcp 0-7, dei 0 or 1",
+	"oui 应为3字节":                         "oui must fit in 3 bytes",
+	"icmp 不需要端口与数据":                     "icmp takes no port or data",
+	"%s 需要目的端口":                         "%s requires a destination port",
+	"未知的协议 %q, 应为 icmp、udp 或 tcp":       "unknown protocol %q, must be icmp, udp or tcp",
+	"OK, 输入 run 或 step 推进模拟":            "OK, type run or step to advance the simulation",
+	"未知协议: %s":                          "unknown protocol: %s",
+	"时长应为正数, 如 10ms、1s":                 "duration must be positive, e.g. 10ms, 1s",
+	"处理了 %d 个事件, 虚拟时间 %s\n":             "Processed %d events, virtual time %s\n",
+	"已达到单次运行的事件上限, 网络中可能存在环路": "Reached the per-run event limit; the network may contain a loop",
+	"事件数应为正整数":                     "event count must be a positive integer",
+	"没有待处理的事件":                     "No pending events",
+	"处理了 %d 个事件, 虚拟时间 %s, 剩余 %d\n": "Processed %d events, virtual time %s, %d remaining\n",
+	"主机不存在: ":                      "No such host:",
+	"链路不存在: ":                      "No such link:",
+	"保存快照失败:":                      "Failed to save snapshot:",
+	"OK %s 虚拟时间 %s\n":              "OK %s virtual time %s\n",
+	"恢复快照失败:":                      "Failed to restore snapshot:",
+	"OK 已恢复 %s 保存的快照, 设备 %d, 链路 %d, 待处理事件 %d, 虚拟时间 %s\n": "OK restored snapshot saved at %s, %d devices, %d links, %d pending events, virtual time %s\n",
+	"快照名称不能为空或包含路径: %q":                                  "snapshot name must not be empty or contain a path: %q",
+	"抓包文件只能是抓包目录中的相对路径: %q":                              "capture file must be a relative path inside capture_dir: %q",
+	"%s: 不支持的快照版本 %d":                                    "%s: unsupported snapshot version %d",
+	"(没有快照)":                                             "(no snapshots)",
+	"  %-20s %8d 字节  %s\n":                               "  %-20s %8d bytes  %s\n",
+
+	"生成树只能在交换机上设置: %s":            "spanning tree can only be configured on switches: %s",
+	"生成树是整台交换机的设置, 不能指定端口":        "this spanning tree setting applies to the whole switch, do not give a port",
+	"协议应为 stp 或 rstp: %s":         "protocol must be stp or rstp: %s",
+	"网桥优先级应为0~61440之间4096的倍数: %s": "bridge priority must be a multiple of 4096 between 0 and 61440: %s",
+	"路径开销应为正整数或 auto: %s":         "path cost must be a positive integer or auto: %s",
+	"端口优先级应为0~240之间16的倍数: %s":     "port priority must be a multiple of 16 between 0 and 240: %s",
+	"应为 on 或 off: %s":             "must be on or off: %s",
+
+	"误码率应在0~1之间: %s":                   "bit error rate must be between 0 and 1: %s",
+	"数据应为十六进制: %s":                     "data must be hex: %s",
+	"from 不能为负: %s":                    "from must not be negative: %s",
+	"bits 应大于0: %s":                    "bits must be greater than 0: %s",
+	"errors 应为逗号分隔的电平下标: %s":           "errors must be comma-separated level indexes: %s",
+	"解码失败: %v\n":                       "Decoding failed: %v\n",
+	"解码结果与原数据相同":                       "Decoded data matches the original",
+	"解码结果: %s, %d 个字节改变\n":             "Decoded: %s, %d bytes changed\n",
+	"前导码与起始定界符: 线路比特 0-%d, 帧: %d-%d\n": "Preamble and start delimiter: line bits 0-%d, frame: %d-%d\n",
+	"前导码与起始定界符: 线路比特 0-%d, 帧: %d-%d, 结束定界符: %d-%d\n": "Preamble and start delimiters: line bits 0-%d, frame: %d-%d, end delimiters: %d-%d\n",
+	"物理层发现错误, 丢弃: %v\n":                              "The PHY detected the error and dropped the frame: %v\n",
+	"误码没有影响帧的内容":                                     "The bit errors did not change the frame",
+	"物理层未发现错误, 帧中 %d 个字节改变\n":                        "The PHY did not detect the errors, %d bytes of the frame changed\n",
+	"接收方FCS检查发现错误, 丢弃: %v\n":                         "The receiver's FCS check caught the error and dropped the frame: %v\n",
+	"接收方无法解析, 丢弃: %v\n":                              "The receiver could not parse the frame and dropped it: %v\n",
+	"FCS 未能发现错误, 接收方收下了被改变的帧":                        "The FCS did not catch the error; the receiver accepted the altered frame",
+	"电平下标 %d 超出范围, 共 %d 个电平\n":                       "Level index %d is out of range, there are %d levels\n",
+	"注入 %d 个误码, 电平下标: %s\n":                          "Injected %d bit errors at levels: %s\n",
+	"线路编码 %s: %d 个线路比特, %d 个电平, 跳变 %d 次, 直流分量 %+d\n": "Line code %s: %d line bits, %d levels, %d transitions, DC balance %+d\n",
+	"OK, 波形已写入 %s\n":                                 "OK, waveform written to %s\n",
+
+	// 抓包与回放 Capture and replay
+	"OK, 共抓到 %d 帧\n":                  "OK, %d frames captured\n",
+	"帧编号应在 1-%d 之间":                   "Frame number must be between 1 and %d",
+	"帧 %d: %s %s %s, %d 字节\n":         "Frame %d: %s %s %s, %d bytes\n",
+	"OK, 已写入 %d 帧\n":                  "OK, %d frames written\n",
+	"多余的参数: %s":                       "unexpected arguments: %s",
+	"已经在抓包: %s":                       "already capturing: %s",
+	"没有进行中的抓包: %s":                    "no capture in progress: %s",
+	"缺少链路编号":                          "missing link number",
+	"链路不存在: %s":                       "no such link: %s",
+	"没有抓包记录: ":                        "No capture named:",
+	"写入抓包文件失败":                        "failed to write capture file",
+	"%s 没有端口":                         "%s has no ports",
+	"已注入 %s:%s, 输入 run 或 step 推进模拟\n": "Injected at %s:%s, type run or step to advance the simulation\n",
+	"端口发送队列已满":                        "port transmit queue is full",
+	"'/' 前缺少协议层":                      "missing layer before '/'",
+	"未知协议层: %s":                       "unknown layer: %s",
+	"stp 下层应为 llc, 而不是 %s":            "stp must be carried in an llc layer, not %s",
+	"字段格式应为 name=value: %s":           "field must be name=value: %s",
+	"%s 没有字段 %s, 可用字段: %s":            "%s has no field %s, available fields: %s",
+	"'/' 后缺少协议层":                      "missing layer after '/'",
+	"字段 %s=%s 无效":                     "invalid field %s=%s",
+	"字段 hex=%s 无效":                    "invalid field hex=%s",
+	"未知TCP标志: %s":                     "unknown TCP flag: %s",
+	"未知TCP标志: %c":                     "unknown TCP flag: %c",
+	"端口不存在: ":                         "No such port:",
+	"OK, 已安排回放 %d 帧, 最后一帧在 %s, 输入 run 推进模拟\n": "OK, scheduled %d frames for replay, the last at %s, type run to advance the simulation\n",
+	"第 %d 帧之后读取失败: %w":                        "read failed after frame %d: %w",
+
+	// 场景脚本 Scenario scripts
+	"断言失败":                    "assertion failed",
+	"脚本嵌套超过 %d 层: %s":         "scripts nested deeper than %d levels: %s",
+	"脚本 %s: 断言通过 %d, 失败 %d\n": "Script %s: %d assertions passed, %d failed\n",
+	"%s断言失败: %s: %v\n":        "%sassertion failed: %s: %v\n",
+	"%s断言通过: %s\n":            "%sassertion passed: %s\n",
+	"参数错误":                    "invalid arguments",
+	"%s 的ARP缓存":               "ARP cache of %s",
+	"交换机不存在: %s":              "no such switch: %s",
+	"%s 的MAC地址表":              "MAC address table of %s",
+	"%s 上 %s 状态的TCP连接":        "TCP connections on %s in state %s",
+	"%s 中满足 %s 的帧":            "frames in %s matching %s",
+	"虚拟时间为 %s, 期望 %s %s":      "virtual time is %s, expected %s %s",
+	"%s 中没有 %s":               "%s has no %s",
+	"%s 中有 %s (%s)":           "%s has %s (%s)",
+	"%s 到 %s 的路由为 %s, 期望 %s":  "route from %s to %s is %s, expected %s",
+	"%s 有 %d 个, 期望 %s %d":     "%s: %d, expected %s %d",
+	"未知字段: %s":                "unknown field: %s",
+	"%s 的取值错误: %s":            "invalid value for %s: %s",
+	"%s 只能用 == 或 != 比较":       "%s can only be compared with == or !=",
+
+	"%s 没有启用生成树":        "%s does not run spanning tree",
+	"%s 不是根桥, 根桥为 %s":   "%s is not the root bridge, the root is %s",
+	"%s 的角色为 %s, 期望 %s": "%s has role %s, expected %s",
+	"%s 的状态为 %s, 期望 %s": "%s is %s, expected %s",
+
+	// 拓扑 Topology
+	"OK 主机 %d, 路由器 %d, 交换机 %d, 链路 %d\n":      "OK %d hosts, %d routers, %d switches, %d links\n",
+	"设备名称重复: %s":                             "duplicate device name: %s",
+	"交换机 %s 的端口数不能为负: %d":                    "switch %s port count must not be negative: %d",
+	"交换机 %s 没有端口 %s":                         "switch %s has no port %s",
+	"交换机 %s 的端口 %s 重复设置VLAN":                 "switch %s port %s has more than one VLAN setting",
+	"交换机 %s 端口 %s 的模式应为 access/trunk: %q":    "switch %s port %s: mode must be access/trunk: %q",
+	"交换机 %s 端口 %s: 只有 trunk 端口可以设置 allowed":  "switch %s port %s: only trunk ports can set allowed",
+	"交换机 %s 端口 %s: VLAN编号应在1~%d之间: %d":       "switch %s port %s: VLAN ID must be between 1 and %d: %d",
+	"%s: VLAN编号应在1~%d之间: %d":                 "%s: VLAN ID must be between 1 and %d: %d",
+	"%s: 子接口重复":                              "%s: duplicate subinterface",
+	"%s: MTU应在68~%d之间: %d":                   "%s: MTU must be between 68 and %d: %d",
+	"%s: MAC地址格式错误: %q":                      "%s: invalid MAC address: %q",
+	"%s: MAC地址不能是组播地址: %s":                   "%s: MAC address must not be multicast: %s",
+	"MAC地址重复: %s (%s, %s)":                   "duplicate MAC address: %s (%s, %s)",
+	"%s: 地址格式错误: %q":                         "%s: invalid address: %q",
+	"%s: %s 是网络地址或广播地址":                      "%s: %s is a network or broadcast address",
+	"%s 与 %s 的网段重叠: %s, %s":                  "%s and %s have overlapping networks: %s, %s",
+	"IP地址重复: %s (%s, %s)":                    "duplicate IP address: %s (%s, %s)",
+	"%s: 路由目的网络格式错误: %q":                     "%s: invalid route destination: %q",
+	"%s: 路由重复: %s":                           "%s: duplicate route: %s",
+	"%s: 路由 %s 的下一跳格式错误: %q":                 "%s: invalid next hop for route %s: %q",
+	"%s: 路由 %s 的下一跳 %s 不在直连网段中":              "%s: route %s has next hop %s outside the connected networks",
+	"%s: tcp-server 需要 port":                 "%s: tcp-server requires port",
+	"%s: %s 的目的地址格式错误: %q":                   "%s: invalid destination address for %s: %q",
+	"%s: %s 需要 port":                         "%s: %s requires port",
+	"%s: 应用类型应为 tcp-server/ping/udp/tcp: %q": "%s: application type must be tcp-server/ping/udp/tcp: %q",
+	"链路 %d: 端点 %s 指向不存在的设备":                  "link %d: endpoint %s refers to a nonexistent device",
+	"链路 %d: 端点 %s 指向不存在的端口":                  "link %d: endpoint %s refers to a nonexistent port",
+	"链路 %d: 端口 %s 已连接到链路 %d":                 "link %d: port %s is already connected to link %d",
+	"链路 %d: 不能将端口连接到自身":                      "link %d: cannot connect a port to itself",
+	"链路 %d: 时延不能为负":                          "link %d: delay must not be negative",
+	"链路 %d: 带宽不能为负":                          "link %d: bandwidth must not be negative",
+	"链路 %d: 丢包率应在0~1之间":                      "link %d: loss rate must be between 0 and 1",
+	"网段重叠: %s (%s) 与 %s (%s) 不在同一个二层网段":      "overlapping networks: %s (%s) and %s (%s) are not on the same layer 2 segment",
+	"拓扑错误:":                                  "invalid topology:",
+	"链路 %d: %v":                              "link %d: %v",
+	"%s: 启动 %s 失败: %v":                       "%s: failed to start %s: %v",
+	"第%d行: 缩进不能使用制表符":                        "line %d: tabs are not allowed in indentation",
+	"第%d行: 不支持多行块标量":                         "line %d: block scalars are not supported",
+	"第%d行: 缩进错误":                             "line %d: bad indentation",
+	"第%d行: 应为 key: value":                    "line %d: expected key: value",
+	"第%d行: 重复的键 %q":                          "line %d: duplicate key %q",
+	"第%d行: 键不能为空":                            "line %d: empty key",
+	"第%d行: 键格式错误: %s":                        "line %d: malformed key: %s",
+	"多余的内容 %q":                               "unexpected content %q",
+	"第%d行: %v":                               "line %d: %v",
+	"不支持的YAML语法 %q":                          "unsupported YAML syntax %q",
+	"缺少 %q":                                  "missing %q",
+	"应为 key: value":                          "expected key: value",
+	"应为 , 或 %q":                              "expected , or %q",
+	"字符串格式错误: %s":                            "malformed string: %s",
+	"字符串缺少结束引号: %s":                          "unterminated string: %s",
+	"%s名称不能为空或包含冒号、空白: %q":                   "%s name must not be empty or contain colons or whitespace: %q",
+	"主机":  "host",
+	"路由器": "router",
+	"交换机": "switch",
+	"%s: 第%d个接口的名称应为 eth%d: %q": "%s: interface %d must be named eth%d: %q",
+
+	"交换机 %s 的生成树协议应为 stp/rstp: %q":          "switch %s: spanning tree protocol must be stp/rstp: %q",
+	"交换机 %s 的网桥优先级应为0~61440之间4096的倍数: %d":   "switch %s: bridge priority must be a multiple of 4096 between 0 and 61440: %d",
+	"交换机 %s 的端口 %s 重复设置生成树":                 "switch %s port %s has more than one spanning tree setting",
+	"交换机 %s 端口 %s: 端口优先级应为0~240之间16的倍数: %d": "switch %s port %s: port priority must be a multiple of 16 between 0 and 240: %d",
+
+	"链路 %d: 误码率应在0~1之间": "link %d: bit error rate must be between 0 and 1",
+
+	// HTTP 服务 HTTP server
+	"未知的事件类型: %s":                          "unknown event type: %s",
+	"层应为 1-7 或 L1-L7: %s":                  "layer must be 1-7 or L1-L7: %s",
+	": 已连接\n\n":                            ": connected\n\n",
+	": 客户端过慢, 丢弃了 %d 个事件\n\n":              ": client too slow, dropped %d events\n\n",
+	"需要 WebSocket 握手":                      "WebSocket handshake required",
+	"连接不支持 WebSocket: %v":                  "connection does not support WebSocket: %v",
+	"过滤条件不是合法的JSON: %v":                    "filter is not valid JSON: %v",
+	"WebSocket 消息过长: %d 字节":                "WebSocket message too long: %d bytes",
+	"HTTP 服务启动失败":                          "HTTP server failed to start",
+	"Web 界面: http://%s/\n":                 "Web UI: http://%s/\n",
+	"HTTP 服务已启动":                           "HTTP server started",
+	"HTTP 请求":                              "HTTP request",
+	"请求体不是合法的JSON: %v":                     "request body is not valid JSON: %v",
+	"不接受跨站请求: %s":                          "cross-site request refused: %s",
+	"请求的 Content-Type 应为 application/json": "request Content-Type must be application/json",
+	"主机不存在: %s":                            "no such host: %s",
+	"缺少主机名称":                               "missing host name",
+	"缺少抓包对象":                               "missing capture target",
+	"没有抓包记录: %s":                           "no capture named %s",
+
+	"帧编号应在 1-%d 之间: %s": "frame number must be between 1 and %d: %s",
+
+	// 模拟器 Simulator
+	"%s 收到无法解析的帧(%d 字节), 丢弃: %v":       "%s received an undecodable frame (%d bytes), dropped: %v",
+	"%s 收到CRC错误的帧, 丢弃":                 "%s received a frame with a bad CRC, dropped",
+	"ARP 请求: 谁是 %s? 请告诉 %s":            "ARP request: who has %s? Tell %s",
+	"ARP 应答: %s 在 %s":                  "ARP reply: %s is at %s",
+	"等待 %s 的报文超过 %d 个, 丢弃最早的报文":        "more than %[2]d packets waiting for %[1]s, dropped the oldest",
+	"%d 次ARP请求没有应答, %s 不可达, 丢弃 %d 个报文": "no reply to %d ARP requests, %s unreachable, dropped %d packets",
+	"%s MAC地址: %s\n":                   "%s MAC address: %s\n",
+	"%s IPv4地址: %s/%d\n":               "%s IPv4 address: %s/%d\n",
+	"%s 没有端口 %d":                       "%s has no port %d",
+	"%s 是子接口, 不能再划分子接口":                "%s is a subinterface and cannot have subinterfaces",
+	"子接口已存在: %s":                       "subinterface already exists: %s",
+	"VLAN编号应在1~%d之间: %d":               "VLAN ID must be between 1 and %d: %d",
+	"%s 没有VLAN %d 的子接口, 丢弃":            "%s has no subinterface for VLAN %d, dropped",
+	"MAC地址前缀不能是组播地址: %s":               "MAC address prefix must not be multicast: %s",
+	"地址池前缀长度应在8~30之间: %d":              "address pool prefix length must be between 8 and 30: %d",
+	"%s 没有端口 %s":                       "%s has no port %s",
+	"设备不存在: %s":                        "no such device: %s",
+	"端口 %s 发送队列已满, 丢弃帧":                "port %s transmit queue is full, frame dropped",
+	"查路由 %s: %s 下一跳 %s":                "route lookup %s: %s next hop %s",
+	"查路由 %s: 没有路由":                     "route lookup %s: no route",
+	"ARP 缓存 %s -> %s":                  "ARP cache %s -> %s",
+	"TTL耗尽":                            "TTL exceeded",
+	"没有路由":                             "no route",
+	"超过MTU":                            "exceeds MTU",
+	"无法解析的帧":                           "malformed frame",
+	"ARP队列已满":                          "ARP queue full",
+	"ARP解析失败":                          "ARP resolution failed",
+	"链路 %d 已删除":                        "link %d was deleted",
+	"CRC错误":                            "CRC error",
+	"%s 没有到 %s 的路由":                    "%s has no route to %s",
+	"%s 报文长度 %d 超过 %s 的MTU %d":         "%s packet length %d exceeds the MTU of %s (%d)",
+	"%s -> %s TTL耗尽, 丢弃":               "%s -> %s TTL exceeded, dropped",
+	"没有到 %s 的路由, 丢弃":                   "no route to %s, dropped",
+	"%s -> %s 报文长度 %d 超过 %s 的MTU, 丢弃":  "%s -> %s packet length %d exceeds the MTU of %s, dropped",
+	"转发 %s -> %s: %s => %s 下一跳 %s":     "forward %s -> %s: %s => %s next hop %s",
+	"收到 UDP %s:%d -> :%d %q":           "received UDP %s:%d -> :%d %q",
+	"收到来自 %s 的 ICMP 回显请求 seq=%d":       "received ICMP echo request from %s seq=%d",
+	"来自 %s 的回复: seq=%d ttl=%d%s":       "reply from %s: seq=%d ttl=%d%s",
+	"收到来自 %s 的 ICMP %s":                "received ICMP %[2]s from %[1]s",
+	"不能将端口连接到自身":                       "cannot connect a port to itself",
+	"端口 %s 已连接到链路 %d":                  "port %s is already connected to link %d",
+	"%s 是子接口, 链路应连接其父接口":               "%s is a subinterface; connect the link to its parent interface",
+	"链路不存在: %d":                        "no such link: %d",
+	"%s 收到 %s":                         "%s received %s",
+	"%s 未连接链路, 丢弃 %s":                  "%s is not connected to a link, dropped %s",
+	"%s 发送 %s":                         "%s sent %s",
+	"链路 %d 丢失了一帧":                      "link %d lost a frame",
+	"快照中没有设备 %s 的状态":                   "snapshot has no state for device %s",
+	"快照中的设备列表不完整":                      "snapshot device list is incomplete",
+	"快照中的链路端点不存在: %s:%d":               "snapshot link endpoint does not exist: %s:%d",
+	"学习 %s 在 %s (VLAN %d)":             "learned %s on %s (VLAN %d)",
+	"%s 转发到 %s":                        "%s forwarded to %s",
+	"%s 未知或为广播, 在VLAN %d 内泛洪":          "%s unknown or broadcast, flooding in VLAN %d",
+	"%s 设为 %s":                         "%s set to %s",
+	"接入端口 %s 收到VLAN %d 的帧, 丢弃":         "access port %s received a frame for VLAN %d, dropped",
+	"%s 不允许VLAN %d, 丢弃":                "%s does not allow VLAN %d, dropped",
+	"%s 无法改写标签, 丢弃: %v":                "%s cannot rewrite the tag, dropped: %v",
+	"%s 没有到 %s:%d 的已建立连接":              "%s has no established connection to %s:%d",
+	"TCP 端口 %d 未监听, 回复 RST":            "TCP port %d not listening, replying RST",
+	"TCP %s 收到数据 %q":                   "TCP %s received data %q",
+
+	"网桥优先级应为0~61440之间4096的倍数: %d": "bridge priority must be a multiple of 4096 between 0 and 61440: %d",
+	"端口优先级应为0~240之间16的倍数: %d":     "port priority must be a multiple of 16 between 0 and 240: %d",
+	"关闭生成树协议":                     "spanning tree disabled",
+	"启用生成树协议 %s, 网桥 %s":           "spanning tree %s enabled, bridge %s",
+	"%s 上的生成树消息已老化":               "spanning tree information on %s aged out",
+	"本交换机成为根桥":                    "this switch became the root bridge",
+	"根桥为 %s, 根端口 %s, 开销 %d":       "root bridge is %s, root port %s, cost %d",
+	"%s 角色 %s -> %s":              "%s role %s -> %s",
+	"生成树 %s: %s %s":               "spanning tree %s: %s %s",
+	"拓扑变化: %s":                    "topology change: %s",
+	"丢弃BPDU: %v":                  "BPDU dropped: %v",
+	"丢弃发往网桥组地址的非BPDU帧":            "dropped a non-BPDU frame sent to the bridge group address",
+	"%s 收到BPDU, 不再作为边缘端口":         "%s received a BPDU, no longer an edge port",
+	"%s 的对端运行802.1D, 改发配置BPDU":    "the peer on %s runs 802.1D, sending configuration BPDUs",
+	"BPDU 的消息年龄已达最大老化时间, 丢弃":      "BPDU message age reached max age, dropped",
+	"%s 收到拓扑变化通知":                 "%s received a topology change notification",
+	"%s 收到同意":                     "%s received an agreement",
+	"%s 收到提议, 回应同意":               "%s received a proposal, replying with an agreement",
+	"%s 处于 %s 状态, 丢弃":             "%s is in state %s, dropped",
+
+	"链路 %d 上的帧出现 %d 个误码, 物理层丢弃: %v": "frame on link %d hit %d bit errors, dropped by the PHY: %v",
+	"链路 %d 上的帧出现 %d 个误码":            "frame on link %d hit %d bit errors",
+
+	// 协议解码 Protocol layers
+	"CRC校验失败":        "CRC check failed",
+	"缺少起始行":          "missing start line",
+	"MAC地址格式错误: %s":  "invalid MAC address: %s",
+	"IPv4地址格式错误: %s": "invalid IPv4 address: %s",
+	"前缀长度错误: %s":     "invalid prefix length: %s",
+	"[未使用]":          "[unused]",
+	"[正确]":           "[correct]",
+	"[错误]":           "[incorrect]",
+	"%s 被截断: 需要 %d 字节, 只有 %d 字节":  "%s truncated: needs %d bytes, only %d available",
+	"%s %s 错误: 应为 0x%x, 实际为 0x%x": "%s bad %s: expected 0x%x, got 0x%x",
+	"%s 版本错误: %d":                 "%s bad version: %d",
+	"%s %s 错误: %d 字节":             "%s bad %s: %d bytes",
+	"数据格式错误，不是有效的%s报文":            "malformed data, not a valid %s packet",
+	"(%d 字节, 不完整的帧)":              "(%d bytes, incomplete frame)",
+	"%s, ethertype 0x%04x, %d 字节": "%s, ethertype 0x%04x, %d bytes",
+
+	"网桥标识格式应为 优先级/MAC: %q": "bridge ID must be priority/MAC: %q",
+
+	"线路编码应为 auto/nrz/nrzi/manchester/4b5b/8b10b: %q": "line code must be auto/nrz/nrzi/manchester/4b5b/8b10b: %q",
+	"%s 编码错误: 第 %d 个码元%s":                            "%s code violation: symbol %d %s",
+	"%s 编码错误: 第 %d 个码组 %s %s":                        "%s code violation: code group %d %s %s",
+	"%s 前导码之后没有帧起始定界符 0xd5":                          "%s no start frame delimiter 0xd5 after the preamble",
+	"中间没有跳变":                                         "has no mid-bit transition",
+	"不是合法的码组":                                        "is not a valid code group",
+	"运行不一致性错误":                                       "has a running disparity error",
+	"数据中出现控制码组":                                      "is a control code group inside data",
+	"不是起始定界符":                                        "is not the start delimiter",
+	"帧中出现控制码组":                                       "is a control code group inside the frame",
+	"之前没有结束定界符":                                      "reached without an end delimiter",
+	"结束定界符不完整":                                       "is an incomplete end delimiter",
+
+	// 报文诊断 Packet validation
+	"诊断:": "Findings:",
+	"提示":  "info",
+	"警告":  "warning",
+	"错误":  "error",
+	"源地址 %s 是组播地址, 源地址必须是单播地址":           "source address %s is multicast; source addresses must be unicast",
+	"长度字段 %d 超过数据的 %d 字节":                "length field %d exceeds the %d bytes of data",
+	"长度字段 %d 小于数据的 %d 字节":                "length field %d is less than the %d bytes of data",
+	"类型字段 0x%04x 位于未定义的区间 1501~1535":     "type field 0x%04x is in the undefined range 1501-1535",
+	"数据 %d 字节, 超过最大 %d 字节":               "payload of %d bytes exceeds the maximum of %d bytes",
+	"数据 %d 字节, 不足 %d 字节, 发送时需要填充":        "payload of %d bytes is below %d bytes and needs padding on the wire",
+	"VLAN编号 4095 保留, 不能用于帧中":             "VLAN ID 4095 is reserved and must not appear in frames",
+	"802.1ad 标签应在最外层":                    "an 802.1ad tag should be the outermost tag",
+	"帧带 %d 层标签":                          "frame carries %d tags",
+	"组地址 0x%02x 只能用于U格式帧, 实际为 %s":        "group address 0x%02x may only be used with U-format frames, got %s",
+	"SNAP 报文应使用UI帧, 实际为 %s":              "SNAP PDUs should use UI frames, got %s",
+	"组织代码为0时协议标识应为以太网类型, 实际为 0x%04x":     "with organization code 0 the protocol ID should be an EtherType, got 0x%04x",
+	"DSAP 为SNAP时 SSAP 也应为 0xaa":          "SSAP should also be 0xaa when DSAP is SNAP",
+	"BPDU 应使用UI帧, 实际为 %s":                "BPDUs should use UI frames, got %s",
+	"硬件类型 %d 不是以太网(1)":                   "hardware type %d is not Ethernet (1)",
+	"协议类型 0x%04x 不是IPv4(0x0800)":         "protocol type 0x%04x is not IPv4 (0x0800)",
+	"硬件地址长度 %d 与以太网地址的 6 字节不符":           "hardware size %d does not match the 6-byte Ethernet address",
+	"协议地址长度 %d 与IPv4地址的 4 字节不符":          "protocol size %d does not match the 4-byte IPv4 address",
+	"操作码 %d 属于RARP, 不是ARP":               "opcode %d belongs to RARP, not ARP",
+	"未定义的操作码 %d":                         "undefined opcode %d",
+	"发送方MAC地址 %s 是组播地址":                  "sender MAC address %s is multicast",
+	"发送方IP为0.0.0.0, 这是地址冲突探测":            "sender IP is 0.0.0.0: this is an address conflict probe",
+	"发送方与目标IP相同, 这是免费ARP":                "sender and target IP are equal: this is a gratuitous ARP",
+	"类型 %d 不是NDP报文(133~137)":             "type %d is not an NDP message (133-137)",
+	"代码 %d 必须为0":                         "code %d must be 0",
+	"保留字段 0x%08x 应为0":                    "reserved field 0x%08x should be 0",
+	"目标地址不能是组播地址":                        "target address must not be multicast",
+	"第 %d 字节处的选项长度为0":                    "option at byte %d has length 0",
+	"回显报文的代码 %d 必须为0":                    "echo message code %d must be 0",
+	"未定义的目的不可达代码 %d":                     "undefined destination unreachable code %d",
+	"未定义的超时代码 %d":                        "undefined time exceeded code %d",
+	"未定义的类型 %d":                          "undefined type %d",
+	"差错报文只带回 %d 字节, 应至少包含原IP头部与 8 字节数据":  "error message quotes only %d bytes; it should carry the original IP header and 8 data bytes",
+	"版本 %d 必须为4":                         "version %d must be 4",
+	"首部长度 %d 字节, 小于最小的 20 字节":            "header length of %d bytes is below the minimum of 20 bytes",
+	"首部长度 %d 字节, 与 20 字节固定首部加 %d 字节选项不符": "header length of %d bytes does not match the 20-byte fixed header plus %d bytes of options",
+	"总长度 %d, 与首部加数据的 %d 字节不符":            "total length %d does not match the %d bytes of header and data",
+	"保留位必须为0":                            "reserved bit must be 0",
+	"设置了禁止分片的报文不应是分片":                    "a packet with Don't Fragment set should not be a fragment",
+	"生存时间为0, 发送方不应发出这样的报文":               "time to live is 0; senders must not send such packets",
+	"源地址 %s 是广播或组播地址":                    "source address %s is broadcast or multicast",
+	"源地址 0.0.0.0 只能在获取地址期间使用":            "source address 0.0.0.0 is only allowed while acquiring an address",
+	"版本 %d 必须为6":                         "version %d must be 6",
+	"载荷长度 %d, 与实际的 %d 字节不符":              "payload length %d does not match the actual %d bytes",
+	"跳数限制为0, 发送方不应发出这样的报文":               "hop limit is 0; senders must not send such packets",
+	"源地址不能是组播地址":                         "source address must not be multicast",
+	"保留位应为0":                             "reserved bits should be 0",
+	"端口0是保留端口, 不能用于通信":                   "port 0 is reserved and must not be used",
+	"没有设置任何标志位":                          "no flags are set",
+	"SYN与FIN不能同时设置":                      "SYN and FIN must not be set together",
+	"SYN与RST不能同时设置":                      "SYN and RST must not be set together",
+	"除SYN与RST外的报文段都应设置ACK":               "every segment other than SYN and RST should have ACK set",
+	"未设置URG, 紧急指针 %d 应为0":                "URG is not set, so urgent pointer %d should be 0",
+	"未设置ACK, 确认号 %d 没有意义":                "ACK is not set, so acknowledgment number %d is meaningless",
+	"长度 %d, 小于首部的 8 字节":                  "length %d is below the 8-byte header",
+	"长度 %d, 与首部加数据的 %d 字节不符":             "length %d does not match the %d bytes of header and data",
+	"发送方没有计算校验和":                         "the sender did not compute a checksum",
+	"未定义的内容类型 %d":                        "undefined content type %d",
+	"记录层版本 0x%02x%02x 不是TLS 1.2(0x0303)": "record version 0x%02x%02x is not TLS 1.2 (0x0303)",
+	"长度 %d, 与实际的 %d 字节不符":                "length %d does not match the actual %d bytes",
+	"长度 %d 超过上限 %d 字节":                   "length %d exceeds the limit of %d bytes",
+	"握手、告警与密码规格变更记录不能为空":                 "handshake, alert and change cipher spec records must not be empty",
+	"记录层版本 0x0301 只允许用于初始的 ClientHello":  "record version 0x0301 is only allowed for the initial ClientHello",
+	"记录层版本 0x%02x%02x 应为0x0303":          "record version 0x%02x%02x should be 0x0303",
+	"握手与告警记录不能为空":                        "handshake and alert records must not be empty",
+	"反向查询(opcode 1)已废弃":                  "inverse query (opcode 1) is obsolete",
+	"Z位必须为0":                             "the Z bit must be 0",
+	"报文不含任何记录":                           "the message carries no records",
+	"查询应恰好包含 1 个问题, 实际为 %d":              "a query should carry exactly 1 question, got %d",
+	"查询不应包含回答":                           "a query should not carry answers",
+	"查询的响应码 %d 应为0":                      "response code %d of a query should be 0",
+	"缺少命令":                               "missing command",
+	"应答码 %s 不合法":                         "invalid reply code %s",
+	"未知的命令 %s":                           "unknown command %s",
+	"命令 %s 缺少参数":                         "command %s is missing its argument",
+	"起始行 %q 应由三部分组成":                     "start line %q should have three parts",
+	"方法 %s 区分大小写, 标准方法均为大写":              "method %s is case-sensitive and standard methods are uppercase",
+	"HTTP/1.1 请求必须包含 Host 首部":            "an HTTP/1.1 request must carry a Host header",
+	"状态码 %s 不合法":                         "invalid status code %s",
+	"不支持的版本 %s":                          "unsupported version %s",
+	"Content-Length %s 与正文的 %d 字节不符":     "Content-Length %s does not match the %d-byte body",
+	"版本 1.99 表示同时兼容SSH 1与SSH 2":          "version 1.99 means compatible with both SSH 1 and SSH 2",
+	"版本交换行 %q 应以 SSH-2.0- 开头":            "identification string %q should start with SSH-2.0-",
+	"版本交换行 %d 字节, 超过最大 255 字节":           "identification string of %d bytes exceeds the maximum of 255 bytes",
+	"缺少软件版本":                             "missing software version",
+
+	"协议标识应为0, 实际为 0x%04x":                          "protocol identifier should be 0, got 0x%04x",
+	"RST BPDU 的版本应不小于 %d, 实际为 %d":                  "RST BPDU version should be at least %d, got %d",
+	"未定义的BPDU类型 0x%02x":                            "undefined BPDU type 0x%02x",
+	"配置BPDU只使用拓扑变化与拓扑变化确认标志, 实际为 0x%02x":           "configuration BPDUs only use the topology change and acknowledgment flags, got 0x%02x",
+	"消息年龄 %s 已达最大老化时间 %s, 接收方会丢弃":                  "message age %s reached max age %s, receivers will discard it",
+	"计时器应满足 2×(转发延迟−1秒) ≥ 最大老化时间 ≥ 2×(Hello时间+1秒)": "timers should satisfy 2×(forward delay−1 s) ≥ max age ≥ 2×(hello time+1 s)",
+
+	// 日志 Logging
+	"日志级别应为 trace/debug/info/warn/error: %q": "log level must be trace/debug/info/warn/error: %q",
+	"日志格式应为 console/text/json: %q":           "log format must be console/text/json: %q",
+	"未知的子系统 %q, 应为 l2/l3/l4/app":             "unknown subsystem %q, must be l2/l3/l4/app",
+
+	// 抓包文件 Capture files
+	"读取文件头失败":         "failed to read file header",
+	"不是pcap或pcapng文件": "not a pcap or pcapng file",
+	"不支持的链路类型: %d":    "unsupported link type: %d",
+	"记录头被截断":          "record header truncated",
+	"记录长度错误: %d":      "bad record length: %d",
+	"记录数据被截断":         "record data truncated",
+	"块头被截断":           "block header truncated",
+	"块长度错误: %d":       "bad block length: %d",
+	"块数据被截断":          "block data truncated",
+	"节头块被截断":          "section header truncated",
+	"节头块字节序魔数错误":      "bad byte-order magic in section header",
+	"接口描述块被截断":        "interface description truncated",
+	"接口未定义: %d":       "undefined interface: %d",
+	"增强分组块被截断":        "enhanced packet truncated",
+	"简单分组块被截断":        "simple packet truncated",
+	"未知的抓包文件格式":       "unknown capture file format",
+	"接口未登记":           "interface not registered",
+}
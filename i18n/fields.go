@@ -0,0 +1,125 @@
+package i18n
+
+// fieldNames 协议层与字段的中文名称, 键为解码器使用的英文名称
+// Chinese names of protocol layers and fields, keyed by the dissector's English names
+var fieldNames = map[string]string{
+	// 协议层 Layers
+	"Ethernet II":                       "以太网 II",
+	"802.1Q Virtual LAN":                "虚拟局域网 (802.1Q)",
+	"802.1ad Service VLAN":              "服务商VLAN (802.1ad)",
+	"IEEE 802.3 Ethernet":               "IEEE 802.3 以太网",
+	"Logical-Link Control":              "逻辑链路控制 (LLC)",
+	"Spanning Tree Protocol":            "生成树协议 (STP)",
+	"Address Resolution Protocol":       "地址解析协议 (ARP)",
+	"Internet Protocol Version 4":       "网际协议第4版 (IPv4)",
+	"Internet Protocol Version 6":       "网际协议第6版 (IPv6)",
+	"Internet Control Message Protocol": "网际控制报文协议 (ICMP)",
+	"Transmission Control Protocol":     "传输控制协议 (TCP)",
+	"User Datagram Protocol":            "用户数据报协议 (UDP)",
+	"Malformed Packet":                  "畸形报文",
+
+	// 以太网 Ethernet
+	"Destination":          "目的地址",
+	"Source":               "源地址",
+	"Type":                 "类型",
+	"Padding":              "填充",
+	"Frame Check Sequence": "帧校验序列",
+
+	// 802.1Q
+	"Priority": "优先级",
+	"DEI":      "可丢弃指示",
+	"ID":       "VLAN编号",
+
+	// LLC 与 SNAP
+	"DSAP":              "目的服务访问点",
+	"SSAP":              "源服务访问点",
+	"IG Bit":            "个体/组地址位",
+	"CR Bit":            "命令/响应位",
+	"Control":           "控制",
+	"Organization Code": "组织代码",
+	"PID":               "协议标识",
+
+	// STP
+	"Protocol Identifier":            "协议标识符",
+	"Protocol Version Identifier":    "协议版本",
+	"BPDU Type":                      "BPDU类型",
+	"BPDU flags":                     "BPDU标志",
+	"Topology Change Acknowledgment": "拓扑变化确认",
+	"Agreement":                      "同意",
+	"Forwarding":                     "转发",
+	"Learning":                       "学习",
+	"Port Role":                      "端口角色",
+	"Proposal":                       "提议",
+	"Topology Change":                "拓扑变化",
+	"Root Identifier":                "根桥标识",
+	"Root Path Cost":                 "根路径开销",
+	"Bridge Identifier":              "网桥标识",
+	"Port identifier":                "端口标识",
+	"Message Age":                    "消息年龄",
+	"Max Age":                        "最大老化时间",
+	"Hello Time":                     "Hello时间",
+	"Forward Delay":                  "转发延迟",
+	"Version 1 Length":               "版本1长度",
+
+	// ARP
+	"Hardware type":      "硬件类型",
+	"Protocol type":      "协议类型",
+	"Hardware size":      "硬件地址长度",
+	"Protocol size":      "协议地址长度",
+	"Opcode":             "操作码",
+	"Sender MAC address": "发送方MAC地址",
+	"Sender IP address":  "发送方IP地址",
+	"Target MAC address": "目标MAC地址",
+	"Target IP address":  "目标IP地址",
+
+	// IPv4 与 IPv6
+	"Version":             "版本",
+	"Header Length":       "首部长度",
+	"Type of Service":     "服务类型",
+	"Total Length":        "总长度",
+	"Identification":      "标识",
+	"Flags":               "标志",
+	"Reserved bit":        "保留位",
+	"Don't fragment":      "不分片",
+	"More fragments":      "更多分片",
+	"Fragment Offset":     "片偏移",
+	"Time to Live":        "生存时间",
+	"Protocol":            "协议",
+	"Header Checksum":     "首部校验和",
+	"Source Address":      "源地址",
+	"Destination Address": "目的地址",
+	"Options":             "选项",
+	"Traffic Class":       "流量类别",
+	"Flow Label":          "流标签",
+	"Payload Length":      "载荷长度",
+	"Next Header":         "下一个首部",
+	"Hop Limit":           "跳数限制",
+
+	// ICMP
+	"Code":           "代码",
+	"Checksum":       "校验和",
+	"Identifier":     "标识符",
+	"Rest of Header": "首部其余部分",
+
+	// TCP 与 UDP
+	"Source Port":               "源端口",
+	"Destination Port":          "目的端口",
+	"Sequence Number":           "序号",
+	"Acknowledgment Number":     "确认号",
+	"Reserved":                  "保留",
+	"Nonce":                     "随机数",
+	"Congestion Window Reduced": "拥塞窗口减小",
+	"ECN-Echo":                  "ECN回显",
+	"Urgent":                    "紧急",
+	"Acknowledgment":            "确认",
+	"Push":                      "推送",
+	"Reset":                     "复位",
+	"Syn":                       "同步",
+	"Fin":                       "终止",
+	"Window":                    "窗口",
+	"Urgent Pointer":            "紧急指针",
+	"Length":                    "长度",
+
+	// 数据 Data
+	"Data": "数据",
+}
@@ -0,0 +1,143 @@
+// Package i18n 界面文字的中英文目录与带错误代码的错误值
+// Chinese/English message catalog and error values carrying codes
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// const 界面语言
+const (
+	// ZH 中文, 文字原样显示
+	ZH = "zh"
+	// EN 英文, 按目录翻译
+	EN = "en"
+)
+
+// Langs 全部界面语言
+var Langs = []string{ZH, EN}
+
+// english 当前是否显示英文, 命令行与HTTP请求可能同时读取
+var english atomic.Bool
+
+// SetLang 设置界面语言
+// Set the interface language
+// @param lang zh 或 en
+func SetLang(lang string) error {
+	switch lang {
+	case ZH:
+		english.Store(false)
+	case EN:
+		english.Store(true)
+	default:
+		return Errorf("i18n.lang", "界面语言应为 zh/en: %q", lang)
+	}
+	return nil
+}
+
+// Lang 当前界面语言
+// Current interface language
+func Lang() string {
+	if english.Load() {
+		return EN
+	}
+	return ZH
+}
+
+// T 翻译界面文字, key 为中文原文, 有参数时作为格式串
+// 英文界面下查目录, 目录中没有的文字原样显示
+// Translate a message keyed by its Chinese text, formatting it when args are given
+// @param key 中文原文或格式串
+// @param args 格式参数
+// @return string 当前语言的文字
+func T(key string, args ...any) string {
+	if english.Load() {
+		if s, ok := catalog[key]; ok {
+			key = s
+		}
+	}
+	if len(args) == 0 {
+		return key
+	}
+	return fmt.Sprintf(key, args...)
+}
+
+// Field 协议字段与协议层的显示名称, name 为英文名称
+// 中文界面下查字段名目录, 英文界面原样显示
+// Display name of a protocol field or layer
+// @param name 英文名称, 如 Destination
+// @return string 当前语言的名称
+func Field(name string) string {
+	if !english.Load() {
+		if s, ok := fieldNames[name]; ok {
+			return s
+		}
+	}
+	return name
+}
+
+// Error 带代码的错误, 消息按当前界面语言生成
+// 代码用于程序判断, 不随语言变化; errors.Is 按代码比较
+// An error value with a stable code; its message follows the interface language
+type Error struct {
+	// 错误代码, 如 level.short_packet
+	Code string
+	// 中文消息格式, 也是查英文翻译的键
+	Format string
+	// 格式参数
+	Args []any
+	// 包装的下层错误
+	Err error
+}
+
+// Error 当前语言的错误消息
+func (e *Error) Error() string {
+	msg := T(e.Format, e.Args...)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 下层错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 代码相同即视为同一种错误, 可以用只有 Code 的 Error 作为哨兵
+// Errors with the same code match, so an Error with only Code set works as a sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Errorf 创建带代码的错误
+// Create an error with a code
+// @param code 错误代码
+// @param format 中文消息格式
+// @param args 格式参数
+func Errorf(code, format string, args ...any) error {
+	return &Error{Code: code, Format: format, Args: args}
+}
+
+// Wrap 创建包装下层错误的带代码错误, 消息为 "本层消息: 下层消息"
+// Create an error with a code wrapping err
+// @param code 错误代码
+// @param err 下层错误
+// @param format 中文消息格式
+// @param args 格式参数
+func Wrap(code string, err error, format string, args ...any) error {
+	return &Error{Code: code, Format: format, Args: args, Err: err}
+}
+
+// Code 取出错误链中第一个带代码错误的代码, 没有时为空
+// Code of the first coded error in the chain, empty if none
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
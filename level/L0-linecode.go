@@ -0,0 +1,457 @@
+package level
+
+import (
+	"fmt"
+
+	"osiweb-go/i18n"
+)
+
+// LineCode 线路编码, 决定比特如何变为线路上的电平
+// Line code that turns bits into signal levels on the wire
+type LineCode int
+
+// const 线路编码
+const (
+	// LineAuto 按速率选择, 见 LineCodeFor Chosen from the bit rate, see LineCodeFor
+	LineAuto LineCode = iota
+	// LineNRZ 不归零: 1为高电平, 0为低电平 Non-return-to-zero level
+	LineNRZ
+	// LineNRZI 不归零反转: 1在比特开始处跳变, 0不跳变 Non-return-to-zero inverted
+	LineNRZI
+	// LineManchester 曼彻斯特编码(IEEE 802.3, 10BASE-T): 0为高到低, 1为低到高 Manchester as in 802.3
+	LineManchester
+	// Line4B5B 4B/5B 块编码后按NRZI发送(100BASE-X) 4B/5B block code sent as NRZI
+	Line4B5B
+	// Line8B10B 8B/10B 块编码后按NRZ发送(1000BASE-X) 8B/10B block code sent as NRZ
+	Line8B10B
+)
+
+// lineCodeNames 线路编码名称, 与 ParseLineCode 接受的写法相同
+var lineCodeNames = [...]string{"auto", "nrz", "nrzi", "manchester", "4b5b", "8b10b"}
+
+// String 线路编码名称 Line code name
+func (c LineCode) String() string {
+	if c < 0 || int(c) >= len(lineCodeNames) {
+		return fmt.Sprintf("LineCode(%d)", int(c))
+	}
+	return lineCodeNames[c]
+}
+
+// ParseLineCode 解析线路编码名称
+// Parse a line code name
+// @param s auto/nrz/nrzi/manchester/4b5b/8b10b
+// @return LineCode 线路编码
+// @return error 名称无效时的错误
+func ParseLineCode(s string) (LineCode, error) {
+	for i, name := range lineCodeNames {
+		if s == name {
+			return LineCode(i), nil
+		}
+	}
+	return LineAuto, fmt.Errorf(i18n.T("线路编码应为 auto/nrz/nrzi/manchester/4b5b/8b10b: %q"), s)
+}
+
+// LineCodeFor 以太网各速率使用的线路编码: 10M及以下为曼彻斯特, 100M为4B/5B, 更高或不限速为8B/10B
+// Line code used by Ethernet at a bit rate
+// @param bitrate 速率(bit/s), 0表示不限
+// @return LineCode 线路编码
+func LineCodeFor(bitrate int64) LineCode {
+	switch {
+	case bitrate > 0 && bitrate <= 10_000_000:
+		return LineManchester
+	case bitrate > 0 && bitrate <= 100_000_000:
+		return Line4B5B
+	default:
+		return Line8B10B
+	}
+}
+
+// resolve 未指定的编码按NRZ处理
+func (c LineCode) resolve() LineCode {
+	if c <= LineAuto || c > Line8B10B {
+		return LineNRZ
+	}
+	return c
+}
+
+// levelsPerBit 每个线路比特占用的电平数, 曼彻斯特编码为2
+func (c LineCode) levelsPerBit() int {
+	if c.resolve() == LineManchester {
+		return 2
+	}
+	return 1
+}
+
+// groupBits 块编码的码组长度, 不做块编码时为0
+func (c LineCode) groupBits() int {
+	switch c.resolve() {
+	case Line4B5B:
+		return 5
+	case Line8B10B:
+		return 10
+	}
+	return 0
+}
+
+// codeSymbol 块编码中的数据或控制码组
+type codeSymbol struct {
+	// 数据值, 4B/5B 控制码组为其字母, 8B/10B 控制码组为 K.x.y 的值
+	value byte
+	// 是否为控制码组
+	control bool
+}
+
+// 4B/5B 码组, 按表中从左到右的顺序发送
+// 4B/5B code groups, transmitted left to right
+var code4B5B = [16]string{
+	"11110", "01001", "10100", "10101", "01010", "01011", "01110", "01111",
+	"10010", "10011", "10110", "10111", "11010", "11011", "11100", "11101",
+}
+
+// const 4B/5B 控制码组 4B/5B control code groups
+const (
+	Symbol4B5BIdle = "11111" // I 空闲
+	Symbol4B5BJ    = "11000" // J 流起始定界符第一部分
+	Symbol4B5BK    = "10001" // K 流起始定界符第二部分
+	Symbol4B5BT    = "01101" // T 流结束定界符第一部分
+	Symbol4B5BR    = "00111" // R 流结束定界符第二部分
+	Symbol4B5BH    = "00100" // H 发送错误
+)
+
+// control4B5B 4B/5B 控制码组的字母
+var control4B5B = map[byte]string{
+	'I': Symbol4B5BIdle, 'J': Symbol4B5BJ, 'K': Symbol4B5BK, 'T': Symbol4B5BT, 'R': Symbol4B5BR, 'H': Symbol4B5BH,
+}
+
+// decode4B5B 5位码组到数据的反查表
+var decode4B5B = func() map[string]codeSymbol {
+	m := make(map[string]codeSymbol)
+	for v, code := range code4B5B {
+		m[code] = codeSymbol{value: byte(v)}
+	}
+	for name, code := range control4B5B {
+		m[code] = codeSymbol{value: name, control: true}
+	}
+	return m
+}()
+
+// 8B/10B 的 5B/6B 子块(abcdei), 每项为 [运行不一致性为负时, 为正时]
+// 5B/6B sub-blocks (abcdei) for running disparity negative and positive
+var code5B6B = [32][2]string{
+	{"100111", "011000"}, {"011101", "100010"}, {"101101", "010010"}, {"110001", "110001"},
+	{"110101", "001010"}, {"101001", "101001"}, {"011001", "011001"}, {"111000", "000111"},
+	{"111001", "000110"}, {"100101", "100101"}, {"010101", "010101"}, {"110100", "110100"},
+	{"001101", "001101"}, {"101100", "101100"}, {"011100", "011100"}, {"010111", "101000"},
+	{"011011", "100100"}, {"100011", "100011"}, {"010011", "010011"}, {"110010", "110010"},
+	{"001011", "001011"}, {"101010", "101010"}, {"011010", "011010"}, {"111010", "000101"},
+	{"110011", "001100"}, {"100110", "100110"}, {"010110", "010110"}, {"110110", "001001"},
+	{"001110", "001110"}, {"101110", "010001"}, {"011110", "100001"}, {"101011", "010100"},
+}
+
+// 8B/10B 数据码组的 3B/4B 子块(fghj), 下标8为 D.x.A7
+// 3B/4B sub-blocks (fghj) for data code groups, index 8 is D.x.A7
+var code3B4B = [9][2]string{
+	{"1011", "0100"}, {"1001", "1001"}, {"0101", "0101"}, {"1100", "0011"},
+	{"1101", "0010"}, {"1010", "1010"}, {"0110", "0110"}, {"1110", "0001"}, {"0111", "1000"},
+}
+
+// 8B/10B 控制码组 K.28 的 5B/6B 子块与控制码组的 3B/4B 子块
+// 5B/6B sub-block of K.28 and 3B/4B sub-blocks of control code groups
+var (
+	code5B6BK28 = [2]string{"001111", "110000"}
+	code3B4BK   = [8][2]string{
+		{"1011", "0100"}, {"0110", "1001"}, {"1010", "0101"}, {"1100", "0011"},
+		{"1101", "0010"}, {"0101", "1010"}, {"1001", "0110"}, {"0111", "1000"},
+	}
+)
+
+// const 1000BASE-X 使用的控制码组 Control code groups used by 1000BASE-X
+const (
+	K28_5 = 0xBC // 逗号, 用于 /I/ 与 /C/ 有序集
+	K23_7 = 0xF7 // /R/ 载波扩展
+	K27_7 = 0xFB // /S/ 帧起始
+	K29_7 = 0xFD // /T/ 帧结束
+	K30_7 = 0xFE // /V/ 错误传播
+)
+
+// encode8B10B 编码一个字节, 返回10位码组与之后的运行不一致性
+// @param v 字节, 控制码组为 K.x.y 的值
+// @param control 是否为控制码组
+// @param rdPos 当前运行不一致性是否为正
+func encode8B10B(v byte, control, rdPos bool) (string, bool) {
+	x, y := v&0x1F, v>>5
+	rd := 0
+	if rdPos {
+		rd = 1
+	}
+	six := code5B6B[x][rd]
+	if control && x == 28 {
+		six = code5B6BK28[rd]
+	}
+	if ones := countOnes(six); ones != 3 {
+		rdPos = ones > 3
+		rd = 1 - rd
+	}
+	var four string
+	switch {
+	case control:
+		four = code3B4BK[y][rd]
+	case y == 7 && ((!rdPos && (x == 17 || x == 18 || x == 20)) || (rdPos && (x == 11 || x == 13 || x == 14))):
+		// 避免与逗号相同的连续5个相同比特
+		four = code3B4B[8][rd]
+	default:
+		four = code3B4B[y][rd]
+	}
+	if ones := countOnes(four); ones != 2 {
+		rdPos = ones > 2
+	}
+	return six + four, rdPos
+}
+
+// decode8B10BEntry 10位码组的反查结果
+type decode8B10BEntry struct {
+	codeSymbol
+	// 码组在负、正运行不一致性下是否合法
+	valid [2]bool
+}
+
+// decode8B10B 10位码组到数据与控制码组的反查表
+var decode8B10B = func() map[string]decode8B10BEntry {
+	m := make(map[string]decode8B10BEntry)
+	add := func(v byte, control bool) {
+		for rd, rdPos := range []bool{false, true} {
+			code, _ := encode8B10B(v, control, rdPos)
+			e := m[code]
+			e.value, e.control = v, control
+			e.valid[rd] = true
+			m[code] = e
+		}
+	}
+	for v := range 256 {
+		add(byte(v), false)
+	}
+	for y := range 8 {
+		add(byte(y<<5|28), true)
+	}
+	for _, v := range []byte{K23_7, K27_7, K29_7, K30_7} {
+		add(v, true)
+	}
+	return m
+}()
+
+// countOnes 码组中1的个数
+func countOnes(code string) int {
+	n := 0
+	for i := range len(code) {
+		if code[i] == '1' {
+			n++
+		}
+	}
+	return n
+}
+
+// BytesToBits 按以太网的发送顺序(每字节低位在前)展开为比特, 每个元素为0或1
+// Expand bytes into bits in Ethernet transmission order, least significant bit first
+// @param data 字节
+// @return []byte 比特
+func BytesToBits(data []byte) []byte {
+	out := make([]byte, 0, len(data)*8)
+	for _, b := range data {
+		for i := range 8 {
+			out = append(out, b>>i&1)
+		}
+	}
+	return out
+}
+
+// BitsToBytes 把低位在前的比特合并为字节, 不足8位的尾部舍去
+// Pack least-significant-bit-first bits into bytes, dropping a partial last byte
+// @param in 比特
+// @return []byte 字节
+func BitsToBytes(in []byte) []byte {
+	out := make([]byte, len(in)/8)
+	for i := range out {
+		for j := range 8 {
+			out[i] |= in[i*8+j] & 1 << j
+		}
+	}
+	return out
+}
+
+// appendCode 追加码组的比特
+func appendCode(out []byte, code string) []byte {
+	for i := range len(code) {
+		out = append(out, code[i]-'0')
+	}
+	return out
+}
+
+// blockEncoder 块编码器, 4B/5B 每个字节先发低4位
+type blockEncoder struct {
+	code  LineCode
+	rdPos bool
+	bits  []byte
+}
+
+// data 编码数据字节
+func (e *blockEncoder) data(data []byte) {
+	for _, b := range data {
+		if e.code == Line4B5B {
+			e.bits = appendCode(e.bits, code4B5B[b&0x0F])
+			e.bits = appendCode(e.bits, code4B5B[b>>4])
+			continue
+		}
+		var code string
+		code, e.rdPos = encode8B10B(b, false, e.rdPos)
+		e.bits = appendCode(e.bits, code)
+	}
+}
+
+// control 编码控制码组, 4B/5B 为码组字母, 8B/10B 为 K.x.y 的值
+func (e *blockEncoder) control(symbols ...byte) {
+	for _, s := range symbols {
+		if e.code == Line4B5B {
+			e.bits = appendCode(e.bits, control4B5B[s])
+			continue
+		}
+		var code string
+		code, e.rdPos = encode8B10B(s, true, e.rdPos)
+		e.bits = appendCode(e.bits, code)
+	}
+}
+
+// decodeBlocks 把线路比特按码组还原, 检查码组与运行不一致性
+// @return []codeSymbol 码组
+// @return error 不合法的码组为 *ErrCodeViolation
+func decodeBlocks(code LineCode, in []byte) ([]codeSymbol, error) {
+	size := code.groupBits()
+	out := make([]codeSymbol, 0, len(in)/size)
+	rd := 0
+	buf := make([]byte, size)
+	for i := 0; i+size <= len(in); i += size {
+		for j := range size {
+			buf[j] = in[i+j] + '0'
+		}
+		group := string(buf)
+		if code == Line4B5B {
+			s, ok := decode4B5B[group]
+			if !ok {
+				return out, &ErrCodeViolation{Code: code.String(), Symbol: i / size, Group: group, Reason: "不是合法的码组"}
+			}
+			out = append(out, s)
+			continue
+		}
+		e, ok := decode8B10B[group]
+		if !ok {
+			return out, &ErrCodeViolation{Code: code.String(), Symbol: i / size, Group: group, Reason: "不是合法的码组"}
+		}
+		if !e.valid[rd] {
+			return out, &ErrCodeViolation{Code: code.String(), Symbol: i / size, Group: group, Reason: "运行不一致性错误"}
+		}
+		rd = subBlockDisparity(group[:6], 3, rd)
+		rd = subBlockDisparity(group[6:], 2, rd)
+		out = append(out, e.codeSymbol)
+	}
+	return out, nil
+}
+
+// subBlockDisparity 子块中1与0个数不等时, 运行不一致性随之变正或变负
+func subBlockDisparity(block string, half, rd int) int {
+	switch ones := countOnes(block); {
+	case ones > half:
+		return 1
+	case ones < half:
+		return 0
+	}
+	return rd
+}
+
+// symbolBytes 把4B/5B的半字节或8B/10B的数据码组合并为字节
+func symbolBytes(code LineCode, symbols []codeSymbol) []byte {
+	if code == Line8B10B {
+		out := make([]byte, len(symbols))
+		for i, s := range symbols {
+			out[i] = s.value
+		}
+		return out
+	}
+	out := make([]byte, len(symbols)/2)
+	for i := range out {
+		out[i] = symbols[2*i].value | symbols[2*i+1].value<<4
+	}
+	return out
+}
+
+// signalLevels 把线路比特变为电平: NRZ 与 8B/10B 直接对应, NRZI 与 4B/5B 遇1跳变, 曼彻斯特每比特两个半周期
+func signalLevels(code LineCode, in []byte) []int8 {
+	levels := make([]int8, 0, len(in)*code.levelsPerBit())
+	level := int8(-1)
+	for _, b := range in {
+		switch code.resolve() {
+		case LineNRZI, Line4B5B:
+			if b == 1 {
+				level = -level
+			}
+			levels = append(levels, level)
+		case LineManchester:
+			if b == 1 {
+				levels = append(levels, -1, 1)
+			} else {
+				levels = append(levels, 1, -1)
+			}
+		default:
+			levels = append(levels, int8(2*int(b)-1))
+		}
+	}
+	return levels
+}
+
+// recoverBits 从电平还原线路比特, 曼彻斯特编码的比特中间没有跳变时为编码错误
+func recoverBits(code LineCode, levels []int8) ([]byte, error) {
+	out := make([]byte, 0, len(levels)/code.levelsPerBit())
+	prev := int8(-1)
+	for i := 0; i < len(levels); i += code.levelsPerBit() {
+		level := levels[i]
+		switch code.resolve() {
+		case LineNRZI, Line4B5B:
+			out = append(out, boolBit(level != prev))
+			prev = level
+		case LineManchester:
+			if i+1 >= len(levels) || levels[i+1] == level {
+				return out, &ErrCodeViolation{Code: code.String(), Symbol: i / 2, Reason: "中间没有跳变"}
+			}
+			out = append(out, boolBit(level < 0))
+		default:
+			out = append(out, boolBit(level > 0))
+		}
+	}
+	return out, nil
+}
+
+// boolBit 布尔值对应的比特
+func boolBit(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// transitions 电平跳变次数, 用于比较各编码的带宽需求
+func transitions(levels []int8) int {
+	n := 0
+	for i := 1; i < len(levels); i++ {
+		if levels[i] != levels[i-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// disparity 电平中高电平比低电平多出的个数, 反映直流分量
+func disparity(levels []int8) int {
+	n := 0
+	for _, l := range levels {
+		n += int(l)
+	}
+	return n
+}
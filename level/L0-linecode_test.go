@@ -0,0 +1,126 @@
+package level
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var lineCodes = []LineCode{LineNRZ, LineNRZI, LineManchester, Line4B5B, Line8B10B}
+
+// allBytes 0x00~0xff 各一个字节
+func allBytes() []byte {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+// testFrame 载荷包含所有字节值的以太网帧
+func testFrame() []byte {
+	ip := NewIPv4Packet(testIP1, testIP2, IPProtocolUDP, NewUDPPacket(1024, 9, allBytes()).Serialize(testIP1, testIP2))
+	return NewEthernet2(testMAC2, testMAC1, "IP", ip.Serialize()).Serialize()
+}
+
+func TestLineCodeRoundTrip(t *testing.T) {
+	data := allBytes()
+	for _, code := range lineCodes {
+		sig := Encode(code, data)
+		if len(sig.Levels) != len(sig.Bits)*code.levelsPerBit() {
+			t.Errorf("%v: %d levels for %d bits", code, len(sig.Levels), len(sig.Bits))
+		}
+		got, err := sig.Decode()
+		if err != nil {
+			t.Errorf("%v: Decode: %v", code, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%v: Decode = %x, want %x", code, got, data)
+		}
+	}
+}
+
+func TestEncodeFrameRoundTrip(t *testing.T) {
+	frame := testFrame()
+	for _, code := range lineCodes {
+		sig := EncodeFrame(code, frame)
+		got, err := sig.DecodeFrame()
+		if err != nil {
+			t.Errorf("%v: DecodeFrame: %v", code, err)
+			continue
+		}
+		if !bytes.Equal(got, frame) {
+			t.Errorf("%v: DecodeFrame returned %d bytes, want the %d byte frame", code, len(got), len(frame))
+		}
+		// 帧所在的线路比特解码后就是帧本身
+		if code.groupBits() == 0 && !bytes.Equal(BitsToBytes(sig.Bits[sig.FrameStart:sig.FrameEnd]), frame) {
+			t.Errorf("%v: bits [%d, %d) are not the frame", code, sig.FrameStart, sig.FrameEnd)
+		}
+	}
+}
+
+// 帧中任意一个电平出错, 都会被物理层的编码检查或接收方的FCS检查发现
+func TestFlippedLevelDetected(t *testing.T) {
+	frame := testFrame()
+	for _, code := range lineCodes {
+		per := code.levelsPerBit()
+		clean := EncodeFrame(code, frame)
+		violations, crcErrors := 0, 0
+		for i := clean.FrameStart * per; i < clean.FrameEnd*per; i++ {
+			sig := EncodeFrame(code, frame)
+			sig.Flip(i)
+			received, err := sig.DecodeFrame()
+			if errors.Is(err, &ErrCodeViolation{}) {
+				violations++
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%v level %d: DecodeFrame: %v", code, i, err)
+			}
+			if _, err := Deserialize(received); !errors.Is(err, &ErrBadChecksum{}) {
+				t.Fatalf("%v level %d: flipped level not detected, Deserialize: %v", code, i, err)
+			}
+			crcErrors++
+		}
+		t.Logf("%v: %d code violations, %d CRC errors", code, violations, crcErrors)
+		// 曼彻斯特编码的每个错误都破坏比特中间的跳变
+		if code == LineManchester && crcErrors != 0 {
+			t.Errorf("manchester: %d errors passed the line code check", crcErrors)
+		}
+	}
+}
+
+func TestDecodeFrameNoSFD(t *testing.T) {
+	sig := Encode(LineNRZ, bytes.Repeat([]byte{PreambleByte}, 16))
+	if _, err := sig.DecodeFrame(); !errors.Is(err, &ErrNoSFD{}) {
+		t.Errorf("DecodeFrame without SFD: %v, want ErrNoSFD", err)
+	}
+}
+
+// 8B/10B 每个6位与4位子块结束时运行不一致性都在 ±1, 连续相同比特不超过5个
+func TestRunningDisparity8B10B(t *testing.T) {
+	for name, sig := range map[string]*Signal{
+		"data":  Encode(Line8B10B, append(allBytes(), allBytes()...)),
+		"frame": EncodeFrame(Line8B10B, testFrame()),
+	} {
+		rd, run := -1, 0
+		for i, level := range sig.Levels {
+			if i > 0 && level == sig.Levels[i-1] {
+				run++
+			} else {
+				run = 1
+			}
+			if run > 5 {
+				t.Fatalf("%s: run of %d equal bits at %d", name, run, i)
+			}
+			rd += int(level)
+			// 子块边界: 每个10位码组的第6位与第10位之后
+			if j := i % 10; j == 5 || j == 9 {
+				if rd != -1 && rd != 1 {
+					t.Fatalf("%s: running disparity %+d after bit %d", name, rd, i)
+				}
+			}
+		}
+	}
+}
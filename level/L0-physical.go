@@ -0,0 +1,125 @@
+package level
+
+// const 物理层常量
+const (
+	PreambleSize = 7    // 前导码字节数
+	PreambleByte = 0x55 // 前导码, 按低位在前发送为 10101010
+	SFD          = 0xD5 // 帧起始定界符, 按低位在前发送为 10101011
+)
+
+// Preamble 前导码与帧起始定界符
+// Preamble followed by the start frame delimiter
+// @return []byte 8字节
+func Preamble() []byte {
+	out := make([]byte, PreambleSize+1)
+	for i := range PreambleSize {
+		out[i] = PreambleByte
+	}
+	out[PreambleSize] = SFD
+	return out
+}
+
+// EncodeFrame 物理层发送一帧: 加上前导码与帧起始定界符后按线路编码发送
+// 4B/5B 用 J/K 代替第一个前导码字节并以 T/R 结束(IEEE 802.3 §24.2),
+// 8B/10B 用 /S/ 代替第一个前导码字节并以 /T/R/ 结束(IEEE 802.3 §36.2.4)
+// Transmit a frame: prepend the preamble and SFD, then line code it with the delimiters of 100BASE-X or 1000BASE-X
+// @param code 线路编码, LineAuto 按NRZ处理
+// @param frame 以太网帧, 含FCS
+// @return *Signal 信号
+func EncodeFrame(code LineCode, frame []byte) *Signal {
+	code = code.resolve()
+	preamble := Preamble()
+	s := &Signal{Code: code}
+	switch code {
+	case Line4B5B:
+		e := &blockEncoder{code: code}
+		e.control('J', 'K')
+		e.data(preamble[1:])
+		s.FrameStart = len(e.bits)
+		e.data(frame)
+		s.FrameEnd = len(e.bits)
+		e.control('T', 'R')
+		s.Bits = e.bits
+	case Line8B10B:
+		e := &blockEncoder{code: code}
+		e.control(K27_7)
+		e.data(preamble[1:])
+		s.FrameStart = len(e.bits)
+		e.data(frame)
+		s.FrameEnd = len(e.bits)
+		e.control(K29_7, K23_7)
+		s.Bits = e.bits
+	default:
+		s.Bits = BytesToBits(append(preamble, frame...))
+		s.FrameStart = len(preamble) * 8
+		s.FrameEnd = len(s.Bits)
+	}
+	s.Levels = signalLevels(code, s.Bits)
+	return s
+}
+
+// DecodeFrame 物理层接收一帧: 从电平还原线路比特, 检查线路编码与定界符, 去掉前导码与帧起始定界符
+// 比特错误可能破坏编码而被物理层发现, 也可能变为另一个合法的比特而留给FCS检查
+// Receive a frame: recover the bits, check the line code and delimiters, and strip the preamble and SFD.
+// Bit errors either break the line code here or slip through to the FCS check
+// @return []byte 以太网帧
+// @return error 编码错误为 *ErrCodeViolation, 找不到帧起始定界符为 *ErrNoSFD
+func (s *Signal) DecodeFrame() ([]byte, error) {
+	bits, err := recoverBits(s.Code, s.Levels)
+	if err != nil {
+		return nil, err
+	}
+	if s.Code.groupBits() == 0 {
+		// 前导码为交替的1与0, 第一次出现连续两个1即为帧起始定界符的结尾
+		for i := 1; i < len(bits); i++ {
+			if bits[i] == 1 && bits[i-1] == 1 {
+				return BitsToBytes(bits[i+1:]), nil
+			}
+		}
+		return nil, &ErrNoSFD{Code: s.Code.String()}
+	}
+	symbols, err := decodeBlocks(s.Code, bits)
+	if err != nil {
+		return nil, err
+	}
+	start := []codeSymbol{{value: 'J', control: true}, {value: 'K', control: true}}
+	end := []codeSymbol{{value: 'T', control: true}, {value: 'R', control: true}}
+	if s.Code == Line8B10B {
+		start = []codeSymbol{{value: K27_7, control: true}}
+		end = []codeSymbol{{value: K29_7, control: true}, {value: K23_7, control: true}}
+	}
+	for i, sym := range start {
+		if i >= len(symbols) || symbols[i] != sym {
+			return nil, &ErrCodeViolation{Code: s.Code.String(), Symbol: i, Group: groupAt(bits, s.Code, i), Reason: "不是起始定界符"}
+		}
+	}
+	for i := len(start); i < len(symbols); i++ {
+		if !symbols[i].control {
+			continue
+		}
+		if symbols[i] != end[0] {
+			return nil, &ErrCodeViolation{Code: s.Code.String(), Symbol: i, Group: groupAt(bits, s.Code, i), Reason: "帧中出现控制码组"}
+		}
+		// 结束定界符的两个码组都要正确, 否则是提前结束的帧
+		for j, sym := range end[1:] {
+			if k := i + 1 + j; k >= len(symbols) || symbols[k] != sym {
+				return nil, &ErrCodeViolation{Code: s.Code.String(), Symbol: k, Group: groupAt(bits, s.Code, k), Reason: "结束定界符不完整"}
+			}
+		}
+		return stripPreamble(s.Code, symbolBytes(s.Code, symbols[len(start):i]))
+	}
+	return nil, &ErrCodeViolation{Code: s.Code.String(), Symbol: len(symbols), Reason: "之前没有结束定界符"}
+}
+
+// stripPreamble 跳过前导码字节, 其后应为帧起始定界符
+func stripPreamble(code LineCode, data []byte) ([]byte, error) {
+	for i, b := range data {
+		if b == SFD {
+			return data[i+1:], nil
+		}
+		if b != PreambleByte {
+			break
+		}
+	}
+	return nil, &ErrNoSFD{Code: code.String()}
+}
@@ -0,0 +1,247 @@
+package level
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Signal 线路上的信号: 块编码后的线路比特与发送的电平
+// Signal on the wire: line bits after block coding and the levels sent for them
+type Signal struct {
+	// 线路编码 Line code
+	Code LineCode
+	// 线路比特, 每个元素为0或1, 块编码时为码组的比特 Line bits, code group bits for block codes
+	Bits []byte
+	// 电平, 每个元素为+1或-1, 曼彻斯特编码每个比特两个 Levels of +1 or -1, two per bit for Manchester
+	Levels []int8
+	// 帧的第一个线路比特, 在前导码与起始定界符之后 First line bit of the frame, after the preamble and delimiters
+	FrameStart int
+	// 帧之后的第一个线路比特 Line bit just past the frame
+	FrameEnd int
+	// 被翻转的电平下标, 按升序排列 Indexes of flipped levels in ascending order
+	Errors []int
+}
+
+// Encode 用线路编码发送数据, 不加前导码与定界符
+// Encode data with a line code, without preamble or delimiters
+// @param code 线路编码, LineAuto 按NRZ处理
+// @param data 数据
+// @return *Signal 信号
+func Encode(code LineCode, data []byte) *Signal {
+	code = code.resolve()
+	var bits []byte
+	if code.groupBits() > 0 {
+		e := &blockEncoder{code: code}
+		e.data(data)
+		bits = e.bits
+	} else {
+		bits = BytesToBits(data)
+	}
+	return &Signal{Code: code, Bits: bits, Levels: signalLevels(code, bits), FrameEnd: len(bits)}
+}
+
+// Decode 从电平还原 Encode 发送的数据
+// Recover the data sent by Encode from the levels
+// @return []byte 数据
+// @return error 编码错误为 *ErrCodeViolation
+func (s *Signal) Decode() ([]byte, error) {
+	bits, err := recoverBits(s.Code, s.Levels)
+	if err != nil {
+		return nil, err
+	}
+	if s.Code.groupBits() == 0 {
+		return BitsToBytes(bits), nil
+	}
+	symbols, err := decodeBlocks(s.Code, bits)
+	if err != nil {
+		return nil, err
+	}
+	for i, sym := range symbols {
+		if sym.control {
+			return nil, &ErrCodeViolation{Code: s.Code.String(), Symbol: i, Group: groupAt(bits, s.Code, i), Reason: "数据中出现控制码组"}
+		}
+	}
+	return symbolBytes(s.Code, symbols), nil
+}
+
+// Flip 翻转一个电平, 模拟线路上的比特错误
+// Flip one level to simulate a bit error on the wire
+// @param i 电平下标
+func (s *Signal) Flip(i int) {
+	if i < 0 || i >= len(s.Levels) {
+		return
+	}
+	s.Levels[i] = -s.Levels[i]
+	if j, found := slices.BinarySearch(s.Errors, i); found {
+		s.Errors = slices.Delete(s.Errors, j, j+1)
+	} else {
+		s.Errors = slices.Insert(s.Errors, j, i)
+	}
+}
+
+// InjectErrors 按误码率随机翻转电平, 错误间隔服从几何分布
+// Flip levels at random with the given symbol error rate, using geometric gaps between errors
+// @param ber 每个电平出错的概率
+// @param rng 随机数来源
+// @return int 翻转的电平数
+func (s *Signal) InjectErrors(ber float64, rng *rand.Rand) int {
+	if ber <= 0 {
+		return 0
+	}
+	n := 0
+	for i := -1; ; n++ {
+		gap := 1
+		if ber < 1 {
+			gap += int(math.Floor(math.Log(1-rng.Float64()) / math.Log(1-ber)))
+		}
+		if gap <= 0 || i+gap >= len(s.Levels) {
+			return n
+		}
+		i += gap
+		s.Flip(i)
+	}
+}
+
+// Transitions 电平跳变次数, 跳变越多接收方越容易同步, 所需带宽也越高
+// Number of level transitions; more transitions ease clock recovery but need more bandwidth
+func (s *Signal) Transitions() int {
+	return transitions(s.Levels)
+}
+
+// Disparity 高电平与低电平个数之差, 反映信号的直流分量
+// Count of high levels minus low levels, the DC component of the signal
+func (s *Signal) Disparity() int {
+	return disparity(s.Levels)
+}
+
+// bitLevels 线路比特 [start, start+n) 对应的电平下标范围
+func (s *Signal) bitLevels(start, n int) (int, int) {
+	per := s.Code.levelsPerBit()
+	start = max(0, min(start, len(s.Bits)))
+	end := start + n
+	if n <= 0 || end > len(s.Bits) {
+		end = len(s.Bits)
+	}
+	return start * per, end * per
+}
+
+// ASCII 以字符画显示线路比特 [start, start+n) 的波形, 第一行为比特, 最后一行用 ^ 标出被翻转的电平
+// Render the waveform of line bits [start, start+n) as text; ^ marks flipped levels
+// @param start 第一个线路比特
+// @param n 比特数, 不大于0时显示到结尾
+// @return string 字符画, 每行以换行结尾
+func (s *Signal) ASCII(start, n int) string {
+	from, to := s.bitLevels(start, n)
+	per := s.Code.levelsPerBit()
+	width := 4 / per
+	var label, high, low, mark strings.Builder
+	for i := from; i < to; i++ {
+		// 单元之间的一列: 电平变化时画竖线
+		edge := i > from && s.Levels[i] != s.Levels[i-1]
+		switch {
+		case i == from:
+		case edge:
+			high.WriteByte(' ')
+			low.WriteByte('|')
+		case s.Levels[i] > 0:
+			high.WriteByte('_')
+			low.WriteByte(' ')
+		default:
+			high.WriteByte(' ')
+			low.WriteByte('_')
+		}
+		if i > from {
+			mark.WriteByte(' ')
+			label.WriteByte(' ')
+		}
+		line, blank := strings.Repeat("_", width), strings.Repeat(" ", width)
+		if s.Levels[i] > 0 {
+			high.WriteString(line)
+			low.WriteString(blank)
+		} else {
+			high.WriteString(blank)
+			low.WriteString(line)
+		}
+		if _, found := slices.BinarySearch(s.Errors, i); found {
+			mark.WriteString(strings.Repeat("^", width))
+		} else {
+			mark.WriteString(blank)
+		}
+		if i%per == 0 {
+			label.WriteString(fmt.Sprintf("%-*d", width, s.Bits[i/per]))
+		} else {
+			label.WriteString(blank)
+		}
+	}
+	out := []string{label.String(), high.String(), low.String()}
+	if strings.TrimSpace(mark.String()) != "" {
+		out = append(out, mark.String())
+	}
+	var b strings.Builder
+	for _, line := range out {
+		b.WriteString(strings.TrimRight(line, " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// const SVG 波形尺寸
+const (
+	svgCell   = 24 // 每个电平的宽度
+	svgHigh   = 20 // 高电平的纵坐标
+	svgLow    = 60 // 低电平的纵坐标
+	svgHeight = 80 // 图像高度
+)
+
+// SVG 以SVG图像显示线路比特 [start, start+n) 的波形, 虚线为比特边界, 被翻转的电平标为红色
+// Render the waveform of line bits [start, start+n) as an SVG image; flipped levels are red
+// @param start 第一个线路比特
+// @param n 比特数, 不大于0时显示到结尾
+// @return string SVG文档
+func (s *Signal) SVG(start, n int) string {
+	from, to := s.bitLevels(start, n)
+	per := s.Code.levelsPerBit()
+	width := max(1, to-from) * svgCell
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="12">`+"\n",
+		width, svgHeight, width, svgHeight)
+	fmt.Fprintf(&b, `<title>%s</title>`+"\n", s.Code)
+	var points []string
+	for i := from; i < to; i++ {
+		x := (i - from) * svgCell
+		y := svgLow
+		if s.Levels[i] > 0 {
+			y = svgHigh
+		}
+		points = append(points, fmt.Sprintf("%d,%d %d,%d", x, y, x+svgCell, y))
+		if _, found := slices.BinarySearch(s.Errors, i); found {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#f88" fill-opacity="0.4"/>`+"\n",
+				x, svgHigh-4, svgCell, svgLow-svgHigh+8)
+		}
+		if i%per == 0 {
+			fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#bbb" stroke-dasharray="2,2"/>`+"\n",
+				x, svgHigh-6, x, svgLow+4)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%d</text>`+"\n",
+				x+svgCell*per/2, svgHigh-8, s.Bits[i/per])
+		}
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="#06c" stroke-width="2"/>`+"\n", strings.Join(points, " "))
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// groupAt 第 i 个码组的比特, 超出范围时为空
+func groupAt(bits []byte, code LineCode, i int) string {
+	size := code.groupBits()
+	if size == 0 || (i+1)*size > len(bits) {
+		return ""
+	}
+	group := make([]byte, size)
+	for j := range size {
+		group[j] = bits[i*size+j] + '0'
+	}
+	return string(group)
+}
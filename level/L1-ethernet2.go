@@ -2,35 +2,99 @@ package level
 
 import (
 	"encoding/binary"
+	"fmt"
 	// "hash/crc32" // 不再需要
+
+	"osiweb-go/i18n"
 )
 
 // Ethernet2 以太网帧
 // @author xuyang
 // @datetime 2025/6/27 8:00
-// [D_MAC][S_MAC][⬆][...DATA...][CheckSum]
+// [D_MAC][S_MAC][TAG...][⬆][...DATA...][CheckSum]
 type Ethernet2 struct {
 	// 目的MAC地址
 	DMacAddress [6]byte
 	// 源MAC地址
 	SMacAddress [6]byte
-	// 上层协议类型
+	// 802.1Q 标签, 由外到内, 不带标签时为空
+	Tags []VLANTag
+	// 上层协议类型, 带标签时为最内层的类型
 	ProtocolType [2]byte
-	// 数据包
+	// 数据包, 不含填充
 	DataPackage []byte
+	// 填充, 数据包不足46字节时补的字节, 或上层长度字段之后多出的字节
+	Padding []byte
 	// CRC校验和
 	CRCCheckSum [4]byte
 }
 
+// FCSMode 反序列化时帧尾是否带CRC
+type FCSMode int
+
+// const 帧尾CRC的处理方式
+const (
+	// FCSPresent 帧尾带4字节CRC, 如模拟链路上传输的帧
+	FCSPresent FCSMode = iota
+	// FCSAbsent 帧尾不带CRC, 如大多数抓包文件中的帧
+	FCSAbsent
+	// FCSAuto 末4字节恰好是前面内容的CRC时视为带CRC, 否则视为不带
+	FCSAuto
+)
+
 // const 以太网帧常量
 // @author xuyang
 // @datetime 2025/6/27 8:00
 const (
-	MinDataSize        = 46   // 最小数据包大小
-	MaxDataSize        = 1500 // 最大数据包大小
-	EthernetHeaderSize = 14   // 以太网头部大小
+	MinDataSize        = 46     // 最小数据包大小
+	MaxDataSize        = 1500   // 最大数据包大小
+	EthernetHeaderSize = 14     // 以太网头部大小
+	FCSSize            = 4      // 帧校验序列大小
+	EtherTypeMin       = 0x0600 // 类型字段的最小值, 不大于1500时为802.3长度字段
+)
+
+// Ethernet2Fields 以太网头部字段布局
+// Ethernet II header field layout
+var Ethernet2Fields = []FieldSpec{
+	{Name: "Destination", Bit: 0, Bits: 48, Format: formatMAC},
+	{Name: "Source", Bit: 48, Bits: 48, Format: formatMAC},
+	{Name: "Type", Bit: 96, Bits: 16, Format: formatEtherType},
+}
+
+// IEEE8023Fields 802.3 头部字段布局, 类型字段的位置为长度字段
+// IEEE 802.3 header field layout with a length field in place of the EtherType
+var IEEE8023Fields = []FieldSpec{
+	{Name: "Destination", Bit: 0, Bits: 48, Format: formatMAC},
+	{Name: "Source", Bit: 48, Bits: 48, Format: formatMAC},
+	{Name: "Length", Bit: 96, Bits: 16},
+}
+
+// const 以太网上层协议类型
+const (
+	EtherTypeIPv4 = 0x0800
+	EtherTypeARP  = 0x0806
+	EtherTypeIPv6 = 0x86DD
 )
 
+// EtherType 返回上层协议类型数值
+// @return uint16 上层协议类型
+func (e *Ethernet2) EtherType() uint16 {
+	return binary.BigEndian.Uint16(e.ProtocolType[:])
+}
+
+// SetEtherType 设置上层协议类型并重新计算CRC
+// @param etherType 上层协议类型
+func (e *Ethernet2) SetEtherType(etherType uint16) {
+	binary.BigEndian.PutUint16(e.ProtocolType[:], etherType)
+	e.generateCRC()
+}
+
+// IsIEEE8023 类型字段不大于1500时为802.3帧, 该字段是LLC数据的长度
+// @return bool 是否为802.3帧
+func (e *Ethernet2) IsIEEE8023() bool {
+	return e.EtherType() <= MaxDataSize
+}
+
 // getProtocolTypeBytes 根据协议类型字符串返回对应的字节数组
 // @param protocolTypeStr 协议类型字符串
 // @return [2]byte 协议类型的字节表示
@@ -63,12 +127,11 @@ func NewEthernet2(dMacAddress, sMacAddress [6]byte, protocolTypeS string,
 	data []byte) *Ethernet2 {
 	// 数据包大小限制
 	dataSize := len(data)
+	var padding []byte
 	if dataSize < MinDataSize {
-		// 如果数据包太小 -> 用零填充到最小大小
+		// 如果数据包太小 -> 用零填充到最小大小, 填充与数据分开保存
 		// panic("以太网帧数据包过小!")
-		paddedData := make([]byte, MinDataSize)
-		copy(paddedData, data)
-		data = paddedData
+		padding = make([]byte, MinDataSize-dataSize)
 	} else if dataSize > MaxDataSize {
 		// 如果数据包太大 -> 截断到最大大小
 		// panic("以太网帧数据包过大!")
@@ -80,40 +143,84 @@ func NewEthernet2(dMacAddress, sMacAddress [6]byte, protocolTypeS string,
 		SMacAddress:  sMacAddress,
 		ProtocolType: protocolType,
 		DataPackage:  data,
+		Padding:      padding,
 	}
 	frame.generateCRC()
 	return frame
 }
 
+// NewIEEE8023 新建带LLC头部的802.3帧, 长度字段为LLC报文的长度
+// @param dMacAddress 目的MAC地址
+// @param sMacAddress 源MAC地址
+// @param llc LLC报文
+// @return *Ethernet2 生成的802.3帧
+func NewIEEE8023(dMacAddress, sMacAddress [6]byte, llc *LLC) *Ethernet2 {
+	frame := NewEthernet2(dMacAddress, sMacAddress, "", llc.Serialize())
+	frame.SetEtherType(uint16(len(frame.DataPackage)))
+	return frame
+}
+
 // Serialize 将以太网帧序列化为[]byte
 // @author xuyang
 // @datetime 2025/6/27 11:00
 // @return []byte 序列化后的字节数组
 func (e *Ethernet2) Serialize() []byte {
-	totalSize := EthernetHeaderSize + len(e.DataPackage) + 4
+	totalSize := EthernetHeaderSize + VLANTagSize*len(e.Tags) + len(e.DataPackage) + len(e.Padding) + 4
 	result := make([]byte, totalSize)
 	offset := 0
 	copy(result[offset:], e.DMacAddress[:])
 	offset += 6
 	copy(result[offset:], e.SMacAddress[:])
 	offset += 6
+	offset += e.putTags(result[offset:])
 	copy(result[offset:], e.ProtocolType[:])
 	offset += 2
 	copy(result[offset:], e.DataPackage)
 	offset += len(e.DataPackage)
+	copy(result[offset:], e.Padding)
+	offset += len(e.Padding)
 	copy(result[offset:], e.CRCCheckSum[:])
 	return result
 }
 
-// Deserialize 将[]byte反序列化为以太网帧
+// Deserialize 将带CRC的[]byte反序列化为以太网帧, 同 DeserializeFrame(data, FCSPresent)
+// 帧不足最小长度时返回 *ErrTruncated; CRC错误时返回解析结果与 *ErrBadChecksum
 // @author xuyang
 // @datetime 2025/6/27 11:00
 // @param data 要反序列化的字节数组
-// @return *Ethernet2 反序列化后的以太网帧，如果失败返回nil
-func Deserialize(data []byte) *Ethernet2 {
-	if len(data) < EthernetHeaderSize+MinDataSize+4 {
-		// panic("数据长度不足，数据包可能不是ErhernetII报文")
-		return nil
+// @return *Ethernet2 反序列化后的以太网帧，长度不足时为nil
+// @return error 反序列化错误
+func Deserialize(data []byte) (*Ethernet2, error) {
+	return DeserializeFrame(data, FCSPresent)
+}
+
+// DeserializeFrame 按 fcs 指定的CRC处理方式反序列化以太网帧
+// 上层协议带长度字段时(ARP、IPv4、IPv6)或为802.3帧时按长度切分数据包与填充, 上层看不到填充字节;
+// 不带CRC时不要求最小帧长, 并补齐填充、计算CRC, 使 Serialize 得到完整的帧
+// 源MAC之后的 802.1Q/802.1ad 标签存入 Tags, ProtocolType 为最内层的类型
+// @param data 从目的MAC开始的帧
+// @param fcs 帧尾CRC的处理方式
+// @return *Ethernet2 反序列化后的以太网帧，长度不足时为nil
+// @return error 反序列化错误
+func DeserializeFrame(data []byte, fcs FCSMode) (*Ethernet2, error) {
+	if fcs == FCSAuto {
+		fcs = FCSAbsent
+		if n := len(data); n >= EthernetHeaderSize+MinDataSize+FCSSize &&
+			calcCRC32IEEE(data[:n-FCSSize]) == binary.BigEndian.Uint32(data[n-FCSSize:]) {
+			fcs = FCSPresent
+		}
+	}
+	end := len(data)
+	if fcs == FCSPresent {
+		if len(data) < EthernetHeaderSize+FCSSize {
+			return nil, truncated("Ethernet II", 0, EthernetHeaderSize+MinDataSize+FCSSize, len(data))
+		}
+		if len(data) < EthernetHeaderSize+MinDataSize+FCSSize {
+			return nil, truncated("Ethernet II", EthernetHeaderSize, EthernetHeaderSize+MinDataSize+FCSSize, len(data))
+		}
+		end -= FCSSize
+	} else if len(data) < EthernetHeaderSize {
+		return nil, truncated("Ethernet II", 0, EthernetHeaderSize, len(data))
 	}
 	frame := &Ethernet2{}
 	offset := 0
@@ -121,14 +228,81 @@ func Deserialize(data []byte) *Ethernet2 {
 	offset += 6
 	copy(frame.SMacAddress[:], data[offset:offset+6])
 	offset += 6
+	for isTPID(binary.BigEndian.Uint16(data[offset : offset+2])) {
+		if end < offset+VLANTagSize+2 {
+			return nil, truncated("Ethernet II", offset, offset+VLANTagSize+2+len(data)-end, len(data))
+		}
+		frame.Tags = append(frame.Tags, VLANTag{
+			TPID: binary.BigEndian.Uint16(data[offset : offset+2]),
+			TCI:  binary.BigEndian.Uint16(data[offset+2 : offset+4]),
+		})
+		offset += VLANTagSize
+	}
 	copy(frame.ProtocolType[:], data[offset:offset+2])
 	offset += 2
-	dataSize := len(data) - EthernetHeaderSize - 4
+	payload := data[offset:end]
+	dataSize := upperLength(frame.EtherType(), payload)
 	frame.DataPackage = make([]byte, dataSize)
-	copy(frame.DataPackage, data[offset:offset+dataSize])
-	offset += dataSize
-	copy(frame.CRCCheckSum[:], data[offset:offset+4])
-	return frame
+	copy(frame.DataPackage, payload[:dataSize])
+	if dataSize < len(payload) {
+		frame.Padding = make([]byte, len(payload)-dataSize)
+		copy(frame.Padding, payload[dataSize:])
+	}
+	if fcs == FCSAbsent {
+		if size := len(payload); size < frame.minDataSize() {
+			frame.Padding = append(frame.Padding, make([]byte, frame.minDataSize()-size)...)
+		}
+		frame.generateCRC()
+		return frame, nil
+	}
+	copy(frame.CRCCheckSum[:], data[end:end+4])
+	if crc := calcCRC32IEEE(data[:end]); crc != binary.BigEndian.Uint32(frame.CRCCheckSum[:]) {
+		return frame, &ErrBadChecksum{Layer: "Ethernet II", Field: "Frame Check Sequence", Offset: end,
+			Want: crc, Got: binary.BigEndian.Uint32(frame.CRCCheckSum[:])}
+	}
+	return frame, nil
+}
+
+// upperLength 按上层协议的长度字段得出数据包的实际长度, 长度字段不可用时为整个载荷
+func upperLength(etherType uint16, payload []byte) int {
+	n := len(payload)
+	if etherType <= MaxDataSize && int(etherType) <= n {
+		return int(etherType)
+	}
+	switch etherType {
+	case EtherTypeARP:
+		if n >= 28 {
+			return 28
+		}
+	case EtherTypeIPv4:
+		if n >= 20 {
+			if total := int(binary.BigEndian.Uint16(payload[2:4])); total >= 20 && total <= n {
+				return total
+			}
+		}
+	case EtherTypeIPv6:
+		if n >= 40 {
+			if total := 40 + int(binary.BigEndian.Uint16(payload[4:6])); total <= n {
+				return total
+			}
+		}
+	}
+	return n
+}
+
+// AppendFCS 为不带校验和的帧(如抓包文件中的帧)补齐最小长度并追加CRC
+// @param data 从目的MAC开始、不含CRC的帧
+// @return []byte 可被 Deserialize 解析的完整帧
+func AppendFCS(data []byte) []byte {
+	size := len(data)
+	if size < EthernetHeaderSize+MinDataSize {
+		size = EthernetHeaderSize + MinDataSize
+	}
+	result := make([]byte, size+FCSSize)
+	copy(result, data)
+	crc := calcCRC32IEEE(result[:size])
+	binary.BigEndian.PutUint32(result[size:], crc)
+	return result
 }
 
 // ValidateCRC 检测以太网帧的CRC校验和是否正确
@@ -137,7 +311,56 @@ func Deserialize(data []byte) *Ethernet2 {
 // @return bool 如果CRC正确返回true，否则返回false
 func (e *Ethernet2) ValidateCRC() bool {
 	calculatedCRC := e.calculateCRC()
-	return calculatedCRC == e.CRCCheckSum
+	if calculatedCRC != e.CRCCheckSum {
+		logL2.Debug(i18n.T("CRC校验失败"), "want", fmt.Sprintf("%x", calculatedCRC),
+			"got", fmt.Sprintf("%x", e.CRCCheckSum))
+		return false
+	}
+	return true
+}
+
+// Validate 检查以太网帧头部与数据长度是否符合规范, CRC由 Deserialize 检查
+// Validate Ethernet II header and payload size; the CRC is checked by Deserialize
+// @return Findings 诊断结果
+func (e *Ethernet2) Validate() Findings {
+	c := &check{layer: e.layerName()}
+	if e.SMacAddress[0]&0x01 != 0 {
+		c.add(SeverityError, "Source", "IEEE 802.3 §3.2.3", "源地址 %s 是组播地址, 源地址必须是单播地址", FormatMAC(e.SMacAddress))
+	}
+	switch t := e.EtherType(); {
+	case t <= MaxDataSize:
+		if n := len(e.DataPackage); int(t) > n {
+			c.add(SeverityError, "Length", "IEEE 802.3 §3.2.6", "长度字段 %d 超过数据的 %d 字节", t, n)
+		} else if int(t) < n {
+			c.add(SeverityError, "Length", "IEEE 802.3 §3.2.6", "长度字段 %d 小于数据的 %d 字节", t, n)
+		}
+	case t < EtherTypeMin:
+		c.add(SeverityError, "Type", "IEEE 802.3 §3.2.6", "类型字段 0x%04x 位于未定义的区间 1501~1535", t)
+	}
+	switch n := len(e.DataPackage) + len(e.Padding); {
+	case n > MaxDataSize:
+		c.add(SeverityError, "", "RFC 894", "数据 %d 字节, 超过最大 %d 字节", n, MaxDataSize)
+	case n < e.minDataSize():
+		c.add(SeverityInfo, "", "RFC 894", "数据 %d 字节, 不足 %d 字节, 发送时需要填充", n, e.minDataSize())
+	}
+	return append(c.done(logL2), e.validateTags()...)
+}
+
+// layerName 类型字段不大于1500时为802.3帧
+func (e *Ethernet2) layerName() string {
+	if len(e.Tags) == 0 && e.IsIEEE8023() {
+		return "IEEE 802.3 Ethernet"
+	}
+	return "Ethernet II"
+}
+
+// putTags 写入标签, 返回写入的字节数
+func (e *Ethernet2) putTags(b []byte) int {
+	for i, tag := range e.Tags {
+		binary.BigEndian.PutUint16(b[i*VLANTagSize:], tag.TPID)
+		binary.BigEndian.PutUint16(b[i*VLANTagSize+2:], tag.TCI)
+	}
+	return VLANTagSize * len(e.Tags)
 }
 
 // generateCRC 生成CRC校验和
@@ -152,15 +375,18 @@ func (e *Ethernet2) generateCRC() {
 // @datetime 2025/6/27 12:00
 // @return [4]byte 计算出的CRC校验和
 func (e *Ethernet2) calculateCRC() [4]byte {
-	crcData := make([]byte, EthernetHeaderSize+len(e.DataPackage))
+	crcData := make([]byte, EthernetHeaderSize+VLANTagSize*len(e.Tags)+len(e.DataPackage)+len(e.Padding))
 	offset := 0
 	copy(crcData[offset:], e.DMacAddress[:])
 	offset += 6
 	copy(crcData[offset:], e.SMacAddress[:])
 	offset += 6
+	offset += e.putTags(crcData[offset:])
 	copy(crcData[offset:], e.ProtocolType[:])
 	offset += 2
 	copy(crcData[offset:], e.DataPackage)
+	offset += len(e.DataPackage)
+	copy(crcData[offset:], e.Padding)
 	crc := calcCRC32IEEE(crcData) // 使用自定义的CRC32实现
 	var result [4]byte
 	binary.BigEndian.PutUint32(result[:], crc)
@@ -0,0 +1,132 @@
+package level
+
+import (
+	"bytes"
+	"testing"
+
+	"osiweb-go/i18n"
+)
+
+// shortFrames 数据不足46字节、需要填充的帧
+func shortFrames() map[string][]byte {
+	arp := NewARPPacket(1, testMAC1, testIP1, [6]byte{}, testIP2).Serialize()
+	tcp := NewTCPPacket(1024, 80, 100, 200, TCPFlagACK, 65535, nil).Serialize(testIP1, testIP2)
+	ack := NewIPv4Packet(testIP1, testIP2, IPProtocolTCP, tcp).Serialize()
+	return map[string][]byte{"ARP": arp, "TCP ACK": ack}
+}
+
+func TestDeserializeFramePadding(t *testing.T) {
+	for name, payload := range shortFrames() {
+		protocol := "IP"
+		if name == "ARP" {
+			protocol = "ARP"
+		}
+		withFCS := NewEthernet2(testMAC2, testMAC1, protocol, payload).Serialize()
+		if len(withFCS) != EthernetHeaderSize+MinDataSize+FCSSize {
+			t.Fatalf("%s: frame is %d bytes, want the minimum frame", name, len(withFCS))
+		}
+		withoutFCS := withFCS[:len(withFCS)-FCSSize]
+		tests := []struct {
+			mode FCSMode
+			data []byte
+		}{
+			{FCSPresent, withFCS},
+			{FCSAbsent, withoutFCS},
+			{FCSAuto, withFCS},
+			{FCSAuto, withoutFCS},
+		}
+		for _, tt := range tests {
+			frame, err := DeserializeFrame(tt.data, tt.mode)
+			if err != nil {
+				t.Fatalf("%s %v %d bytes: %v", name, tt.mode, len(tt.data), err)
+			}
+			if !bytes.Equal(frame.DataPackage, payload) {
+				t.Errorf("%s %v %d bytes: DataPackage = %d bytes, want the %d byte payload",
+					name, tt.mode, len(tt.data), len(frame.DataPackage), len(payload))
+			}
+			if want := make([]byte, MinDataSize-len(payload)); !bytes.Equal(frame.Padding, want) {
+				t.Errorf("%s %v %d bytes: Padding = %x, want %d zero bytes", name, tt.mode, len(tt.data), frame.Padding, len(want))
+			}
+			if got := frame.Serialize(); !bytes.Equal(got, withFCS) {
+				t.Errorf("%s %v %d bytes: Serialize = %x, want %x", name, tt.mode, len(tt.data), got, withFCS)
+			}
+		}
+	}
+}
+
+// 抓包时可能去掉了填充与CRC, 不带CRC解码时补齐填充
+func TestDeserializeFrameUnpadded(t *testing.T) {
+	for name, payload := range shortFrames() {
+		protocol := "IP"
+		if name == "ARP" {
+			protocol = "ARP"
+		}
+		full := NewEthernet2(testMAC2, testMAC1, protocol, payload).Serialize()
+		data := full[:EthernetHeaderSize+len(payload)]
+		for _, mode := range []FCSMode{FCSAbsent, FCSAuto} {
+			frame, err := DeserializeFrame(data, mode)
+			if err != nil {
+				t.Fatalf("%s %v: %v", name, mode, err)
+			}
+			if !bytes.Equal(frame.DataPackage, payload) || len(frame.Padding) != MinDataSize-len(payload) {
+				t.Errorf("%s %v: DataPackage %d bytes, Padding %d bytes", name, mode, len(frame.DataPackage), len(frame.Padding))
+			}
+			if !bytes.Equal(frame.Serialize(), full) {
+				t.Errorf("%s %v: Serialize does not restore the padded frame", name, mode)
+			}
+		}
+	}
+}
+
+// 按CRC判断是否带FCS: CRC错误的帧按不带FCS处理, 最后4字节成为填充
+func TestDeserializeFrameAutoBadCRC(t *testing.T) {
+	payload := shortFrames()["ARP"]
+	data := NewEthernet2(testMAC2, testMAC1, "ARP", payload).Serialize()
+	data[len(data)-1] ^= 0xFF
+	frame, err := DeserializeFrame(data, FCSAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame.DataPackage, payload) {
+		t.Errorf("DataPackage = %x, want %x", frame.DataPackage, payload)
+	}
+	if want := len(data) - EthernetHeaderSize - len(payload); len(frame.Padding) != want {
+		t.Errorf("Padding = %d bytes, want %d including the bad CRC", len(frame.Padding), want)
+	}
+	if _, err := DeserializeFrame(data, FCSPresent); err == nil {
+		t.Error("FCSPresent accepted a bad CRC")
+	}
+}
+
+// 802.3 帧的长度字段大于或小于实际数据时分别报告
+func TestValidateIEEE8023Length(t *testing.T) {
+	llc := NewLLC(0x42, 0x42, 0x03, bytes.Repeat([]byte{0xaa}, 40))
+	size := uint16(len(llc.Serialize()))
+	tests := []struct {
+		length uint16
+		want   string
+	}{
+		{size, ""},
+		{size + 10, "长度字段 %d 超过数据的 %d 字节"},
+		{size - 10, "长度字段 %d 小于数据的 %d 字节"},
+	}
+	for _, tt := range tests {
+		frame := NewIEEE8023(testMAC2, testMAC1, llc)
+		frame.SetEtherType(tt.length)
+		var got []string
+		for _, f := range frame.Validate() {
+			if f.Field == "Length" {
+				got = append(got, f.Message)
+			}
+		}
+		if tt.want == "" {
+			if len(got) != 0 {
+				t.Errorf("length %d: %v, want no length finding", tt.length, got)
+			}
+			continue
+		}
+		if want := i18n.T(tt.want, tt.length, size); len(got) != 1 || got[0] != want {
+			t.Errorf("length %d: %v, want %q", tt.length, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package level
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// LLC IEEE 802.2 逻辑链路控制头部, 位于长度字段不大于1500的 802.3 帧中
+// IEEE 802.2 logical link control header, carried in 802.3 frames whose length field is at most 1500
+// [DSAP][SSAP][Control][OUI][PID][...DATA...], DSAP与SSAP都为0xAA时带SNAP头部
+type LLC struct {
+	// 目的服务访问点, 最低位为个体/组地址位 Destination service access point, LSB is the I/G bit
+	DSAP uint8
+	// 源服务访问点, 最低位为命令/响应位 Source service access point, LSB is the C/R bit
+	SSAP uint8
+	// 控制字段, U格式1字节, I格式与S格式2字节 Control: 1 byte for U format, 2 bytes for I and S formats
+	Control uint16
+	// SNAP 组织代码, 0表示PID为以太网类型 SNAP organization code, 0 means the PID is an EtherType
+	OUI [3]byte
+	// SNAP 协议标识 SNAP protocol identifier
+	PID uint16
+	// 数据 Data
+	Data []byte
+}
+
+// const LLC 常量
+const (
+	SAPNull    = 0x00 // 空服务访问点
+	SAPIP      = 0x06 // ARPANET IP
+	SAPSTP     = 0x42 // 生成树协议 BPDU
+	SAPSNAP    = 0xAA // 子网访问协议
+	SAPNetBIOS = 0xF0 // NetBIOS
+	SAPGlobal  = 0xFF // 全局DSAP
+
+	LLCControlUI   = 0x03 // 无编号信息帧
+	LLCHeaderSize  = 3    // U格式的LLC头部大小
+	SNAPHeaderSize = 5    // SNAP头部大小
+)
+
+// LLCFields U格式LLC头部字段布局, I格式与S格式的控制字段为16位
+// LLC header field layout for the U format; I and S formats have a 16-bit control field
+var LLCFields = []FieldSpec{
+	{Name: "DSAP", Bit: 0, Bits: 8, Format: formatSAP, Children: []FieldSpec{
+		{Name: "IG Bit", Bit: 7, Bits: 1},
+	}},
+	{Name: "SSAP", Bit: 8, Bits: 8, Format: formatSAP, Children: []FieldSpec{
+		{Name: "CR Bit", Bit: 15, Bits: 1},
+	}},
+	{Name: "Control", Bit: 16, Bits: 8, Format: formatLLCControl},
+}
+
+// SNAPFields SNAP头部字段布局, 位偏移相对LLC头部起始
+// SNAP header field layout, bit offsets relative to the start of the LLC header
+var SNAPFields = []FieldSpec{
+	{Name: "Organization Code", Bit: 24, Bits: 24, Format: formatHex},
+	{Name: "PID", Bit: 48, Bits: 16, Format: formatEtherType},
+}
+
+// NewLLC 新建LLC报文
+// @param dsap 目的服务访问点
+// @param ssap 源服务访问点
+// @param control 控制字段, 如 LLCControlUI
+// @param data 数据
+// @return *LLC
+func NewLLC(dsap, ssap uint8, control uint16, data []byte) *LLC {
+	return &LLC{DSAP: dsap, SSAP: ssap, Control: control, Data: data}
+}
+
+// NewSNAP 新建带SNAP头部的UI帧
+// @param oui 组织代码, 0表示 pid 为以太网类型(RFC 1042)
+// @param pid 协议标识
+// @param data 数据
+// @return *LLC
+func NewSNAP(oui [3]byte, pid uint16, data []byte) *LLC {
+	return &LLC{DSAP: SAPSNAP, SSAP: SAPSNAP, Control: LLCControlUI, OUI: oui, PID: pid, Data: data}
+}
+
+// IsSNAP 是否带SNAP头部 Whether a SNAP header follows
+func (l *LLC) IsSNAP() bool {
+	return l.DSAP == SAPSNAP && l.SSAP&0xFE == SAPSNAP
+}
+
+// HeaderSize LLC头部与SNAP头部的字节数 Size of the LLC and SNAP headers
+func (l *LLC) HeaderSize() int {
+	size := LLCHeaderSize
+	if !isUFormat(uint8(l.Control)) {
+		size++
+	}
+	if l.IsSNAP() {
+		size += SNAPHeaderSize
+	}
+	return size
+}
+
+// isUFormat 控制字段第一个字节的低2位为11时是U格式, 只有1字节
+func isUFormat(control uint8) bool {
+	return control&0x03 == 0x03
+}
+
+// Serialize 序列化LLC报文
+// Serialize the LLC PDU to []byte
+// @return []byte 序列化后的字节数组
+func (l *LLC) Serialize() []byte {
+	buf := make([]byte, l.HeaderSize()+len(l.Data))
+	buf[0], buf[1] = l.DSAP, l.SSAP
+	offset := 2
+	if isUFormat(uint8(l.Control)) {
+		buf[offset] = uint8(l.Control)
+		offset++
+	} else {
+		// I格式与S格式的控制字段按传输顺序先低字节
+		binary.LittleEndian.PutUint16(buf[offset:], l.Control)
+		offset += 2
+	}
+	if l.IsSNAP() {
+		copy(buf[offset:], l.OUI[:])
+		binary.BigEndian.PutUint16(buf[offset+3:], l.PID)
+		offset += SNAPHeaderSize
+	}
+	copy(buf[offset:], l.Data)
+	return buf
+}
+
+// DeserializeLLC 反序列化LLC报文, 数据为802.3长度字段所指的字节, 不含填充
+// Deserialize an LLC PDU from the bytes covered by the 802.3 length field
+// @param data 长度字段之后的数据
+// @return *LLC 反序列化后的LLC报文, 长度不足时为nil
+// @return error 反序列化错误
+func DeserializeLLC(data []byte) (*LLC, error) {
+	if len(data) < LLCHeaderSize {
+		return nil, truncated("LLC", 0, LLCHeaderSize, len(data))
+	}
+	l := &LLC{DSAP: data[0], SSAP: data[1], Control: uint16(data[2])}
+	if !isUFormat(data[2]) {
+		if len(data) < LLCHeaderSize+1 {
+			return nil, truncated("LLC", 2, LLCHeaderSize+1, len(data))
+		}
+		l.Control = binary.LittleEndian.Uint16(data[2:4])
+	}
+	if size := l.HeaderSize(); len(data) < size {
+		return nil, truncated("LLC", size-SNAPHeaderSize, size, len(data))
+	}
+	offset := l.HeaderSize()
+	if l.IsSNAP() {
+		copy(l.OUI[:], data[offset-SNAPHeaderSize:offset-2])
+		l.PID = binary.BigEndian.Uint16(data[offset-2 : offset])
+	}
+	l.Data = make([]byte, len(data)-offset)
+	copy(l.Data, data[offset:])
+	return l, nil
+}
+
+// Validate 检查LLC头部与SNAP头部是否符合规范
+// Validate the LLC and SNAP headers
+// @return Findings 诊断结果
+func (l *LLC) Validate() Findings {
+	c := &check{layer: "LLC"}
+	if l.DSAP&0x01 != 0 && !isUFormat(uint8(l.Control)) {
+		c.add(SeverityError, "DSAP", "IEEE 802.2 §3.3.1", "组地址 0x%02x 只能用于U格式帧, 实际为 %s", l.DSAP, llcControlName(l.Control))
+	}
+	if l.IsSNAP() {
+		if l.Control != LLCControlUI {
+			c.add(SeverityWarning, "Control", "RFC 1042", "SNAP 报文应使用UI帧, 实际为 %s", llcControlName(l.Control))
+		}
+		if l.OUI == [3]byte{} && l.PID < EtherTypeMin {
+			c.add(SeverityWarning, "PID", "RFC 1042", "组织代码为0时协议标识应为以太网类型, 实际为 0x%04x", l.PID)
+		}
+	} else if l.DSAP == SAPSNAP {
+		c.add(SeverityWarning, "SSAP", "RFC 1042", "DSAP 为SNAP时 SSAP 也应为 0xaa")
+	}
+	if l.DSAP == SAPSTP && l.Control != LLCControlUI {
+		c.add(SeverityWarning, "Control", "IEEE 802.1D §7.12.3", "BPDU 应使用UI帧, 实际为 %s", llcControlName(l.Control))
+	}
+	return c.done(logL2)
+}
+
+// llcControlName 控制字段的格式与名称, 如 UI、I N(S)=1 N(R)=2、RR N(R)=3
+func llcControlName(control uint16) string {
+	switch {
+	case control&0x01 == 0:
+		return fmt.Sprintf("I N(S)=%d N(R)=%d", control>>1&0x7F, control>>9)
+	case control&0x03 == 0x01:
+		name := [4]string{"RR", "RNR", "REJ", "S"}[control>>2&0x03]
+		return fmt.Sprintf("%s N(R)=%d", name, control>>9)
+	}
+	switch control &^ 0x10 {
+	case LLCControlUI:
+		return "UI"
+	case 0xAF:
+		return "XID"
+	case 0xE3:
+		return "TEST"
+	case 0x6F:
+		return "SABME"
+	case 0x43:
+		return "DISC"
+	case 0x63:
+		return "UA"
+	case 0x0F:
+		return "DM"
+	case 0x87:
+		return "FRMR"
+	}
+	return fmt.Sprintf("U 0x%02x", control)
+}
+
+// sapName 服务访问点名称, 不含最低位
+func sapName(sap uint8) string {
+	if sap == SAPGlobal {
+		return "Global"
+	}
+	name := map[uint8]string{SAPNull: "Null", SAPIP: "IP", SAPSTP: "Spanning Tree BPDU", SAPSNAP: "SNAP",
+		0xE0: "NetWare", SAPNetBIOS: "NetBIOS", 0xFE: "ISO Network Layer"}[sap&0xFE]
+	if name == "" {
+		name = "Unknown"
+	}
+	return name
+}
+
+// formatSAP 服务访问点
+func formatSAP(v uint64, raw []byte) string {
+	return fmt.Sprintf("%s (0x%02x)", sapName(uint8(v)), v)
+}
+
+// formatLLCControl 控制字段, 16位时按传输顺序先低字节
+func formatLLCControl(v uint64, raw []byte) string {
+	if len(raw) == 2 {
+		v = uint64(binary.LittleEndian.Uint16(raw))
+		return fmt.Sprintf("%s (0x%04x)", llcControlName(uint16(v)), v)
+	}
+	return fmt.Sprintf("%s (0x%02x)", llcControlName(uint16(v)), v)
+}
@@ -0,0 +1,149 @@
+package level
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// VLANTag IEEE 802.1Q 标签, 插在源MAC与类型字段之间
+// IEEE 802.1Q tag, inserted between the source MAC and the EtherType
+// [TPID][PCP|DEI|VID]
+type VLANTag struct {
+	// 标签协议标识, 0x8100 为 802.1Q, 0x88A8 为 802.1ad (QinQ) 外层标签
+	// Tag protocol identifier: 0x8100 for 802.1Q, 0x88A8 for the 802.1ad (QinQ) outer tag
+	TPID uint16
+	// 标签控制信息: 优先级3位, 可丢弃指示1位, VLAN编号12位
+	// Tag control information: PCP (3 bits), DEI (1 bit), VID (12 bits)
+	TCI uint16
+}
+
+// const 802.1Q 常量
+const (
+	EtherTypeVLAN = 0x8100 // 802.1Q 标签
+	EtherTypeQinQ = 0x88A8 // 802.1ad 服务商标签
+	VLANTagSize   = 4      // 标签大小
+	MaxVLAN       = 4094   // 最大可用VLAN编号, 0与4095保留
+)
+
+// VLANTagFields 802.1Q 标签字段布局, 本层从TCI开始, 包括内层类型字段
+// 802.1Q tag field layout; the layer starts at the TCI and includes the inner EtherType
+var VLANTagFields = []FieldSpec{
+	{Name: "Priority", Bit: 0, Bits: 3},
+	{Name: "DEI", Bit: 3, Bits: 1},
+	{Name: "ID", Bit: 4, Bits: 12},
+	{Name: "Type", Bit: 16, Bits: 16, Format: formatEtherType},
+}
+
+// NewVLANTag 新建标签
+// @param tpid 标签协议标识 EtherTypeVLAN 或 EtherTypeQinQ
+// @param pcp 优先级 0~7
+// @param dei 可丢弃指示
+// @param vid VLAN编号 0~4095
+// @return VLANTag
+func NewVLANTag(tpid uint16, pcp uint8, dei bool, vid uint16) VLANTag {
+	tci := uint16(pcp&0x07)<<13 | vid&0x0FFF
+	if dei {
+		tci |= 0x1000
+	}
+	return VLANTag{TPID: tpid, TCI: tci}
+}
+
+// PCP 优先级 Priority code point
+func (t VLANTag) PCP() uint8 {
+	return uint8(t.TCI >> 13)
+}
+
+// DEI 可丢弃指示 Drop eligible indicator
+func (t VLANTag) DEI() bool {
+	return t.TCI&0x1000 != 0
+}
+
+// VID VLAN编号, 0表示只带优先级的帧 VLAN identifier, 0 for priority-tagged frames
+func (t VLANTag) VID() uint16 {
+	return t.TCI & 0x0FFF
+}
+
+// String 如 vlan 10 或 vlan 10 p 5
+func (t VLANTag) String() string {
+	if t.PCP() != 0 {
+		return fmt.Sprintf("vlan %d p %d", t.VID(), t.PCP())
+	}
+	return fmt.Sprintf("vlan %d", t.VID())
+}
+
+// isTPID 类型字段是否为标签协议标识
+func isTPID(etherType uint16) bool {
+	return etherType == EtherTypeVLAN || etherType == EtherTypeQinQ
+}
+
+// FrameTags 读取帧中源MAC之后的标签, 不检查CRC, 供交换机转发与摘要使用
+// Read the tags after the source MAC without checking the CRC
+// @param frame 从目的MAC开始的帧, 至少14字节
+// @return []VLANTag 由外到内的标签
+// @return uint16 内层的上层协议类型
+// @return int 上层数据在帧中的偏移
+func FrameTags(frame []byte) ([]VLANTag, uint16, int) {
+	var tags []VLANTag
+	offset := 12
+	etherType := binary.BigEndian.Uint16(frame[offset : offset+2])
+	for isTPID(etherType) && len(frame) >= offset+VLANTagSize+2 {
+		tags = append(tags, VLANTag{TPID: etherType, TCI: binary.BigEndian.Uint16(frame[offset+2 : offset+4])})
+		offset += VLANTagSize
+		etherType = binary.BigEndian.Uint16(frame[offset : offset+2])
+	}
+	return tags, etherType, offset + 2
+}
+
+// VLANID 最外层标签的VLAN编号, 不带标签或只带优先级时为0
+// @return uint16 VLAN编号
+func (e *Ethernet2) VLANID() uint16 {
+	if len(e.Tags) == 0 {
+		return 0
+	}
+	return e.Tags[0].VID()
+}
+
+// PushTag 在最外层加上标签并重新计算CRC
+// @param tag 标签
+func (e *Ethernet2) PushTag(tag VLANTag) {
+	e.Tags = append([]VLANTag{tag}, e.Tags...)
+	e.generateCRC()
+}
+
+// PopTag 去掉最外层标签, 数据不足最小长度时补齐填充, 并重新计算CRC
+// @return VLANTag 去掉的标签
+// @return bool 帧不带标签时为false
+func (e *Ethernet2) PopTag() (VLANTag, bool) {
+	if len(e.Tags) == 0 {
+		return VLANTag{}, false
+	}
+	tag := e.Tags[0]
+	e.Tags = e.Tags[1:]
+	if n := len(e.DataPackage) + len(e.Padding); n < e.minDataSize() {
+		e.Padding = append(e.Padding, make([]byte, e.minDataSize()-n)...)
+	}
+	e.generateCRC()
+	return tag, true
+}
+
+// minDataSize 最小帧长不变, 每个标签使最小数据长度减少4字节
+func (e *Ethernet2) minDataSize() int {
+	return max(MinDataSize-VLANTagSize*len(e.Tags), 0)
+}
+
+// validateTags 检查标签, 见 IEEE 802.1Q §9.6
+func (e *Ethernet2) validateTags() Findings {
+	c := &check{layer: "802.1Q"}
+	for i, tag := range e.Tags {
+		if tag.VID() == 0x0FFF {
+			c.add(SeverityError, "ID", "IEEE 802.1Q §9.6", "VLAN编号 4095 保留, 不能用于帧中")
+		}
+		if i > 0 && tag.TPID == EtherTypeQinQ {
+			c.add(SeverityWarning, "Type", "IEEE 802.1Q §9.5", "802.1ad 标签应在最外层")
+		}
+	}
+	if len(e.Tags) > 2 {
+		c.add(SeverityInfo, "", "IEEE 802.1Q §9.5", "帧带 %d 层标签", len(e.Tags))
+	}
+	return c.done(logL2)
+}
@@ -0,0 +1,99 @@
+package level
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestVLANTagFields(t *testing.T) {
+	tests := []struct {
+		pcp uint8
+		dei bool
+		vid uint16
+		tci uint16
+	}{
+		{0, false, 1, 0x0001},
+		{5, false, 10, 0xA00A},
+		{7, true, MaxVLAN, 0xFFFE},
+		{3, true, 0, 0x7000},
+	}
+	for _, tt := range tests {
+		tag := NewVLANTag(EtherTypeVLAN, tt.pcp, tt.dei, tt.vid)
+		if tag.TCI != tt.tci {
+			t.Errorf("NewVLANTag(%d, %v, %d).TCI = 0x%04x, want 0x%04x", tt.pcp, tt.dei, tt.vid, tag.TCI, tt.tci)
+		}
+		if tag.PCP() != tt.pcp || tag.DEI() != tt.dei || tag.VID() != tt.vid {
+			t.Errorf("TCI 0x%04x decodes to pcp %d dei %v vid %d, want %d %v %d",
+				tag.TCI, tag.PCP(), tag.DEI(), tag.VID(), tt.pcp, tt.dei, tt.vid)
+		}
+	}
+}
+
+func TestVLANTagRoundTrip(t *testing.T) {
+	payload := NewARPPacket(1, testMAC1, testIP1, [6]byte{}, testIP2).Serialize()
+	frame := NewEthernet2(testMAC2, testMAC1, "ARP", payload)
+	frame.PushTag(NewVLANTag(EtherTypeVLAN, 5, true, 10))
+	data := frame.Serialize()
+	if got := binary.BigEndian.Uint16(data[12:14]); got != EtherTypeVLAN {
+		t.Fatalf("TPID = 0x%04x, want 0x8100", got)
+	}
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Tags) != 1 || decoded.Tags[0] != frame.Tags[0] {
+		t.Fatalf("Tags = %+v, want %+v", decoded.Tags, frame.Tags)
+	}
+	if decoded.VLANID() != 10 || decoded.EtherType() != EtherTypeARP {
+		t.Errorf("VLANID %d EtherType 0x%04x, want 10 and ARP", decoded.VLANID(), decoded.EtherType())
+	}
+	if !bytes.Equal(decoded.DataPackage, payload) {
+		t.Errorf("DataPackage = %x, want %x", decoded.DataPackage, payload)
+	}
+}
+
+func TestQinQPushPop(t *testing.T) {
+	payload := NewARPPacket(1, testMAC1, testIP1, [6]byte{}, testIP2).Serialize()
+	frame := NewEthernet2(testMAC2, testMAC1, "ARP", payload)
+	untagged := frame.Serialize()
+	inner := NewVLANTag(EtherTypeVLAN, 0, false, 10)
+	outer := NewVLANTag(EtherTypeQinQ, 3, false, 100)
+	frame.PushTag(inner)
+	frame.PushTag(outer)
+	data := frame.Serialize()
+	if got := binary.BigEndian.Uint16(data[12:14]); got != EtherTypeQinQ {
+		t.Errorf("outer TPID = 0x%04x, want 0x88a8", got)
+	}
+	if got := binary.BigEndian.Uint16(data[16:18]); got != EtherTypeVLAN {
+		t.Errorf("inner TPID = 0x%04x, want 0x8100", got)
+	}
+	tags, etherType, offset := FrameTags(data)
+	if len(tags) != 2 || tags[0] != outer || tags[1] != inner || etherType != EtherTypeARP || offset != 22 {
+		t.Errorf("FrameTags = %+v 0x%04x %d, want [outer inner] ARP 22", tags, etherType, offset)
+	}
+	decoded, err := Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.VLANID() != 100 || !bytes.Equal(decoded.DataPackage, payload) {
+		t.Errorf("VLANID %d DataPackage %x", decoded.VLANID(), decoded.DataPackage)
+	}
+
+	// 依次去掉外层与内层标签, 每一步都是合法的最小帧
+	for _, want := range []VLANTag{outer, inner} {
+		tag, ok := decoded.PopTag()
+		if !ok || tag != want {
+			t.Fatalf("PopTag = %+v %v, want %+v", tag, ok, want)
+		}
+		if _, err := Deserialize(decoded.Serialize()); err != nil {
+			t.Errorf("after popping %v: %v", tag, err)
+		}
+	}
+	if _, ok := decoded.PopTag(); ok {
+		t.Error("PopTag on an untagged frame returned true")
+	}
+	if got := decoded.Serialize(); !bytes.Equal(got, untagged) {
+		t.Errorf("untagged frame = %x, want %x", got, untagged)
+	}
+}
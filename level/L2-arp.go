@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // ARP 报文结构体
@@ -31,6 +30,20 @@ type ARPPacket struct {
 	TargetIP [4]byte
 }
 
+// ARPFields ARP 报文字段布局
+// ARP packet field layout
+var ARPFields = []FieldSpec{
+	{Name: "Hardware type", Bit: 0, Bits: 16},
+	{Name: "Protocol type", Bit: 16, Bits: 16, Format: formatEtherType},
+	{Name: "Hardware size", Bit: 32, Bits: 8},
+	{Name: "Protocol size", Bit: 40, Bits: 8},
+	{Name: "Opcode", Bit: 48, Bits: 16, Format: formatARPOp},
+	{Name: "Sender MAC address", Bit: 64, Bits: 48, Format: formatMAC},
+	{Name: "Sender IP address", Bit: 112, Bits: 32, Format: formatIPv4Field},
+	{Name: "Target MAC address", Bit: 144, Bits: 48, Format: formatMAC},
+	{Name: "Target IP address", Bit: 192, Bits: 32, Format: formatIPv4Field},
+}
+
 // NewARPPacket 新建 ARP 报文
 // New ARP Packet
 // @param op 操作码 Operation code
@@ -75,7 +88,7 @@ func (a *ARPPacket) Serialize() []byte {
 // @return *ARPPacket, error
 func DeserializeARPPacket(data []byte) (*ARPPacket, error) {
 	if len(data) < 28 {
-		return nil, errors.New("数据长度不足，不是有效的ARP报文 / Data too short, not a valid ARP packet")
+		return nil, truncated("ARP", 0, 28, len(data))
 	}
 	arp := &ARPPacket{}
 	arp.HardwareType = binary.BigEndian.Uint16(data[0:2])
@@ -90,8 +103,38 @@ func DeserializeARPPacket(data []byte) (*ARPPacket, error) {
 	return arp, nil
 }
 
-// IsValid 检查 ARP 报文是否合法
-// Check if ARP packet is valid
-func (a *ARPPacket) IsValid() bool {
-	return a.HardwareType == 1 && a.ProtocolType == 0x0800 && a.HardwareAddrLen == 6 && a.ProtocolAddrLen == 4
+// Validate 检查 ARP 报文各字段是否符合规范
+// Validate ARP packet fields
+// @return Findings 诊断结果
+func (a *ARPPacket) Validate() Findings {
+	c := &check{layer: "ARP"}
+	if a.HardwareType != 1 {
+		c.add(SeverityError, "Hardware type", "RFC 826", "硬件类型 %d 不是以太网(1)", a.HardwareType)
+	}
+	if a.ProtocolType != EtherTypeIPv4 {
+		c.add(SeverityError, "Protocol type", "RFC 826", "协议类型 0x%04x 不是IPv4(0x0800)", a.ProtocolType)
+	}
+	if a.HardwareAddrLen != 6 {
+		c.add(SeverityError, "Hardware size", "RFC 826", "硬件地址长度 %d 与以太网地址的 6 字节不符", a.HardwareAddrLen)
+	}
+	if a.ProtocolAddrLen != 4 {
+		c.add(SeverityError, "Protocol size", "RFC 826", "协议地址长度 %d 与IPv4地址的 4 字节不符", a.ProtocolAddrLen)
+	}
+	switch a.Operation {
+	case 1, 2:
+	case 3, 4:
+		c.add(SeverityWarning, "Opcode", "RFC 903", "操作码 %d 属于RARP, 不是ARP", a.Operation)
+	default:
+		c.add(SeverityError, "Opcode", "RFC 826", "未定义的操作码 %d", a.Operation)
+	}
+	if a.SenderMAC[0]&0x01 != 0 {
+		c.add(SeverityError, "Sender MAC address", "RFC 1812 §3.3.2", "发送方MAC地址 %s 是组播地址", FormatMAC(a.SenderMAC))
+	}
+	switch {
+	case a.SenderIP == [4]byte{} && a.Operation == 1:
+		c.add(SeverityInfo, "Sender IP address", "RFC 5227 §2.1.1", "发送方IP为0.0.0.0, 这是地址冲突探测")
+	case a.SenderIP == a.TargetIP:
+		c.add(SeverityInfo, "Target IP address", "RFC 5227 §3", "发送方与目标IP相同, 这是免费ARP")
+	}
+	return c.done(logL2)
 }
@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // NDP 邻居发现协议报文结构体
@@ -54,7 +53,7 @@ func (n *NDPPacket) Serialize() []byte {
 // Deserialize []byte to NDP packet
 func DeserializeNDPPacket(data []byte) (*NDPPacket, error) {
 	if len(data) < 24 {
-		return nil, errors.New("数据长度不足，不是有效的NDP报文 / Data too short, not a valid NDP packet")
+		return nil, truncated("NDP", 0, 24, len(data))
 	}
 	n := &NDPPacket{}
 	n.Type = data[0]
@@ -69,8 +68,36 @@ func DeserializeNDPPacket(data []byte) (*NDPPacket, error) {
 	return n, nil
 }
 
-// IsValid 检查 NDP 报文是否合法
-// Check if NDP packet is valid
-func (n *NDPPacket) IsValid() bool {
-	return n.Type >= 133 && n.Type <= 136 // 133~136为NDP相关类型
+// Validate 检查 NDP 报文各字段是否符合规范
+// Validate NDP packet fields
+// @return Findings 诊断结果
+func (n *NDPPacket) Validate() Findings {
+	c := &check{layer: "NDP"}
+	// 133~137 依次为路由器请求/通告、邻居请求/通告、重定向
+	if n.Type < 133 || n.Type > 137 {
+		c.add(SeverityError, "Type", "RFC 4861 §4", "类型 %d 不是NDP报文(133~137)", n.Type)
+	}
+	if n.Code != 0 {
+		c.add(SeverityError, "Code", "RFC 4861 §7.1.1", "代码 %d 必须为0", n.Code)
+	}
+	reserved := n.Reserved
+	if n.Type == 136 {
+		// 邻居通告的前3位为 R/S/O 标志
+		reserved &= 0x1FFFFFFF
+	}
+	if reserved != 0 {
+		c.add(SeverityWarning, "Reserved", "RFC 4861 §4.4", "保留字段 0x%08x 应为0", n.Reserved)
+	}
+	if (n.Type == 135 || n.Type == 136) && n.TargetAddress[0] == 0xFF {
+		c.add(SeverityError, "Target Address", "RFC 4861 §7.1.1", "目标地址不能是组播地址")
+	}
+	for i := 0; i+2 <= len(n.Options); {
+		size := int(n.Options[i+1]) * 8
+		if size == 0 {
+			c.add(SeverityError, "Options", "RFC 4861 §4.6", "第 %d 字节处的选项长度为0", i)
+			break
+		}
+		i += size
+	}
+	return c.done(logL3)
 }
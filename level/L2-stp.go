@@ -0,0 +1,322 @@
+package level
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BridgeID 网桥标识, 高16位为优先级, 低48位为MAC地址, 数值越小越优先
+// Bridge identifier: 16-bit priority followed by the 48-bit MAC address, lower is better
+type BridgeID uint64
+
+// NewBridgeID 由优先级与MAC地址组成网桥标识
+// @param priority 优先级, 含VLAN扩展
+// @param mac 网桥MAC地址
+// @return BridgeID
+func NewBridgeID(priority uint16, mac [6]byte) BridgeID {
+	var b [8]byte
+	binary.BigEndian.PutUint16(b[0:2], priority)
+	copy(b[2:], mac[:])
+	return BridgeID(binary.BigEndian.Uint64(b[:]))
+}
+
+// Priority 优先级 Bridge priority
+func (id BridgeID) Priority() uint16 {
+	return uint16(id >> 48)
+}
+
+// MAC 网桥MAC地址 Bridge MAC address
+func (id BridgeID) MAC() [6]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id))
+	return [6]byte(b[2:])
+}
+
+// String 如 32768/02:b0:00:00:00:01
+func (id BridgeID) String() string {
+	return fmt.Sprintf("%d/%s", id.Priority(), FormatMAC(id.MAC()))
+}
+
+// ParseBridgeID 解析 优先级/MAC 形式的网桥标识
+// @param s 如 4096/02:b0:00:00:00:01
+// @return BridgeID, error
+func ParseBridgeID(s string) (BridgeID, error) {
+	prio, mac, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, badAddress("网桥标识格式应为 优先级/MAC: %q", s)
+	}
+	p, err := strconv.ParseUint(prio, 0, 16)
+	if err != nil {
+		return 0, badAddress("网桥标识格式应为 优先级/MAC: %q", s)
+	}
+	m, err := ParseMAC(mac)
+	if err != nil {
+		return 0, err
+	}
+	return NewBridgeID(uint16(p), m), nil
+}
+
+// const BPDU 常量
+const (
+	BPDUTypeConfig = 0x00 // 配置BPDU
+	BPDUTypeRST    = 0x02 // 快速生成树BPDU
+	BPDUTypeTCN    = 0x80 // 拓扑变化通知BPDU
+
+	STPVersion  = 0 // 802.1D 生成树协议
+	RSTPVersion = 2 // 802.1w 快速生成树协议
+
+	ConfigBPDUSize = 35 // 配置BPDU大小
+	RSTBPDUSize    = 36 // RST BPDU大小
+	TCNBPDUSize    = 4  // 拓扑变化通知BPDU大小
+)
+
+// const BPDU 标志位, 低位在前
+// BPDU flag bits, least significant first
+const (
+	BPDUFlagTC        = 0x01 // 拓扑变化
+	BPDUFlagProposal  = 0x02 // 提议, 仅RST
+	BPDUFlagRoleMask  = 0x0C // 端口角色, 仅RST
+	BPDUFlagLearning  = 0x10 // 学习, 仅RST
+	BPDUFlagForward   = 0x20 // 转发, 仅RST
+	BPDUFlagAgreement = 0x40 // 同意, 仅RST
+	BPDUFlagTCAck     = 0x80 // 拓扑变化确认
+)
+
+// const RST BPDU 标志中的端口角色
+const (
+	BPDURoleUnknown    = 0 << 2
+	BPDURoleAlternate  = 1 << 2 // 替代端口或备份端口
+	BPDURoleRoot       = 2 << 2
+	BPDURoleDesignated = 3 << 2
+)
+
+// STPMulticast 网桥组地址, BPDU 的目的MAC地址
+var STPMulticast = [6]byte{0x01, 0x80, 0xC2, 0x00, 0x00, 0x00}
+
+// BPDU 生成树协议的网桥协议数据单元, 由LLC承载, DSAP与SSAP为0x42
+// Bridge protocol data unit, carried in LLC with DSAP and SSAP 0x42
+// [协议标识][版本][类型][标志][根桥][根路径开销][网桥][端口][消息年龄][最大老化时间][Hello时间][转发延迟][版本1长度]
+type BPDU struct {
+	// 协议标识, 恒为0 Protocol identifier, always 0
+	ProtocolID uint16
+	// 版本, 0为STP, 2为RSTP Protocol version
+	Version uint8
+	// 类型 BPDUTypeConfig、BPDUTypeRST 或 BPDUTypeTCN BPDU type
+	Type uint8
+	// 标志 Flags
+	Flags uint8
+	// 根桥 Root bridge identifier
+	RootID BridgeID
+	// 到根桥的路径开销 Root path cost
+	RootPathCost uint32
+	// 发送方网桥 Sending bridge identifier
+	BridgeID BridgeID
+	// 发送端口, 高4位为优先级 Sending port identifier, priority in the top 4 bits
+	PortID uint16
+	// 以下计时器在报文中以1/256秒为单位 Timers below are sent in units of 1/256 s
+	// 消息年龄, 从根桥发出以来经过的时间 Message age since the root sent it
+	MessageAge time.Duration
+	// 最大老化时间 Max age
+	MaxAge time.Duration
+	// Hello时间 Hello time
+	HelloTime time.Duration
+	// 转发延迟 Forward delay
+	ForwardDelay time.Duration
+}
+
+// BPDUFields 配置BPDU与RST BPDU的字段布局, TCN BPDU只有前3个字段
+// Field layout of configuration and RST BPDUs; a TCN BPDU has only the first three fields
+var BPDUFields = []FieldSpec{
+	{Name: "Protocol Identifier", Bit: 0, Bits: 16, Format: formatHex},
+	{Name: "Protocol Version Identifier", Bit: 16, Bits: 8},
+	{Name: "BPDU Type", Bit: 24, Bits: 8, Format: formatBPDUType},
+	{Name: "BPDU flags", Bit: 32, Bits: 8, Format: formatHex, Children: []FieldSpec{
+		{Name: "Topology Change Acknowledgment", Bit: 32, Bits: 1, Format: formatFlag},
+		{Name: "Agreement", Bit: 33, Bits: 1, Format: formatFlag},
+		{Name: "Forwarding", Bit: 34, Bits: 1, Format: formatFlag},
+		{Name: "Learning", Bit: 35, Bits: 1, Format: formatFlag},
+		{Name: "Port Role", Bit: 36, Bits: 2, Format: formatBPDURole},
+		{Name: "Proposal", Bit: 38, Bits: 1, Format: formatFlag},
+		{Name: "Topology Change", Bit: 39, Bits: 1, Format: formatFlag},
+	}},
+	{Name: "Root Identifier", Bit: 40, Bits: 64, Format: formatBridgeID},
+	{Name: "Root Path Cost", Bit: 104, Bits: 32},
+	{Name: "Bridge Identifier", Bit: 136, Bits: 64, Format: formatBridgeID},
+	{Name: "Port identifier", Bit: 200, Bits: 16, Format: formatHex},
+	{Name: "Message Age", Bit: 216, Bits: 16, Format: formatBPDUTime},
+	{Name: "Max Age", Bit: 232, Bits: 16, Format: formatBPDUTime},
+	{Name: "Hello Time", Bit: 248, Bits: 16, Format: formatBPDUTime},
+	{Name: "Forward Delay", Bit: 264, Bits: 16, Format: formatBPDUTime},
+	{Name: "Version 1 Length", Bit: 280, Bits: 8},
+}
+
+// Size 按类型得出的BPDU大小 BPDU size by type
+func (b *BPDU) Size() int {
+	switch b.Type {
+	case BPDUTypeTCN:
+		return TCNBPDUSize
+	case BPDUTypeRST:
+		return RSTBPDUSize
+	default:
+		return ConfigBPDUSize
+	}
+}
+
+// Serialize 序列化BPDU
+// Serialize the BPDU to []byte
+// @return []byte 序列化后的字节数组
+func (b *BPDU) Serialize() []byte {
+	buf := make([]byte, b.Size())
+	binary.BigEndian.PutUint16(buf[0:2], b.ProtocolID)
+	buf[2], buf[3] = b.Version, b.Type
+	if b.Type == BPDUTypeTCN {
+		return buf
+	}
+	buf[4] = b.Flags
+	binary.BigEndian.PutUint64(buf[5:13], uint64(b.RootID))
+	binary.BigEndian.PutUint32(buf[13:17], b.RootPathCost)
+	binary.BigEndian.PutUint64(buf[17:25], uint64(b.BridgeID))
+	binary.BigEndian.PutUint16(buf[25:27], b.PortID)
+	for i, d := range []time.Duration{b.MessageAge, b.MaxAge, b.HelloTime, b.ForwardDelay} {
+		binary.BigEndian.PutUint16(buf[27+2*i:], uint16(d*256/time.Second))
+	}
+	// RST BPDU 的版本1长度恒为0
+	return buf
+}
+
+// DeserializeBPDU 反序列化BPDU, 按类型字段确定长度
+// Deserialize a BPDU whose length follows from its type
+// @param data LLC头部之后的数据
+// @return *BPDU 反序列化后的BPDU, 长度不足时为nil
+// @return error 反序列化错误
+func DeserializeBPDU(data []byte) (*BPDU, error) {
+	if len(data) < TCNBPDUSize {
+		return nil, truncated("STP", 0, TCNBPDUSize, len(data))
+	}
+	b := &BPDU{ProtocolID: binary.BigEndian.Uint16(data[0:2]), Version: data[2], Type: data[3]}
+	if b.Type == BPDUTypeTCN {
+		return b, nil
+	}
+	if len(data) < b.Size() {
+		return nil, truncated("STP", TCNBPDUSize, b.Size(), len(data))
+	}
+	b.Flags = data[4]
+	b.RootID = BridgeID(binary.BigEndian.Uint64(data[5:13]))
+	b.RootPathCost = binary.BigEndian.Uint32(data[13:17])
+	b.BridgeID = BridgeID(binary.BigEndian.Uint64(data[17:25]))
+	b.PortID = binary.BigEndian.Uint16(data[25:27])
+	for i, d := range []*time.Duration{&b.MessageAge, &b.MaxAge, &b.HelloTime, &b.ForwardDelay} {
+		*d = time.Duration(binary.BigEndian.Uint16(data[27+2*i:])) * time.Second / 256
+	}
+	return b, nil
+}
+
+// Validate 检查BPDU的编码与计时器是否符合规范
+// Validate the BPDU encoding and timer values
+// @return Findings 诊断结果
+func (b *BPDU) Validate() Findings {
+	c := &check{layer: "STP"}
+	if b.ProtocolID != 0 {
+		c.add(SeverityError, "Protocol Identifier", "IEEE 802.1D §9.3", "协议标识应为0, 实际为 0x%04x", b.ProtocolID)
+	}
+	switch b.Type {
+	case BPDUTypeConfig, BPDUTypeTCN:
+	case BPDUTypeRST:
+		if b.Version < RSTPVersion {
+			c.add(SeverityError, "Protocol Version Identifier", "IEEE 802.1D §9.3.3", "RST BPDU 的版本应不小于 %d, 实际为 %d", RSTPVersion, b.Version)
+		}
+	default:
+		c.add(SeverityError, "BPDU Type", "IEEE 802.1D §9.3", "未定义的BPDU类型 0x%02x", b.Type)
+	}
+	if b.Type == BPDUTypeTCN {
+		return c.done(logL2)
+	}
+	if b.Type == BPDUTypeConfig && b.Flags&^(BPDUFlagTC|BPDUFlagTCAck) != 0 {
+		c.add(SeverityWarning, "BPDU flags", "IEEE 802.1D §9.3.1", "配置BPDU只使用拓扑变化与拓扑变化确认标志, 实际为 0x%02x", b.Flags)
+	}
+	if b.MessageAge >= b.MaxAge {
+		c.add(SeverityWarning, "Message Age", "IEEE 802.1D §17.14", "消息年龄 %s 已达最大老化时间 %s, 接收方会丢弃", b.MessageAge, b.MaxAge)
+	}
+	if 2*(b.ForwardDelay-time.Second) < b.MaxAge || b.MaxAge < 2*(b.HelloTime+time.Second) {
+		c.add(SeverityWarning, "Max Age", "IEEE 802.1D §17.14", "计时器应满足 2×(转发延迟−1秒) ≥ 最大老化时间 ≥ 2×(Hello时间+1秒)")
+	}
+	return c.done(logL2)
+}
+
+// Summary 如 RST root 32768/02:b0:00:00:00:01 cost 19 port 0x8001 [Proposal, Designated]
+func (b *BPDU) Summary() string {
+	if b.Type == BPDUTypeTCN {
+		return "STP TCN"
+	}
+	kind := "STP Conf."
+	if b.Type == BPDUTypeRST {
+		kind = "RSTP RST."
+	}
+	s := fmt.Sprintf("%s root %s cost %d bridge %s port 0x%04x", kind, b.RootID, b.RootPathCost, b.BridgeID, b.PortID)
+	if flags := bpduFlagNames(b.Type, b.Flags); flags != "" {
+		s += " [" + flags + "]"
+	}
+	return s
+}
+
+// bpduFlagNames 标志位名称, 配置BPDU不列出端口角色
+func bpduFlagNames(typ, flags uint8) string {
+	var names []string
+	for _, f := range []struct {
+		bit  uint8
+		name string
+	}{
+		{BPDUFlagTC, "TC"}, {BPDUFlagProposal, "Proposal"}, {BPDUFlagLearning, "Learning"},
+		{BPDUFlagForward, "Forwarding"}, {BPDUFlagAgreement, "Agreement"}, {BPDUFlagTCAck, "TCAck"},
+	} {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	if typ == BPDUTypeRST {
+		names = append(names, bpduRoleName(flags&BPDUFlagRoleMask))
+	}
+	return strings.Join(names, ", ")
+}
+
+// bpduRoleName RST BPDU 标志中的端口角色名称
+func bpduRoleName(role uint8) string {
+	switch role {
+	case BPDURoleAlternate:
+		return "Alternate/Backup"
+	case BPDURoleRoot:
+		return "Root"
+	case BPDURoleDesignated:
+		return "Designated"
+	default:
+		return "Unknown"
+	}
+}
+
+// formatBPDUType BPDU类型
+func formatBPDUType(v uint64, raw []byte) string {
+	name := map[uint64]string{BPDUTypeConfig: "Configuration", BPDUTypeRST: "Rapid/Multiple Spanning Tree",
+		BPDUTypeTCN: "Topology Change Notification"}[v]
+	if name == "" {
+		name = "Unknown"
+	}
+	return fmt.Sprintf("%s (0x%02x)", name, v)
+}
+
+// formatBPDURole 端口角色, v 为2位的角色值
+func formatBPDURole(v uint64, raw []byte) string {
+	return fmt.Sprintf("%s (%d)", bpduRoleName(uint8(v)<<2), v)
+}
+
+// formatBridgeID 网桥标识
+func formatBridgeID(v uint64, raw []byte) string {
+	return BridgeID(v).String()
+}
+
+// formatBPDUTime 以1/256秒为单位的计时器
+func formatBPDUTime(v uint64, raw []byte) string {
+	return (time.Duration(v) * time.Second / 256).String()
+}
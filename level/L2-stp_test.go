@@ -0,0 +1,88 @@
+package level
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBPDURoundTrip(t *testing.T) {
+	root := NewBridgeID(4096, [6]byte{0x02, 0xb0, 0, 0, 0, 1})
+	bridge := NewBridgeID(32768, [6]byte{0x02, 0xb0, 0, 0, 0, 2})
+	config := BPDU{Version: STPVersion, Type: BPDUTypeConfig, Flags: BPDUFlagTC | BPDUFlagTCAck,
+		RootID: root, RootPathCost: 19, BridgeID: bridge, PortID: 0x8002,
+		MessageAge: time.Second, MaxAge: 20 * time.Second, HelloTime: 2 * time.Second, ForwardDelay: 15 * time.Second}
+	rst := config
+	rst.Version, rst.Type = RSTPVersion, BPDUTypeRST
+	rst.Flags = BPDUFlagProposal | BPDURoleDesignated | BPDUFlagLearning | BPDUFlagForward
+	// 计时器以1/256秒为单位, 可以表示小数秒
+	rst.MessageAge = 3 * time.Second / 2
+	tcn := BPDU{Type: BPDUTypeTCN}
+	tests := []struct {
+		name string
+		bpdu BPDU
+		size int
+	}{
+		{"config", config, ConfigBPDUSize},
+		{"RST", rst, RSTBPDUSize},
+		{"TCN", tcn, TCNBPDUSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.bpdu.Serialize()
+			if len(data) != tt.size || tt.bpdu.Size() != tt.size {
+				t.Fatalf("serialized %d bytes, Size %d, want %d", len(data), tt.bpdu.Size(), tt.size)
+			}
+			got, err := DeserializeBPDU(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.bpdu {
+				t.Errorf("round trip = %+v, want %+v", *got, tt.bpdu)
+			}
+			if !got.Validate().Valid() {
+				t.Errorf("Validate: %v", got.Validate())
+			}
+			if _, err := DeserializeBPDU(data[:len(data)-1]); err == nil {
+				t.Error("accepted a truncated BPDU")
+			}
+		})
+	}
+}
+
+func TestBPDUInFrame(t *testing.T) {
+	bpdu := &BPDU{Version: RSTPVersion, Type: BPDUTypeRST, Flags: BPDURoleRoot | BPDUFlagAgreement,
+		RootID: NewBridgeID(4096, testMAC1), BridgeID: NewBridgeID(32768, testMAC2), PortID: 0x8001,
+		MaxAge: 20 * time.Second, HelloTime: 2 * time.Second, ForwardDelay: 15 * time.Second}
+	frame := NewIEEE8023(STPMulticast, testMAC1, NewLLC(0x42, 0x42, 0x03, bpdu.Serialize())).Serialize()
+	eth, err := Deserialize(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llc, err := DeserializeLLC(eth.DataPackage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DeserializeBPDU(llc.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *bpdu {
+		t.Errorf("BPDU = %+v, want %+v", *got, *bpdu)
+	}
+}
+
+func TestParseBridgeID(t *testing.T) {
+	id := NewBridgeID(8192, [6]byte{0x02, 0xb0, 0, 0, 0, 3})
+	if id.Priority() != 8192 || id.MAC() != [6]byte{0x02, 0xb0, 0, 0, 0, 3} {
+		t.Errorf("Priority %d MAC %v", id.Priority(), id.MAC())
+	}
+	got, err := ParseBridgeID(id.String())
+	if err != nil || got != id {
+		t.Errorf("ParseBridgeID(%q) = %v, %v", id.String(), got, err)
+	}
+	for _, s := range []string{"8192", "x/02:b0:00:00:00:03", "8192/zz"} {
+		if _, err := ParseBridgeID(s); err == nil {
+			t.Errorf("ParseBridgeID(%q) accepted", s)
+		}
+	}
+}
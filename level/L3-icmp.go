@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // ICMP 报文结构体
@@ -25,6 +24,25 @@ type ICMPPacket struct {
 	Data []byte
 }
 
+// const ICMP 类型
+// ICMP types
+const (
+	ICMPTypeEchoReply    = 0
+	ICMPTypeUnreachable  = 3
+	ICMPTypeEchoRequest  = 8
+	ICMPTypeTimeExceeded = 11
+)
+
+// ICMPFields ICMP 回显报文头部字段布局, 其他类型只有前三个字段
+// ICMP echo header field layout, other types share the first three fields
+var ICMPFields = []FieldSpec{
+	{Name: "Type", Bit: 0, Bits: 8, Format: formatICMPType},
+	{Name: "Code", Bit: 8, Bits: 8},
+	{Name: "Checksum", Bit: 16, Bits: 16, Format: formatHex},
+	{Name: "Identifier", Bit: 32, Bits: 16},
+	{Name: "Sequence Number", Bit: 48, Bits: 16},
+}
+
 // NewICMPPacket 新建 ICMP 报文
 // New ICMP Packet
 func NewICMPPacket(typ, code uint8, id, seq uint16, data []byte) *ICMPPacket {
@@ -56,7 +74,7 @@ func (icmp *ICMPPacket) Serialize() []byte {
 // Deserialize []byte to ICMP packet
 func DeserializeICMPPacket(data []byte) (*ICMPPacket, error) {
 	if len(data) < 8 {
-		return nil, errors.New("数据长度不足，不是有效的ICMP报文 / Data too short, not a valid ICMP packet")
+		return nil, truncated("ICMP", 0, 8, len(data))
 	}
 	icmp := &ICMPPacket{}
 	icmp.Type = data[0]
@@ -68,13 +86,45 @@ func DeserializeICMPPacket(data []byte) (*ICMPPacket, error) {
 		icmp.Data = make([]byte, len(data)-8)
 		copy(icmp.Data, data[8:])
 	}
+	// 校验和置0后重新计算
+	msg := append([]byte(nil), data...)
+	msg[2], msg[3] = 0, 0
+	if want := calcICMPChecksum(msg); want != icmp.Checksum {
+		return icmp, &ErrBadChecksum{Layer: "ICMP", Field: "Checksum", Offset: 2,
+			Want: uint32(want), Got: uint32(icmp.Checksum)}
+	}
 	return icmp, nil
 }
 
-// IsValid 检查 ICMP 报文是否合法
-// Check if ICMP packet is valid
-func (icmp *ICMPPacket) IsValid() bool {
-	return icmp.Type <= 255 && icmp.Code <= 255
+// Validate 检查 ICMP 报文的类型与代码是否符合规范
+// Validate ICMP type and code
+// @return Findings 诊断结果
+func (icmp *ICMPPacket) Validate() Findings {
+	c := &check{layer: "ICMP"}
+	switch icmp.Type {
+	case ICMPTypeEchoReply, ICMPTypeEchoRequest:
+		if icmp.Code != 0 {
+			c.add(SeverityError, "Code", "RFC 792", "回显报文的代码 %d 必须为0", icmp.Code)
+		}
+	case ICMPTypeUnreachable:
+		if icmp.Code > 15 {
+			c.add(SeverityWarning, "Code", "RFC 1812 §5.2.7.1", "未定义的目的不可达代码 %d", icmp.Code)
+		}
+	case ICMPTypeTimeExceeded:
+		if icmp.Code > 1 {
+			c.add(SeverityError, "Code", "RFC 792", "未定义的超时代码 %d", icmp.Code)
+		}
+	case 4, 5, 9, 10, 12, 13, 14:
+	default:
+		c.add(SeverityWarning, "Type", "RFC 792", "未定义的类型 %d", icmp.Type)
+	}
+	// 差错报文应带回原报文的IP头部与前8字节
+	if icmp.Type == ICMPTypeUnreachable || icmp.Type == ICMPTypeTimeExceeded {
+		if len(icmp.Data) < 28 {
+			c.add(SeverityWarning, "Data", "RFC 792", "差错报文只带回 %d 字节, 应至少包含原IP头部与 8 字节数据", len(icmp.Data))
+		}
+	}
+	return c.done(logL3)
 }
 
 // calcICMPChecksum 计算ICMP校验和
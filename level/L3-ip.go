@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // IPv4 报文结构体
@@ -37,11 +36,40 @@ type IPv4Packet struct {
 	Data []byte
 }
 
+// const IPv4 上层协议号
+// IPv4 protocol numbers
+const (
+	IPProtocolICMP = 1
+	IPProtocolTCP  = 6
+	IPProtocolUDP  = 17
+)
+
+// IPv4Fields IPv4 头部字段布局(不含选项)
+// IPv4 header field layout (without options)
+var IPv4Fields = []FieldSpec{
+	{Name: "Version", Bit: 0, Bits: 4},
+	{Name: "Header Length", Bit: 4, Bits: 4, Format: formatHeaderLen},
+	{Name: "Type of Service", Bit: 8, Bits: 8, Format: formatHex},
+	{Name: "Total Length", Bit: 16, Bits: 16},
+	{Name: "Identification", Bit: 32, Bits: 16, Format: formatHex},
+	{Name: "Flags", Bit: 48, Bits: 3, Format: formatIPFlags, Children: []FieldSpec{
+		{Name: "Reserved bit", Bit: 48, Bits: 1, Format: formatFlag},
+		{Name: "Don't fragment", Bit: 49, Bits: 1, Format: formatFlag},
+		{Name: "More fragments", Bit: 50, Bits: 1, Format: formatFlag},
+	}},
+	{Name: "Fragment Offset", Bit: 51, Bits: 13},
+	{Name: "Time to Live", Bit: 64, Bits: 8},
+	{Name: "Protocol", Bit: 72, Bits: 8, Format: formatIPProtocol},
+	{Name: "Header Checksum", Bit: 80, Bits: 16, Format: formatHex},
+	{Name: "Source Address", Bit: 96, Bits: 32, Format: formatIPv4Field},
+	{Name: "Destination Address", Bit: 128, Bits: 32, Format: formatIPv4Field},
+}
+
 // NewIPv4Packet 新建 IPv4 报文
 // New IPv4 Packet
 func NewIPv4Packet(srcIP, dstIP [4]byte, protocol uint8, data []byte) *IPv4Packet {
 	ihl := uint8(5) // 无选项时IHL=5
-	totalLen := uint16(ihl*4 + len(data))
+	totalLen := uint16(ihl)*4 + uint16(len(data))
 	return &IPv4Packet{
 		VersionIHL:    (4 << 4) | ihl,
 		TOS:           0,
@@ -88,12 +116,25 @@ func (ip *IPv4Packet) Serialize() []byte {
 // Deserialize []byte to IPv4 packet
 func DeserializeIPv4Packet(data []byte) (*IPv4Packet, error) {
 	if len(data) < 20 {
-		return nil, errors.New("数据长度不足，不是有效的IPv4报文 / Data too short, not a valid IPv4 packet")
+		return nil, truncated("IPv4", 0, 20, len(data))
+	}
+	if version := int(data[0] >> 4); version != 4 {
+		return nil, &ErrBadVersion{Layer: "IPv4", Version: version}
 	}
 	ihl := data[0] & 0x0F
 	headLen := int(ihl) * 4
+	if headLen < 20 {
+		return nil, &ErrBadLength{Layer: "IPv4", Field: "Header Length", Value: headLen}
+	}
 	if len(data) < headLen {
-		return nil, errors.New("数据长度不足，不是有效的IPv4头部 / Data too short for IPv4 header")
+		return nil, truncated("IPv4", 20, headLen, len(data))
+	}
+	total := int(binary.BigEndian.Uint16(data[2:4]))
+	if total < headLen {
+		return nil, &ErrBadLength{Layer: "IPv4", Field: "Total Length", Offset: 2, Value: total}
+	}
+	if total > len(data) {
+		return nil, truncated("IPv4", headLen, total, len(data))
 	}
 	ip := &IPv4Packet{}
 	ip.VersionIHL = data[0]
@@ -110,20 +151,56 @@ func DeserializeIPv4Packet(data []byte) (*IPv4Packet, error) {
 		ip.Options = make([]byte, headLen-20)
 		copy(ip.Options, data[20:headLen])
 	}
-	if int(ip.TotalLength) > headLen && int(ip.TotalLength) <= len(data) {
-		ip.Data = make([]byte, int(ip.TotalLength)-headLen)
-		copy(ip.Data, data[headLen:int(ip.TotalLength)])
-	} else if len(data) > headLen {
-		ip.Data = make([]byte, len(data)-headLen)
-		copy(ip.Data, data[headLen:])
+	// 总长度之后为链路层填充, 不属于本报文
+	if total > headLen {
+		ip.Data = make([]byte, total-headLen)
+		copy(ip.Data, data[headLen:total])
+	}
+	// 校验和置0后重新计算
+	header := append([]byte(nil), data[:headLen]...)
+	header[10], header[11] = 0, 0
+	if want := calcIPv4Checksum(header); want != ip.HeaderChecksum {
+		return ip, &ErrBadChecksum{Layer: "IPv4", Field: "Header Checksum", Offset: 10,
+			Want: uint32(want), Got: uint32(ip.HeaderChecksum)}
 	}
 	return ip, nil
 }
 
-// IsValid 检查 IPv4 报文是否合法
-// Check if IPv4 packet is valid
-func (ip *IPv4Packet) IsValid() bool {
-	return (ip.VersionIHL>>4) == 4 && ip.TotalLength >= 20
+// Validate 检查 IPv4 报文各字段是否符合规范, 校验和由 Deserialize 检查
+// Validate IPv4 packet fields; the checksum is checked by Deserialize
+// @return Findings 诊断结果
+func (ip *IPv4Packet) Validate() Findings {
+	c := &check{layer: "IPv4"}
+	if version := ip.VersionIHL >> 4; version != 4 {
+		c.add(SeverityError, "Version", "RFC 791 §3.1", "版本 %d 必须为4", version)
+	}
+	headLen := int(ip.VersionIHL&0x0F) * 4
+	switch {
+	case headLen < 20:
+		c.add(SeverityError, "Header Length", "RFC 791 §3.1", "首部长度 %d 字节, 小于最小的 20 字节", headLen)
+	case headLen-20 != len(ip.Options):
+		c.add(SeverityError, "Header Length", "RFC 791 §3.1", "首部长度 %d 字节, 与 20 字节固定首部加 %d 字节选项不符", headLen, len(ip.Options))
+	}
+	if total := 20 + len(ip.Options) + len(ip.Data); int(ip.TotalLength) != total {
+		c.add(SeverityError, "Total Length", "RFC 791 §3.1", "总长度 %d, 与首部加数据的 %d 字节不符", ip.TotalLength, total)
+	}
+	flags := ip.FlagsFragOffset >> 13
+	if flags&0x4 != 0 {
+		c.add(SeverityError, "Reserved bit", "RFC 791 §3.1", "保留位必须为0")
+	}
+	if flags&0x2 != 0 && (flags&0x1 != 0 || ip.FlagsFragOffset&0x1FFF != 0) {
+		c.add(SeverityWarning, "Flags", "RFC 791 §3.2", "设置了禁止分片的报文不应是分片")
+	}
+	if ip.TTL == 0 {
+		c.add(SeverityWarning, "Time to Live", "RFC 1122 §3.2.1.7", "生存时间为0, 发送方不应发出这样的报文")
+	}
+	switch src := ip.SourceIP; {
+	case src == [4]byte{255, 255, 255, 255} || src[0] >= 224 && src[0] <= 239:
+		c.add(SeverityError, "Source Address", "RFC 1122 §3.2.1.3", "源地址 %s 是广播或组播地址", FormatIPv4(src))
+	case src == [4]byte{}:
+		c.add(SeverityInfo, "Source Address", "RFC 1122 §3.2.1.3", "源地址 0.0.0.0 只能在获取地址期间使用")
+	}
+	return c.done(logL3)
 }
 
 // calcIPv4Checksum 计算IPv4头部校验和
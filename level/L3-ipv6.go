@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // IPv6 报文结构体
@@ -29,6 +28,19 @@ type IPv6Packet struct {
 	Data []byte
 }
 
+// IPv6Fields IPv6 头部字段布局
+// IPv6 header field layout
+var IPv6Fields = []FieldSpec{
+	{Name: "Version", Bit: 0, Bits: 4},
+	{Name: "Traffic Class", Bit: 4, Bits: 8, Format: formatHex},
+	{Name: "Flow Label", Bit: 12, Bits: 20, Format: formatHex},
+	{Name: "Payload Length", Bit: 32, Bits: 16},
+	{Name: "Next Header", Bit: 48, Bits: 8, Format: formatIPProtocol},
+	{Name: "Hop Limit", Bit: 56, Bits: 8},
+	{Name: "Source Address", Bit: 64, Bits: 128, Format: formatIPv6Field},
+	{Name: "Destination Address", Bit: 192, Bits: 128, Format: formatIPv6Field},
+}
+
 // NewIPv6Packet 新建 IPv6 报文
 // New IPv6 Packet
 func NewIPv6Packet(src, dst [16]byte, nextHeader uint8, data []byte) *IPv6Packet {
@@ -63,7 +75,14 @@ func (ip *IPv6Packet) Serialize() []byte {
 // Deserialize []byte to IPv6 packet
 func DeserializeIPv6Packet(data []byte) (*IPv6Packet, error) {
 	if len(data) < 40 {
-		return nil, errors.New("数据长度不足，不是有效的IPv6报文 / Data too short, not a valid IPv6 packet")
+		return nil, truncated("IPv6", 0, 40, len(data))
+	}
+	if version := int(data[0] >> 4); version != 6 {
+		return nil, &ErrBadVersion{Layer: "IPv6", Version: version}
+	}
+	end := 40 + int(binary.BigEndian.Uint16(data[4:6]))
+	if end > len(data) {
+		return nil, truncated("IPv6", 40, end, len(data))
 	}
 	ip := &IPv6Packet{}
 	ip.VersionTrafficClass = data[0]
@@ -73,15 +92,29 @@ func DeserializeIPv6Packet(data []byte) (*IPv6Packet, error) {
 	ip.HopLimit = data[7]
 	copy(ip.SourceAddr[:], data[8:24])
 	copy(ip.DestAddr[:], data[24:40])
-	if len(data) > 40 {
-		ip.Data = make([]byte, len(data)-40)
-		copy(ip.Data, data[40:])
+	if end > 40 {
+		ip.Data = make([]byte, end-40)
+		copy(ip.Data, data[40:end])
 	}
 	return ip, nil
 }
 
-// IsValid 检查 IPv6 报文是否合法
-// Check if IPv6 packet is valid
-func (ip *IPv6Packet) IsValid() bool {
-	return (ip.VersionTrafficClass>>4) == 6 && ip.PayloadLength+40 <= uint16(40+len(ip.Data))
+// Validate 检查 IPv6 报文各字段是否符合规范
+// Validate IPv6 packet fields
+// @return Findings 诊断结果
+func (ip *IPv6Packet) Validate() Findings {
+	c := &check{layer: "IPv6"}
+	if version := ip.VersionTrafficClass >> 4; version != 6 {
+		c.add(SeverityError, "Version", "RFC 8200 §3", "版本 %d 必须为6", version)
+	}
+	if int(ip.PayloadLength) != len(ip.Data) {
+		c.add(SeverityError, "Payload Length", "RFC 8200 §3", "载荷长度 %d, 与实际的 %d 字节不符", ip.PayloadLength, len(ip.Data))
+	}
+	if ip.HopLimit == 0 {
+		c.add(SeverityWarning, "Hop Limit", "RFC 8200 §3", "跳数限制为0, 发送方不应发出这样的报文")
+	}
+	if ip.SourceAddr[0] == 0xFF {
+		c.add(SeverityError, "Source Address", "RFC 4291 §2.7", "源地址不能是组播地址")
+	}
+	return c.done(logL3)
 }
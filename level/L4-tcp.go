@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // TCP 报文结构体
@@ -33,6 +32,42 @@ type TCPPacket struct {
 	Data []byte
 }
 
+// const TCP 标志位
+// TCP flags
+const (
+	TCPFlagFIN = 0x01
+	TCPFlagSYN = 0x02
+	TCPFlagRST = 0x04
+	TCPFlagPSH = 0x08
+	TCPFlagACK = 0x10
+	TCPFlagURG = 0x20
+)
+
+// TCPFields TCP 头部字段布局(不含选项)
+// TCP header field layout (without options)
+var TCPFields = []FieldSpec{
+	{Name: "Source Port", Bit: 0, Bits: 16},
+	{Name: "Destination Port", Bit: 16, Bits: 16},
+	{Name: "Sequence Number", Bit: 32, Bits: 32},
+	{Name: "Acknowledgment Number", Bit: 64, Bits: 32},
+	{Name: "Header Length", Bit: 96, Bits: 4, Format: formatHeaderLen},
+	{Name: "Reserved", Bit: 100, Bits: 3},
+	{Name: "Flags", Bit: 103, Bits: 9, Format: formatTCPFlags, Children: []FieldSpec{
+		{Name: "Nonce", Bit: 103, Bits: 1, Format: formatFlag},
+		{Name: "Congestion Window Reduced", Bit: 104, Bits: 1, Format: formatFlag},
+		{Name: "ECN-Echo", Bit: 105, Bits: 1, Format: formatFlag},
+		{Name: "Urgent", Bit: 106, Bits: 1, Format: formatFlag},
+		{Name: "Acknowledgment", Bit: 107, Bits: 1, Format: formatFlag},
+		{Name: "Push", Bit: 108, Bits: 1, Format: formatFlag},
+		{Name: "Reset", Bit: 109, Bits: 1, Format: formatFlag},
+		{Name: "Syn", Bit: 110, Bits: 1, Format: formatFlag},
+		{Name: "Fin", Bit: 111, Bits: 1, Format: formatFlag},
+	}},
+	{Name: "Window", Bit: 112, Bits: 16},
+	{Name: "Checksum", Bit: 128, Bits: 16, Format: formatHex},
+	{Name: "Urgent Pointer", Bit: 144, Bits: 16},
+}
+
 // NewTCPPacket 新建 TCP 报文
 // New TCP Packet
 func NewTCPPacket(srcPort, dstPort uint16, seq, ack uint32, flags uint16, window uint16, data []byte) *TCPPacket {
@@ -78,12 +113,15 @@ func (tcp *TCPPacket) Serialize(srcIP, dstIP [4]byte) []byte {
 // Deserialize []byte to TCP packet
 func DeserializeTCPPacket(data []byte) (*TCPPacket, error) {
 	if len(data) < 20 {
-		return nil, errors.New("数据长度不足，不是有效的TCP报文 / Data too short, not a valid TCP packet")
+		return nil, truncated("TCP", 0, 20, len(data))
 	}
 	dataOffset := (binary.BigEndian.Uint16(data[12:14]) >> 12) & 0xF
 	headLen := int(dataOffset) * 4
+	if headLen < 20 {
+		return nil, &ErrBadLength{Layer: "TCP", Field: "Header Length", Offset: 12, Value: headLen}
+	}
 	if len(data) < headLen {
-		return nil, errors.New("数据长度不足，不是有效的TCP头部 / Data too short for TCP header")
+		return nil, truncated("TCP", 20, headLen, len(data))
 	}
 	tcp := &TCPPacket{}
 	tcp.SourcePort = binary.BigEndian.Uint16(data[0:2])
@@ -105,10 +143,48 @@ func DeserializeTCPPacket(data []byte) (*TCPPacket, error) {
 	return tcp, nil
 }
 
-// IsValid 检查 TCP 报文是否合法
-// Check if TCP packet is valid
-func (tcp *TCPPacket) IsValid() bool {
-	return tcp.SourcePort > 0 && tcp.DestPort > 0
+// Flags 返回 TCP 标志位
+// Return TCP flags
+func (tcp *TCPPacket) Flags() uint16 {
+	return tcp.DataOffsetFlags & 0x01FF
+}
+
+// Validate 检查 TCP 报文段各字段与标志位组合是否符合规范, 校验和需要伪首部, 由调用方检查
+// Validate TCP header fields and flag combinations; the checksum needs the pseudo header
+// @return Findings 诊断结果
+func (tcp *TCPPacket) Validate() Findings {
+	c := &check{layer: "TCP"}
+	headLen := int(tcp.DataOffsetFlags>>12) * 4
+	switch {
+	case headLen < 20:
+		c.add(SeverityError, "Header Length", "RFC 9293 §3.1", "首部长度 %d 字节, 小于最小的 20 字节", headLen)
+	case headLen-20 != len(tcp.Options):
+		c.add(SeverityError, "Header Length", "RFC 9293 §3.1", "首部长度 %d 字节, 与 20 字节固定首部加 %d 字节选项不符", headLen, len(tcp.Options))
+	}
+	if tcp.DataOffsetFlags&0x0E00 != 0 {
+		c.add(SeverityWarning, "Reserved", "RFC 9293 §3.1", "保留位应为0")
+	}
+	if tcp.SourcePort == 0 || tcp.DestPort == 0 {
+		c.add(SeverityError, "", "RFC 6335 §6", "端口0是保留端口, 不能用于通信")
+	}
+	flags := tcp.Flags()
+	switch {
+	case flags&(TCPFlagSYN|TCPFlagFIN|TCPFlagRST|TCPFlagPSH|TCPFlagACK|TCPFlagURG) == 0:
+		c.add(SeverityError, "Flags", "RFC 9293 §3.10.7", "没有设置任何标志位")
+	case flags&TCPFlagSYN != 0 && flags&TCPFlagFIN != 0:
+		c.add(SeverityError, "Flags", "RFC 9293 §3.10.7", "SYN与FIN不能同时设置")
+	case flags&TCPFlagSYN != 0 && flags&TCPFlagRST != 0:
+		c.add(SeverityError, "Flags", "RFC 9293 §3.10.7", "SYN与RST不能同时设置")
+	case flags&(TCPFlagSYN|TCPFlagRST|TCPFlagACK) == 0:
+		c.add(SeverityWarning, "Flags", "RFC 9293 §3.10.7", "除SYN与RST外的报文段都应设置ACK")
+	}
+	if flags&TCPFlagURG == 0 && tcp.UrgentPointer != 0 {
+		c.add(SeverityWarning, "Urgent Pointer", "RFC 9293 §3.1", "未设置URG, 紧急指针 %d 应为0", tcp.UrgentPointer)
+	}
+	if flags&TCPFlagACK == 0 && tcp.AckNum != 0 {
+		c.add(SeverityInfo, "Acknowledgment Number", "RFC 9293 §3.1", "未设置ACK, 确认号 %d 没有意义", tcp.AckNum)
+	}
+	return c.done(logL4)
 }
 
 // calcTCPChecksum 计算TCP校验和
@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // UDP 报文结构体
@@ -23,6 +22,15 @@ type UDPPacket struct {
 	Data []byte
 }
 
+// UDPFields UDP 头部字段布局
+// UDP header field layout
+var UDPFields = []FieldSpec{
+	{Name: "Source Port", Bit: 0, Bits: 16},
+	{Name: "Destination Port", Bit: 16, Bits: 16},
+	{Name: "Length", Bit: 32, Bits: 16},
+	{Name: "Checksum", Bit: 48, Bits: 16, Format: formatHex},
+}
+
 // NewUDPPacket 新建 UDP 报文
 // New UDP Packet
 func NewUDPPacket(srcPort, dstPort uint16, data []byte) *UDPPacket {
@@ -53,24 +61,45 @@ func (udp *UDPPacket) Serialize(srcIP, dstIP [4]byte) []byte {
 // Deserialize []byte to UDP packet
 func DeserializeUDPPacket(data []byte) (*UDPPacket, error) {
 	if len(data) < 8 {
-		return nil, errors.New("数据长度不足，不是有效的UDP报文 / Data too short, not a valid UDP packet")
+		return nil, truncated("UDP", 0, 8, len(data))
+	}
+	length := int(binary.BigEndian.Uint16(data[4:6]))
+	if length < 8 {
+		return nil, &ErrBadLength{Layer: "UDP", Field: "Length", Offset: 4, Value: length}
+	}
+	if length > len(data) {
+		return nil, truncated("UDP", 8, length, len(data))
 	}
 	udp := &UDPPacket{}
 	udp.SourcePort = binary.BigEndian.Uint16(data[0:2])
 	udp.DestPort = binary.BigEndian.Uint16(data[2:4])
 	udp.Length = binary.BigEndian.Uint16(data[4:6])
 	udp.Checksum = binary.BigEndian.Uint16(data[6:8])
-	if len(data) > 8 {
-		udp.Data = make([]byte, len(data)-8)
-		copy(udp.Data, data[8:])
+	if length > 8 {
+		udp.Data = make([]byte, length-8)
+		copy(udp.Data, data[8:length])
 	}
 	return udp, nil
 }
 
-// IsValid 检查 UDP 报文是否合法
-// Check if UDP packet is valid
-func (udp *UDPPacket) IsValid() bool {
-	return udp.SourcePort > 0 && udp.DestPort > 0 && udp.Length >= 8
+// Validate 检查 UDP 数据报各字段是否符合规范, 校验和需要伪首部, 由调用方检查
+// Validate UDP datagram fields; the checksum needs the pseudo header
+// @return Findings 诊断结果
+func (udp *UDPPacket) Validate() Findings {
+	c := &check{layer: "UDP"}
+	switch {
+	case udp.Length < 8:
+		c.add(SeverityError, "Length", "RFC 768", "长度 %d, 小于首部的 8 字节", udp.Length)
+	case int(udp.Length) != 8+len(udp.Data):
+		c.add(SeverityError, "Length", "RFC 768", "长度 %d, 与首部加数据的 %d 字节不符", udp.Length, 8+len(udp.Data))
+	}
+	if udp.DestPort == 0 {
+		c.add(SeverityError, "Destination Port", "RFC 6335 §6", "端口0是保留端口, 不能用于通信")
+	}
+	if udp.Checksum == 0 {
+		c.add(SeverityInfo, "Checksum", "RFC 768", "发送方没有计算校验和")
+	}
+	return c.done(logL4)
 }
 
 // calcUDPChecksum 计算UDP校验和
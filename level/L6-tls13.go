@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // TLS 1.3 报文结构体
@@ -47,21 +46,55 @@ func (tls *TLS13Packet) Serialize() []byte {
 // Deserialize []byte to TLS 1.3 packet
 func DeserializeTLS13Packet(data []byte) (*TLS13Packet, error) {
 	if len(data) < 5 {
-		return nil, errors.New("数据长度不足，不是有效的TLS 1.3报文 / Data too short, not a valid TLS 1.3 packet")
+		return nil, truncated("TLS 1.3", 0, 5, len(data))
+	}
+	if data[1] != 0x03 {
+		return nil, &ErrBadVersion{Layer: "TLS 1.3", Offset: 1, Version: int(binary.BigEndian.Uint16(data[1:3]))}
+	}
+	end := 5 + int(binary.BigEndian.Uint16(data[3:5]))
+	if end > len(data) {
+		return nil, truncated("TLS 1.3", 5, end, len(data))
 	}
 	tls := &TLS13Packet{}
 	tls.ContentType = data[0]
 	copy(tls.Version[:], data[1:3])
 	tls.Length = binary.BigEndian.Uint16(data[3:5])
-	if len(data) > 5 {
-		tls.Payload = make([]byte, len(data)-5)
-		copy(tls.Payload, data[5:])
+	if end > 5 {
+		tls.Payload = make([]byte, end-5)
+		copy(tls.Payload, data[5:end])
 	}
 	return tls, nil
 }
 
-// IsValid 检查 TLS 1.3 报文是否合法
-// Check if TLS 1.3 packet is valid
-func (tls *TLS13Packet) IsValid() bool {
-	return tls.Version == [2]byte{0x03, 0x03} && int(tls.Length) == len(tls.Payload)
+// Validate 检查 TLS 1.3 记录各字段是否符合规范
+// Validate TLS 1.3 record fields
+// @return Findings 诊断结果
+func (tls *TLS13Packet) Validate() Findings {
+	c := &check{layer: "TLS 1.3"}
+	// 20~23 依次为 change_cipher_spec(仅为兼容), alert, handshake, application_data
+	if tls.ContentType < 20 || tls.ContentType > 23 {
+		c.add(SeverityError, "Content Type", "RFC 8446 §5", "未定义的内容类型 %d", tls.ContentType)
+	}
+	switch tls.Version {
+	case [2]byte{0x03, 0x03}:
+	case [2]byte{0x03, 0x01}:
+		c.add(SeverityInfo, "Version", "RFC 8446 §5.1", "记录层版本 0x0301 只允许用于初始的 ClientHello")
+	default:
+		c.add(SeverityWarning, "Version", "RFC 8446 §5.1", "记录层版本 0x%02x%02x 应为0x0303", tls.Version[0], tls.Version[1])
+	}
+	if int(tls.Length) != len(tls.Payload) {
+		c.add(SeverityError, "Length", "RFC 8446 §5.1", "长度 %d, 与实际的 %d 字节不符", tls.Length, len(tls.Payload))
+	}
+	// 明文最多 2^14 字节, 加密后最多再多 256 字节
+	limit := 1 << 14
+	if tls.ContentType == 23 {
+		limit += 256
+	}
+	if int(tls.Length) > limit {
+		c.add(SeverityError, "Length", "RFC 8446 §5.2", "长度 %d 超过上限 %d 字节", tls.Length, limit)
+	}
+	if tls.Length == 0 && (tls.ContentType == 21 || tls.ContentType == 22) {
+		c.add(SeverityError, "Length", "RFC 8446 §5.1", "握手与告警记录不能为空")
+	}
+	return c.done(logL4)
 }
@@ -2,7 +2,6 @@ package level
 
 import (
 	"encoding/binary"
-	"errors"
 )
 
 // DNS 报文结构体
@@ -59,7 +58,7 @@ func (dns *DNSPacket) Serialize() []byte {
 // Deserialize []byte to DNS packet
 func DeserializeDNSPacket(data []byte) (*DNSPacket, error) {
 	if len(data) < 12 {
-		return nil, errors.New("数据长度不足，不是有效的DNS报文 / Data too short, not a valid DNS packet")
+		return nil, truncated("DNS", 0, 12, len(data))
 	}
 	dns := &DNSPacket{}
 	dns.ID = binary.BigEndian.Uint16(data[0:2])
@@ -75,8 +74,36 @@ func DeserializeDNSPacket(data []byte) (*DNSPacket, error) {
 	return dns, nil
 }
 
-// IsValid 检查 DNS 报文是否合法
-// Check if DNS packet is valid
-func (dns *DNSPacket) IsValid() bool {
-	return dns.QDCount > 0 || dns.ANCount > 0 || dns.NSCount > 0 || dns.ARCount > 0
+// Validate 检查 DNS 报文首部是否符合规范
+// Validate DNS header fields
+// @return Findings 诊断结果
+func (dns *DNSPacket) Validate() Findings {
+	c := &check{layer: "DNS"}
+	response := dns.Flags&0x8000 != 0
+	// 0 查询, 1 反向查询(已废弃), 2 状态, 4 通知, 5 更新
+	switch opcode := dns.Flags >> 11 & 0xF; opcode {
+	case 0, 2, 4, 5:
+	case 1:
+		c.add(SeverityWarning, "Flags", "RFC 3425", "反向查询(opcode 1)已废弃")
+	default:
+		c.add(SeverityWarning, "Flags", "RFC 1035 §4.1.1", "未定义的操作码 %d", opcode)
+	}
+	if dns.Flags&0x0040 != 0 {
+		c.add(SeverityWarning, "Flags", "RFC 1035 §4.1.1", "Z位必须为0")
+	}
+	if dns.QDCount == 0 && dns.ANCount == 0 && dns.NSCount == 0 && dns.ARCount == 0 {
+		c.add(SeverityError, "", "RFC 1035 §4.1.1", "报文不含任何记录")
+	}
+	if !response {
+		if dns.QDCount != 1 {
+			c.add(SeverityWarning, "Questions", "RFC 9619", "查询应恰好包含 1 个问题, 实际为 %d", dns.QDCount)
+		}
+		if dns.ANCount != 0 {
+			c.add(SeverityWarning, "Answer RRs", "RFC 1035 §4.1.1", "查询不应包含回答")
+		}
+		if rcode := dns.Flags & 0xF; rcode != 0 {
+			c.add(SeverityWarning, "Flags", "RFC 1035 §4.1.1", "查询的响应码 %d 应为0", rcode)
+		}
+	}
+	return c.done(logL4)
 }
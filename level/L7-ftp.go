@@ -1,7 +1,7 @@
 package level
 
 import (
-	"errors"
+	"strings"
 )
 
 // FTP 报文结构体
@@ -41,7 +41,7 @@ func (ftp *FTPPacket) Serialize() []byte {
 func DeserializeFTPPacket(data []byte) (*FTPPacket, error) {
 	line := string(data)
 	if len(line) < 2 || line[len(line)-2:] != "\r\n" {
-		return nil, errors.New("数据格式错误，不是有效的FTP报文 / Invalid FTP packet format")
+		return nil, badFormat("FTP")
 	}
 	line = line[:len(line)-2]
 	cmd := ""
@@ -66,8 +66,42 @@ func indexOfSpace(s string) int {
 	return -1
 }
 
-// IsValid 检查 FTP 报文是否合法
-// Check if FTP packet is valid
-func (ftp *FTPPacket) IsValid() bool {
-	return ftp.Command != ""
+// ftpCommands RFC 959 及其扩展定义的命令
+var ftpCommands = map[string]bool{
+	"USER": true, "PASS": true, "ACCT": true, "CWD": true, "CDUP": true, "SMNT": true,
+	"QUIT": true, "REIN": true, "PORT": true, "PASV": true, "TYPE": true, "STRU": true,
+	"MODE": true, "RETR": true, "STOR": true, "STOU": true, "APPE": true, "ALLO": true,
+	"REST": true, "RNFR": true, "RNTO": true, "ABOR": true, "DELE": true, "RMD": true,
+	"MKD": true, "PWD": true, "LIST": true, "NLST": true, "SITE": true, "SYST": true,
+	"STAT": true, "HELP": true, "NOOP": true, "FEAT": true, "OPTS": true, "EPRT": true,
+	"EPSV": true, "SIZE": true, "MDTM": true, "MLSD": true, "MLST": true, "AUTH": true,
+	"PBSZ": true, "PROT": true,
+}
+
+// ftpNeedArgs 必须带参数的命令
+var ftpNeedArgs = map[string]bool{
+	"USER": true, "PASS": true, "CWD": true, "PORT": true, "TYPE": true, "RETR": true,
+	"STOR": true, "DELE": true, "RMD": true, "MKD": true, "RNFR": true, "RNTO": true,
+}
+
+// Validate 检查 FTP 命令或应答是否符合规范
+// Validate FTP command or reply
+// @return Findings 诊断结果
+func (ftp *FTPPacket) Validate() Findings {
+	c := &check{layer: "FTP"}
+	cmd := strings.ToUpper(ftp.Command)
+	switch {
+	case cmd == "":
+		c.add(SeverityError, "", "RFC 959 §4.1", "缺少命令")
+	case len(cmd) == 3 && cmd[0] >= '0' && cmd[0] <= '9':
+		// 应答码为3位数字, 首位为1~5
+		if cmd[0] < '1' || cmd[0] > '5' || cmd[1] < '0' || cmd[1] > '9' || cmd[2] < '0' || cmd[2] > '9' {
+			c.add(SeverityError, "", "RFC 959 §4.2", "应答码 %s 不合法", ftp.Command)
+		}
+	case !ftpCommands[cmd]:
+		c.add(SeverityWarning, "", "RFC 959 §4.1", "未知的命令 %s", ftp.Command)
+	case ftpNeedArgs[cmd] && ftp.Arguments == "":
+		c.add(SeverityError, "", "RFC 959 §5.3.1", "命令 %s 缺少参数", cmd)
+	}
+	return c.done(logL4)
 }
@@ -1,8 +1,10 @@
 package level
 
 import (
-	"errors"
+	"strconv"
 	"strings"
+
+	"osiweb-go/i18n"
 )
 
 // HTTP 报文结构体
@@ -49,11 +51,11 @@ func DeserializeHTTPPacket(data []byte) (*HTTPPacket, error) {
 	text := string(data)
 	parts := strings.SplitN(text, "\r\n\r\n", 2)
 	if len(parts) < 1 {
-		return nil, errors.New("数据格式错误，不是有效的HTTP报文 / Invalid HTTP packet format")
+		return nil, badFormat("HTTP")
 	}
 	headersAndStart := strings.Split(parts[0], "\r\n")
 	if len(headersAndStart) < 1 {
-		return nil, errors.New("缺少起始行 / Missing start line")
+		return nil, i18n.Errorf(ErrBadFormat.Code, "缺少起始行")
 	}
 	http := &HTTPPacket{
 		StartLine: headersAndStart[0],
@@ -73,8 +75,51 @@ func DeserializeHTTPPacket(data []byte) (*HTTPPacket, error) {
 	return http, nil
 }
 
-// IsValid 检查 HTTP 报文是否合法
-// Check if HTTP packet is valid
-func (http *HTTPPacket) IsValid() bool {
-	return http.StartLine != ""
+// header 按名称查找首部, 名称不区分大小写
+func (http *HTTPPacket) header(name string) (string, bool) {
+	for k, v := range http.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Validate 检查 HTTP 起始行与首部是否符合规范
+// Validate HTTP start line and headers
+// @return Findings 诊断结果
+func (http *HTTPPacket) Validate() Findings {
+	c := &check{layer: "HTTP"}
+	parts := strings.SplitN(http.StartLine, " ", 3)
+	if http.StartLine == "" || len(parts) < 3 {
+		c.add(SeverityError, "", "RFC 9112 §2.1", "起始行 %q 应由三部分组成", http.StartLine)
+		return c.done(logL4)
+	}
+	request := !strings.HasPrefix(parts[0], "HTTP/")
+	version := parts[0]
+	if request {
+		version = parts[2]
+		if method := parts[0]; method != strings.ToUpper(method) {
+			c.add(SeverityWarning, "", "RFC 9110 §9.1", "方法 %s 区分大小写, 标准方法均为大写", method)
+		}
+		if version == "HTTP/1.1" {
+			if _, ok := http.header("Host"); !ok {
+				c.add(SeverityError, "", "RFC 9112 §3.2", "HTTP/1.1 请求必须包含 Host 首部")
+			}
+		}
+	} else {
+		code, err := strconv.Atoi(parts[1])
+		if err != nil || len(parts[1]) != 3 || code < 100 || code > 599 {
+			c.add(SeverityError, "", "RFC 9110 §15", "状态码 %s 不合法", parts[1])
+		}
+	}
+	if version != "HTTP/1.0" && version != "HTTP/1.1" {
+		c.add(SeverityError, "", "RFC 9112 §2.3", "不支持的版本 %s", version)
+	}
+	if v, ok := http.header("Content-Length"); ok {
+		if n, err := strconv.Atoi(v); err != nil || n != len(http.Body) {
+			c.add(SeverityError, "", "RFC 9112 §6.3", "Content-Length %s 与正文的 %d 字节不符", v, len(http.Body))
+		}
+	}
+	return c.done(logL4)
 }
@@ -1,7 +1,7 @@
 package level
 
 import (
-	"errors"
+	"strings"
 )
 
 // SSH 报文结构体
@@ -50,7 +50,7 @@ func DeserializeSSHPacket(data []byte) (*SSHPacket, error) {
 		}
 	}
 	if lineEnd == -1 {
-		return nil, errors.New("数据格式错误，不是有效的SSH报文 / Invalid SSH packet format")
+		return nil, badFormat("SSH")
 	}
 	line := string(data[:lineEnd])
 	protoVer := ""
@@ -79,8 +79,24 @@ func indexOfDash(s string) int {
 	return -1
 }
 
-// IsValid 检查 SSH 报文是否合法
-// Check if SSH packet is valid
-func (ssh *SSHPacket) IsValid() bool {
-	return ssh.ProtocolVersion != ""
+// Validate 检查 SSH 版本交换行是否符合规范
+// Validate SSH identification string
+// @return Findings 诊断结果
+func (ssh *SSHPacket) Validate() Findings {
+	c := &check{layer: "SSH"}
+	line := ssh.ProtocolVersion + "-" + ssh.SoftwareVersion
+	switch {
+	case strings.HasPrefix(line, "SSH-2.0-"):
+	case strings.HasPrefix(line, "SSH-1.99-"):
+		c.add(SeverityInfo, "", "RFC 4253 §5.1", "版本 1.99 表示同时兼容SSH 1与SSH 2")
+	default:
+		c.add(SeverityError, "", "RFC 4253 §4.2", "版本交换行 %q 应以 SSH-2.0- 开头", line)
+	}
+	if len(line)+2 > 255 {
+		c.add(SeverityError, "", "RFC 4253 §4.2", "版本交换行 %d 字节, 超过最大 255 字节", len(line)+2)
+	}
+	if rest := strings.TrimPrefix(strings.TrimPrefix(line, "SSH-2.0-"), "SSH-1.99-"); rest == "" || rest[0] == ' ' {
+		c.add(SeverityError, "", "RFC 4253 §4.2", "缺少软件版本")
+	}
+	return c.done(logL4)
 }
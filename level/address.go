@@ -0,0 +1,106 @@
+package level
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BroadcastMAC 以太网广播地址
+// Ethernet broadcast address
+var BroadcastMAC = [6]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// FormatMAC 将MAC地址格式化为 aa:bb:cc:dd:ee:ff
+// Format MAC address as aa:bb:cc:dd:ee:ff
+func FormatMAC(mac [6]byte) string {
+	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
+		mac[0], mac[1], mac[2], mac[3], mac[4], mac[5])
+}
+
+// FormatIPv4 将IPv4地址格式化为点分十进制
+// Format IPv4 address as dotted decimal
+func FormatIPv4(ip [4]byte) string {
+	return fmt.Sprintf("%d.%d.%d.%d", ip[0], ip[1], ip[2], ip[3])
+}
+
+// ParseMAC 解析 aa:bb:cc:dd:ee:ff 或 aa-bb-cc-dd-ee-ff 格式的MAC地址
+// Parse MAC address in aa:bb:cc:dd:ee:ff or aa-bb-cc-dd-ee-ff form
+// @param s MAC地址字符串
+// @return [6]byte, error
+func ParseMAC(s string) ([6]byte, error) {
+	var mac [6]byte
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' })
+	if len(parts) != 6 {
+		return mac, badAddress("MAC地址格式错误: %s", s)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 16, 8)
+		if err != nil {
+			return mac, badAddress("MAC地址格式错误: %s", s)
+		}
+		mac[i] = byte(v)
+	}
+	return mac, nil
+}
+
+// ParseIPv4 解析点分十进制IPv4地址
+// Parse dotted decimal IPv4 address
+// @param s IPv4地址字符串
+// @return [4]byte, error
+func ParseIPv4(s string) ([4]byte, error) {
+	var ip [4]byte
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return ip, badAddress("IPv4地址格式错误: %s", s)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return ip, badAddress("IPv4地址格式错误: %s", s)
+		}
+		ip[i] = byte(v)
+	}
+	return ip, nil
+}
+
+// ParseIPv4Prefix 解析 a.b.c.d/n 格式的地址与前缀长度，缺省前缀为32
+// Parse a.b.c.d/n address with prefix length, /32 when omitted
+// @param s 地址字符串
+// @return [4]byte, int, error
+func ParseIPv4Prefix(s string) ([4]byte, int, error) {
+	addr, bits, found := strings.Cut(s, "/")
+	ip, err := ParseIPv4(addr)
+	if err != nil {
+		return ip, 0, err
+	}
+	if !found {
+		return ip, 32, nil
+	}
+	n, err := strconv.Atoi(bits)
+	if err != nil || n < 0 || n > 32 {
+		return ip, 0, badAddress("前缀长度错误: %s", s)
+	}
+	return ip, n, nil
+}
+
+// PrefixMask 返回前缀长度对应的子网掩码
+// Return the netmask of a prefix length
+func PrefixMask(prefixLen int) [4]byte {
+	var mask [4]byte
+	for i := 0; i < prefixLen && i < 32; i++ {
+		mask[i/8] |= 0x80 >> (i % 8)
+	}
+	return mask
+}
+
+// SameSubnet 判断两个地址是否位于同一子网
+// Check whether two addresses share the same subnet
+func SameSubnet(a, b [4]byte, prefixLen int) bool {
+	mask := PrefixMask(prefixLen)
+	for i := 0; i < 4; i++ {
+		if a[i]&mask[i] != b[i]&mask[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,603 @@
+package level
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// FieldSpec 协议头部字段描述符, 位偏移相对本层起始
+// Header field descriptor, bit offset relative to the start of the layer
+type FieldSpec struct {
+	// 字段名称 Field name
+	Name string
+	// 起始位偏移, 0为本层第一个字节的最高位 Bit offset, 0 is the MSB of the first byte
+	Bit int
+	// 位宽 Width in bits
+	Bits int
+	// 值的可读形式, nil时按十进制显示 Human-readable value, decimal when nil
+	Format func(v uint64, raw []byte) string
+	// 子字段, 如标志位 Sub-fields such as flag bits
+	Children []FieldSpec
+}
+
+// Field 解码后的字段, 偏移相对整个帧
+// Decoded field, offsets relative to the whole frame
+type Field struct {
+	// 字段名称 Field name
+	Name string
+	// 起始字节 First byte
+	Offset int
+	// 覆盖的字节数 Number of bytes covered
+	Length int
+	// 起始位, 等于 Offset*8 加字节内偏移 First bit, Offset*8 plus the bit within the byte
+	Bit int
+	// 位宽 Width in bits
+	Bits int
+	// 数值, 位宽超过64时为0 Numeric value, 0 when wider than 64 bits
+	Value uint64
+	// 可读形式 Human-readable value
+	Display string
+	// 子字段 Sub-fields
+	Children []Field
+}
+
+// Layer 解码后的一层协议
+// Decoded protocol layer
+type Layer struct {
+	// 协议名称 Protocol name
+	Name string
+	// 摘要, 如源与目的地址 Summary such as source and destination
+	Summary string
+	// 起始字节 First byte
+	Offset int
+	// 字节数 Number of bytes
+	Length int
+	// 字段 Fields
+	Fields []Field
+}
+
+// Aligned 字段是否按字节对齐
+// Whether the field is byte-aligned
+func (f Field) Aligned() bool {
+	return f.Bit%8 == 0 && f.Bits%8 == 0
+}
+
+// Field 按名称查找本层的顶层字段, 不存在时返回nil
+// Find a top-level field of the layer by name, nil if absent
+func (l *Layer) Field(name string) *Field {
+	for i := range l.Fields {
+		if l.Fields[i].Name == name {
+			return &l.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Dissect 逐层解码以太网帧, 得到带偏移与位宽的字段树; 截断的层以 Malformed 层结束
+// Dissect an Ethernet frame into a field tree with offsets and bit widths
+// @param frame 序列化后的以太网帧(含CRC)
+// @return []Layer 从外到内的各层
+func Dissect(frame []byte) []Layer {
+	d := &dissector{frame: frame, hasFCS: true}
+	d.ethernet()
+	return d.layers
+}
+
+// DissectWithoutFCS 解码不带CRC的帧, 如抓包文件中的帧
+// Dissect a frame captured without its CRC
+// @param frame 不含CRC的以太网帧
+// @return []Layer 从外到内的各层
+func DissectWithoutFCS(frame []byte) []Layer {
+	d := &dissector{frame: frame}
+	d.ethernet()
+	return d.layers
+}
+
+// dissector 解码状态
+type dissector struct {
+	frame  []byte
+	hasFCS bool
+	layers []Layer
+}
+
+// add 追加一层并返回其下标
+func (d *dissector) add(name string, offset, length int, specs []FieldSpec) int {
+	d.layers = append(d.layers, Layer{
+		Name:   name,
+		Offset: offset,
+		Length: length,
+		Fields: buildFields(d.frame, offset, specs),
+	})
+	return len(d.layers) - 1
+}
+
+// malformed 追加错误层, 从 base 所在层中出错的偏移开始; 出错的字段已解码时一并标出
+func (d *dissector) malformed(base, end int, err error) {
+	offset := min(base+ErrorOffset(err), end)
+	if name := errorField(err); name != "" && len(d.layers) > 0 {
+		if f := d.layers[len(d.layers)-1].Field(name); f != nil {
+			f.Display += " " + checkStatus(false)
+		}
+	}
+	d.layers = append(d.layers, Layer{
+		Name:    "Malformed Packet",
+		Summary: err.Error(),
+		Offset:  offset,
+		Length:  end - offset,
+	})
+}
+
+// buildFields 按描述符从帧中取出字段值
+func buildFields(frame []byte, base int, specs []FieldSpec) []Field {
+	fields := make([]Field, 0, len(specs))
+	for _, s := range specs {
+		bit := base*8 + s.Bit
+		first, last := bit/8, (bit+s.Bits+7)/8
+		raw := frame[first:last]
+		f := Field{Name: s.Name, Offset: first, Length: last - first, Bit: bit, Bits: s.Bits}
+		if s.Bits <= 64 {
+			f.Value = readBits(frame, bit, s.Bits)
+		}
+		if s.Format != nil {
+			f.Display = s.Format(f.Value, raw)
+		} else {
+			f.Display = fmt.Sprintf("%d", f.Value)
+		}
+		f.Children = buildFields(frame, base, s.Children)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// bytesField 变长字节字段, 如选项与数据
+func bytesField(frame []byte, name string, offset, length int) Field {
+	return Field{
+		Name:    name,
+		Offset:  offset,
+		Length:  length,
+		Bit:     offset * 8,
+		Bits:    length * 8,
+		Display: formatBytes(frame[offset : offset+length]),
+	}
+}
+
+// readBits 读取大端位串
+func readBits(data []byte, bit, bits int) uint64 {
+	var v uint64
+	for i := bit; i < bit+bits; i++ {
+		v = v<<1 | uint64(data[i/8]>>(7-i%8)&1)
+	}
+	return v
+}
+
+// ethernet 解码以太网头部, 并在内层解码后补上填充与FCS
+func (d *dissector) ethernet() {
+	if len(d.frame) < EthernetHeaderSize {
+		d.malformed(0, len(d.frame), truncated("Ethernet II", 0, EthernetHeaderSize, len(d.frame)))
+		return
+	}
+	end := len(d.frame)
+	hasFCS := d.hasFCS && end >= EthernetHeaderSize+FCSSize
+	if hasFCS {
+		end -= FCSSize
+	}
+	name, specs := "Ethernet II", Ethernet2Fields
+	if binary.BigEndian.Uint16(d.frame[12:14]) <= MaxDataSize {
+		name, specs = "IEEE 802.3 Ethernet", IEEE8023Fields
+	}
+	eth := d.add(name, 0, len(d.frame), specs)
+	var dst, src [6]byte
+	copy(dst[:], d.frame[0:6])
+	copy(src[:], d.frame[6:12])
+	d.layers[eth].Summary = fmt.Sprintf("Src: %s, Dst: %s", FormatMAC(src), FormatMAC(dst))
+	used := d.payload(binary.BigEndian.Uint16(d.frame[12:14]), EthernetHeaderSize, end)
+	if used < end {
+		d.layers[eth].Fields = append(d.layers[eth].Fields, bytesField(d.frame, "Padding", used, end-used))
+	}
+	if hasFCS {
+		fcs := bytesField(d.frame, "Frame Check Sequence", end, FCSSize)
+		fcs.Value = uint64(binary.BigEndian.Uint32(d.frame[end:]))
+		fcs.Display = fmt.Sprintf("0x%08x %s", fcs.Value,
+			checkStatus(calcCRC32IEEE(d.frame[:end]) == uint32(fcs.Value)))
+		d.layers[eth].Fields = append(d.layers[eth].Fields, fcs)
+	}
+}
+
+// payload 按类型字段解码上层, 不大于1500时为长度字段, 其后为LLC; 返回结束偏移
+func (d *dissector) payload(etherType uint16, offset, end int) int {
+	if etherType <= MaxDataSize {
+		return d.llc(offset, min(offset+int(etherType), end))
+	}
+	switch etherType {
+	case EtherTypeVLAN, EtherTypeQinQ:
+		return d.vlan(etherType, offset, end)
+	case EtherTypeARP:
+		return d.arp(offset, end)
+	case EtherTypeIPv4:
+		return d.ipv4(offset, end)
+	case EtherTypeIPv6:
+		return d.ipv6(offset, end)
+	default:
+		return d.data(offset, end)
+	}
+}
+
+// vlan 解码802.1Q标签及其内层, 返回结束偏移
+func (d *dissector) vlan(tpid uint16, offset, end int) int {
+	if end-offset < VLANTagSize {
+		d.malformed(offset, end, truncated("802.1Q", 0, VLANTagSize, end-offset))
+		return end
+	}
+	name := "802.1Q Virtual LAN"
+	if tpid == EtherTypeQinQ {
+		name = "802.1ad Service VLAN"
+	}
+	i := d.add(name, offset, VLANTagSize, VLANTagFields)
+	tag := VLANTag{TPID: tpid, TCI: binary.BigEndian.Uint16(d.frame[offset : offset+2])}
+	dei := 0
+	if tag.DEI() {
+		dei = 1
+	}
+	d.layers[i].Summary = fmt.Sprintf("PRI: %d, DEI: %d, ID: %d", tag.PCP(), dei, tag.VID())
+	return d.payload(binary.BigEndian.Uint16(d.frame[offset+2:offset+4]), offset+VLANTagSize, end)
+}
+
+// llc 解码LLC与SNAP头部, 组织代码为0的SNAP按以太网类型继续解码, 返回结束偏移
+func (d *dissector) llc(offset, end int) int {
+	llc, err := DeserializeLLC(d.frame[offset:end])
+	if err != nil {
+		d.malformed(offset, end, err)
+		return end
+	}
+	specs := LLCFields
+	if !isUFormat(uint8(llc.Control)) {
+		specs = append([]FieldSpec(nil), LLCFields...)
+		specs[2].Bits = 16
+	}
+	i := d.add("Logical-Link Control", offset, llc.HeaderSize(), specs)
+	d.layers[i].Summary = fmt.Sprintf("DSAP: %s, SSAP: %s, %s", sapName(llc.DSAP), sapName(llc.SSAP),
+		llcControlName(llc.Control))
+	if llc.DSAP == SAPSTP && llc.SSAP&0xFE == SAPSTP {
+		return d.stp(offset+llc.HeaderSize(), end)
+	}
+	if !llc.IsSNAP() {
+		return d.data(offset+llc.HeaderSize(), end)
+	}
+	d.layers[i].Fields = append(d.layers[i].Fields, buildFields(d.frame, offset, SNAPFields)...)
+	d.layers[i].Summary += fmt.Sprintf(", OUI: 0x%02x%02x%02x, PID: 0x%04x", llc.OUI[0], llc.OUI[1], llc.OUI[2], llc.PID)
+	if llc.OUI == [3]byte{} && llc.PID >= EtherTypeMin {
+		return d.payload(llc.PID, offset+llc.HeaderSize(), end)
+	}
+	return d.data(offset+llc.HeaderSize(), end)
+}
+
+// stp 解码BPDU, TCN BPDU只有前3个字段, 只有RST BPDU带版本1长度; 返回结束偏移
+func (d *dissector) stp(offset, end int) int {
+	bpdu, err := DeserializeBPDU(d.frame[offset:end])
+	if err != nil {
+		d.malformed(offset, end, err)
+		return end
+	}
+	// 未知类型与 Size 一致按配置BPDU的长度解码
+	specs := BPDUFields[:len(BPDUFields)-1]
+	switch bpdu.Type {
+	case BPDUTypeTCN:
+		specs = BPDUFields[:3]
+	case BPDUTypeRST:
+		specs = BPDUFields
+	}
+	i := d.add("Spanning Tree Protocol", offset, bpdu.Size(), specs)
+	d.layers[i].Summary = bpdu.Summary()
+	return offset + bpdu.Size()
+}
+
+// arp 解码ARP报文, 返回结束偏移
+func (d *dissector) arp(offset, end int) int {
+	arp, err := DeserializeARPPacket(d.frame[offset:end])
+	if err != nil {
+		d.malformed(offset, end, err)
+		return end
+	}
+	i := d.add("Address Resolution Protocol", offset, 28, ARPFields)
+	d.layers[i].Summary = arpOpName(arp.Operation)
+	return offset + 28
+}
+
+// ipv4 解码IPv4报文, 返回结束偏移
+func (d *dissector) ipv4(offset, end int) int {
+	ip, err := DeserializeIPv4Packet(d.frame[offset:end])
+	if ip == nil {
+		// 固定头部完整时仍显示其字段, 便于看出错在哪里
+		if end-offset >= 20 {
+			d.add("Internet Protocol Version 4", offset, end-offset, IPv4Fields)
+		}
+		d.malformed(offset, end, err)
+		return end
+	}
+	headLen := int(ip.VersionIHL&0x0F) * 4
+	total := int(ip.TotalLength)
+	i := d.add("Internet Protocol Version 4", offset, total, IPv4Fields)
+	layer := &d.layers[i]
+	layer.Field("Header Checksum").Display += " " + checkStatus(err == nil)
+	if headLen > 20 {
+		layer.Fields = append(layer.Fields, bytesField(d.frame, "Options", offset+20, headLen-20))
+	}
+	var src, dst [4]byte
+	copy(src[:], d.frame[offset+12:offset+16])
+	copy(dst[:], d.frame[offset+16:offset+20])
+	layer.Summary = fmt.Sprintf("Src: %s, Dst: %s", FormatIPv4(src), FormatIPv4(dst))
+	payload, payloadEnd := offset+headLen, offset+total
+	switch d.frame[offset+9] {
+	case IPProtocolICMP:
+		d.icmp(payload, payloadEnd)
+	case IPProtocolTCP:
+		d.tcp(payload, payloadEnd, src[:], dst[:])
+	case IPProtocolUDP:
+		d.udp(payload, payloadEnd, src[:], dst[:])
+	default:
+		d.data(payload, payloadEnd)
+	}
+	return payloadEnd
+}
+
+// ipv6 解码IPv6报文, 返回结束偏移
+func (d *dissector) ipv6(offset, end int) int {
+	ip, err := DeserializeIPv6Packet(d.frame[offset:end])
+	if err != nil {
+		if end-offset >= 40 {
+			d.add("Internet Protocol Version 6", offset, end-offset, IPv6Fields)
+		}
+		d.malformed(offset, end, err)
+		return end
+	}
+	payloadEnd := offset + 40 + len(ip.Data)
+	i := d.add("Internet Protocol Version 6", offset, payloadEnd-offset, IPv6Fields)
+	d.layers[i].Summary = fmt.Sprintf("Src: %s, Dst: %s",
+		formatIPv6(d.frame[offset+8:offset+24]), formatIPv6(d.frame[offset+24:offset+40]))
+	switch d.frame[offset+6] {
+	case IPProtocolTCP:
+		d.tcp(offset+40, payloadEnd, nil, nil)
+	case IPProtocolUDP:
+		d.udp(offset+40, payloadEnd, nil, nil)
+	default:
+		d.data(offset+40, payloadEnd)
+	}
+	return payloadEnd
+}
+
+// icmp 解码ICMP报文
+func (d *dissector) icmp(offset, end int) {
+	icmp, err := DeserializeICMPPacket(d.frame[offset:end])
+	if icmp == nil {
+		d.malformed(offset, end, err)
+		return
+	}
+	typ := icmp.Type
+	specs := ICMPFields
+	if typ != ICMPTypeEchoRequest && typ != ICMPTypeEchoReply {
+		specs = append(specs[:3:3], FieldSpec{Name: "Rest of Header", Bit: 32, Bits: 32, Format: formatHex})
+	}
+	i := d.add("Internet Control Message Protocol", offset, end-offset, specs)
+	layer := &d.layers[i]
+	layer.Field("Checksum").Display += " " + checkStatus(err == nil)
+	layer.Summary = ICMPTypeName(typ)
+	if end > offset+8 {
+		layer.Fields = append(layer.Fields, bytesField(d.frame, "Data", offset+8, end-offset-8))
+	}
+}
+
+// tcp 解码TCP报文段, src/dst 为IPv4地址时校验伪首部校验和
+func (d *dissector) tcp(offset, end int, src, dst []byte) {
+	if _, err := DeserializeTCPPacket(d.frame[offset:end]); err != nil {
+		if end-offset >= 20 {
+			d.add("Transmission Control Protocol", offset, end-offset, TCPFields)
+		}
+		d.malformed(offset, end, err)
+		return
+	}
+	headLen := int(d.frame[offset+12]>>4) * 4
+	i := d.add("Transmission Control Protocol", offset, headLen, TCPFields)
+	layer := &d.layers[i]
+	if src != nil {
+		ok := calcTCPChecksum(d.frame[offset:end], [4]byte(src), [4]byte(dst)) == 0
+		layer.Field("Checksum").Display += " " + checkStatus(ok)
+	}
+	if headLen > 20 {
+		layer.Fields = append(layer.Fields, bytesField(d.frame, "Options", offset+20, headLen-20))
+	}
+	flags := binary.BigEndian.Uint16(d.frame[offset+12:offset+14]) & 0x01FF
+	layer.Summary = fmt.Sprintf("Src Port: %d, Dst Port: %d, [%s], Len: %d",
+		binary.BigEndian.Uint16(d.frame[offset:offset+2]), binary.BigEndian.Uint16(d.frame[offset+2:offset+4]),
+		TCPFlagNames(flags), end-offset-headLen)
+	d.data(offset+headLen, end)
+}
+
+// udp 解码UDP数据报, src/dst 为IPv4地址时校验伪首部校验和
+func (d *dissector) udp(offset, end int, src, dst []byte) {
+	udp, err := DeserializeUDPPacket(d.frame[offset:end])
+	if err != nil {
+		if end-offset >= 8 {
+			d.add("User Datagram Protocol", offset, end-offset, UDPFields)
+		}
+		d.malformed(offset, end, err)
+		return
+	}
+	end = offset + int(udp.Length)
+	i := d.add("User Datagram Protocol", offset, 8, UDPFields)
+	layer := &d.layers[i]
+	checksum := binary.BigEndian.Uint16(d.frame[offset+6 : offset+8])
+	switch {
+	case checksum == 0:
+		layer.Field("Checksum").Display += " " + i18n.T("[未使用]")
+	case src != nil:
+		ok := calcUDPChecksum(d.frame[offset:end], [4]byte(src), [4]byte(dst)) == 0
+		layer.Field("Checksum").Display += " " + checkStatus(ok)
+	}
+	layer.Summary = fmt.Sprintf("Src Port: %d, Dst Port: %d",
+		binary.BigEndian.Uint16(d.frame[offset:offset+2]), binary.BigEndian.Uint16(d.frame[offset+2:offset+4]))
+	d.data(offset+8, end)
+}
+
+// data 剩余的应用数据, 返回结束偏移
+func (d *dissector) data(offset, end int) int {
+	if end <= offset {
+		return offset
+	}
+	d.layers = append(d.layers, Layer{
+		Name:    "Data",
+		Summary: fmt.Sprintf("%d bytes", end-offset),
+		Offset:  offset,
+		Length:  end - offset,
+		Fields:  []Field{bytesField(d.frame, "Data", offset, end-offset)},
+	})
+	return end
+}
+
+// checkStatus 校验结果
+func checkStatus(ok bool) string {
+	if ok {
+		return i18n.T("[正确]")
+	}
+	return i18n.T("[错误]")
+}
+
+// formatBytes 字节串: 可打印时加引号显示, 否则十六进制, 过长时截断
+func formatBytes(b []byte) string {
+	const limit = 24
+	shown := b
+	if len(shown) > limit {
+		shown = shown[:limit]
+	}
+	printable := true
+	for _, c := range shown {
+		if (c < 32 || c > 126) && c != '\r' && c != '\n' && c != '\t' {
+			printable = false
+			break
+		}
+	}
+	var s string
+	if printable {
+		s = fmt.Sprintf("%q", shown)
+	} else {
+		s = fmt.Sprintf("%x", shown)
+	}
+	if len(b) > limit {
+		s += "…"
+	}
+	return fmt.Sprintf("%s (%d bytes)", s, len(b))
+}
+
+// formatHex 按位宽输出十六进制
+func formatHex(v uint64, raw []byte) string {
+	return fmt.Sprintf("0x%0*x", len(raw)*2, v)
+}
+
+// formatMAC MAC地址, 广播地址附加说明
+func formatMAC(v uint64, raw []byte) string {
+	var mac [6]byte
+	copy(mac[:], raw)
+	if mac == BroadcastMAC {
+		return FormatMAC(mac) + " (Broadcast)"
+	}
+	return FormatMAC(mac)
+}
+
+// formatIPv4Field IPv4地址
+func formatIPv4Field(v uint64, raw []byte) string {
+	var ip [4]byte
+	copy(ip[:], raw)
+	return FormatIPv4(ip)
+}
+
+// formatIPv6 IPv6地址
+func formatIPv6(raw []byte) string {
+	var ip [16]byte
+	copy(ip[:], raw)
+	return netip.AddrFrom16(ip).String()
+}
+
+// formatIPv6Field IPv6地址字段
+func formatIPv6Field(v uint64, raw []byte) string {
+	return formatIPv6(raw)
+}
+
+// formatFlag 单个标志位
+func formatFlag(v uint64, raw []byte) string {
+	if v != 0 {
+		return "Set"
+	}
+	return "Not set"
+}
+
+// formatEtherType 上层协议类型
+func formatEtherType(v uint64, raw []byte) string {
+	if v <= MaxDataSize {
+		return fmt.Sprintf("802.3 length %d", v)
+	}
+	name := map[uint64]string{EtherTypeIPv4: "IPv4", EtherTypeARP: "ARP", EtherTypeIPv6: "IPv6",
+		EtherTypeVLAN: "802.1Q", EtherTypeQinQ: "802.1ad"}[v]
+	if name == "" {
+		name = "Unknown"
+	}
+	return fmt.Sprintf("%s (0x%04x)", name, v)
+}
+
+// formatIPProtocol IP上层协议号
+func formatIPProtocol(v uint64, raw []byte) string {
+	name := map[uint64]string{IPProtocolICMP: "ICMP", IPProtocolTCP: "TCP", IPProtocolUDP: "UDP", 58: "ICMPv6"}[v]
+	if name == "" {
+		name = "Unknown"
+	}
+	return fmt.Sprintf("%s (%d)", name, v)
+}
+
+// formatHeaderLen 以4字节为单位的头部长度
+func formatHeaderLen(v uint64, raw []byte) string {
+	return fmt.Sprintf("%d bytes (%d)", v*4, v)
+}
+
+// formatIPFlags IPv4标志位
+func formatIPFlags(v uint64, raw []byte) string {
+	var set []string
+	if v&0x2 != 0 {
+		set = append(set, "Don't fragment")
+	}
+	if v&0x1 != 0 {
+		set = append(set, "More fragments")
+	}
+	if len(set) == 0 {
+		return fmt.Sprintf("0x%x", v)
+	}
+	return fmt.Sprintf("0x%x, %s", v, strings.Join(set, ", "))
+}
+
+// formatTCPFlags TCP标志位, 如 0x012 [SYN, ACK]
+func formatTCPFlags(v uint64, raw []byte) string {
+	return fmt.Sprintf("0x%03x [%s]", v, TCPFlagNames(uint16(v)))
+}
+
+// formatARPOp ARP操作码
+func formatARPOp(v uint64, raw []byte) string {
+	return fmt.Sprintf("%s (%d)", arpOpName(uint16(v)), v)
+}
+
+// arpOpName ARP操作码名称
+func arpOpName(op uint16) string {
+	switch op {
+	case 1:
+		return "request"
+	case 2:
+		return "reply"
+	default:
+		return "unknown"
+	}
+}
+
+// formatICMPType ICMP类型
+func formatICMPType(v uint64, raw []byte) string {
+	return fmt.Sprintf("%d (%s)", v, ICMPTypeName(uint8(v)))
+}
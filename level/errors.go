@@ -0,0 +1,199 @@
+package level
+
+import (
+	"errors"
+
+	"osiweb-go/i18n"
+)
+
+// 错误代码, 可用 errors.Is 判断错误种类
+// Error sentinels for errors.Is; messages follow the interface language
+var (
+	// ErrBadFormat 文本协议的报文格式错误 Invalid text protocol message
+	ErrBadFormat = &i18n.Error{Code: "level.bad_format"}
+	// ErrBadAddress 地址格式错误 Invalid address
+	ErrBadAddress = &i18n.Error{Code: "level.bad_address"}
+)
+
+// ErrTruncated 数据不足以构成一层报文或其中的一部分
+// 可用 errors.Is(err, &ErrTruncated{}) 判断, errors.As 取出偏移与长度
+// Data too short for a layer or part of it
+type ErrTruncated struct {
+	// 协议层 Layer
+	Layer string
+	// 不完整部分在本层中的起始偏移 Offset of the incomplete part within the layer
+	Offset int
+	// 从本层起始需要的字节数 Bytes needed from the start of the layer
+	Need int
+	// 实际的字节数 Bytes available
+	Have int
+}
+
+func (e *ErrTruncated) Error() string {
+	return i18n.T("%s 被截断: 需要 %d 字节, 只有 %d 字节", e.Layer, e.Need, e.Have)
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrTruncated) Is(target error) bool {
+	_, ok := target.(*ErrTruncated)
+	return ok
+}
+
+func (e *ErrTruncated) offset() int { return e.Offset }
+
+// ErrBadChecksum 校验和错误, 反序列化仍返回解析结果, 由调用方决定是否丢弃
+// Checksum mismatch; Deserialize still returns the decoded packet
+type ErrBadChecksum struct {
+	// 协议层 Layer
+	Layer string
+	// 校验和字段名称, 与解码结果中的字段名称相同 Checksum field name as in the dissection
+	Field string
+	// 校验和字段在本层中的偏移 Offset of the checksum field within the layer
+	Offset int
+	// 计算出的校验和 Computed checksum
+	Want uint32
+	// 报文中的校验和 Checksum carried in the packet
+	Got uint32
+}
+
+func (e *ErrBadChecksum) Error() string {
+	return i18n.T("%s %s 错误: 应为 0x%x, 实际为 0x%x", e.Layer, i18n.Field(e.Field), e.Want, e.Got)
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrBadChecksum) Is(target error) bool {
+	_, ok := target.(*ErrBadChecksum)
+	return ok
+}
+
+func (e *ErrBadChecksum) offset() int   { return e.Offset }
+func (e *ErrBadChecksum) field() string { return e.Field }
+
+// ErrBadVersion 版本号不是本层协议的版本
+// Version field does not match the protocol
+type ErrBadVersion struct {
+	// 协议层 Layer
+	Layer string
+	// 版本字段在本层中的偏移 Offset of the version field within the layer
+	Offset int
+	// 报文中的版本号 Version carried in the packet
+	Version int
+}
+
+func (e *ErrBadVersion) Error() string {
+	return i18n.T("%s 版本错误: %d", e.Layer, e.Version)
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrBadVersion) Is(target error) bool {
+	_, ok := target.(*ErrBadVersion)
+	return ok
+}
+
+func (e *ErrBadVersion) offset() int   { return e.Offset }
+func (e *ErrBadVersion) field() string { return "Version" }
+
+// ErrBadLength 长度字段的取值不合理, 如首部长度小于最小首部
+// A length field holds an impossible value
+type ErrBadLength struct {
+	// 协议层 Layer
+	Layer string
+	// 长度字段名称, 与解码结果中的字段名称相同 Length field name as in the dissection
+	Field string
+	// 长度字段在本层中的偏移 Offset of the length field within the layer
+	Offset int
+	// 字段表示的字节数 Length in bytes given by the field
+	Value int
+}
+
+func (e *ErrBadLength) Error() string {
+	return i18n.T("%s %s 错误: %d 字节", e.Layer, i18n.Field(e.Field), e.Value)
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrBadLength) Is(target error) bool {
+	_, ok := target.(*ErrBadLength)
+	return ok
+}
+
+func (e *ErrBadLength) offset() int   { return e.Offset }
+func (e *ErrBadLength) field() string { return e.Field }
+
+// ErrCodeViolation 线路上的码元不符合线路编码, 物理层无法还原比特
+// Symbols on the wire do not follow the line code; the PHY cannot recover the bits
+type ErrCodeViolation struct {
+	// 线路编码 Line code
+	Code string
+	// 出错的比特或码组序号 Index of the offending bit or code group
+	Symbol int
+	// 出错的码组, 曼彻斯特编码为空 Offending code group, empty for Manchester
+	Group string
+	// 原因 Reason
+	Reason string
+}
+
+func (e *ErrCodeViolation) Error() string {
+	if e.Group == "" {
+		return i18n.T("%s 编码错误: 第 %d 个码元%s", e.Code, e.Symbol, i18n.T(e.Reason))
+	}
+	return i18n.T("%s 编码错误: 第 %d 个码组 %s %s", e.Code, e.Symbol, e.Group, i18n.T(e.Reason))
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrCodeViolation) Is(target error) bool {
+	_, ok := target.(*ErrCodeViolation)
+	return ok
+}
+
+// ErrNoSFD 前导码之后没有帧起始定界符, 接收方无法找到帧的开始
+// No start frame delimiter after the preamble; the receiver cannot find the frame
+type ErrNoSFD struct {
+	// 线路编码 Line code
+	Code string
+}
+
+func (e *ErrNoSFD) Error() string {
+	return i18n.T("%s 前导码之后没有帧起始定界符 0xd5", e.Code)
+}
+
+// Is 同类型的错误视为同一种错误
+func (e *ErrNoSFD) Is(target error) bool {
+	_, ok := target.(*ErrNoSFD)
+	return ok
+}
+
+// ErrorOffset 错误在本层中的字节偏移, 不是反序列化错误时为0
+// Byte offset within the layer where a Deserialize error occurred, 0 otherwise
+// @param err Deserialize 返回的错误
+// @return int 偏移
+func ErrorOffset(err error) int {
+	var e interface{ offset() int }
+	if errors.As(err, &e) {
+		return e.offset()
+	}
+	return 0
+}
+
+// errorField 出错的字段名称, 截断或不是反序列化错误时为空
+func errorField(err error) string {
+	var e interface{ field() string }
+	if errors.As(err, &e) {
+		return e.field()
+	}
+	return ""
+}
+
+// truncated 数据不足 need 字节
+func truncated(layer string, offset, need, have int) error {
+	return &ErrTruncated{Layer: layer, Offset: offset, Need: need, Have: have}
+}
+
+// badFormat 数据不是 layer 报文的格式
+func badFormat(layer string) error {
+	return i18n.Errorf(ErrBadFormat.Code, "数据格式错误，不是有效的%s报文", layer)
+}
+
+// badAddress 地址字符串无法解析
+func badAddress(format, s string) error {
+	return i18n.Errorf(ErrBadAddress.Code, format, s)
+}
@@ -0,0 +1,235 @@
+package level
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+var (
+	testMAC1 = [6]byte{0x02, 0, 0, 0, 0, 0x01}
+	testMAC2 = [6]byte{0x02, 0, 0, 0, 0, 0x02}
+	testIP1  = [4]byte{10, 0, 0, 1}
+	testIP2  = [4]byte{10, 0, 0, 2}
+)
+
+// errorCase 反序列化的一个错误输入
+type errorCase struct {
+	name string
+	data []byte
+	want error
+}
+
+// modify 复制 data 后用 fn 修改
+func modify(data []byte, fn func(b []byte)) []byte {
+	b := append([]byte(nil), data...)
+	fn(b)
+	return b
+}
+
+// checkError 检查错误的种类与偏移、长度等字段
+func checkError(t *testing.T, err, want error) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+		return
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("err = %v (%T), want %T", err, err, want)
+	}
+	switch w := want.(type) {
+	case *ErrTruncated:
+		var got *ErrTruncated
+		if !errors.As(err, &got) || *got != *w {
+			t.Errorf("err = %+v, want %+v", got, w)
+		}
+	case *ErrBadVersion:
+		var got *ErrBadVersion
+		if !errors.As(err, &got) || *got != *w {
+			t.Errorf("err = %+v, want %+v", got, w)
+		}
+	case *ErrBadLength:
+		var got *ErrBadLength
+		if !errors.As(err, &got) || *got != *w {
+			t.Errorf("err = %+v, want %+v", got, w)
+		}
+	case *ErrBadChecksum:
+		var got *ErrBadChecksum
+		if !errors.As(err, &got) || got.Layer != w.Layer || got.Field != w.Field || got.Offset != w.Offset {
+			t.Errorf("err = %+v, want %+v", got, w)
+		} else if got.Want == got.Got {
+			t.Errorf("checksum error with equal values 0x%x", got.Want)
+		}
+	}
+	if got, want := ErrorOffset(err), ErrorOffset(want); got != want {
+		t.Errorf("ErrorOffset = %d, want %d", got, want)
+	}
+}
+
+func TestDeserializeIPv4PacketErrors(t *testing.T) {
+	valid := NewIPv4Packet(testIP1, testIP2, IPProtocolUDP, []byte("hello")).Serialize()
+	withOptions := modify(append(append([]byte(nil), valid[:20]...), 1, 1, 1, 0), func(b []byte) {
+		b[0] = 0x46
+		binary.BigEndian.PutUint16(b[2:4], 24)
+	})
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"empty", nil, &ErrTruncated{Layer: "IPv4", Offset: 0, Need: 20, Have: 0}},
+		{"short header", valid[:10], &ErrTruncated{Layer: "IPv4", Offset: 0, Need: 20, Have: 10}},
+		{"version 6", modify(valid, func(b []byte) { b[0] = 0x65 }), &ErrBadVersion{Layer: "IPv4", Version: 6}},
+		{"IHL below 5", modify(valid, func(b []byte) { b[0] = 0x44 }),
+			&ErrBadLength{Layer: "IPv4", Field: "Header Length", Value: 16}},
+		{"options cut off", withOptions[:22], &ErrTruncated{Layer: "IPv4", Offset: 20, Need: 24, Have: 22}},
+		{"total length below header", modify(valid, func(b []byte) { binary.BigEndian.PutUint16(b[2:4], 12) }),
+			&ErrBadLength{Layer: "IPv4", Field: "Total Length", Offset: 2, Value: 12}},
+		{"payload cut off", valid[:22], &ErrTruncated{Layer: "IPv4", Offset: 20, Need: len(valid), Have: 22}},
+		{"bad checksum", modify(valid, func(b []byte) { b[11] ^= 0xFF }),
+			&ErrBadChecksum{Layer: "IPv4", Field: "Header Checksum", Offset: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := DeserializeIPv4Packet(tt.data)
+			checkError(t, err, tt.want)
+			// 校验和错误仍返回解析结果
+			if errors.Is(err, &ErrBadChecksum{}) && ip == nil {
+				t.Error("packet is nil on a checksum error")
+			}
+		})
+	}
+}
+
+func TestDeserializeFrameErrors(t *testing.T) {
+	valid := NewEthernet2(testMAC2, testMAC1, "IP", NewIPv4Packet(testIP1, testIP2, IPProtocolUDP, nil).Serialize()).Serialize()
+	minFrame := EthernetHeaderSize + MinDataSize + FCSSize
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"header cut off", valid[:10], &ErrTruncated{Layer: "Ethernet II", Offset: 0, Need: minFrame, Have: 10}},
+		{"runt", valid[:40], &ErrTruncated{Layer: "Ethernet II", Offset: EthernetHeaderSize, Need: minFrame, Have: 40}},
+		{"bad FCS", modify(valid, func(b []byte) { b[len(b)-1] ^= 0xFF }),
+			&ErrBadChecksum{Layer: "Ethernet II", Field: "Frame Check Sequence", Offset: len(valid) - FCSSize}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Deserialize(tt.data)
+			checkError(t, err, tt.want)
+		})
+	}
+
+	// 不带CRC时不要求最小帧长
+	unpadded := valid[:len(valid)-MinDataSize+20-FCSSize]
+	tagCut := modify(valid[:16], func(b []byte) { binary.BigEndian.PutUint16(b[12:14], EtherTypeVLAN) })
+	absent := []errorCase{
+		{"valid", unpadded, nil},
+		{"header cut off", valid[:10], &ErrTruncated{Layer: "Ethernet II", Offset: 0, Need: EthernetHeaderSize, Have: 10}},
+		{"VLAN tag cut off", tagCut, &ErrTruncated{Layer: "Ethernet II", Offset: 12, Need: 18, Have: 16}},
+	}
+	for _, tt := range absent {
+		t.Run("FCS absent/"+tt.name, func(t *testing.T) {
+			_, err := DeserializeFrame(tt.data, FCSAbsent)
+			checkError(t, err, tt.want)
+		})
+	}
+}
+
+func TestDeserializeTCPPacketErrors(t *testing.T) {
+	valid := NewTCPPacket(1024, 80, 1, 0, TCPFlagSYN, 65535, nil).Serialize(testIP1, testIP2)
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"short header", valid[:19], &ErrTruncated{Layer: "TCP", Offset: 0, Need: 20, Have: 19}},
+		{"data offset below 5", modify(valid, func(b []byte) { b[12] = 0x40 }),
+			&ErrBadLength{Layer: "TCP", Field: "Header Length", Offset: 12, Value: 16}},
+		{"options cut off", modify(valid, func(b []byte) { b[12] = 0x60 }),
+			&ErrTruncated{Layer: "TCP", Offset: 20, Need: 24, Have: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DeserializeTCPPacket(tt.data)
+			checkError(t, err, tt.want)
+		})
+	}
+}
+
+func TestDeserializeUDPPacketErrors(t *testing.T) {
+	valid := NewUDPPacket(1024, 53, []byte("query")).Serialize(testIP1, testIP2)
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"short header", valid[:7], &ErrTruncated{Layer: "UDP", Offset: 0, Need: 8, Have: 7}},
+		{"length below header", modify(valid, func(b []byte) { binary.BigEndian.PutUint16(b[4:6], 4) }),
+			&ErrBadLength{Layer: "UDP", Field: "Length", Offset: 4, Value: 4}},
+		{"payload cut off", valid[:10], &ErrTruncated{Layer: "UDP", Offset: 8, Need: len(valid), Have: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DeserializeUDPPacket(tt.data)
+			checkError(t, err, tt.want)
+		})
+	}
+}
+
+func TestDeserializeICMPPacketErrors(t *testing.T) {
+	valid := NewICMPPacket(ICMPTypeEchoRequest, 0, 1, 1, []byte("ping")).Serialize()
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"short header", valid[:7], &ErrTruncated{Layer: "ICMP", Offset: 0, Need: 8, Have: 7}},
+		{"bad checksum", modify(valid, func(b []byte) { b[len(b)-1] ^= 0xFF }),
+			&ErrBadChecksum{Layer: "ICMP", Field: "Checksum", Offset: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DeserializeICMPPacket(tt.data)
+			checkError(t, err, tt.want)
+		})
+	}
+}
+
+func TestDeserializeARPPacketErrors(t *testing.T) {
+	valid := NewARPPacket(1, testMAC1, testIP1, [6]byte{}, testIP2).Serialize()
+	tests := []errorCase{
+		{"valid", valid, nil},
+		{"short", valid[:27], &ErrTruncated{Layer: "ARP", Offset: 0, Need: 28, Have: 27}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DeserializeARPPacket(tt.data)
+			checkError(t, err, tt.want)
+		})
+	}
+}
+
+// FuzzDeserialize 任意输入都不应使反序列化与逐层解码崩溃, 解码出的各层不超出帧的范围
+func FuzzDeserialize(f *testing.F) {
+	ip := NewIPv4Packet(testIP1, testIP2, IPProtocolTCP, NewTCPPacket(1024, 80, 1, 1, TCPFlagACK, 65535, []byte("GET /")).Serialize(testIP1, testIP2))
+	tagged := NewEthernet2(testMAC2, testMAC1, "IP", ip.Serialize())
+	tagged.PushTag(NewVLANTag(EtherTypeVLAN, 3, false, 10))
+	seeds := [][]byte{
+		NewEthernet2(testMAC2, testMAC1, "ARP", NewARPPacket(1, testMAC1, testIP1, [6]byte{}, testIP2).Serialize()).Serialize(),
+		NewEthernet2(testMAC2, testMAC1, "IP", ip.Serialize()).Serialize(),
+		tagged.Serialize(),
+		NewIEEE8023(testMAC2, testMAC1, NewSNAP([3]byte{}, EtherTypeIPv4, ip.Serialize())).Serialize(),
+		nil,
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if frame, err := Deserialize(data); frame != nil {
+			frame.Serialize()
+		} else if err == nil {
+			t.Fatal("nil frame without an error")
+		}
+		for _, mode := range []FCSMode{FCSPresent, FCSAbsent, FCSAuto} {
+			if frame, err := DeserializeFrame(data, mode); err == nil {
+				frame.Serialize()
+			}
+		}
+		for _, layers := range [][]Layer{Dissect(data), DissectWithoutFCS(data)} {
+			for _, l := range layers {
+				if l.Offset < 0 || l.Length < 0 || l.Offset+l.Length > len(data) {
+					t.Fatalf("layer %s [%d, +%d) outside the %d byte frame", l.Name, l.Offset, l.Length, len(data))
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,11 @@
+package level
+
+import "osiweb-go/logging"
+
+// 各层的日志, 报文校验失败时以 debug 级别记录原因
+// Per-layer loggers; validation failures are logged at debug level
+var (
+	logL2 = logging.Logger(logging.L2)
+	logL3 = logging.Logger(logging.L3)
+	logL4 = logging.Logger(logging.L4)
+)
@@ -0,0 +1,209 @@
+package level
+
+import (
+	"fmt"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// Summarize 生成以太网帧的单行摘要
+// One-line summary of an Ethernet frame
+// @param frame 序列化后的以太网帧(含CRC)
+// @return string 摘要
+func Summarize(frame []byte) string {
+	if len(frame) < EthernetHeaderSize {
+		return i18n.T("(%d 字节, 不完整的帧)", len(frame))
+	}
+	var dst, src [6]byte
+	copy(dst[:], frame[0:6])
+	copy(src[:], frame[6:12])
+	eth := fmt.Sprintf("%s > %s", FormatMAC(src), FormatMAC(dst))
+	tags, etherType, offset := FrameTags(frame)
+	for _, tag := range tags {
+		eth += ", " + tag.String()
+	}
+	payload := frame[offset:]
+	if len(payload) >= 4 {
+		payload = payload[:len(payload)-4]
+	}
+	if etherType <= MaxDataSize {
+		return eth + ", " + summarizeLLC(payload[:min(int(etherType), len(payload))])
+	}
+	switch etherType {
+	case EtherTypeARP:
+		return eth + ", " + summarizeARP(payload)
+	case EtherTypeIPv4:
+		return eth + ", " + summarizeIPv4(payload)
+	case EtherTypeIPv6:
+		return eth + ", IPv6"
+	default:
+		return i18n.T("%s, ethertype 0x%04x, %d 字节", eth, etherType, len(frame))
+	}
+}
+
+// summarizeARP ARP 报文摘要
+// ARP packet summary
+func summarizeARP(data []byte) string {
+	arp, err := DeserializeARPPacket(data)
+	if err != nil {
+		return err.Error()
+	}
+	switch arp.Operation {
+	case 1:
+		return fmt.Sprintf("ARP who-has %s tell %s", FormatIPv4(arp.TargetIP), FormatIPv4(arp.SenderIP))
+	case 2:
+		return fmt.Sprintf("ARP %s is-at %s", FormatIPv4(arp.SenderIP), FormatMAC(arp.SenderMAC))
+	default:
+		return fmt.Sprintf("ARP op=%d", arp.Operation)
+	}
+}
+
+// summarizeLLC 802.3 帧中的 LLC 报文摘要
+// LLC PDU summary for an 802.3 frame
+func summarizeLLC(data []byte) string {
+	llc, err := DeserializeLLC(data)
+	if err != nil {
+		return err.Error()
+	}
+	if llc.DSAP == SAPSTP {
+		bpdu, err := DeserializeBPDU(llc.Data)
+		if err != nil {
+			return err.Error()
+		}
+		return bpdu.Summary()
+	}
+	if llc.IsSNAP() {
+		return fmt.Sprintf("802.3 LLC SNAP oui 0x%02x%02x%02x pid 0x%04x len=%d",
+			llc.OUI[0], llc.OUI[1], llc.OUI[2], llc.PID, len(llc.Data))
+	}
+	return fmt.Sprintf("802.3 LLC dsap 0x%02x ssap 0x%02x %s len=%d",
+		llc.DSAP, llc.SSAP, llcControlName(llc.Control), len(llc.Data))
+}
+
+// summarizeIPv4 IPv4 报文摘要
+// IPv4 packet summary
+func summarizeIPv4(data []byte) string {
+	// 校验和错误时仍有解析结果, 照常给出摘要
+	ip, err := DeserializeIPv4Packet(data)
+	if ip == nil {
+		return err.Error()
+	}
+	src, dst := FormatIPv4(ip.SourceIP), FormatIPv4(ip.DestIP)
+	switch ip.Protocol {
+	case IPProtocolICMP:
+		icmp, err := DeserializeICMPPacket(ip.Data)
+		if icmp == nil {
+			return fmt.Sprintf("IPv4 %s > %s %s", src, dst, err.Error())
+		}
+		return fmt.Sprintf("IPv4 %s > %s ICMP %s id=%d seq=%d ttl=%d",
+			src, dst, ICMPTypeName(icmp.Type), icmp.Identifier, icmp.Sequence, ip.TTL)
+	case IPProtocolTCP:
+		tcp, err := DeserializeTCPPacket(ip.Data)
+		if err != nil {
+			return fmt.Sprintf("IPv4 %s > %s %s", src, dst, err.Error())
+		}
+		return fmt.Sprintf("IPv4 %s:%d > %s:%d TCP [%s] seq=%d ack=%d len=%d",
+			src, tcp.SourcePort, dst, tcp.DestPort, TCPFlagNames(tcp.Flags()),
+			tcp.SeqNum, tcp.AckNum, len(tcp.Data))
+	case IPProtocolUDP:
+		udp, err := DeserializeUDPPacket(ip.Data)
+		if err != nil {
+			return fmt.Sprintf("IPv4 %s > %s %s", src, dst, err.Error())
+		}
+		return fmt.Sprintf("IPv4 %s:%d > %s:%d UDP len=%d",
+			src, udp.SourcePort, dst, udp.DestPort, len(udp.Data))
+	default:
+		return fmt.Sprintf("IPv4 %s > %s proto=%d", src, dst, ip.Protocol)
+	}
+}
+
+// ICMPTypeName 返回 ICMP 类型名称
+// Return ICMP type name
+func ICMPTypeName(typ uint8) string {
+	switch typ {
+	case ICMPTypeEchoReply:
+		return "echo reply"
+	case ICMPTypeUnreachable:
+		return "destination unreachable"
+	case ICMPTypeEchoRequest:
+		return "echo request"
+	case ICMPTypeTimeExceeded:
+		return "time exceeded"
+	default:
+		return fmt.Sprintf("type %d", typ)
+	}
+}
+
+// TCPFlagNames 返回 TCP 标志位名称, 如 "SYN, ACK"
+// Return TCP flag names such as "SYN, ACK"
+func TCPFlagNames(flags uint16) string {
+	names := []struct {
+		bit  uint16
+		name string
+	}{
+		{TCPFlagSYN, "SYN"}, {TCPFlagFIN, "FIN"}, {TCPFlagRST, "RST"},
+		{TCPFlagPSH, "PSH"}, {TCPFlagACK, "ACK"}, {TCPFlagURG, "URG"},
+	}
+	var set []string
+	for _, n := range names {
+		if flags&n.bit != 0 {
+			set = append(set, n.name)
+		}
+	}
+	return strings.Join(set, ", ")
+}
+
+// Protocols 返回以太网帧从外到内的协议名称, 如 ethernet, ipv4, tcp, 带标签时含 vlan, 802.3帧为 ethernet, llc[, snap|stp]
+// Protocol names of an Ethernet frame from outermost to innermost, e.g. ethernet, ipv4, tcp
+// @param frame 序列化后的以太网帧(含CRC)
+// @return []string 协议名称, 帧不完整时为空
+func Protocols(frame []byte) []string {
+	if len(frame) < EthernetHeaderSize {
+		return nil
+	}
+	names := []string{"ethernet"}
+	tags, etherType, offset := FrameTags(frame)
+	if len(tags) > 0 {
+		names = append(names, "vlan")
+	}
+	payload := frame[offset:]
+	if len(payload) >= 4 {
+		payload = payload[:len(payload)-4]
+	}
+	if etherType <= MaxDataSize {
+		names = append(names, "llc")
+		llc, err := DeserializeLLC(payload[:min(int(etherType), len(payload))])
+		switch {
+		case err != nil:
+		case llc.IsSNAP():
+			names = append(names, "snap")
+		case llc.DSAP == SAPSTP:
+			names = append(names, "stp")
+		}
+		return names
+	}
+	switch etherType {
+	case EtherTypeARP:
+		return append(names, "arp")
+	case EtherTypeIPv6:
+		return append(names, "ipv6")
+	case EtherTypeIPv4:
+	default:
+		return names
+	}
+	names = append(names, "ipv4")
+	ip, _ := DeserializeIPv4Packet(payload)
+	if ip == nil {
+		return names
+	}
+	switch ip.Protocol {
+	case IPProtocolICMP:
+		names = append(names, "icmp")
+	case IPProtocolTCP:
+		names = append(names, "tcp")
+	case IPProtocolUDP:
+		names = append(names, "udp")
+	}
+	return names
+}
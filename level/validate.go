@@ -0,0 +1,308 @@
+package level
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// Severity 诊断结果的严重程度
+// Severity of a validation finding
+type Severity int
+
+// const 严重程度, 由轻到重
+// Severities from least to most serious
+const (
+	// SeverityInfo 合法但值得注意, 如免费ARP Legal but notable
+	SeverityInfo Severity = iota
+	// SeverityWarning 违反建议(SHOULD)或可疑的取值 Violates a SHOULD or looks suspicious
+	SeverityWarning
+	// SeverityError 违反规范(MUST), 接收方应丢弃 Violates a MUST; receivers drop it
+	SeverityError
+)
+
+// String 严重程度名称
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return i18n.T("提示")
+	case SeverityWarning:
+		return i18n.T("警告")
+	default:
+		return i18n.T("错误")
+	}
+}
+
+// Finding 一条诊断结果, 指出哪一层哪个字段违反了哪条规范
+// A validation finding: which field of which layer breaks which rule
+type Finding struct {
+	// 严重程度 Severity
+	Severity Severity
+	// 协议层 Layer
+	Layer string
+	// 相关字段, 与解码结果中的字段名称相同, 可为空 Field name as in the dissection, may be empty
+	Field string
+	// 说明 Description
+	Message string
+	// 依据的规范, 如 RFC 791 §3.1 Specification reference such as RFC 791 §3.1
+	Ref string
+}
+
+// String 如 [错误] IPv4 总长度: ... (RFC 791 §3.1), 反序列化错误的消息已带有层名
+func (f Finding) String() string {
+	if strings.HasPrefix(f.Message, f.Layer+" ") {
+		return fmt.Sprintf("[%s] %s (%s)", f.Severity, f.Message, f.Ref)
+	}
+	where := f.Layer
+	if f.Field != "" {
+		where += " " + i18n.Field(f.Field)
+	}
+	return fmt.Sprintf("[%s] %s: %s (%s)", f.Severity, where, f.Message, f.Ref)
+}
+
+// Findings 一层或整个帧的诊断结果
+// Findings of a layer or a whole frame
+type Findings []Finding
+
+// Valid 没有错误级别的诊断结果
+// Whether no finding is an error
+func (fs Findings) Valid() bool {
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// check 收集一层的诊断结果
+type check struct {
+	layer    string
+	findings Findings
+}
+
+// add 追加一条诊断结果, format 为中文消息, 按界面语言翻译
+func (c *check) add(sev Severity, field, ref, format string, args ...any) {
+	c.findings = append(c.findings, Finding{
+		Severity: sev,
+		Layer:    c.layer,
+		Field:    field,
+		Message:  i18n.T(format, args...),
+		Ref:      ref,
+	})
+}
+
+// done 返回结果, 错误级别的结果以 debug 级别记入本层日志
+func (c *check) done(log *slog.Logger) Findings {
+	for _, f := range c.findings {
+		if f.Severity == SeverityError {
+			log.Debug(f.Message, "layer", f.Layer, "field", f.Field, "ref", f.Ref)
+		}
+	}
+	return c.findings
+}
+
+// layerRefs 各层的规范, 用于反序列化错误
+var layerRefs = map[string]string{
+	"Ethernet II": "IEEE 802.3 §3.2",
+	"802.1Q":      "IEEE 802.1Q §9",
+	"LLC":         "IEEE 802.2 §3",
+	"STP":         "IEEE 802.1D §9.3",
+	"ARP":         "RFC 826",
+	"NDP":         "RFC 4861 §4",
+	"IPv4":        "RFC 791 §3.1",
+	"IPv6":        "RFC 8200 §3",
+	"ICMP":        "RFC 792",
+	"TCP":         "RFC 9293 §3.1",
+	"UDP":         "RFC 768",
+	"DNS":         "RFC 1035 §4.1",
+	"TLS 1.2":     "RFC 5246 §6.2",
+	"TLS 1.3":     "RFC 8446 §5.1",
+	"HTTP":        "RFC 9112 §2",
+	"FTP":         "RFC 959 §4",
+	"SSH":         "RFC 4253 §4.2",
+}
+
+// errorFinding 反序列化错误转为错误级别的诊断结果
+func errorFinding(layer string, err error) Finding {
+	ref := layerRefs[layer]
+	var badSum *ErrBadChecksum
+	if errors.As(err, &badSum) && layer == "IPv4" {
+		ref = "RFC 1071"
+	}
+	return Finding{Severity: SeverityError, Layer: layer, Field: errorField(err), Message: err.Error(), Ref: ref}
+}
+
+// ValidateFrame 逐层解码以太网帧并汇总各层的诊断结果, 某层无法解码时到此为止
+// Validate every layer of an Ethernet frame, stopping at the first undecodable layer
+// @param frame 以太网帧
+// @param hasFCS 帧尾是否带CRC
+// @return Findings 从外到内各层的诊断结果
+func ValidateFrame(frame []byte, hasFCS bool) Findings {
+	var fs Findings
+	mode := FCSPresent
+	if !hasFCS {
+		mode = FCSAbsent
+	}
+	eth, err := DeserializeFrame(frame, mode)
+	if err != nil {
+		fs = append(fs, errorFinding("Ethernet II", err))
+	}
+	if eth == nil {
+		return fs
+	}
+	fs = append(fs, eth.Validate()...)
+	if eth.IsIEEE8023() {
+		llc, err := DeserializeLLC(eth.DataPackage)
+		if err != nil {
+			return append(fs, errorFinding("LLC", err))
+		}
+		fs = append(fs, llc.Validate()...)
+		if llc.DSAP == SAPSTP {
+			bpdu, err := DeserializeBPDU(llc.Data)
+			if err != nil {
+				return append(fs, errorFinding("STP", err))
+			}
+			fs = append(fs, bpdu.Validate()...)
+		}
+		return fs
+	}
+	switch eth.EtherType() {
+	case EtherTypeARP:
+		arp, err := DeserializeARPPacket(eth.DataPackage)
+		if err != nil {
+			return append(fs, errorFinding("ARP", err))
+		}
+		fs = append(fs, arp.Validate()...)
+	case EtherTypeIPv4:
+		fs = append(fs, validateIPv4(eth.DataPackage)...)
+	case EtherTypeIPv6:
+		ip, err := DeserializeIPv6Packet(eth.DataPackage)
+		if err != nil {
+			return append(fs, errorFinding("IPv6", err))
+		}
+		fs = append(fs, ip.Validate()...)
+	}
+	return fs
+}
+
+// validateIPv4 IPv4及其上层的诊断结果
+func validateIPv4(data []byte) Findings {
+	ip, err := DeserializeIPv4Packet(data)
+	if ip == nil {
+		return Findings{errorFinding("IPv4", err)}
+	}
+	var fs Findings
+	if err != nil {
+		fs = append(fs, errorFinding("IPv4", err))
+	}
+	fs = append(fs, ip.Validate()...)
+	// 分片只有第一片带上层头部
+	if ip.FlagsFragOffset&0x1FFF != 0 {
+		return fs
+	}
+	switch ip.Protocol {
+	case IPProtocolICMP:
+		icmp, err := DeserializeICMPPacket(ip.Data)
+		if err != nil {
+			fs = append(fs, errorFinding("ICMP", err))
+		}
+		if icmp != nil {
+			fs = append(fs, icmp.Validate()...)
+		}
+	case IPProtocolTCP:
+		tcp, err := DeserializeTCPPacket(ip.Data)
+		if err != nil {
+			return append(fs, errorFinding("TCP", err))
+		}
+		if calcTCPChecksum(ip.Data, ip.SourceIP, ip.DestIP) != 0 {
+			fs = append(fs, pseudoChecksumFinding("TCP", ip.Data, 16, ip))
+		}
+		fs = append(fs, tcp.Validate()...)
+		fs = append(fs, validateApp(tcp.SourcePort, tcp.DestPort, tcp.Data, true)...)
+	case IPProtocolUDP:
+		udp, err := DeserializeUDPPacket(ip.Data)
+		if err != nil {
+			return append(fs, errorFinding("UDP", err))
+		}
+		// 校验和为0表示发送方未计算
+		if udp.Checksum != 0 && calcUDPChecksum(ip.Data[:udp.Length], ip.SourceIP, ip.DestIP) != 0 {
+			fs = append(fs, pseudoChecksumFinding("UDP", ip.Data[:udp.Length], 6, ip))
+		}
+		fs = append(fs, udp.Validate()...)
+		fs = append(fs, validateApp(udp.SourcePort, udp.DestPort, udp.Data, false)...)
+	}
+	return fs
+}
+
+// pseudoChecksumFinding 带伪首部的校验和错误, at 为校验和字段的偏移
+func pseudoChecksumFinding(layer string, data []byte, at int, ip *IPv4Packet) Finding {
+	segment := append([]byte(nil), data...)
+	got := binary.BigEndian.Uint16(segment[at : at+2])
+	segment[at], segment[at+1] = 0, 0
+	want := calcTCPChecksum(segment, ip.SourceIP, ip.DestIP)
+	if layer == "UDP" {
+		want = calcUDPChecksum(segment, ip.SourceIP, ip.DestIP)
+	}
+	return errorFinding(layer, &ErrBadChecksum{Layer: layer, Field: "Checksum", Offset: at,
+		Want: uint32(want), Got: uint32(got)})
+}
+
+// validateApp 按知名端口识别应用层并诊断, 无数据或端口未知时不诊断
+func validateApp(src, dst uint16, data []byte, tcp bool) Findings {
+	if len(data) == 0 {
+		return nil
+	}
+	port := dst
+	if src < dst {
+		port = src
+	}
+	switch {
+	case port == 53:
+		if tcp {
+			// TCP上的DNS报文前有2字节长度
+			if len(data) < 2 {
+				return nil
+			}
+			data = data[2:]
+		}
+		dns, err := DeserializeDNSPacket(data)
+		if err != nil {
+			return Findings{errorFinding("DNS", err)}
+		}
+		return dns.Validate()
+	case port == 80 && tcp:
+		http, err := DeserializeHTTPPacket(data)
+		if err != nil {
+			return Findings{errorFinding("HTTP", err)}
+		}
+		return http.Validate()
+	case port == 21 && tcp:
+		ftp, err := DeserializeFTPPacket(data)
+		if err != nil {
+			return Findings{errorFinding("FTP", err)}
+		}
+		return ftp.Validate()
+	case port == 22 && tcp:
+		// 只有版本交换阶段的报文是文本
+		if len(data) < 4 || string(data[:4]) != "SSH-" {
+			return nil
+		}
+		ssh, err := DeserializeSSHPacket(data)
+		if err != nil {
+			return Findings{errorFinding("SSH", err)}
+		}
+		return ssh.Validate()
+	case port == 443 && tcp:
+		tls, err := DeserializeTLS12Packet(data)
+		if err != nil {
+			return Findings{errorFinding("TLS 1.2", err)}
+		}
+		return tls.Validate()
+	}
+	return nil
+}
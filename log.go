@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/logging"
+)
+
+// appLog 程序本身的日志: HTTP服务、抓包文件等
+var appLog = logging.Logger(logging.App)
+
+// cmdLog 查看或修改日志设置
+// @param args []string 子命令与参数
+func cmdLog(args []string) {
+	if len(args) == 0 {
+		showLogSettings()
+		return
+	}
+	switch args[0] {
+	case "level":
+		var sub, name string
+		switch len(args) {
+		case 2:
+			name = args[1]
+		case 3:
+			sub, name = strings.ToLower(args[1]), args[2]
+		default:
+			printUsage("log")
+			return
+		}
+		l, err := logging.ParseLevel(name)
+		if err != nil {
+			printError(err)
+			return
+		}
+		if err := logging.SetLevel(sub, l); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	case "trace":
+		if len(args) < 2 || len(args) > 3 {
+			printUsage("log")
+			return
+		}
+		if len(args) == 3 && args[2] != "on" && args[2] != "off" {
+			printArgError("log", 2, i18n.T("应为 on 或 off"))
+			return
+		}
+		if host.FindDevice(args[1]) == nil {
+			printError(i18n.T("设备不存在: "), args[1])
+			return
+		}
+		logging.Trace(args[1], len(args) == 2 || args[2] == "on")
+		fmt.Println("OK")
+	case "format":
+		if len(args) != 2 {
+			printUsage("log")
+			return
+		}
+		if err := setLogFormat(args[1]); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	default:
+		printUsage("log")
+	}
+}
+
+// setLogFormat 切换日志格式, 输出位置不变
+func setLogFormat(format string) error {
+	cfg := *appConfig
+	cfg.LogFormat = format
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if logFile != nil {
+		w = logFile
+	}
+	return logging.SetOutput(w, format)
+}
+
+// showLogSettings 打印日志格式、各子系统级别与 trace 设备
+func showLogSettings() {
+	fmt.Printf(i18n.T("格式: %s\n"), logging.Format())
+	for _, sub := range logging.Subsystems {
+		fmt.Printf("  %-4s %s\n", sub, logging.LevelName(logging.Level(sub)))
+	}
+	traced := logging.TracedHosts()
+	if len(traced) == 0 {
+		fmt.Println(i18n.T("trace: (无)"))
+		return
+	}
+	fmt.Println("trace:", strings.Join(traced, " "))
+}
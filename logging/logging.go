@@ -0,0 +1,331 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"osiweb-go/i18n"
+)
+
+// const 子系统
+const (
+	// L2 数据链路层: 以太网、ARP、交换机
+	L2 = "l2"
+	// L3 网络层: IP、ICMP、路由
+	L3 = "l3"
+	// L4 传输层: TCP、UDP
+	L4 = "l4"
+	// App 程序本身: HTTP服务、抓包文件等
+	App = "app"
+)
+
+// Subsystems 全部子系统
+var Subsystems = []string{L2, L3, L4, App}
+
+// LevelTrace 逐帧日志, 比 debug 更详细
+// Per-frame logs, more verbose than debug
+const LevelTrace = slog.LevelDebug - 4
+
+// const 输出格式
+const (
+	// FormatConsole 适合终端阅读: [虚拟时刻] 设备: 消息
+	FormatConsole = "console"
+	// FormatText slog 的 key=value 格式
+	FormatText = "text"
+	// FormatJSON 每行一个JSON对象
+	FormatJSON = "json"
+)
+
+// const 日志中有特殊含义的属性
+const (
+	// KeyClock 虚拟时刻
+	KeyClock = "clock"
+	// KeyHost 设备名称, 用于按设备开启 trace
+	KeyHost = "host"
+	// KeySubsystem 子系统
+	KeySubsystem = "subsystem"
+)
+
+// 错误代码, 可用 errors.Is 判断错误种类
+// Error sentinels for errors.Is
+var (
+	// ErrLevel 未知的日志级别 Unknown log level
+	ErrLevel = &i18n.Error{Code: "logging.level"}
+	// ErrFormat 未知的日志格式 Unknown log format
+	ErrFormat = &i18n.Error{Code: "logging.format"}
+	// ErrSubsystem 未知的子系统 Unknown subsystem
+	ErrSubsystem = &i18n.Error{Code: "logging.subsystem"}
+)
+
+var (
+	mu sync.RWMutex
+	// 当前输出
+	output slog.Handler = newConsoleHandler(os.Stdout)
+	// 当前格式
+	format = FormatConsole
+	// 各子系统的级别
+	levels = map[string]slog.Level{L2: slog.LevelInfo, L3: slog.LevelInfo, L4: slog.LevelInfo, App: slog.LevelInfo}
+	// 开启 trace 的设备
+	traced = make(map[string]bool)
+)
+
+// ParseLevel 解析 trace/debug/info/warn/error
+// Parse trace/debug/info/warn/error
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, i18n.Errorf(ErrLevel.Code, "日志级别应为 trace/debug/info/warn/error: %q", s)
+}
+
+// LevelName 级别名称, 与 ParseLevel 对应
+// Level name accepted by ParseLevel
+func LevelName(l slog.Level) string {
+	if l == LevelTrace {
+		return "trace"
+	}
+	return strings.ToLower(l.String())
+}
+
+// SetOutput 设置输出位置与格式
+// Set output writer and format
+// @param w 输出位置
+// @param f console/text/json
+func SetOutput(w io.Writer, f string) error {
+	var h slog.Handler
+	opts := &slog.HandlerOptions{Level: LevelTrace, ReplaceAttr: replaceLevel}
+	switch f {
+	case FormatConsole:
+		h = newConsoleHandler(w)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return i18n.Errorf(ErrFormat.Code, "日志格式应为 console/text/json: %q", f)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	output, format = h, f
+	return nil
+}
+
+// Format 当前输出格式
+// Current output format
+func Format() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return format
+}
+
+// replaceLevel 把自定义的 trace 级别输出为 TRACE
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if l, ok := a.Value.Any().(slog.Level); ok && l == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// SetLevel 设置子系统的级别, subsystem 为空时设置全部子系统
+// Set the level of a subsystem, or of all subsystems when empty
+func SetLevel(subsystem string, l slog.Level) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if subsystem == "" {
+		for s := range levels {
+			levels[s] = l
+		}
+		return nil
+	}
+	if _, ok := levels[subsystem]; !ok {
+		return i18n.Errorf(ErrSubsystem.Code, "未知的子系统 %q, 应为 l2/l3/l4/app", subsystem)
+	}
+	levels[subsystem] = l
+	return nil
+}
+
+// Level 子系统当前的级别
+// Current level of a subsystem
+func Level(subsystem string) slog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	return levels[subsystem]
+}
+
+// Trace 为单个设备开启或关闭 trace, 开启后该设备所有子系统的日志都会输出
+// Turn trace on or off for a single device
+// @param host 设备名称
+// @param on 是否开启
+func Trace(host string, on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if on {
+		traced[host] = true
+	} else {
+		delete(traced, host)
+	}
+}
+
+// TracedHosts 已开启 trace 的设备, 按名称排序
+// Devices with trace turned on, sorted by name
+func TracedHosts() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	hosts := make([]string, 0, len(traced))
+	for h := range traced {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Logger 子系统的日志记录器, 级别与输出可以随时修改
+// Logger of a subsystem; level and output can change at any time
+func Logger(subsystem string) *slog.Logger {
+	return slog.New(&handler{subsystem: subsystem})
+}
+
+// handler 按子系统级别与 trace 设备过滤, 再交给当前输出
+type handler struct {
+	subsystem string
+	attrs     []slog.Attr
+	groups    []string
+}
+
+// Enabled 低于子系统级别的日志, 只有存在 trace 设备时才可能输出
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return l >= levels[h.subsystem] || len(traced) > 0
+}
+
+// Handle 过滤后输出
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	mu.RLock()
+	out := output
+	enabled := r.Level >= levels[h.subsystem] || traced[h.host(r)]
+	mu.RUnlock()
+	if !enabled {
+		return nil
+	}
+	r = r.Clone()
+	r.AddAttrs(slog.String(KeySubsystem, h.subsystem))
+	r.AddAttrs(h.attrs...)
+	for _, g := range h.groups {
+		out = out.WithGroup(g)
+	}
+	return out.Handle(ctx, r)
+}
+
+// host 日志中的设备名称
+func (h *handler) host(r slog.Record) string {
+	for _, a := range h.attrs {
+		if a.Key == KeyHost {
+			return a.Value.String()
+		}
+	}
+	host := ""
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == KeyHost {
+			host = a.Value.String()
+			return false
+		}
+		return true
+	})
+	return host
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &c
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.groups = append(append([]string{}, h.groups...), name)
+	return &c
+}
+
+// consoleHandler 终端格式: [0.001000s] h1: 消息 key=value
+// 与模拟器原来的输出一致, info 以外的级别在消息前标出
+type consoleHandler struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func newConsoleHandler(w io.Writer) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w}
+}
+
+func (h *consoleHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	var clock, host string
+	var rest []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case KeyClock:
+			if d, ok := a.Value.Any().(time.Duration); ok {
+				clock = fmt.Sprintf("%.6fs", d.Seconds())
+			} else {
+				clock = a.Value.String()
+			}
+		case KeyHost:
+			host = a.Value.String()
+		case KeySubsystem:
+		default:
+			rest = append(rest, a)
+		}
+		return true
+	})
+	if clock != "" {
+		fmt.Fprintf(&b, "[%s] ", clock)
+	}
+	if host != "" {
+		b.WriteString(host + ": ")
+	}
+	if r.Level != slog.LevelInfo {
+		b.WriteString(strings.ToUpper(LevelName(r.Level)) + " ")
+	}
+	b.WriteString(r.Message)
+	for _, a := range rest {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindString && strings.ContainsAny(v.String(), " \"=") {
+			data, _ := json.Marshal(v.String())
+			fmt.Fprintf(&b, " %s=%s", a.Key, data)
+		} else {
+			fmt.Fprintf(&b, " %s=%v", a.Key, v)
+		}
+	}
+	b.WriteByte('\n')
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs 属性已经由 handler 放进记录, 这里不必保存
+func (h *consoleHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *consoleHandler) WithGroup(string) slog.Handler { return h }
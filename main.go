@@ -1,153 +1,169 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+
+	"osiweb-go/i18n"
+)
+
+// inputHandler 全局输入处理器
+var inputHandler = NewInputHandler()
+
+// historyFile 数据目录中的命令历史文件
+const historyFile = "history"
+
+// simMu 保护模拟器状态, 命令行与HTTP请求互斥执行
+var simMu sync.Mutex
 
 func main() {
-	cfg, err := loadConfig("config.json")
-	if err != nil {
-		fmt.Printf("读取配置文件失败: %v\n", err)
+	cfg, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
 		return
 	}
+	if err != nil {
+		fmt.Printf(i18n.T("读取配置失败: %v\n"), err)
+		os.Exit(2)
+	}
+	if err := cfg.apply(); err != nil {
+		fmt.Printf(i18n.T("读取配置失败: %v\n"), err)
+		os.Exit(2)
+	}
+	appConfig = cfg
+	if cfg.Topology != "" {
+		if _, err := loadTopology(cfg.Topology); err != nil {
+			fmt.Printf(i18n.T("加载拓扑失败: %v\n"), err)
+			os.Exit(2)
+		}
+	}
+	switch {
+	case cfg.Script != "":
+		os.Exit(runHeadless(cfg.Script))
+	case cfg.Commands != "":
+		os.Exit(runBatch("-c", strings.NewReader(splitCommands(cfg.Commands)), cfg.JSONOutput, cfg.ErrExit))
+	case cfg.BatchFile == "" && !term.IsTerminal(int(os.Stdin.Fd())):
+		// 标准输入不是终端时, 把输入当作命令文件执行
+		os.Exit(runBatch("-", os.Stdin, cfg.JSONOutput, cfg.ErrExit))
+	case cfg.BatchFile != "":
+		f, err := openBatch(cfg.BatchFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		code := runBatch(cfg.BatchFile, f, cfg.JSONOutput, cfg.ErrExit)
+		f.Close()
+		os.Exit(code)
+	}
 	fmt.Println(" ██████╗ ███████╗██╗██╗    ██╗███████╗██████╗      ██████╗  ██████╗ ")
 	fmt.Println("██╔═══██╗██╔════╝██║██║    ██║██╔════╝██╔══██╗    ██╔════╝ ██╔═══██╗")
 	fmt.Println("██║   ██║███████╗██║██║ █╗ ██║█████╗  ██████╔╝    ██║  ███╗██║   ██║")
 	fmt.Println("██║   ██║╚════██║██║██║███╗██║██╔══╝  ██╔══██╗    ██║   ██║██║   ██║")
 	fmt.Println("╚██████╔╝███████║██║╚███╔███╔╝███████╗██████╔╝    ╚██████╔╝╚██████╔╝")
 	fmt.Println(" ╚═════╝ ╚══════╝╚═╝ ╚══╝╚══╝ ╚══════╝╚═════╝      ╚═════╝  ╚═════╝ ")
-	fmt.Println("欢迎使用 OSIWeb-Go !")
+	fmt.Println(i18n.T("欢迎使用 OSIWeb-Go !"))
+	if cfg.HistorySize > 0 {
+		if err := inputHandler.LoadHistory(filepath.Join(cfg.DataDir, historyFile), cfg.HistorySize); err != nil {
+			fmt.Printf(i18n.T("读取命令历史失败: %v\n"), err)
+		}
+	}
+	if cfg.HTTP.Port > 0 {
+		startHTTPServer(cfg.HTTP.Listen, cfg.HTTP.Port)
+	}
 	for {
-		line, err := inputHandler.ReadLine("gkv> ")
+		line, err := inputHandler.ReadLine("osi> ")
 		if err != nil {
-			if err.Error() == "用户中断" {
-				fmt.Println("再见! :D")
-				return
-			}
-			if err.Error() == "EOF" {
-				fmt.Println("再见! :D")
+			if errors.Is(err, errInterrupted) || errors.Is(err, io.EOF) {
+				fmt.Println(i18n.T("再见! :D"))
 				return
 			}
-			fmt.Printf("输入错误: %v\n", err)
+			fmt.Printf(i18n.T("输入错误: %v\n"), err)
 			continue
 		}
 		inputHandler.saveCurrentLine(line)
-		fields := parseFields(line)
-		if len(fields) == 0 {
-			continue
-		}
-		switch strings.ToLower(fields[0]) {
-		case "set":
-			if len(fields) != 3 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: set \"key\" \"value\"")
-				continue
-			}
-			data.DataGkvString.Set(fields[1], []byte(fields[2]))
-			fmt.Println("OK")
-		case "get":
-			if len(fields) != 2 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: get \"key\"")
-				continue
-			}
-			v, ok := data.DataGkvString.Get(fields[1])
-			if ok {
-				fmt.Println(string(v))
-			} else {
-				fmt.Println("(nil)")
-			}
-		case "setnx":
-			if len(fields) != 3 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: setnx \"key\" \"value\"")
-				continue
-			}
-			if data.DataGkvString.SetNX(fields[1], []byte(fields[2])) {
-				fmt.Println("OK")
-			} else {
-				fmt.Println("插入失败,Key已存在")
-			}
-		case "setxx":
-			if len(fields) != 3 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: setxx \"key\" \"value\"")
-				continue
-			}
-			if data.DataGkvString.SetXX(fields[1], []byte(fields[2])) {
-				fmt.Println("OK")
-			} else {
-				fmt.Println("插入失败,Key不存在")
-			}
-		case "del":
-			if len(fields) != 2 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: del \"key\"")
-				continue
-			}
-			data.DataGkvString.Delete(fields[1])
-			fmt.Println("OK")
-		case "keys":
-			if len(fields) != 1 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: keys")
-				continue
-			}
-			keys := data.DataGkvString.GetAllKeys()
-			if len(keys) == 0 {
-				fmt.Println("(empty list or set)")
-			} else {
-				for i, key := range keys {
-					if i > 0 {
-						fmt.Print(" ")
-					}
-					fmt.Printf("\"%s\"", key)
-				}
-				fmt.Println()
-			}
-		case "kvs":
-			if len(fields) != 1 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: kvs")
-				continue
-			}
-			kvs := data.DataGkvString.GetAllKVs()
-			if len(kvs) == 0 {
-				fmt.Println("(empty list or set)")
-			} else {
-				for k, v := range kvs {
-					fmt.Println(k, " -> ", v)
-				}
-			}
-		case "settime":
-			if len(fields) != 3 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: settime \"key\" (milliseconds)")
-				continue
-			}
-			num, _ := strconv.Atoi(fields[2])
-			data.DataGkvString.SetTime(fields[1], num)
-		case "getlasttime":
-			if len(fields) != 2 {
-				fmt.Println("参数错误!")
-				fmt.Println("用法: getlasttime \"key\"")
-				continue
-			}
-			ttl := data.DataGkvString.GetTTL(fields[1])
-			switch ttl {
-			case -1:
-				fmt.Println("(nil)")
-			case -2:
-				fmt.Println("已过期")
-			default:
-				fmt.Printf("%d\n", ttl)
-			}
-		case "help":
-			showHelp()
-		case "quit":
-			fmt.Println("再见! :D")
+		simMu.Lock()
+		ok := execLine(line)
+		simMu.Unlock()
+		if !ok {
+			fmt.Println(i18n.T("再见! :D"))
 			return
-		default:
-			fmt.Println("未知命令: ", fields[0])
-			showSimilarCommands(fields[0])
 		}
 	}
 }
+
+// execLine 执行一行命令
+// @param line string 整行输入
+// @return bool 是否继续运行, quit 时返回false
+func execLine(line string) bool {
+	fields := parseFields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	prev := commandLine
+	commandLine = line
+	defer func() { commandLine = prev }()
+	args := fields[1:]
+	name := ""
+	if cmd := findCommand(fields[0]); cmd != nil {
+		name = cmd.Name
+	}
+	switch name {
+	case "host":
+		cmdHost(args)
+	case "switch":
+		cmdSwitch(args)
+	case "router":
+		cmdRouter(args)
+	case "link":
+		cmdLink(args)
+	case "ip":
+		cmdIP(args)
+	case "route":
+		cmdRoute(args)
+	case "vlan":
+		cmdVLAN(args)
+	case "stp":
+		cmdSTP(args)
+	case "show":
+		cmdShow(args)
+	case "topology":
+		cmdTopology(args)
+	case "snapshot":
+		cmdSnapshot(args)
+	case "run-script":
+		cmdRunScript(args)
+	case "expect":
+		cmdExpect(args)
+	case "send":
+		cmdSend(args)
+	case "craft":
+		cmdCraft(args)
+	case "capture":
+		cmdCapture(args)
+	case "phy":
+		cmdPhy(args)
+	case "pcap":
+		cmdPcap(args)
+	case "log":
+		cmdLog(args)
+	case "run":
+		cmdRun(args)
+	case "step":
+		cmdStep(args)
+	case "help":
+		showHelp(args)
+	case "quit":
+		return false
+	default:
+		printError(i18n.T("未知命令: "), fields[0])
+		showSimilarCommands(fields[0])
+	}
+	return true
+}
@@ -0,0 +1,982 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// cmdHost 主机命令
+// @param args []string 子命令与参数
+func cmdHost(args []string) {
+	if len(args) == 0 {
+		printUsage("host")
+		return
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 2 || len(args) > 4 {
+			printUsage("host")
+			return
+		}
+		var address, gateway string
+		if len(args) >= 3 {
+			address = args[2]
+		}
+		if len(args) == 4 {
+			gateway = args[3]
+		}
+		h, err := addHost(args[1], address, gateway)
+		if err != nil {
+			printError(err)
+			return
+		}
+		iface := h.Interfaces[0]
+		fmt.Printf("OK %s %s %s/%d\n", h.Name, level.FormatMAC(iface.MACAddress),
+			level.FormatIPv4(iface.IPv4Address), iface.PrefixLen)
+	case "listen":
+		if len(args) != 3 {
+			printUsage("host")
+			return
+		}
+		h := findHost(args[1])
+		if h == nil {
+			return
+		}
+		port, err := strconv.ParseUint(args[2], 10, 16)
+		if err != nil {
+			printArgError("host", 2, i18n.T("端口应为0~65535的整数"))
+			return
+		}
+		h.Listen(uint16(port))
+		fmt.Println("OK")
+	case "close":
+		if len(args) != 4 {
+			printUsage("host")
+			return
+		}
+		h := findHost(args[1])
+		if h == nil {
+			return
+		}
+		dst, err := level.ParseIPv4(args[2])
+		if err != nil {
+			printError(err)
+			return
+		}
+		port, err := strconv.ParseUint(args[3], 10, 16)
+		if err != nil {
+			printArgError("host", 3, i18n.T("端口应为0~65535的整数"))
+			return
+		}
+		if err := h.CloseTCP(dst, uint16(port)); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	default:
+		printUsage("host")
+	}
+}
+
+// addHost 添加主机, address 与 gateway 可为空
+// @param name 主机名称
+// @param address ip/prefix, 为空时自动分配
+// @param gateway 默认网关, 为空时不设置
+// @return *host.BaseHost, error
+func addHost(name, address, gateway string) (*host.BaseHost, error) {
+	if host.FindDevice(name) != nil {
+		return nil, fmt.Errorf(i18n.T("设备已存在: %s"), name)
+	}
+	var ip, gw [4]byte
+	prefixLen := 0
+	var err error
+	if address != "" {
+		if ip, prefixLen, err = level.ParseIPv4Prefix(address); err != nil {
+			return nil, err
+		}
+	}
+	if gateway != "" {
+		if gw, err = level.ParseIPv4(gateway); err != nil {
+			return nil, err
+		}
+	}
+	h := host.NewHost(name)
+	if address != "" {
+		h.SetAddress(0, ip, prefixLen)
+	}
+	if gateway != "" {
+		h.SetGateway(gw)
+	}
+	return h, nil
+}
+
+// cmdSwitch 交换机命令
+// @param args []string 子命令与参数
+func cmdSwitch(args []string) {
+	if len(args) < 2 || len(args) > 3 || args[0] != "add" {
+		printUsage("switch")
+		return
+	}
+	if host.FindDevice(args[1]) != nil {
+		printError(i18n.T("设备已存在: "), args[1])
+		return
+	}
+	ports := 8
+	if len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil || n < 0 {
+			printArgError("switch", 2, i18n.T("端口数应为非负整数"))
+			return
+		}
+		ports = n
+	}
+	host.NewSwitch(args[1], ports)
+	fmt.Println("OK")
+}
+
+// cmdRouter 路由器命令
+// @param args []string 子命令与参数
+func cmdRouter(args []string) {
+	if len(args) != 2 || args[0] != "add" {
+		printUsage("router")
+		return
+	}
+	if host.FindDevice(args[1]) != nil {
+		printError(i18n.T("设备已存在: "), args[1])
+		return
+	}
+	host.NewRouter(args[1])
+	fmt.Println("OK")
+}
+
+// cmdLink 链路命令
+// @param args []string 子命令与参数
+func cmdLink(args []string) {
+	if len(args) < 2 {
+		printUsage("link")
+		return
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			printUsage("link")
+			return
+		}
+		link, err := addLink(args[1], args[2], args[3:])
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK", link)
+	case "set":
+		link := findLink(args[1])
+		if link == nil {
+			return
+		}
+		if err := setLinkParams(link, args[2:]); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK", link)
+	case "del":
+		link := findLink(args[1])
+		if link == nil {
+			return
+		}
+		host.Disconnect(link.ID)
+		fmt.Println("OK")
+	default:
+		printUsage("link")
+	}
+}
+
+// addLink 连接两个端点并设置链路参数
+// @param a, b <dev>[:port]
+// @param params key=value 形式的链路参数
+// @return *host.Link, error
+func addLink(a, b string, params []string) (*host.Link, error) {
+	epA, err := parseLinkEndpoint(a)
+	if err != nil {
+		return nil, err
+	}
+	epB, err := parseLinkEndpoint(b)
+	if err != nil {
+		return nil, err
+	}
+	link, err := host.Connect(epA, epB)
+	if err != nil {
+		return nil, err
+	}
+	if err := setLinkParams(link, params); err != nil {
+		host.Disconnect(link.ID)
+		return nil, err
+	}
+	return link, nil
+}
+
+// parseLinkEndpoint 解析链路端点, 未指定端口时使用第一个空闲端口
+func parseLinkEndpoint(s string) (host.Endpoint, error) {
+	dev, port, err := host.ParseEndpoint(s)
+	if err != nil {
+		return host.Endpoint{}, err
+	}
+	if port < 0 {
+		port = host.FreePort(dev)
+	}
+	return host.Endpoint{Device: dev, Port: port}, nil
+}
+
+// setLinkParams 解析 key=value 形式的链路参数
+func setLinkParams(link *host.Link, params []string) error {
+	// 先全部解析, 都正确后才修改链路, 出错时链路保持原样
+	delay, bw, loss, ber, code := link.Delay, link.Bandwidth, link.Loss, link.BER, link.LineCode
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return fmt.Errorf(i18n.T("链路参数格式错误: %s"), p)
+		}
+		var err error
+		switch key {
+		case "delay":
+			delay, err = time.ParseDuration(value)
+			if err != nil || delay < 0 {
+				return fmt.Errorf(i18n.T("时延格式错误: %s"), value)
+			}
+		case "bw":
+			if bw, err = parseBandwidth(value); err != nil {
+				return err
+			}
+		case "loss":
+			loss, err = strconv.ParseFloat(value, 64)
+			if err != nil || loss < 0 || loss > 1 {
+				return fmt.Errorf(i18n.T("丢包率应在0~1之间: %s"), value)
+			}
+		case "ber":
+			ber, err = strconv.ParseFloat(value, 64)
+			if err != nil || ber < 0 || ber > 1 {
+				return fmt.Errorf(i18n.T("误码率应在0~1之间: %s"), value)
+			}
+		case "code":
+			if code, err = level.ParseLineCode(value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(i18n.T("未知链路参数: %s"), key)
+		}
+	}
+	link.Delay, link.Bandwidth, link.Loss, link.BER, link.LineCode = delay, bw, loss, ber, code
+	return nil
+}
+
+// parseBandwidth 解析带宽, 支持 K/M/G 后缀, inf 表示不计发送时延
+func parseBandwidth(s string) (int64, error) {
+	if s == "inf" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"), strings.HasSuffix(s, "k"):
+		multiplier = 1_000
+	case strings.HasSuffix(s, "M"), strings.HasSuffix(s, "m"):
+		multiplier = 1_000_000
+	case strings.HasSuffix(s, "G"), strings.HasSuffix(s, "g"):
+		multiplier = 1_000_000_000
+	}
+	digits := s
+	if multiplier > 1 {
+		digits = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf(i18n.T("带宽格式错误: %s"), s)
+	}
+	return n * multiplier, nil
+}
+
+// cmdIP 接口地址命令
+// @param args []string 子命令与参数
+func cmdIP(args []string) {
+	if len(args) != 3 || args[0] != "set" {
+		printUsage("ip")
+		return
+	}
+	dev, port, err := host.ParseEndpoint(args[1])
+	if err != nil {
+		printError(err)
+		return
+	}
+	h, ok := dev.(*host.BaseHost)
+	if !ok || port < 0 {
+		fmt.Println(i18n.T("只能为主机或路由器的指定接口设置地址"))
+		return
+	}
+	ip, prefixLen, err := level.ParseIPv4Prefix(args[2])
+	if err != nil {
+		printError(err)
+		return
+	}
+	h.SetAddress(port, ip, prefixLen)
+	fmt.Println("OK")
+}
+
+// cmdRoute 路由命令
+// @param args []string 子命令与参数
+func cmdRoute(args []string) {
+	if len(args) != 4 || args[0] != "add" {
+		printUsage("route")
+		return
+	}
+	h := findHost(args[1])
+	if h == nil {
+		return
+	}
+	var prefix [4]byte
+	prefixLen := 0
+	if args[2] != "default" {
+		var err error
+		if prefix, prefixLen, err = level.ParseIPv4Prefix(args[2]); err != nil {
+			printError(err)
+			return
+		}
+	}
+	nextHop, err := level.ParseIPv4(args[3])
+	if err != nil {
+		printError(err)
+		return
+	}
+	h.AddRoute(prefix, prefixLen, nextHop)
+	fmt.Println("OK")
+}
+
+// cmdVLAN VLAN命令: 交换机端口模式与主机、路由器的子接口
+// @param args []string 子命令与参数
+func cmdVLAN(args []string) {
+	if len(args) < 3 {
+		printUsage("vlan")
+		return
+	}
+	dev, port, err := host.ParseEndpoint(args[1])
+	if err != nil {
+		printError(err)
+		return
+	}
+	if port < 0 {
+		printArgError("vlan", 1, i18n.T("需要指定端口, 如 s1:eth1"))
+		return
+	}
+	switch args[0] {
+	case "access":
+		sw, ok := dev.(*host.Switch)
+		if !ok || len(args) != 3 {
+			printUsage("vlan")
+			return
+		}
+		vlan, err := parseVLAN(args[2])
+		if err != nil {
+			printArgError("vlan", 2, err.Error())
+			return
+		}
+		if err := sw.SetAccess(port, vlan); err != nil {
+			printError(err)
+			return
+		}
+	case "trunk":
+		sw, ok := dev.(*host.Switch)
+		if !ok {
+			printUsage("vlan")
+			return
+		}
+		native, allowed := uint16(host.DefaultVLAN), []uint16(nil)
+		for i, p := range args[2:] {
+			key, value, _ := strings.Cut(p, "=")
+			switch key {
+			case "native":
+				native, err = parseVLAN(value)
+			case "allowed":
+				allowed, err = parseVLANList(value)
+			default:
+				err = fmt.Errorf(i18n.T("未知参数: %s"), p)
+			}
+			if err != nil {
+				printArgError("vlan", i+2, err.Error())
+				return
+			}
+		}
+		if err := sw.SetTrunk(port, native, allowed); err != nil {
+			printError(err)
+			return
+		}
+	case "sub":
+		h, ok := dev.(*host.BaseHost)
+		if !ok || len(args) > 4 {
+			printUsage("vlan")
+			return
+		}
+		vlan, err := parseVLAN(args[2])
+		if err != nil {
+			printArgError("vlan", 2, err.Error())
+			return
+		}
+		var ip [4]byte
+		prefixLen := 0
+		if len(args) == 4 {
+			if ip, prefixLen, err = level.ParseIPv4Prefix(args[3]); err != nil {
+				printError(err)
+				return
+			}
+		}
+		sub, err := h.AddSubinterface(port, vlan)
+		if err != nil {
+			printError(err)
+			return
+		}
+		if len(args) == 4 {
+			h.SetAddress(sub, ip, prefixLen)
+		}
+		fmt.Println("OK", h.Name+":"+h.PortName(sub))
+		return
+	default:
+		printUsage("vlan")
+		return
+	}
+	fmt.Println("OK")
+}
+
+// parseVLAN 解析VLAN编号 1~4094
+func parseVLAN(s string) (uint16, error) {
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || n < 1 || n > level.MaxVLAN {
+		return 0, fmt.Errorf(i18n.T("VLAN编号应在1~%d之间: %s"), level.MaxVLAN, s)
+	}
+	return uint16(n), nil
+}
+
+// parseVLANList 解析以逗号分隔的VLAN编号或范围, 如 10,20,30-35
+func parseVLANList(s string) ([]uint16, error) {
+	var vlans []uint16
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		lo, err := parseVLAN(first)
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if isRange {
+			if hi, err = parseVLAN(last); err != nil {
+				return nil, err
+			}
+			if hi < lo {
+				return nil, fmt.Errorf(i18n.T("VLAN范围的起点大于终点: %s"), part)
+			}
+		}
+		for v := lo; v <= hi; v++ {
+			vlans = append(vlans, v)
+		}
+	}
+	return vlans, nil
+}
+
+// cmdSTP 生成树命令: 启用或关闭, 设置网桥优先级、端口开销、端口优先级与边缘端口
+// @param args []string 子命令与参数
+func cmdSTP(args []string) {
+	if len(args) < 2 {
+		printUsage("stp")
+		return
+	}
+	dev, port, err := host.ParseEndpoint(args[1])
+	if err != nil {
+		printError(err)
+		return
+	}
+	sw, ok := dev.(*host.Switch)
+	if !ok {
+		printArgError("stp", 1, i18n.T("生成树只能在交换机上设置: %s", dev.DeviceName()))
+		return
+	}
+	switch args[0] {
+	case "enable", "disable", "priority":
+		if port >= 0 {
+			printArgError("stp", 1, i18n.T("生成树是整台交换机的设置, 不能指定端口"))
+			return
+		}
+		if len(args) > 3 || (args[0] == "disable" && len(args) != 2) || (args[0] == "priority" && len(args) != 3) {
+			printUsage("stp")
+			return
+		}
+	case "cost", "port-priority", "edge":
+		if port < 0 {
+			printArgError("stp", 1, i18n.T("需要指定端口, 如 s1:eth1"))
+			return
+		}
+		if len(args) != 3 {
+			printUsage("stp")
+			return
+		}
+	}
+	switch args[0] {
+	case "enable":
+		mode := host.STPClassic
+		if len(args) == 3 {
+			if mode, ok = host.ParseSTPMode(args[2]); !ok {
+				printArgError("stp", 2, i18n.T("协议应为 stp 或 rstp: %s", args[2]))
+				return
+			}
+		}
+		sw.EnableSTP(mode)
+	case "disable":
+		sw.EnableSTP(host.STPOff)
+	case "priority":
+		n, err := strconv.Atoi(args[2])
+		if err == nil {
+			err = sw.SetBridgePriority(n)
+		}
+		if err != nil {
+			printArgError("stp", 2, i18n.T("网桥优先级应为0~61440之间4096的倍数: %s", args[2]))
+			return
+		}
+	case "cost":
+		cost := uint64(0)
+		if args[2] != "auto" {
+			if cost, err = strconv.ParseUint(args[2], 10, 32); err != nil || cost == 0 {
+				printArgError("stp", 2, i18n.T("路径开销应为正整数或 auto: %s", args[2]))
+				return
+			}
+		}
+		err = sw.SetPortCost(port, uint32(cost))
+	case "port-priority":
+		n, err := strconv.Atoi(args[2])
+		if err == nil {
+			err = sw.SetPortPriority(port, n)
+		}
+		if err != nil {
+			printArgError("stp", 2, i18n.T("端口优先级应为0~240之间16的倍数: %s", args[2]))
+			return
+		}
+	case "edge":
+		if args[2] != "on" && args[2] != "off" {
+			printArgError("stp", 2, i18n.T("应为 on 或 off: %s", args[2]))
+			return
+		}
+		err = sw.SetEdge(port, args[2] == "on")
+	default:
+		printUsage("stp")
+		return
+	}
+	if err != nil {
+		printError(err)
+		return
+	}
+	fmt.Println("OK")
+}
+
+// cmdShow 查看命令
+// @param args []string 查看对象
+func cmdShow(args []string) {
+	if len(args) == 0 {
+		printUsage("show")
+		return
+	}
+	switch args[0] {
+	case "hosts":
+		showHosts()
+	case "links":
+		if len(host.LinkList) == 0 {
+			fmt.Println(i18n.T("(没有链路)"))
+		}
+		for _, l := range host.LinkList {
+			fmt.Println(l)
+		}
+	case "arp":
+		for _, h := range selectHosts(args[1:]) {
+			showARP(h)
+		}
+	case "routes":
+		if len(args) != 2 {
+			printUsage("show")
+			return
+		}
+		if h := findHost(args[1]); h != nil {
+			showRoutes(h)
+		}
+	case "mac":
+		for _, sw := range host.SwitchList {
+			if len(args) < 2 || sw.Name == args[1] {
+				showMACTable(sw)
+			}
+		}
+	case "vlan":
+		for _, sw := range host.SwitchList {
+			if len(args) < 2 || sw.Name == args[1] {
+				showVLANs(sw)
+			}
+		}
+	case "spanning-tree":
+		for _, sw := range host.SwitchList {
+			if len(args) < 2 || sw.Name == args[1] {
+				showSpanningTree(sw)
+			}
+		}
+	case "tcp":
+		for _, h := range selectHosts(args[1:]) {
+			showTCP(h)
+		}
+	case "clock":
+		fmt.Printf(i18n.T("虚拟时间 %s, 待处理事件 %d\n"), host.FormatClock(host.Clock), host.Pending())
+	case "config":
+		showConfig()
+	default:
+		printUsage("show")
+	}
+}
+
+// showHosts 列出所有设备及接口
+func showHosts() {
+	if len(host.DeviceList) == 0 {
+		fmt.Println(i18n.T("(没有设备)"))
+		return
+	}
+	for _, dev := range host.DeviceList {
+		switch d := dev.(type) {
+		case *host.BaseHost:
+			kind := "host"
+			if d.Forwarding {
+				kind = "router"
+			}
+			fmt.Printf("%s (%s)\n", d.Name, kind)
+			for i, iface := range d.Interfaces {
+				fmt.Printf("  %-6s %s %s/%d%s\n", iface.Name, level.FormatMAC(iface.MACAddress),
+					level.FormatIPv4(iface.IPv4Address), iface.PrefixLen, linkSuffix(d, i))
+			}
+		case *host.Switch:
+			fmt.Printf(i18n.T("%s (switch, %d 端口)\n"), d.Name, len(d.NetChannel))
+			for i := range d.NetChannel {
+				if s := linkSuffix(d, i); s != "" {
+					fmt.Printf("  %-6s%s\n", d.PortName(i), s)
+				}
+			}
+		}
+	}
+}
+
+// linkSuffix 端口所连链路的描述
+func linkSuffix(dev host.Device, port int) string {
+	l := host.LinkAt(dev, port)
+	if l == nil {
+		return ""
+	}
+	peer := l.A
+	if peer.Device == dev && peer.Port == port {
+		peer = l.B
+	}
+	return i18n.T(" -- 链路%d -- %s", l.ID, peer)
+}
+
+// showARP 打印ARP缓存
+func showARP(h *host.BaseHost) {
+	fmt.Printf(i18n.T("%s ARP缓存:\n"), h.Name)
+	if len(h.ARPTable) == 0 {
+		fmt.Println("  (empty)")
+		return
+	}
+	ips := make([][4]byte, 0, len(h.ARPTable))
+	for ip := range h.ARPTable {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool { return string(ips[i][:]) < string(ips[j][:]) })
+	for _, ip := range ips {
+		e := h.ARPTable[ip]
+		fmt.Printf("  %-15s %s %-6s %s\n", level.FormatIPv4(ip), level.FormatMAC(e.MAC),
+			h.PortName(e.Port), host.FormatClock(e.Updated))
+	}
+}
+
+// showRoutes 打印路由表(含直连路由)
+func showRoutes(h *host.BaseHost) {
+	fmt.Printf(i18n.T("%s 路由表:\n"), h.Name)
+	for _, iface := range h.Interfaces {
+		if iface.IPv4Address == ([4]byte{}) {
+			continue
+		}
+		mask := level.PrefixMask(iface.PrefixLen)
+		var network [4]byte
+		for i := range network {
+			network[i] = iface.IPv4Address[i] & mask[i]
+		}
+		fmt.Printf(i18n.T("  C %s/%d 直连 %s\n"), level.FormatIPv4(network), iface.PrefixLen, iface.Name)
+	}
+	for _, r := range h.Routes {
+		fmt.Printf(i18n.T("  S %s/%d 下一跳 %s\n"), level.FormatIPv4(r.Prefix), r.PrefixLen, level.FormatIPv4(r.NextHop))
+	}
+}
+
+// showMACTable 打印交换机MAC地址表, 按VLAN排列
+func showMACTable(sw *host.Switch) {
+	fmt.Printf(i18n.T("%s MAC地址表:\n"), sw.Name)
+	if len(sw.MACTable) == 0 {
+		fmt.Println("  (empty)")
+		return
+	}
+	keys := make([]host.MACKey, 0, len(sw.MACTable))
+	for key := range sw.MACTable {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].VLAN != keys[j].VLAN {
+			return keys[i].VLAN < keys[j].VLAN
+		}
+		return string(keys[i].MAC[:]) < string(keys[j].MAC[:])
+	})
+	for _, key := range keys {
+		e := sw.MACTable[key]
+		fmt.Printf("  %-4d %s %-6s %s\n", key.VLAN, level.FormatMAC(key.MAC), sw.PortName(e.Port), host.FormatClock(e.Updated))
+	}
+}
+
+// showVLANs 打印交换机各端口的VLAN设置
+func showVLANs(sw *host.Switch) {
+	fmt.Printf(i18n.T("%s 端口VLAN:\n"), sw.Name)
+	for i, cfg := range sw.PortConfig {
+		fmt.Printf("  %-6s %s%s\n", sw.PortName(i), cfg, linkSuffix(sw, i))
+	}
+}
+
+// showSpanningTree 仿照交换机的 show spanning-tree 打印根桥、本网桥与各端口的角色和状态
+func showSpanningTree(sw *host.Switch) {
+	stp := &sw.STP
+	fmt.Println(sw.Name)
+	if stp.Mode == host.STPOff {
+		fmt.Println("  No spanning tree instance exists.")
+		fmt.Println()
+		return
+	}
+	protocol := "ieee"
+	if stp.Mode == host.STPRapid {
+		protocol = "rstp"
+	}
+	timers := fmt.Sprintf("             Hello Time  %2d sec  Max Age %2d sec  Forward Delay %2d sec\n",
+		int(stp.HelloTime/time.Second), int(stp.MaxAge/time.Second), int(stp.ForwardDelay/time.Second))
+	fmt.Printf("  Spanning tree enabled protocol %s\n", protocol)
+	fmt.Printf("  Root ID    Priority    %d\n", stp.RootID.Priority())
+	fmt.Printf("             Address     %s\n", level.FormatMAC(stp.RootID.MAC()))
+	if sw.IsRoot() {
+		fmt.Println("             This bridge is the root")
+	} else {
+		fmt.Printf("             Cost        %d\n", stp.RootCost)
+		fmt.Printf("             Port        %d (%s)\n", stp.RootPort+1, sw.PortName(stp.RootPort))
+	}
+	fmt.Print(timers)
+	fmt.Println()
+	fmt.Printf("  Bridge ID  Priority    %d\n", stp.Priority)
+	fmt.Printf("             Address     %s\n", level.FormatMAC(sw.MACAddress))
+	fmt.Print(timers)
+	if stp.TopologyChanges > 0 {
+		fmt.Printf("             Topology changes %d, last change at %s\n", stp.TopologyChanges, host.FormatClock(stp.LastChange))
+	}
+	fmt.Println()
+	fmt.Println("Interface        Role Sts Cost      Prio.Nbr Type")
+	fmt.Println("---------------- ---- --- --------- -------- --------------------------------")
+	for i, port := range stp.Ports {
+		if host.LinkAt(sw, i) == nil {
+			continue
+		}
+		kind := "P2p"
+		if port.Edge {
+			kind += " Edge"
+		}
+		if port.Legacy {
+			kind += " Peer(STP)"
+		}
+		fmt.Printf("%-16s %-4s %-3s %-9d %-8s %s\n", sw.PortName(i), port.Role, port.State, sw.PortCost(i),
+			fmt.Sprintf("%d.%d", port.Priority, i+1), kind)
+	}
+	fmt.Println()
+}
+
+// showTCP 打印TCP监听端口与连接
+func showTCP(h *host.BaseHost) {
+	fmt.Printf("%s TCP:\n", h.Name)
+	ports := make([]int, 0, len(h.Listening))
+	for p := range h.Listening {
+		ports = append(ports, int(p))
+	}
+	sort.Ints(ports)
+	for _, p := range ports {
+		fmt.Printf("  LISTEN :%d\n", p)
+	}
+	keys := make([]string, 0, len(h.TCPConns))
+	for k := range h.TCPConns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		conn := h.TCPConns[k]
+		fmt.Printf("  %-12s %s\n", conn.State, conn)
+	}
+}
+
+// cmdSend 发送命令
+// @param args []string 主机、目的地址、协议与数据
+func cmdSend(args []string) {
+	if len(args) < 2 {
+		printUsage("send")
+		return
+	}
+	proto := "icmp"
+	if len(args) >= 3 {
+		proto = strings.ToLower(args[2])
+	}
+	var port uint64
+	var data string
+	switch proto {
+	case "icmp":
+		if len(args) > 3 {
+			printArgError("send", 3, i18n.T("icmp 不需要端口与数据"))
+			return
+		}
+	case "udp", "tcp":
+		if len(args) < 4 {
+			printArgError("send", len(args), i18n.T("%s 需要目的端口", proto))
+			return
+		}
+		if len(args) > 5 {
+			printUsage("send")
+			return
+		}
+		var err error
+		if port, err = strconv.ParseUint(args[3], 10, 16); err != nil {
+			printArgError("send", 3, i18n.T("端口应为0~65535的整数"))
+			return
+		}
+		if len(args) == 5 {
+			data = args[4]
+		}
+	default:
+		printArgError("send", 2, i18n.T("未知的协议 %q, 应为 icmp、udp 或 tcp", args[2]))
+		return
+	}
+	if err := sendPacket(args[0], args[1], proto, uint16(port), data); err != nil {
+		printError(err)
+		return
+	}
+	fmt.Println(i18n.T("OK, 输入 run 或 step 推进模拟"))
+}
+
+// sendPacket 从主机发送ICMP回显请求、UDP数据报或TCP数据
+// @param name 主机名称
+// @param dst 目的IPv4地址
+// @param proto icmp|udp|tcp
+// @param port 目的端口, icmp时忽略
+// @param data 数据
+// @return error
+func sendPacket(name, dst, proto string, port uint16, data string) error {
+	h := host.FindHost(name)
+	if h == nil {
+		return fmt.Errorf(i18n.T("主机不存在: %s"), name)
+	}
+	ip, err := level.ParseIPv4(dst)
+	if err != nil {
+		return err
+	}
+	switch proto {
+	case "icmp":
+		return h.Ping(ip)
+	case "udp":
+		return h.SendUDP(ip, 49152, port, []byte(data))
+	case "tcp":
+		return h.SendTCP(ip, port, []byte(data))
+	default:
+		return fmt.Errorf(i18n.T("未知协议: %s"), proto)
+	}
+}
+
+// cmdRun 运行模拟
+// @param args []string 可选的虚拟时长
+func cmdRun(args []string) {
+	if len(args) > 1 {
+		printUsage("run")
+		return
+	}
+	var limit time.Duration
+	if len(args) == 1 {
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 {
+			printArgError("run", 0, i18n.T("时长应为正数, 如 10ms、1s"))
+			return
+		}
+		limit = d
+	}
+	n := host.Run(limit)
+	fmt.Printf(i18n.T("处理了 %d 个事件, 虚拟时间 %s\n"), n, host.FormatClock(host.Clock))
+	if n >= host.MaxEventsPerRun {
+		fmt.Println(i18n.T("已达到单次运行的事件上限, 网络中可能存在环路"))
+	}
+}
+
+// cmdStep 单步模拟
+// @param args []string 可选的事件数
+func cmdStep(args []string) {
+	if len(args) > 1 {
+		printUsage("step")
+		return
+	}
+	n := 1
+	if len(args) == 1 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			printArgError("step", 0, i18n.T("事件数应为正整数"))
+			return
+		}
+		n = v
+	}
+	done := 0
+	for done < n && host.Step() {
+		done++
+	}
+	if done == 0 {
+		fmt.Println(i18n.T("没有待处理的事件"))
+		return
+	}
+	fmt.Printf(i18n.T("处理了 %d 个事件, 虚拟时间 %s, 剩余 %d\n"), done, host.FormatClock(host.Clock), host.Pending())
+}
+
+// findHost 查找主机, 不存在时打印提示
+func findHost(name string) *host.BaseHost {
+	h := host.FindHost(name)
+	if h == nil {
+		printError(i18n.T("主机不存在: "), name)
+	}
+	return h
+}
+
+// findLink 按编号查找链路, 不存在时打印提示
+func findLink(s string) *host.Link {
+	id, err := strconv.Atoi(s)
+	link := host.FindLink(id)
+	if err != nil || link == nil {
+		printError(i18n.T("链路不存在: "), s)
+		return nil
+	}
+	return link
+}
+
+// selectHosts 按名称选择主机, 未指定时返回全部
+func selectHosts(names []string) []*host.BaseHost {
+	if len(names) == 0 {
+		return host.HostList
+	}
+	var hosts []*host.BaseHost
+	for _, name := range names {
+		if h := findHost(name); h != nil {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
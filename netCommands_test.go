@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"osiweb-go/host"
+)
+
+// 参数中有错误时链路保持原样
+func TestSetLinkParamsAtomic(t *testing.T) {
+	resetSimulator(t)
+	execLine("host add h1")
+	execLine("host add h2")
+	link, err := addLink("h1", "h2", []string{"delay=2ms", "loss=0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range [][]string{
+		{"delay=5ms", "loss=2"},
+		{"bw=10M", "ber=0.5", "code=x"},
+		{"loss=0.5", "mtu=1"},
+		{"delay=1ms", "bad"},
+	} {
+		if err := setLinkParams(link, params); err == nil {
+			t.Errorf("%q: want an error", params)
+		}
+		if link.Delay != 2*time.Millisecond || link.Loss != 0.1 || link.Bandwidth != host.DefaultLinkBandwidth || link.BER != 0 {
+			t.Errorf("%q: link changed to %s", params, link)
+		}
+	}
+	if err := setLinkParams(link, []string{"delay=5ms", "ber=0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if link.Delay != 5*time.Millisecond || link.BER != 0.5 || link.Loss != 0.1 {
+		t.Errorf("link = %s, want delay 5ms and ber 0.5", link)
+	}
+}
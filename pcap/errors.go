@@ -0,0 +1,29 @@
+package pcap
+
+import "osiweb-go/i18n"
+
+// 错误代码, 可用 errors.Is 判断错误种类
+// Error sentinels for errors.Is; messages follow the interface language
+var (
+	// ErrReadHeader 读取文件头失败 Failed to read file header
+	ErrReadHeader = &i18n.Error{Code: "pcap.read_header"}
+	// ErrNotCapture 不是pcap或pcapng文件 Not a pcap or pcapng file
+	ErrNotCapture = &i18n.Error{Code: "pcap.not_capture"}
+	// ErrLinkType 不支持的链路类型 Unsupported link type
+	ErrLinkType = &i18n.Error{Code: "pcap.link_type"}
+	// ErrTruncated 记录或块被截断 Record or block truncated
+	ErrTruncated = &i18n.Error{Code: "pcap.truncated"}
+	// ErrBadLength 记录或块的长度错误 Bad record or block length
+	ErrBadLength = &i18n.Error{Code: "pcap.bad_length"}
+	// ErrBadMagic 节头块字节序魔数错误 Bad byte-order magic
+	ErrBadMagic = &i18n.Error{Code: "pcap.bad_magic"}
+	// ErrInterface 接口未定义或未登记 Undefined or unregistered interface
+	ErrInterface = &i18n.Error{Code: "pcap.interface"}
+	// ErrFormat 未知的抓包文件格式 Unknown capture file format
+	ErrFormat = &i18n.Error{Code: "pcap.format"}
+)
+
+// truncated 记录或块的某部分被截断
+func truncated(what string) error {
+	return i18n.Errorf(ErrTruncated.Code, what)
+}
@@ -0,0 +1,253 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// testPacket 写入的一帧
+type testPacket struct {
+	ts    time.Duration
+	iface int
+	dir   Direction
+	frame []byte
+}
+
+// testFrame 带4字节FCS的帧, 长度不是4的倍数以检查pcapng的补齐
+func testFrame(n int, fill byte) []byte {
+	frame := bytes.Repeat([]byte{fill}, n)
+	copy(frame[n-FCSLen:], []byte{0xde, 0xad, 0xbe, 0xef})
+	return frame
+}
+
+// writeCapture 按格式写入接口与帧, 返回文件内容
+func writeCapture(t *testing.T, format Format, includeFCS bool, names []string, packets []testPacket) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, includeFCS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		id, err := w.AddInterface(name, "test "+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == FormatPcapNG && id != i {
+			t.Fatalf("AddInterface(%s) = %d, want %d", name, id, i)
+		}
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p.ts, p.iface, p.dir, p.frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// readCapture 读出全部帧
+func readCapture(t *testing.T, data []byte) (*Reader, []*Packet) {
+	t.Helper()
+	rd, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packets []*Packet
+	for {
+		p, err := rd.Next()
+		if err == io.EOF {
+			return rd, packets
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, p)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	names := []string{"h1:eth0", "s1:eth1"}
+	packets := []testPacket{
+		{0, 0, DirectionOutbound, testFrame(64, 0x11)},
+		{time.Second + 123456789, 1, DirectionInbound, testFrame(65, 0x22)},
+		// 超过32位纳秒, 检查时间戳高32位
+		{90*time.Minute + 7, 0, DirectionUnknown, testFrame(1518, 0x33)},
+	}
+	for _, format := range []Format{FormatPcap, FormatPcapNG} {
+		for _, includeFCS := range []bool{false, true} {
+			data := writeCapture(t, format, includeFCS, names, packets)
+			rd, got := readCapture(t, data)
+			if rd.Format() != format {
+				t.Errorf("format %d fcs %v: Format = %d", format, includeFCS, rd.Format())
+			}
+			if len(got) != len(packets) {
+				t.Fatalf("format %d fcs %v: read %d packets, want %d", format, includeFCS, len(got), len(packets))
+			}
+			for i, want := range packets {
+				p := got[i]
+				frame := want.frame
+				if !includeFCS {
+					frame = frame[:len(frame)-FCSLen]
+				}
+				if p.Timestamp != want.ts {
+					t.Errorf("format %d fcs %v packet %d: Timestamp = %v, want %v", format, includeFCS, i, p.Timestamp, want.ts)
+				}
+				if !bytes.Equal(p.Data, frame) || p.OrigLen != len(frame) {
+					t.Errorf("format %d fcs %v packet %d: %d bytes (orig %d), want %d", format, includeFCS, i, len(p.Data), p.OrigLen, len(frame))
+				}
+				if p.HasFCS != includeFCS {
+					t.Errorf("format %d fcs %v packet %d: HasFCS = %v", format, includeFCS, i, p.HasFCS)
+				}
+				// 经典pcap只有一个接口, 也不记录方向
+				wantIface, wantDir := want.iface, want.dir
+				if format == FormatPcap {
+					wantIface, wantDir = 0, DirectionUnknown
+				}
+				if p.Interface != wantIface || p.Direction != wantDir {
+					t.Errorf("format %d fcs %v packet %d: interface %d direction %d, want %d %d",
+						format, includeFCS, i, p.Interface, p.Direction, wantIface, wantDir)
+				}
+			}
+			if format == FormatPcapNG {
+				for i, name := range names {
+					if rd.InterfaceName(i) != name {
+						t.Errorf("fcs %v: InterfaceName(%d) = %q, want %q", includeFCS, i, rd.InterfaceName(i), name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// 经典pcap在链路类型的高4位声明FCS: F位(第28位)与以16位为单位的长度(第29~31位)
+func TestPcapLinkTypeFCS(t *testing.T) {
+	for _, includeFCS := range []bool{false, true} {
+		data := writeCapture(t, FormatPcap, includeFCS, nil, nil)
+		if len(data) != 24 {
+			t.Fatalf("fcs %v: header is %d bytes, want 24", includeFCS, len(data))
+		}
+		if magic := binary.LittleEndian.Uint32(data[0:4]); magic != magicNanoseconds {
+			t.Errorf("fcs %v: magic 0x%08x, want nanosecond magic", includeFCS, magic)
+		}
+		want := uint32(LinkTypeEthernet)
+		if includeFCS {
+			want = 0x50000001
+		}
+		if got := binary.LittleEndian.Uint32(data[20:24]); got != want {
+			t.Errorf("fcs %v: link type 0x%08x, want 0x%08x", includeFCS, got, want)
+		}
+		rd, _ := readCapture(t, data)
+		wantFCS := 0
+		if includeFCS {
+			wantFCS = FCSLen
+		}
+		if rd.linkType != LinkTypeEthernet || rd.fcsLen != wantFCS {
+			t.Errorf("fcs %v: read link type %d fcs %d", includeFCS, rd.linkType, rd.fcsLen)
+		}
+	}
+}
+
+// pcapng 的接口描述块声明纳秒精度 if_tsresol=9, 带FCS时声明 if_fcslen=4
+func TestPcapNGInterfaceOptions(t *testing.T) {
+	for _, includeFCS := range []bool{false, true} {
+		data := writeCapture(t, FormatPcapNG, includeFCS, []string{"h1:eth0"}, nil)
+		rd, _ := readCapture(t, data)
+		if len(rd.interfaces) != 1 {
+			t.Fatalf("fcs %v: %d interfaces, want 1", includeFCS, len(rd.interfaces))
+		}
+		iface := rd.interfaces[0]
+		if iface.perSecond != uint64(time.Second) {
+			t.Errorf("fcs %v: %d units per second, want nanoseconds", includeFCS, iface.perSecond)
+		}
+		wantFCS := 0
+		if includeFCS {
+			wantFCS = FCSLen
+		}
+		if iface.fcsLen != wantFCS {
+			t.Errorf("fcs %v: if_fcslen %d, want %d", includeFCS, iface.fcsLen, wantFCS)
+		}
+		if !bytes.Contains(data, ngOption(optIfTsresol, []byte{pcapngTimestampNanosec})) {
+			t.Errorf("fcs %v: no if_tsresol option", includeFCS)
+		}
+		if got := bytes.Contains(data, ngOption(optIfFCSLen, []byte{FCSLen})); got != includeFCS {
+			t.Errorf("fcs %v: if_fcslen option present %v", includeFCS, got)
+		}
+	}
+}
+
+func TestTsResolution(t *testing.T) {
+	tests := []struct {
+		v    byte
+		want uint64
+	}{
+		{6, 1e6},
+		{9, 1e9},
+		{0, 1},
+		{0x80 | 10, 1024},
+	}
+	for _, tt := range tests {
+		if got := tsResolution(tt.v); got != tt.want {
+			t.Errorf("tsResolution(0x%02x) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+	// 微秒与2的负10次方秒精度的时间戳换算为纳秒
+	micro := ngInterface{perSecond: 1e6}
+	if got := micro.timestamp(0, 1500001); got != time.Second+500001*time.Microsecond {
+		t.Errorf("microsecond timestamp = %v", got)
+	}
+	binaryRes := ngInterface{perSecond: 1024}
+	if got := binaryRes.timestamp(0, 1024+512); got != 3*time.Second/2 {
+		t.Errorf("1/1024 s timestamp = %v", got)
+	}
+}
+
+// 大端微秒精度的经典pcap文件
+func TestReadBigEndianMicroseconds(t *testing.T) {
+	frame := testFrame(60, 0x44)
+	header := make([]byte, 24)
+	binary.BigEndian.PutUint32(header[0:4], magicMicroseconds)
+	binary.BigEndian.PutUint16(header[4:6], 2)
+	binary.BigEndian.PutUint16(header[6:8], 4)
+	binary.BigEndian.PutUint32(header[16:20], SnapLen)
+	binary.BigEndian.PutUint32(header[20:24], LinkTypeEthernet)
+	record := make([]byte, 16)
+	binary.BigEndian.PutUint32(record[0:4], 7)
+	binary.BigEndian.PutUint32(record[4:8], 250000)
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(frame)))
+	binary.BigEndian.PutUint32(record[12:16], uint32(len(frame)))
+	data := append(append(header, record...), frame...)
+	_, packets := readCapture(t, data)
+	if len(packets) != 1 {
+		t.Fatalf("read %d packets, want 1", len(packets))
+	}
+	if p := packets[0]; p.Timestamp != 7250*time.Millisecond || !bytes.Equal(p.Data, frame) || p.HasFCS {
+		t.Errorf("packet %v %x fcs %v", p.Timestamp, p.Data, p.HasFCS)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	good := writeCapture(t, FormatPcapNG, true, []string{"h1:eth0"},
+		[]testPacket{{time.Second, 0, DirectionInbound, testFrame(64, 0x55)}})
+	if _, err := NewReader(bytes.NewReader([]byte("nope"))); !errors.Is(err, ErrNotCapture) {
+		t.Errorf("garbage: %v, want ErrNotCapture", err)
+	}
+	rd, err := NewReader(bytes.NewReader(good[:len(good)-8]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, err = rd.Next()
+	}
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated packet block: %v, want ErrTruncated", err)
+	}
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, FormatPcapNG, false)
+	if err := w.WritePacket(0, 0, DirectionUnknown, testFrame(64, 0)); !errors.Is(err, ErrInterface) {
+		t.Errorf("unregistered interface: %v, want ErrInterface", err)
+	}
+}
@@ -0,0 +1,357 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"time"
+
+	"osiweb-go/i18n"
+)
+
+// const pcapng 读取用到的块类型与选项
+const (
+	blockSimplePacket = 0x00000003
+	optEndOfOptLen    = 4
+)
+
+// Packet 从抓包文件读出的一帧
+type Packet struct {
+	// 时间戳, 自1970年起或自抓包开始的时长
+	Timestamp time.Duration
+	// 接口编号, 经典pcap恒为0
+	Interface int
+	// 帧方向, 仅pcapng记录
+	Direction Direction
+	// 帧数据
+	Data []byte
+	// 原始长度, 大于len(Data)表示被截断
+	OrigLen int
+	// 帧尾是否带FCS
+	HasFCS bool
+}
+
+// ngInterface pcapng 接口描述
+type ngInterface struct {
+	linkType uint16
+	name     string
+	// 每秒的时间戳单位数
+	perSecond uint64
+	fcsLen    int
+}
+
+// Reader 抓包文件读取器, 自动识别经典pcap(微秒/纳秒, 大小端)与pcapng
+type Reader struct {
+	r      io.Reader
+	format Format
+	order  binary.ByteOrder
+	// 经典pcap的时间精度
+	tsUnit time.Duration
+	// 经典pcap的链路类型与FCS长度
+	linkType uint32
+	fcsLen   int
+	// pcapng 当前节的接口
+	interfaces []ngInterface
+}
+
+// NewReader 读取文件头并创建读取器
+// @param r 输入
+// @return *Reader, error
+func NewReader(r io.Reader) (*Reader, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, i18n.Wrap(ErrReadHeader.Code, err, "读取文件头失败")
+	}
+	rd := &Reader{r: r}
+	if binary.LittleEndian.Uint32(magic) == blockSectionHeader {
+		rd.format = FormatPcapNG
+		if err := rd.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return rd, nil
+	}
+	rd.format = FormatPcap
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(magic) {
+		case magicMicroseconds:
+			rd.order, rd.tsUnit = order, time.Microsecond
+		case magicNanoseconds:
+			rd.order, rd.tsUnit = order, time.Nanosecond
+		}
+	}
+	if rd.order == nil {
+		return nil, i18n.Errorf(ErrNotCapture.Code, "不是pcap或pcapng文件")
+	}
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, i18n.Wrap(ErrReadHeader.Code, err, "读取文件头失败")
+	}
+	linkType := rd.order.Uint32(header[16:20])
+	rd.linkType = linkType & 0x0FFFFFFF
+	if linkType&(1<<28) != 0 {
+		rd.fcsLen = int(linkType>>29) * 2
+	}
+	if rd.linkType != LinkTypeEthernet {
+		return nil, i18n.Errorf(ErrLinkType.Code, "不支持的链路类型: %d", rd.linkType)
+	}
+	return rd, nil
+}
+
+// Format 文件格式
+func (rd *Reader) Format() Format {
+	return rd.format
+}
+
+// InterfaceName 接口名称, 经典pcap或未命名接口返回空字符串
+func (rd *Reader) InterfaceName(iface int) string {
+	if iface < 0 || iface >= len(rd.interfaces) {
+		return ""
+	}
+	return rd.interfaces[iface].name
+}
+
+// Next 读取下一帧, 文件结束时返回 io.EOF
+// @return *Packet, error
+func (rd *Reader) Next() (*Packet, error) {
+	if rd.format == FormatPcap {
+		return rd.nextPcap()
+	}
+	for {
+		p, err := rd.nextBlock()
+		if err != nil || p != nil {
+			return p, err
+		}
+	}
+}
+
+// nextPcap 读取经典pcap记录
+// [秒][微秒或纳秒][抓取长度][原始长度][数据]
+func (rd *Reader) nextPcap() (*Packet, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(rd.r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, truncated("记录头被截断")
+		}
+		return nil, err
+	}
+	capLen := rd.order.Uint32(header[8:12])
+	if capLen > SnapLen {
+		return nil, i18n.Errorf(ErrBadLength.Code, "记录长度错误: %d", capLen)
+	}
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(rd.r, data); err != nil {
+		return nil, truncated("记录数据被截断")
+	}
+	ts := time.Duration(rd.order.Uint32(header[0:4]))*time.Second +
+		time.Duration(rd.order.Uint32(header[4:8]))*rd.tsUnit
+	return &Packet{
+		Timestamp: ts,
+		Data:      data,
+		OrigLen:   int(rd.order.Uint32(header[12:16])),
+		HasFCS:    rd.fcsLen > 0,
+	}, nil
+}
+
+// readBlockBody 读取块的长度与内容, 块类型已被读取
+func (rd *Reader) readBlockBody() ([]byte, error) {
+	lenBuf := make([]byte, 4)
+	if _, err := io.ReadFull(rd.r, lenBuf); err != nil {
+		return nil, truncated("块头被截断")
+	}
+	total := rd.order.Uint32(lenBuf)
+	if total < 12 || total%4 != 0 || total > 16*SnapLen {
+		return nil, i18n.Errorf(ErrBadLength.Code, "块长度错误: %d", total)
+	}
+	rest := make([]byte, total-8)
+	if _, err := io.ReadFull(rd.r, rest); err != nil {
+		return nil, truncated("块数据被截断")
+	}
+	return rest[:len(rest)-4], nil
+}
+
+// readSectionHeader 读取节头块, 据字节序魔数确定字节序
+func (rd *Reader) readSectionHeader() error {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(rd.r, head); err != nil {
+		return truncated("节头块被截断")
+	}
+	switch {
+	case binary.LittleEndian.Uint32(head[4:8]) == byteOrderMagic:
+		rd.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(head[4:8]) == byteOrderMagic:
+		rd.order = binary.BigEndian
+	default:
+		return i18n.Errorf(ErrBadMagic.Code, "节头块字节序魔数错误")
+	}
+	total := rd.order.Uint32(head[0:4])
+	if total < 28 || total%4 != 0 {
+		return i18n.Errorf(ErrBadLength.Code, "块长度错误: %d", total)
+	}
+	// 跳过版本、节长度、选项与尾部长度
+	if _, err := io.CopyN(io.Discard, rd.r, int64(total-12)); err != nil {
+		return truncated("节头块被截断")
+	}
+	rd.interfaces = nil
+	return nil
+}
+
+// nextBlock 读取下一个块, 分组块返回Packet, 其余块返回nil
+func (rd *Reader) nextBlock() (*Packet, error) {
+	typeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(rd.r, typeBuf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, truncated("块头被截断")
+		}
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(typeBuf) == blockSectionHeader {
+		return nil, rd.readSectionHeader()
+	}
+	blockType := rd.order.Uint32(typeBuf)
+	body, err := rd.readBlockBody()
+	if err != nil {
+		return nil, err
+	}
+	switch blockType {
+	case blockInterfaceDesc:
+		return nil, rd.parseInterface(body)
+	case blockEnhancedPacket:
+		return rd.parseEnhancedPacket(body)
+	case blockSimplePacket:
+		return rd.parseSimplePacket(body)
+	}
+	// 其余块(名称解析、统计等)忽略
+	return nil, nil
+}
+
+// parseOptions 遍历选项
+func (rd *Reader) parseOptions(data []byte, fn func(code uint16, value []byte)) {
+	for len(data) >= optEndOfOptLen {
+		code := rd.order.Uint16(data[0:2])
+		length := int(rd.order.Uint16(data[2:4]))
+		if code == optEndOfOpt || 4+length > len(data) {
+			return
+		}
+		fn(code, data[4:4+length])
+		data = data[min(4+pad4(length), len(data)):]
+	}
+}
+
+// parseInterface 解析接口描述块
+func (rd *Reader) parseInterface(body []byte) error {
+	if len(body) < 8 {
+		return truncated("接口描述块被截断")
+	}
+	iface := ngInterface{
+		linkType:  rd.order.Uint16(body[0:2]),
+		perSecond: 1e6,
+	}
+	rd.parseOptions(body[8:], func(code uint16, value []byte) {
+		switch code {
+		case optIfName:
+			iface.name = string(value)
+		case optIfTsresol:
+			if len(value) == 1 {
+				iface.perSecond = tsResolution(value[0])
+			}
+		case optIfFCSLen:
+			if len(value) == 1 {
+				iface.fcsLen = int(value[0])
+			}
+		}
+	})
+	rd.interfaces = append(rd.interfaces, iface)
+	return nil
+}
+
+// tsResolution 解析 if_tsresol: 最高位为0表示10的负n次方秒, 为1表示2的负n次方秒
+// @return uint64 每秒的时间戳单位数
+func tsResolution(v byte) uint64 {
+	n := uint(v & 0x7F)
+	if v&0x80 != 0 {
+		return 1 << min(n, 63)
+	}
+	perSecond := uint64(1)
+	for i := uint(0); i < n && perSecond < 1e19; i++ {
+		perSecond *= 10
+	}
+	return perSecond
+}
+
+// timestamp 按接口精度将64位时间戳换算为纳秒
+func (iface ngInterface) timestamp(high, low uint32) time.Duration {
+	ts := uint64(high)<<32 | uint64(low)
+	seconds, rest := ts/iface.perSecond, ts%iface.perSecond
+	hi, lo := bits.Mul64(rest, uint64(time.Second))
+	nanos, _ := bits.Div64(hi, lo, iface.perSecond)
+	return time.Duration(seconds)*time.Second + time.Duration(nanos)
+}
+
+// iface 查找接口并检查链路类型
+func (rd *Reader) iface(id uint32) (ngInterface, error) {
+	if int(id) >= len(rd.interfaces) {
+		return ngInterface{}, i18n.Errorf(ErrInterface.Code, "接口未定义: %d", id)
+	}
+	iface := rd.interfaces[id]
+	if iface.linkType != LinkTypeEthernet {
+		return ngInterface{}, i18n.Errorf(ErrLinkType.Code, "不支持的链路类型: %d", iface.linkType)
+	}
+	return iface, nil
+}
+
+// parseEnhancedPacket 解析增强分组块
+// [接口编号][时间戳高32位][时间戳低32位][抓取长度][原始长度][数据][选项]
+func (rd *Reader) parseEnhancedPacket(body []byte) (*Packet, error) {
+	if len(body) < 20 {
+		return nil, truncated("增强分组块被截断")
+	}
+	id := rd.order.Uint32(body[0:4])
+	iface, err := rd.iface(id)
+	if err != nil {
+		return nil, err
+	}
+	capLen := int(rd.order.Uint32(body[12:16]))
+	if 20+capLen > len(body) {
+		return nil, truncated("增强分组块被截断")
+	}
+	p := &Packet{
+		Timestamp: iface.timestamp(rd.order.Uint32(body[4:8]), rd.order.Uint32(body[8:12])),
+		Interface: int(id),
+		Data:      append([]byte(nil), body[20:20+capLen]...),
+		OrigLen:   int(rd.order.Uint32(body[16:20])),
+		HasFCS:    iface.fcsLen > 0,
+	}
+	rd.parseOptions(body[min(20+pad4(capLen), len(body)):], func(code uint16, value []byte) {
+		if code == optEPBFlags && len(value) == 4 {
+			switch rd.order.Uint32(value) & 0x3 {
+			case epbFlagInbound:
+				p.Direction = DirectionInbound
+			case epbFlagOutbound:
+				p.Direction = DirectionOutbound
+			}
+		}
+	})
+	return p, nil
+}
+
+// parseSimplePacket 解析简单分组块, 属于第一个接口且没有时间戳
+// [原始长度][数据]
+func (rd *Reader) parseSimplePacket(body []byte) (*Packet, error) {
+	if len(body) < 4 {
+		return nil, truncated("简单分组块被截断")
+	}
+	iface, err := rd.iface(0)
+	if err != nil {
+		return nil, err
+	}
+	origLen := int(rd.order.Uint32(body[0:4]))
+	data := body[4:]
+	if origLen < len(data) {
+		data = data[:origLen]
+	}
+	return &Packet{
+		Data:    append([]byte(nil), data...),
+		OrigLen: origLen,
+		HasFCS:  iface.fcsLen > 0,
+	}, nil
+}
@@ -0,0 +1,245 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+
+	"osiweb-go/i18n"
+)
+
+// const 文件格式常量
+const (
+	// LinkTypeEthernet 以太网链路类型 LINKTYPE_ETHERNET
+	LinkTypeEthernet = 1
+	// SnapLen 最大抓包长度
+	SnapLen = 65535
+	// FCSLen 以太网帧校验序列长度
+	FCSLen = 4
+
+	// 经典pcap纳秒精度魔数
+	magicNanoseconds = 0xA1B23C4D
+	// 经典pcap微秒精度魔数
+	magicMicroseconds = 0xA1B2C3D4
+
+	// pcapng 块类型
+	blockSectionHeader     = 0x0A0D0D0A
+	blockInterfaceDesc     = 0x00000001
+	blockEnhancedPacket    = 0x00000006
+	byteOrderMagic         = 0x1A2B3C4D
+	optEndOfOpt            = 0
+	optSHBUserAppl         = 4
+	optIfName              = 2
+	optIfDescription       = 3
+	optIfTsresol           = 9
+	optIfFCSLen            = 13
+	optEPBFlags            = 2
+	epbFlagInbound         = 0x1
+	epbFlagOutbound        = 0x2
+	pcapngUserApplication  = "OSIWeb-Go"
+	pcapngTimestampNanosec = 9
+)
+
+// Format 抓包文件格式
+type Format int
+
+// const 抓包文件格式
+const (
+	// FormatPcap 经典 pcap
+	FormatPcap Format = iota
+	// FormatPcapNG pcapng
+	FormatPcapNG
+)
+
+// FormatFromName 根据文件扩展名判断格式, .pcapng 为 pcapng, 其余为经典 pcap
+// @param filename 文件名
+// @return Format
+func FormatFromName(filename string) Format {
+	if strings.HasSuffix(strings.ToLower(filename), ".pcapng") {
+		return FormatPcapNG
+	}
+	return FormatPcap
+}
+
+// Direction 帧方向
+type Direction int
+
+// const 帧方向
+const (
+	DirectionUnknown Direction = iota
+	DirectionInbound
+	DirectionOutbound
+)
+
+// Writer 抓包文件写入器
+type Writer interface {
+	// AddInterface 登记一个接口, 返回接口编号; 经典pcap只有一个接口
+	AddInterface(name, description string) (int, error)
+	// WritePacket 写入一帧, ts 为虚拟时间
+	WritePacket(ts time.Duration, iface int, dir Direction, frame []byte) error
+}
+
+// NewWriter 创建写入器并写入文件头
+// @param w 输出
+// @param format 文件格式
+// @param includeFCS 为true时保留帧尾4字节FCS并在文件中声明, 否则写入前去掉FCS
+// @return Writer, error
+func NewWriter(w io.Writer, format Format, includeFCS bool) (Writer, error) {
+	switch format {
+	case FormatPcap:
+		pw := &pcapWriter{w: w, includeFCS: includeFCS}
+		return pw, pw.writeHeader()
+	case FormatPcapNG:
+		nw := &ngWriter{w: w, includeFCS: includeFCS}
+		return nw, nw.writeSectionHeader()
+	default:
+		return nil, i18n.Errorf(ErrFormat.Code, "未知的抓包文件格式")
+	}
+}
+
+// frameData 按FCS设置截取要写入的数据
+func frameData(frame []byte, includeFCS bool) []byte {
+	if !includeFCS && len(frame) >= FCSLen {
+		return frame[:len(frame)-FCSLen]
+	}
+	return frame
+}
+
+// pcapWriter 经典pcap写入器, 纳秒时间戳
+type pcapWriter struct {
+	w          io.Writer
+	includeFCS bool
+}
+
+// writeHeader 写入24字节全局头部
+// [魔数][主版本][次版本][时区][精度][快照长度][链路类型]
+func (pw *pcapWriter) writeHeader() error {
+	buf := make([]byte, 24)
+	binary.LittleEndian.PutUint32(buf[0:4], magicNanoseconds)
+	binary.LittleEndian.PutUint16(buf[4:6], 2)
+	binary.LittleEndian.PutUint16(buf[6:8], 4)
+	binary.LittleEndian.PutUint32(buf[16:20], SnapLen)
+	linkType := uint32(LinkTypeEthernet)
+	if pw.includeFCS {
+		// F位置1, FCS长度以16位为单位
+		linkType |= 1<<28 | (FCSLen/2)<<29
+	}
+	binary.LittleEndian.PutUint32(buf[20:24], linkType)
+	_, err := pw.w.Write(buf)
+	return err
+}
+
+// AddInterface 经典pcap只有一个接口
+func (pw *pcapWriter) AddInterface(name, description string) (int, error) {
+	return 0, nil
+}
+
+// WritePacket 写入16字节记录头与帧数据
+// [秒][纳秒][抓取长度][原始长度][数据]
+func (pw *pcapWriter) WritePacket(ts time.Duration, iface int, dir Direction, frame []byte) error {
+	data := frameData(frame, pw.includeFCS)
+	buf := make([]byte, 16+len(data))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(ts/time.Second))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(ts%time.Second))
+	binary.LittleEndian.PutUint32(buf[8:12], uint32(len(data)))
+	binary.LittleEndian.PutUint32(buf[12:16], uint32(len(data)))
+	copy(buf[16:], data)
+	_, err := pw.w.Write(buf)
+	return err
+}
+
+// ngWriter pcapng写入器, 每个接口一个接口描述块, 纳秒时间戳
+type ngWriter struct {
+	w          io.Writer
+	includeFCS bool
+	interfaces int
+}
+
+// ngOption 序列化一个pcapng选项, 值按4字节对齐补零
+func ngOption(code uint16, value []byte) []byte {
+	buf := make([]byte, 4+pad4(len(value)))
+	binary.LittleEndian.PutUint16(buf[0:2], code)
+	binary.LittleEndian.PutUint16(buf[2:4], uint16(len(value)))
+	copy(buf[4:], value)
+	return buf
+}
+
+// pad4 向上取整到4的倍数
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// writeBlock 写入一个pcapng块: [类型][总长度][内容][总长度]
+func (nw *ngWriter) writeBlock(blockType uint32, body []byte) error {
+	total := 12 + len(body)
+	buf := make([]byte, total)
+	binary.LittleEndian.PutUint32(buf[0:4], blockType)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(total))
+	copy(buf[8:], body)
+	binary.LittleEndian.PutUint32(buf[total-4:], uint32(total))
+	_, err := nw.w.Write(buf)
+	return err
+}
+
+// writeSectionHeader 写入节头块
+// [字节序魔数][主版本][次版本][节长度][选项]
+func (nw *ngWriter) writeSectionHeader() error {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body[0:4], byteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:6], 1)
+	binary.LittleEndian.PutUint16(body[6:8], 0)
+	binary.LittleEndian.PutUint64(body[8:16], 0xFFFFFFFFFFFFFFFF)
+	body = append(body, ngOption(optSHBUserAppl, []byte(pcapngUserApplication))...)
+	body = append(body, ngOption(optEndOfOpt, nil)...)
+	return nw.writeBlock(blockSectionHeader, body)
+}
+
+// AddInterface 写入接口描述块
+// [链路类型][保留][快照长度][选项]
+func (nw *ngWriter) AddInterface(name, description string) (int, error) {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:2], LinkTypeEthernet)
+	binary.LittleEndian.PutUint32(body[4:8], SnapLen)
+	body = append(body, ngOption(optIfName, []byte(name))...)
+	if description != "" {
+		body = append(body, ngOption(optIfDescription, []byte(description))...)
+	}
+	body = append(body, ngOption(optIfTsresol, []byte{pcapngTimestampNanosec})...)
+	if nw.includeFCS {
+		body = append(body, ngOption(optIfFCSLen, []byte{FCSLen})...)
+	}
+	body = append(body, ngOption(optEndOfOpt, nil)...)
+	if err := nw.writeBlock(blockInterfaceDesc, body); err != nil {
+		return 0, err
+	}
+	nw.interfaces++
+	return nw.interfaces - 1, nil
+}
+
+// WritePacket 写入增强分组块
+// [接口编号][时间戳高32位][时间戳低32位][抓取长度][原始长度][数据][选项]
+func (nw *ngWriter) WritePacket(ts time.Duration, iface int, dir Direction, frame []byte) error {
+	if iface < 0 || iface >= nw.interfaces {
+		return i18n.Errorf(ErrInterface.Code, "接口未登记")
+	}
+	data := frameData(frame, nw.includeFCS)
+	body := make([]byte, 20+pad4(len(data)))
+	binary.LittleEndian.PutUint32(body[0:4], uint32(iface))
+	binary.LittleEndian.PutUint32(body[4:8], uint32(uint64(ts)>>32))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(uint64(ts)))
+	binary.LittleEndian.PutUint32(body[12:16], uint32(len(data)))
+	binary.LittleEndian.PutUint32(body[16:20], uint32(len(data)))
+	copy(body[20:], data)
+	if dir != DirectionUnknown {
+		flags := make([]byte, 4)
+		if dir == DirectionInbound {
+			binary.LittleEndian.PutUint32(flags, epbFlagInbound)
+		} else {
+			binary.LittleEndian.PutUint32(flags, epbFlagOutbound)
+		}
+		body = append(body, ngOption(optEPBFlags, flags)...)
+		body = append(body, ngOption(optEndOfOpt, nil)...)
+	}
+	return nw.writeBlock(blockEnhancedPacket, body)
+}
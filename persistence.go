@@ -2,77 +2,158 @@ package main
 
 import (
 	"encoding/gob"
+	"fmt"
 	"os"
-	"gopherkv/data"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
 )
 
-// SaveGkvStringToFile 将DataGkvString的数据持久化到文件
-func SaveGkvStringToFile(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// snapshotVersion 快照文件格式版本, host.Snapshot 结构不兼容地变化时加一
+const snapshotVersion = 1
 
-	encoder := gob.NewEncoder(file)
-	data.DataGkvString.keyLock.tableLock.Lock()
-	defer data.DataGkvString.keyLock.tableLock.Unlock()
-	tmp := struct {
-		Data        map[string][]byte
-		ExpireTimes map[string]time.Time
-	}{
-		Data:        data.DataGkvString.DataCopy(),
-		ExpireTimes: data.DataGkvString.ExpireTimesCopy(),
-	}
-	return encoder.Encode(tmp)
+// snapshotExt 快照文件扩展名
+const snapshotExt = ".snapshot"
+
+// defaultSnapshot 未指定名称时使用的快照
+const defaultSnapshot = "default"
+
+// snapshotFile 快照文件内容, 以 gob 编码
+type snapshotFile struct {
+	// 格式版本
+	Version int
+	// 保存时刻
+	Saved time.Time
+	// 模拟器状态
+	Simulator *host.Snapshot
 }
 
-// LoadGkvStringFromFile 从文件加载数据到DataGkvString
-func LoadGkvStringFromFile(filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
+// cmdSnapshot 快照命令
+// @param args []string 子命令与快照名称
+func cmdSnapshot(args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		printUsage("snapshot")
+		return
 	}
-	defer file.Close()
-
-	decoder := gob.NewDecoder(file)
-	tmp := struct {
-		Data        map[string][]byte
-		ExpireTimes map[string]time.Time
-	}{}
-	if err := decoder.Decode(&tmp); err != nil {
-		return err
+	name := defaultSnapshot
+	if len(args) == 2 {
+		name = args[1]
+	}
+	switch args[0] {
+	case "save":
+		path, err := saveSnapshot(name)
+		if err != nil {
+			printError(i18n.T("保存快照失败:"), err)
+			return
+		}
+		fmt.Printf(i18n.T("OK %s 虚拟时间 %s\n"), path, host.FormatClock(host.Clock))
+	case "load":
+		saved, err := loadSnapshot(name)
+		if err != nil {
+			printError(i18n.T("恢复快照失败:"), err)
+			return
+		}
+		fmt.Printf(i18n.T("OK 已恢复 %s 保存的快照, 设备 %d, 链路 %d, 待处理事件 %d, 虚拟时间 %s\n"),
+			saved.Format("2006-01-02 15:04:05"), len(host.DeviceList), len(host.LinkList),
+			host.Pending(), host.FormatClock(host.Clock))
+	case "list":
+		if len(args) != 1 {
+			printUsage("snapshot")
+			return
+		}
+		listSnapshots()
+	case "del":
+		path, err := snapshotPath(name)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	default:
+		printUsage("snapshot")
 	}
-	data.DataGkvString.keyLock.tableLock.Lock()
-	defer data.DataGkvString.keyLock.tableLock.Unlock()
-	data.DataGkvString.SetAll(tmp.Data, tmp.ExpireTimes)
-	return nil
 }
 
-// DataCopy 返回data的深拷贝
-func (g *data.GkvString) DataCopy() map[string][]byte {
-	result := make(map[string][]byte, len(g.data))
-	for k, v := range g.data {
-		copyV := make([]byte, len(v))
-		copy(copyV, v)
-		result[k] = copyV
+// snapshotPath 快照文件位于数据目录中, 名称不能包含路径
+func snapshotPath(name string) (string, error) {
+	name = strings.TrimSuffix(name, snapshotExt)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf(i18n.T("快照名称不能为空或包含路径: %q"), name)
 	}
-	return result
+	return filepath.Join(appConfig.DataDir, name+snapshotExt), nil
 }
 
-// ExpireTimesCopy 返回expireTimes的深拷贝
-func (g *data.GkvString) ExpireTimesCopy() map[string]time.Time {
-	result := make(map[string]time.Time, len(g.expireTimes))
-	for k, v := range g.expireTimes {
-		result[k] = v
+// saveSnapshot 保存当前模拟状态, 先写临时文件再改名, 中途失败不会破坏已有快照
+// @param name 快照名称
+// @return string 快照文件路径
+func saveSnapshot(name string) (string, error) {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(appConfig.DataDir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(appConfig.DataDir, ".snapshot-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	file := snapshotFile{Version: snapshotVersion, Saved: time.Now(), Simulator: host.TakeSnapshot()}
+	if err := gob.NewEncoder(tmp).Encode(&file); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
 	}
-	return result
+	return path, os.Rename(tmp.Name(), path)
 }
 
-// SetAll 用于批量设置data和expireTimes
-func (g *data.GkvString) SetAll(dataMap map[string][]byte, expireMap map[string]time.Time) {
-	g.data = dataMap
-	g.expireTimes = expireMap
+// loadSnapshot 恢复快照, 替换当前的全部设备、链路与事件
+// @param name 快照名称
+// @return time.Time 快照保存时刻
+func loadSnapshot(name string) (time.Time, error) {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	var file snapshotFile
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if file.Version != snapshotVersion || file.Simulator == nil {
+		return time.Time{}, fmt.Errorf(i18n.T("%s: 不支持的快照版本 %d"), path, file.Version)
+	}
+	return file.Saved, host.RestoreSnapshot(file.Simulator)
 }
 
+// listSnapshots 列出数据目录中的快照
+func listSnapshots() {
+	paths, _ := filepath.Glob(filepath.Join(appConfig.DataDir, "*"+snapshotExt))
+	if len(paths) == 0 {
+		fmt.Println(i18n.T("(没有快照)"))
+		return
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		fmt.Printf(i18n.T("  %-20s %8d 字节  %s\n"), strings.TrimSuffix(filepath.Base(p), snapshotExt),
+			info.Size(), info.ModTime().Format("2006-01-02 15:04:05"))
+	}
+}
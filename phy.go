@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// phyRowBits 字符画每行显示的线路比特数
+const phyRowBits = 16
+
+// phyRand 误码注入的随机数来源, 固定种子使脚本结果可重复
+var phyRand = rand.New(rand.NewSource(1))
+
+// phyOptions phy 命令的 key=value 参数
+type phyOptions struct {
+	// 线路编码, LineAuto 表示按链路选择
+	code level.LineCode
+	// 显示的第一个线路比特, -1 表示默认位置
+	from int
+	// 显示的线路比特数
+	bits int
+	// 要翻转的电平下标
+	flips []int
+	// 误码率
+	ber float64
+	// 写入的SVG文件
+	svg string
+}
+
+// cmdPhy 物理层命令: 查看线路编码的波形, 注入比特错误并观察物理层与FCS能否发现
+// @param args []string 子命令与参数
+func cmdPhy(args []string) {
+	if len(args) < 2 {
+		printUsage("phy")
+		return
+	}
+	positional, opts, err := parsePhyOptions(args[1:])
+	if err != nil {
+		printArgError("phy", len(positional)+1, err.Error())
+		return
+	}
+	switch args[0] {
+	case "encode":
+		if len(positional) != 2 {
+			printUsage("phy")
+			return
+		}
+		code, err := level.ParseLineCode(positional[0])
+		if err != nil {
+			printArgError("phy", 1, err.Error())
+			return
+		}
+		data, err := hex.DecodeString(strings.ReplaceAll(positional[1], ":", ""))
+		if err != nil || len(data) == 0 {
+			printArgError("phy", 2, i18n.T("数据应为十六进制: %s", positional[1]))
+			return
+		}
+		phyEncode(code, data, opts)
+	case "frame":
+		s, n, ok := phyCapturedFrame(positional)
+		if !ok {
+			return
+		}
+		f := s.frames[n-1]
+		name, _, _ := s.interfaceOf(f)
+		fmt.Printf(i18n.T("帧 %d: %s %s %s, %d 字节\n"), n, host.FormatClock(f.Time), name, f.Direction, len(f.Data))
+		if opts.code == level.LineAuto {
+			opts.code = frameLineCode(f)
+		}
+		phyFrame(f.Data, opts)
+	default:
+		printUsage("phy")
+	}
+}
+
+// parsePhyOptions 分出位置参数与 key=value 参数
+func parsePhyOptions(args []string) ([]string, phyOptions, error) {
+	opts := phyOptions{from: -1, bits: 3 * phyRowBits}
+	var positional []string
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			positional = append(positional, arg)
+			continue
+		}
+		var err error
+		switch key {
+		case "code":
+			opts.code, err = level.ParseLineCode(value)
+		case "from":
+			opts.from, err = strconv.Atoi(value)
+			if err == nil && opts.from < 0 {
+				err = errors.New(i18n.T("from 不能为负: %s", value))
+			}
+		case "bits":
+			opts.bits, err = strconv.Atoi(value)
+			if err == nil && opts.bits <= 0 {
+				err = errors.New(i18n.T("bits 应大于0: %s", value))
+			}
+		case "errors":
+			for _, s := range strings.Split(value, ",") {
+				i, e := strconv.Atoi(s)
+				if e != nil || i < 0 {
+					return positional, opts, errors.New(i18n.T("errors 应为逗号分隔的电平下标: %s", value))
+				}
+				opts.flips = append(opts.flips, i)
+			}
+		case "ber":
+			opts.ber, err = strconv.ParseFloat(value, 64)
+			if err == nil && (opts.ber < 0 || opts.ber > 1) {
+				err = errors.New(i18n.T("误码率应在0~1之间: %s", value))
+			}
+		case "svg":
+			opts.svg = value
+		default:
+			err = errors.New(i18n.T("未知参数: %s", key))
+		}
+		if err != nil {
+			return positional, opts, err
+		}
+	}
+	return positional, opts, nil
+}
+
+// phyCapturedFrame 按 <dev> <n> 或 link <id> <n> 查找抓包会话与帧编号
+func phyCapturedFrame(positional []string) (*captureSession, int, bool) {
+	if len(positional) < 2 {
+		printUsage("phy")
+		return nil, 0, false
+	}
+	s := findCapture(positional[:len(positional)-1])
+	if s == nil {
+		return nil, 0, false
+	}
+	n, err := strconv.Atoi(positional[len(positional)-1])
+	if err != nil || n < 1 || n > len(s.frames) {
+		printError(fmt.Sprintf(i18n.T("帧编号应在 1-%d 之间"), len(s.frames)))
+		return nil, 0, false
+	}
+	return s, n, true
+}
+
+// frameLineCode 帧所在链路使用的线路编码, 链路已删除时按默认带宽选择
+func frameLineCode(f host.CapturedFrame) level.LineCode {
+	link := host.FindLink(f.LinkID)
+	if link == nil {
+		if dev := host.FindDevice(f.Device); dev != nil {
+			link = host.LinkAt(dev, f.Port)
+		}
+	}
+	if link == nil {
+		return level.LineCodeFor(host.DefaultLinkBandwidth)
+	}
+	return link.Code()
+}
+
+// phyEncode 编码任意数据, 显示波形, 注入错误后再解码
+func phyEncode(code level.LineCode, data []byte, opts phyOptions) {
+	sig := level.Encode(code, data)
+	printSignalStats(sig)
+	injected := injectPhyErrors(sig, opts)
+	printSignal(sig, max(opts.from, 0), opts.bits)
+	if !writeSignalSVG(sig, max(opts.from, 0), opts.bits, opts.svg) || injected == 0 {
+		return
+	}
+	decoded, err := sig.Decode()
+	switch {
+	case err != nil:
+		fmt.Printf(i18n.T("解码失败: %v\n"), err)
+	case bytes.Equal(decoded, data):
+		fmt.Println(i18n.T("解码结果与原数据相同"))
+	default:
+		fmt.Printf(i18n.T("解码结果: %s, %d 个字节改变\n"), hex.EncodeToString(decoded), diffBytes(decoded, data))
+	}
+}
+
+// phyFrame 物理层发送一帧, 显示波形, 注入错误后由接收方的物理层与FCS检查
+func phyFrame(frame []byte, opts phyOptions) {
+	sig := level.EncodeFrame(opts.code, frame)
+	printSignalStats(sig)
+	if sig.FrameEnd < len(sig.Bits) {
+		fmt.Printf(i18n.T("前导码与起始定界符: 线路比特 0-%d, 帧: %d-%d, 结束定界符: %d-%d\n"),
+			sig.FrameStart-1, sig.FrameStart, sig.FrameEnd-1, sig.FrameEnd, len(sig.Bits)-1)
+	} else {
+		fmt.Printf(i18n.T("前导码与起始定界符: 线路比特 0-%d, 帧: %d-%d\n"), sig.FrameStart-1, sig.FrameStart, sig.FrameEnd-1)
+	}
+	from := opts.from
+	if from < 0 {
+		// 默认从帧起始定界符附近开始显示
+		from = max(0, sig.FrameStart-phyRowBits)
+	}
+	injected := injectPhyErrors(sig, opts)
+	printSignal(sig, from, opts.bits)
+	if !writeSignalSVG(sig, from, opts.bits, opts.svg) || injected == 0 {
+		return
+	}
+	received, err := sig.DecodeFrame()
+	if err != nil {
+		fmt.Printf(i18n.T("物理层发现错误, 丢弃: %v\n"), err)
+		return
+	}
+	if bytes.Equal(received, frame) {
+		fmt.Println(i18n.T("误码没有影响帧的内容"))
+		return
+	}
+	fmt.Printf(i18n.T("物理层未发现错误, 帧中 %d 个字节改变\n"), diffBytes(received, frame))
+	var badCRC *level.ErrBadChecksum
+	switch _, err := level.Deserialize(received); {
+	case errors.As(err, &badCRC):
+		fmt.Printf(i18n.T("接收方FCS检查发现错误, 丢弃: %v\n"), err)
+	case err != nil:
+		fmt.Printf(i18n.T("接收方无法解析, 丢弃: %v\n"), err)
+	default:
+		fmt.Println(i18n.T("FCS 未能发现错误, 接收方收下了被改变的帧"))
+	}
+}
+
+// injectPhyErrors 翻转指定的电平或按误码率随机翻转, 返回翻转的电平数
+func injectPhyErrors(sig *level.Signal, opts phyOptions) int {
+	for _, i := range opts.flips {
+		if i >= len(sig.Levels) {
+			fmt.Printf(i18n.T("电平下标 %d 超出范围, 共 %d 个电平\n"), i, len(sig.Levels))
+			continue
+		}
+		sig.Flip(i)
+	}
+	sig.InjectErrors(opts.ber, phyRand)
+	if len(sig.Errors) > 0 {
+		fmt.Printf(i18n.T("注入 %d 个误码, 电平下标: %s\n"), len(sig.Errors), joinInts(sig.Errors))
+	}
+	return len(sig.Errors)
+}
+
+// printSignalStats 打印线路编码的统计
+func printSignalStats(sig *level.Signal) {
+	fmt.Printf(i18n.T("线路编码 %s: %d 个线路比特, %d 个电平, 跳变 %d 次, 直流分量 %+d\n"),
+		sig.Code, len(sig.Bits), len(sig.Levels), sig.Transitions(), sig.Disparity())
+}
+
+// printSignal 分行打印波形, 每行开头为该行第一个线路比特的下标
+func printSignal(sig *level.Signal, from, bits int) {
+	end := min(from+bits, len(sig.Bits))
+	for start := from; start < end; start += phyRowBits {
+		lines := strings.Split(strings.TrimSuffix(sig.ASCII(start, min(phyRowBits, end-start)), "\n"), "\n")
+		for i, line := range lines {
+			prefix := "      "
+			if i == 0 {
+				prefix = fmt.Sprintf("%5d ", start)
+			}
+			fmt.Println(strings.TrimRight(prefix+line, " "))
+		}
+	}
+}
+
+// writeSignalSVG 把波形写入SVG文件, 相对路径放在 capture_dir 中, 失败时返回false
+func writeSignalSVG(sig *level.Signal, from, bits int, file string) bool {
+	if file == "" {
+		return true
+	}
+	path, err := capturePath(file)
+	if err == nil {
+		err = os.WriteFile(path, []byte(sig.SVG(from, bits)), 0o644)
+	}
+	if err != nil {
+		printError(err)
+		return false
+	}
+	fmt.Printf(i18n.T("OK, 波形已写入 %s\n"), path)
+	return true
+}
+
+// diffBytes 两段数据中不同的字节数, 长度不同的部分都计入
+func diffBytes(a, b []byte) int {
+	n := max(len(a), len(b)) - min(len(a), len(b))
+	for i := range min(len(a), len(b)) {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// joinInts 逗号分隔的整数列表
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
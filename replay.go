@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+	"osiweb-go/pcap"
+)
+
+// cmdPcap 读取抓包文件: 打印解码结果或回放到模拟网络
+// @param args []string 子命令与参数
+func cmdPcap(args []string) {
+	if len(args) < 2 {
+		printUsage("pcap")
+		return
+	}
+	switch args[0] {
+	case "read":
+		detail, count, ok := parseReadOptions(args[2:])
+		if !ok {
+			printUsage("pcap")
+			return
+		}
+		if err := readPcap(args[1], detail, count); err != nil {
+			printError(err)
+		}
+	case "replay":
+		if len(args) < 3 {
+			printUsage("pcap")
+			return
+		}
+		dev, port, err := host.ParseEndpoint(args[2])
+		if err != nil {
+			printError(err)
+			return
+		}
+		if port < 0 {
+			port = 0
+		}
+		if port >= len(dev.Ports()) {
+			printError(i18n.T("端口不存在: "), args[2])
+			return
+		}
+		speed, count, ok := parseReplayOptions(args[3:])
+		if !ok {
+			printUsage("pcap")
+			return
+		}
+		n, last, err := replayPcap(args[1], dev, port, speed, count)
+		if err != nil {
+			printError(err)
+			if n == 0 {
+				return
+			}
+		}
+		fmt.Printf(i18n.T("OK, 已安排回放 %d 帧, 最后一帧在 %s, 输入 run 推进模拟\n"), n, host.FormatClock(last))
+	default:
+		printUsage("pcap")
+	}
+}
+
+// parseReadOptions 解析 read 的可选参数: detail 与帧数
+func parseReadOptions(args []string) (bool, int, bool) {
+	detail, count := false, 0
+	for _, arg := range args {
+		if arg == "detail" {
+			detail = true
+			continue
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return false, 0, false
+		}
+		count = n
+	}
+	return detail, count, true
+}
+
+// parseReplayOptions 解析 replay 的可选参数: speed=倍速 与帧数
+func parseReplayOptions(args []string) (float64, int, bool) {
+	speed, count := 1.0, 0
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(arg, "speed="); ok {
+			s, err := strconv.ParseFloat(v, 64)
+			if err != nil || s <= 0 {
+				return 0, 0, false
+			}
+			speed = s
+			continue
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		count = n
+	}
+	return speed, count, true
+}
+
+// openPcap 打开抓包文件, 逐帧回调直到文件结束或达到帧数
+// @param count 最多读取的帧数, 0表示不限
+// @return int 读取的帧数
+func openPcap(path string, count int, fn func(rd *pcap.Reader, p *pcap.Packet)) (int, error) {
+	file, err := os.Open(findCaptureFile(path))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	rd, err := pcap.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for count == 0 || n < count {
+		p, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf(i18n.T("第 %d 帧之后读取失败: %w"), n, err)
+		}
+		n++
+		fn(rd, p)
+	}
+	return n, nil
+}
+
+// simFrame 转换为模拟网络使用的帧: 帧尾必须带CRC
+func simFrame(p *pcap.Packet) []byte {
+	if p.HasFCS {
+		return p.Data
+	}
+	return level.AppendFCS(p.Data)
+}
+
+// readPcap 打印抓包文件中每一帧的摘要, detail 时附加逐层解码与十六进制
+func readPcap(path string, detail bool, count int) error {
+	first := time.Duration(-1)
+	n, err := openPcap(path, count, func(rd *pcap.Reader, p *pcap.Packet) {
+		if first < 0 {
+			first = p.Timestamp
+		}
+		frame := simFrame(p)
+		iface := rd.InterfaceName(p.Interface)
+		if iface == "" {
+			iface = strconv.Itoa(p.Interface)
+		}
+		fmt.Printf("%s %-10s %4d %s\n", host.FormatClock(p.Timestamp-first), iface, len(p.Data),
+			level.Summarize(frame))
+		if detail {
+			showDissection(p.Data, p.HasFCS)
+			fmt.Println()
+		}
+	})
+	if n == 0 && err == nil {
+		fmt.Println("(empty)")
+	}
+	return err
+}
+
+// replayPcap 按原始帧间隔(除以倍速)从设备端口发出抓包文件中的帧
+// @return int 安排的帧数
+// @return time.Duration 最后一帧的虚拟时间
+func replayPcap(path string, dev host.Device, port int, speed float64, count int) (int, time.Duration, error) {
+	start := host.Clock
+	first := time.Duration(-1)
+	last := start
+	n, err := openPcap(path, count, func(rd *pcap.Reader, p *pcap.Packet) {
+		if first < 0 {
+			first = p.Timestamp
+		}
+		offset := p.Timestamp - first
+		if offset < 0 {
+			offset = 0
+		}
+		at := start + time.Duration(float64(offset)/speed)
+		if at < last {
+			// 时间戳乱序的帧紧跟前一帧发出
+			at = last
+		}
+		last = at
+		host.InjectAt(at, dev, port, simFrame(p))
+	})
+	return n, last, err
+}
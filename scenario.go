@@ -0,0 +1,657 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// maxScriptDepth run-script 最多嵌套的层数, 防止脚本互相调用
+const maxScriptDepth = 8
+
+// scriptRun 正在执行的场景脚本
+type scriptRun struct {
+	// 脚本文件
+	path string
+	// 当前行号
+	line int
+	// 通过与失败的断言数, 包括嵌套脚本中的断言
+	passed, failed int
+	// 调用本脚本的脚本
+	parent *scriptRun
+}
+
+// currentScript 正在执行的脚本, 交互输入时为nil
+var currentScript *scriptRun
+
+// errScriptFailed 场景脚本中有断言失败
+var errScriptFailed = &i18n.Error{Code: "script.failed", Format: "断言失败"}
+
+// cmdRunScript 执行场景脚本
+// @param args []string 脚本文件
+func cmdRunScript(args []string) {
+	if len(args) != 1 {
+		printUsage("run-script")
+		return
+	}
+	if err := runScript(args[0]); err != nil && !errors.Is(err, errScriptFailed) {
+		printError(err)
+	}
+}
+
+// runScript 逐行执行脚本中的命令, # 开头的行为注释, quit 结束脚本
+// @param path 脚本文件
+// @return error 无法读取脚本时返回读取错误, 有断言失败时返回 errScriptFailed
+func runScript(path string) error {
+	depth := 0
+	for s := currentScript; s != nil; s = s.parent {
+		depth++
+	}
+	if depth >= maxScriptDepth {
+		return fmt.Errorf(i18n.T("脚本嵌套超过 %d 层: %s"), maxScriptDepth, path)
+	}
+	run := &scriptRun{path: path, parent: currentScript}
+	currentScript = run
+	err := LoadAndExecHistoryFromFile(path, func(num int, line string) bool {
+		run.line = num
+		fmt.Println(">", line)
+		return execLine(line)
+	})
+	currentScript = run.parent
+	if run.parent != nil {
+		run.parent.passed += run.passed
+		run.parent.failed += run.failed
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.T("脚本 %s: 断言通过 %d, 失败 %d\n"), path, run.passed, run.failed)
+	if run.failed > 0 {
+		return errScriptFailed
+	}
+	return nil
+}
+
+// runHeadless 无界面运行场景脚本, 返回进程退出码
+// @param path 脚本文件
+// @return int 0 全部通过, 1 有断言失败, 2 无法读取脚本
+func runHeadless(path string) int {
+	err := runScript(path)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errScriptFailed):
+		return 1
+	default:
+		fmt.Println(err)
+		return 2
+	}
+}
+
+// cmdExpect 检查断言并记录结果
+// @param args []string 断言对象与条件
+func cmdExpect(args []string) {
+	if len(args) < 2 {
+		printUsage("expect")
+		return
+	}
+	err := checkExpectation(args)
+	var usage usageError
+	if errors.As(err, &usage) {
+		// 写错的断言在脚本中也算失败, 以免评分时被忽略
+		printUsage("expect")
+		if currentScript != nil {
+			currentScript.failed++
+		}
+		return
+	}
+	where := ""
+	if currentScript != nil {
+		where = fmt.Sprintf("%s:%d ", currentScript.path, currentScript.line)
+	}
+	text := "expect " + strings.Join(args, " ")
+	if err != nil {
+		fmt.Printf(i18n.T("%s断言失败: %s: %v\n"), where, text, err)
+		failCommand(err.Error())
+	} else {
+		fmt.Printf(i18n.T("%s断言通过: %s\n"), where, text)
+	}
+	if currentScript != nil {
+		if err != nil {
+			currentScript.failed++
+		} else {
+			currentScript.passed++
+		}
+	}
+}
+
+// usageError 断言的写法错误, 与断言不成立区分
+type usageError struct{}
+
+func (usageError) Error() string { return i18n.T("参数错误") }
+
+// checkExpectation 检查一条断言
+// @return error 断言不成立的原因, 写法错误时为 usageError
+func checkExpectation(args []string) error {
+	switch args[0] {
+	case "arp":
+		// expect arp <host> contains|lacks <ip>
+		if len(args) != 4 {
+			return usageError{}
+		}
+		h, err := expectHost(args[1])
+		if err != nil {
+			return err
+		}
+		ip, err := level.ParseIPv4(args[3])
+		if err != nil {
+			return err
+		}
+		entry, ok := h.ARPTable[ip]
+		return expectContains(args[2], ok, i18n.T("%s 的ARP缓存", h.Name), args[3],
+			func() string { return level.FormatMAC(entry.MAC) })
+	case "mac":
+		// expect mac <switch> contains|lacks <mac> [vlan]
+		if len(args) != 4 && len(args) != 5 {
+			return usageError{}
+		}
+		sw, ok := host.FindDevice(args[1]).(*host.Switch)
+		if !ok {
+			return fmt.Errorf(i18n.T("交换机不存在: %s"), args[1])
+		}
+		mac, err := level.ParseMAC(args[3])
+		if err != nil {
+			return err
+		}
+		// 未指定VLAN时在所有VLAN中查找, 取编号最小的VLAN
+		var entry host.MACEntry
+		var found host.MACKey
+		ok = false
+		for key, e := range sw.MACTable {
+			if key.MAC == mac && (len(args) == 4 || strconv.Itoa(int(key.VLAN)) == args[4]) &&
+				(!ok || key.VLAN < found.VLAN) {
+				entry, found, ok = e, key, true
+			}
+		}
+		return expectContains(args[2], ok, i18n.T("%s 的MAC地址表", sw.Name), args[3],
+			func() string { return sw.PortName(entry.Port) })
+	case "stp":
+		// expect stp <switch> root | expect stp <switch>:<port> <role|state>
+		if len(args) != 3 {
+			return usageError{}
+		}
+		return expectSTP(args[1], args[2])
+	case "route":
+		// expect route <host> <dst-ip> via <next-hop>|direct|none
+		if len(args) != 4 && !(len(args) == 5 && args[3] == "via") {
+			return usageError{}
+		}
+		return expectRoute(args[1], args[2], args[len(args)-1])
+	case "tcp":
+		// expect tcp <host> <state> [count]
+		if len(args) != 3 && len(args) != 4 {
+			return usageError{}
+		}
+		h, err := expectHost(args[1])
+		if err != nil {
+			return err
+		}
+		state := strings.ToUpper(args[2])
+		n := 0
+		if state == "LISTEN" {
+			for _, on := range h.Listening {
+				if on {
+					n++
+				}
+			}
+		}
+		for _, conn := range h.TCPConns {
+			if conn.State == state {
+				n++
+			}
+		}
+		want := ">=1"
+		if len(args) == 4 {
+			want = args[3]
+		}
+		return expectCount(i18n.T("%s 上 %s 状态的TCP连接", h.Name, state), n, want)
+	case "capture":
+		// expect capture <dev>|link <id> count <filter> <n>
+		target := args[1:2]
+		rest := args[2:]
+		if args[1] == "link" {
+			if len(args) < 3 {
+				return usageError{}
+			}
+			target, rest = args[1:3], args[3:]
+		}
+		if len(rest) != 3 || rest[0] != "count" {
+			return usageError{}
+		}
+		name := target[0]
+		if len(target) == 2 {
+			name = "link" + target[1]
+		}
+		s, ok := captures[name]
+		if !ok {
+			return fmt.Errorf(i18n.T("没有抓包记录: %s"), name)
+		}
+		filter, err := parseFrameFilter(rest[1])
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, f := range s.frames {
+			if filter.match(f.Data) {
+				n++
+			}
+		}
+		return expectCount(i18n.T("%s 中满足 %s 的帧", name, rest[1]), n, rest[2])
+	case "clock":
+		// expect clock <op><duration>, 如 expect clock <= 50ms
+		spec := strings.Join(args[1:], "")
+		op, value := splitComparison(spec)
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return usageError{}
+		}
+		if !compareInt(int64(host.Clock), op, int64(d)) {
+			return fmt.Errorf(i18n.T("虚拟时间为 %s, 期望 %s %s"), host.FormatClock(host.Clock), op, d)
+		}
+		return nil
+	}
+	return usageError{}
+}
+
+// expectHost 查找断言中的主机
+func expectHost(name string) (*host.BaseHost, error) {
+	if h := host.FindHost(name); h != nil {
+		return h, nil
+	}
+	return nil, fmt.Errorf(i18n.T("主机不存在: %s"), name)
+}
+
+// expectContains 检查 contains/lacks 断言
+// @param found 表中是否有该条目
+// @param detail 条目内容, 用于失败信息
+func expectContains(mode string, found bool, table, key string, detail func() string) error {
+	switch mode {
+	case "contains":
+		if !found {
+			return fmt.Errorf(i18n.T("%s 中没有 %s"), table, key)
+		}
+	case "lacks":
+		if found {
+			return fmt.Errorf(i18n.T("%s 中有 %s (%s)"), table, key, detail())
+		}
+	default:
+		return usageError{}
+	}
+	return nil
+}
+
+// expectSTP 检查交换机是否为根桥, 或端口的生成树角色、状态
+// @param want 交换机为 root; 端口为角色(root/designated/alternate/backup/disabled)或状态(blocking/discarding/listening/learning/forwarding), 也可以写缩写 Desg、FWD 等
+func expectSTP(target, want string) error {
+	dev, port, err := host.ParseEndpoint(target)
+	if err != nil {
+		return err
+	}
+	sw, ok := dev.(*host.Switch)
+	if !ok {
+		return fmt.Errorf(i18n.T("交换机不存在: %s"), target)
+	}
+	if sw.STP.Mode == host.STPOff {
+		return fmt.Errorf(i18n.T("%s 没有启用生成树"), sw.Name)
+	}
+	if port < 0 {
+		if want != "root" {
+			return usageError{}
+		}
+		if !sw.IsRoot() {
+			return fmt.Errorf(i18n.T("%s 不是根桥, 根桥为 %s"), sw.Name, sw.STP.RootID)
+		}
+		return nil
+	}
+	p := sw.STP.Ports[port]
+	want = strings.ToLower(want)
+	if want == "discarding" {
+		want = "blocking"
+	}
+	for role := host.RoleDisabled; role <= host.RoleBackup; role++ {
+		if want == role.Name() || want == strings.ToLower(role.String()) {
+			if p.Role != role {
+				return fmt.Errorf(i18n.T("%s 的角色为 %s, 期望 %s"), target, p.Role.Name(), role.Name())
+			}
+			return nil
+		}
+	}
+	for state := host.StateDisabled; state <= host.StateForwarding; state++ {
+		if want == state.Name() || want == strings.ToLower(state.String()) {
+			if p.State != state {
+				return fmt.Errorf(i18n.T("%s 的状态为 %s, 期望 %s"), target, p.State.Name(), state.Name())
+			}
+			return nil
+		}
+	}
+	return usageError{}
+}
+
+// expectRoute 检查主机到目的地址的路由
+// @param via 下一跳地址, direct 表示直连, none 表示没有路由
+func expectRoute(name, dst, via string) error {
+	h, err := expectHost(name)
+	if err != nil {
+		return err
+	}
+	ip, err := level.ParseIPv4(dst)
+	if err != nil {
+		return err
+	}
+	port, nextHop, ok := h.LookupRoute(ip)
+	actual := "none"
+	if ok && nextHop == ip {
+		actual = "direct"
+	} else if ok {
+		actual = level.FormatIPv4(nextHop)
+	}
+	if via != "none" && via != "direct" {
+		want, err := level.ParseIPv4(via)
+		if err != nil {
+			return err
+		}
+		via = level.FormatIPv4(want)
+	}
+	if actual != via {
+		if ok {
+			actual += " (" + h.PortName(port) + ")"
+		}
+		return fmt.Errorf(i18n.T("%s 到 %s 的路由为 %s, 期望 %s"), h.Name, dst, actual, via)
+	}
+	return nil
+}
+
+// expectCount 检查数量, want 为 3、>=1、<5 等形式
+func expectCount(what string, n int, want string) error {
+	op, value := splitComparison(want)
+	expected, err := strconv.Atoi(value)
+	if err != nil {
+		return usageError{}
+	}
+	if !compareInt(int64(n), op, int64(expected)) {
+		return fmt.Errorf(i18n.T("%s 有 %d 个, 期望 %s %d"), what, n, op, expected)
+	}
+	return nil
+}
+
+// splitComparison 拆分 >=3 形式的比较, 没有运算符时为 ==
+func splitComparison(s string) (string, string) {
+	for _, op := range []string{"==", "!=", ">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			if op == "=" {
+				op = "=="
+			}
+			return op, strings.TrimPrefix(s, op)
+		}
+	}
+	return "==", s
+}
+
+// compareInt 按运算符比较
+func compareInt(a int64, op string, b int64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">=":
+		return a >= b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case "<":
+		return a < b
+	}
+	return false
+}
+
+// filterFieldKinds 过滤条件中可用的字段与取值类型, 字段名与 craft 一致
+var filterFieldKinds = map[string]string{
+	"eth.dst": "mac", "eth.src": "mac", "eth.type": "num",
+	"vlan.id": "num", "vlan.pcp": "num", "vlan.dei": "num", "vlan.type": "num",
+	"llc.dsap": "num", "llc.ssap": "num", "llc.ctrl": "num", "snap.oui": "num", "snap.pid": "num",
+	"stp.type": "num", "stp.flags": "num", "stp.cost": "num", "stp.port": "num",
+	"arp.op": "arpop", "arp.sha": "mac", "arp.spa": "ip", "arp.tha": "mac", "arp.tpa": "ip",
+	"ip.src": "ip", "ip.dst": "ip", "ip.ttl": "num", "ip.proto": "ipproto", "ip.id": "num", "ip.tos": "num",
+	"icmp.type": "num", "icmp.code": "num", "icmp.id": "num", "icmp.seq": "num",
+	"udp.sport": "num", "udp.dport": "num", "udp.len": "num",
+	"tcp.sport": "num", "tcp.dport": "num", "tcp.seq": "num", "tcp.ack": "num",
+	"tcp.flags": "flags", "tcp.win": "num", "tcp.len": "num",
+}
+
+// filterProtocols 协议名称及其别名, 对应 level.Protocols 的结果
+var filterProtocols = map[string]string{
+	"eth": "ethernet", "ethernet": "ethernet", "vlan": "vlan", "llc": "llc", "snap": "snap", "stp": "stp", "arp": "arp", "ip": "ipv4", "ipv4": "ipv4",
+	"ipv6": "ipv6", "icmp": "icmp", "tcp": "tcp", "udp": "udp",
+}
+
+// filterTerm 一个过滤条件: 协议, 或 字段 运算符 值
+type filterTerm struct {
+	// 协议, 只有协议时匹配该协议的所有帧
+	proto string
+	// 字段, 如 tcp.flags
+	field string
+	// 运算符 == != < > <= >= &, & 表示值中的标志位全部置位
+	op string
+	// 取值, 地址已规范化, 数值已解析
+	text string
+	num  uint64
+}
+
+// frameFilter 用 && 连接的过滤条件
+type frameFilter []filterTerm
+
+// parseFrameFilter 解析 all、tcp、tcp.flags==SYN、ip.src==10.0.0.1&&udp.dport==53 形式的过滤条件
+func parseFrameFilter(s string) (frameFilter, error) {
+	if s == "all" || s == "*" {
+		return frameFilter{}, nil
+	}
+	var filter frameFilter
+	for _, part := range strings.Split(s, "&&") {
+		part = strings.TrimSpace(part)
+		i := strings.IndexAny(part, "=!<>&")
+		if i < 0 {
+			proto, ok := filterProtocols[part]
+			if !ok {
+				return nil, fmt.Errorf(i18n.T("未知协议: %s"), part)
+			}
+			filter = append(filter, filterTerm{proto: proto})
+			continue
+		}
+		field, rest := part[:i], part[i:]
+		kind, ok := filterFieldKinds[field]
+		if !ok {
+			return nil, fmt.Errorf(i18n.T("未知字段: %s"), field)
+		}
+		op, value := splitComparison(rest)
+		if strings.HasPrefix(rest, "&") {
+			op, value = "&", rest[1:]
+		}
+		t := filterTerm{proto: filterProtocols[strings.Split(field, ".")[0]], field: field, op: op}
+		var err error
+		switch kind {
+		case "mac":
+			var mac [6]byte
+			mac, err = level.ParseMAC(value)
+			t.text = level.FormatMAC(mac)
+		case "ip":
+			var ip [4]byte
+			ip, err = level.ParseIPv4(value)
+			t.text = level.FormatIPv4(ip)
+		case "flags":
+			var flags uint16
+			flags, err = parseTCPFlags(value)
+			t.num = uint64(flags)
+		case "arpop":
+			t.num, err = parseNamedNumber(value, map[string]uint64{"request": 1, "reply": 2})
+		case "ipproto":
+			t.num, err = parseNamedNumber(value, map[string]uint64{"icmp": 1, "tcp": 6, "udp": 17})
+		default:
+			t.num, err = strconv.ParseUint(value, 0, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("%s 的取值错误: %s"), field, value)
+		}
+		if t.text != "" && op != "==" && op != "!=" {
+			return nil, fmt.Errorf(i18n.T("%s 只能用 == 或 != 比较"), field)
+		}
+		filter = append(filter, t)
+	}
+	return filter, nil
+}
+
+// parseNamedNumber 解析数值或名称
+func parseNamedNumber(s string, names map[string]uint64) (uint64, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	return strconv.ParseUint(s, 0, 64)
+}
+
+// match 帧是否满足全部条件
+func (f frameFilter) match(frame []byte) bool {
+	if len(f) == 0 {
+		return true
+	}
+	protocols := level.Protocols(frame)
+	fields := frameFields(frame)
+	for _, t := range f {
+		if !containsString(protocols, t.proto) {
+			return false
+		}
+		if t.field == "" {
+			continue
+		}
+		v, ok := fields[t.field]
+		if !ok {
+			return false
+		}
+		if s, isText := v.(string); isText {
+			if (s == t.text) != (t.op == "==") {
+				return false
+			}
+			continue
+		}
+		n := v.(uint64)
+		if t.op == "&" {
+			if n&t.num != t.num {
+				return false
+			}
+		} else if !compareInt(int64(n), t.op, int64(t.num)) {
+			return false
+		}
+	}
+	return true
+}
+
+// frameFields 取出帧中可用于过滤的字段, 地址为字符串, 其余为 uint64
+func frameFields(frame []byte) map[string]any {
+	fields := make(map[string]any)
+	if len(frame) < level.EthernetHeaderSize+4 {
+		return fields
+	}
+	fields["eth.dst"] = level.FormatMAC([6]byte(frame[0:6]))
+	fields["eth.src"] = level.FormatMAC([6]byte(frame[6:12]))
+	fields["eth.type"] = uint64(uint16(frame[12])<<8 | uint16(frame[13]))
+	// 带标签时取最外层标签, 上层按最内层的类型解码
+	tags, etherType, offset := level.FrameTags(frame)
+	if len(tags) > 0 {
+		fields["vlan.id"] = uint64(tags[0].VID())
+		fields["vlan.pcp"] = uint64(tags[0].PCP())
+		fields["vlan.dei"] = uint64(0)
+		if tags[0].DEI() {
+			fields["vlan.dei"] = uint64(1)
+		}
+		fields["vlan.type"] = uint64(etherType)
+	}
+	if offset > len(frame)-4 {
+		return fields
+	}
+	payload := frame[offset : len(frame)-4]
+	if etherType <= level.MaxDataSize {
+		if llc, err := level.DeserializeLLC(payload[:min(int(etherType), len(payload))]); err == nil {
+			fields["llc.dsap"] = uint64(llc.DSAP)
+			fields["llc.ssap"] = uint64(llc.SSAP)
+			fields["llc.ctrl"] = uint64(llc.Control)
+			if llc.IsSNAP() {
+				fields["snap.oui"] = uint64(llc.OUI[0])<<16 | uint64(llc.OUI[1])<<8 | uint64(llc.OUI[2])
+				fields["snap.pid"] = uint64(llc.PID)
+			}
+			if bpdu, err := level.DeserializeBPDU(llc.Data); err == nil && llc.DSAP == level.SAPSTP {
+				fields["stp.type"] = uint64(bpdu.Type)
+				fields["stp.flags"] = uint64(bpdu.Flags)
+				fields["stp.cost"] = uint64(bpdu.RootPathCost)
+				fields["stp.port"] = uint64(bpdu.PortID)
+			}
+		}
+		return fields
+	}
+	switch etherType {
+	case level.EtherTypeARP:
+		if arp, err := level.DeserializeARPPacket(payload); err == nil {
+			fields["arp.op"] = uint64(arp.Operation)
+			fields["arp.sha"] = level.FormatMAC(arp.SenderMAC)
+			fields["arp.spa"] = level.FormatIPv4(arp.SenderIP)
+			fields["arp.tha"] = level.FormatMAC(arp.TargetMAC)
+			fields["arp.tpa"] = level.FormatIPv4(arp.TargetIP)
+		}
+		return fields
+	case level.EtherTypeIPv4:
+	default:
+		return fields
+	}
+	// 校验和错误的报文仍可按字段过滤
+	ip, _ := level.DeserializeIPv4Packet(payload)
+	if ip == nil {
+		return fields
+	}
+	fields["ip.src"] = level.FormatIPv4(ip.SourceIP)
+	fields["ip.dst"] = level.FormatIPv4(ip.DestIP)
+	fields["ip.ttl"] = uint64(ip.TTL)
+	fields["ip.proto"] = uint64(ip.Protocol)
+	fields["ip.id"] = uint64(ip.Identification)
+	fields["ip.tos"] = uint64(ip.TOS)
+	switch ip.Protocol {
+	case level.IPProtocolICMP:
+		if icmp, _ := level.DeserializeICMPPacket(ip.Data); icmp != nil {
+			fields["icmp.type"] = uint64(icmp.Type)
+			fields["icmp.code"] = uint64(icmp.Code)
+			fields["icmp.id"] = uint64(icmp.Identifier)
+			fields["icmp.seq"] = uint64(icmp.Sequence)
+		}
+	case level.IPProtocolUDP:
+		if udp, err := level.DeserializeUDPPacket(ip.Data); err == nil {
+			fields["udp.sport"] = uint64(udp.SourcePort)
+			fields["udp.dport"] = uint64(udp.DestPort)
+			fields["udp.len"] = uint64(len(udp.Data))
+		}
+	case level.IPProtocolTCP:
+		if tcp, err := level.DeserializeTCPPacket(ip.Data); err == nil {
+			fields["tcp.sport"] = uint64(tcp.SourcePort)
+			fields["tcp.dport"] = uint64(tcp.DestPort)
+			fields["tcp.seq"] = uint64(tcp.SeqNum)
+			fields["tcp.ack"] = uint64(tcp.AckNum)
+			fields["tcp.flags"] = uint64(tcp.Flags())
+			fields["tcp.win"] = uint64(tcp.Window)
+			fields["tcp.len"] = uint64(len(tcp.Data))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeScript 在临时目录中写入场景脚本
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.osi")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunHeadless(t *testing.T) {
+	resetSimulator(t)
+	if code := runHeadless("examples/lab.osi"); code != 0 {
+		t.Errorf("examples/lab.osi exited with %d, want 0", code)
+	}
+
+	resetSimulator(t)
+	failing := writeScript(t, "topology load examples/lab.yaml\nrun\nexpect arp h1 lacks 192.168.1.1\nexpect clock <1ms\n")
+	if code := runHeadless(failing); code != 1 {
+		t.Errorf("failing script exited with %d, want 1", code)
+	}
+
+	// 写错的断言也算失败
+	if code := runHeadless(writeScript(t, "expect arp h1\n")); code != 1 {
+		t.Errorf("malformed expect exited with %d, want 1", code)
+	}
+	if code := runHeadless(filepath.Join(t.TempDir(), "nope.osi")); code != 2 {
+		t.Errorf("missing script exited with %d, want 2", code)
+	}
+}
+
+func TestRunScriptNesting(t *testing.T) {
+	resetSimulator(t)
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "inner.osi")
+	outer := filepath.Join(dir, "outer.osi")
+	os.WriteFile(inner, []byte("expect clock >=0s\nexpect clock <0s\n"), 0o644)
+	os.WriteFile(outer, []byte("run-script "+inner+"\nexpect clock >=0s\n"), 0o644)
+	if err := runScript(outer); !errors.Is(err, errScriptFailed) {
+		t.Errorf("outer script: %v, want errScriptFailed from the nested failure", err)
+	}
+
+	// 脚本调用自身时在嵌套上限处停止
+	self := filepath.Join(dir, "self.osi")
+	os.WriteFile(self, []byte("run-script "+self+"\n"), 0o644)
+	if err := runScript(self); err != nil {
+		t.Errorf("self-recursive script: %v", err)
+	}
+	if currentScript != nil {
+		t.Errorf("currentScript = %s after the script finished", currentScript.path)
+	}
+}
+
+func TestCheckExpectation(t *testing.T) {
+	resetSimulator(t)
+	if _, err := loadTopology("examples/lab.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := startCapture([]string{"h3"}, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := startCapture([]string{"link", "4"}, "", false); err != nil {
+		t.Fatal(err)
+	}
+	execLine("run")
+
+	tests := []struct {
+		args string
+		// ok 断言成立, fail 断言不成立, usage 写法错误
+		want string
+	}{
+		{"arp h1 contains 192.168.1.1", "ok"},
+		{"arp h1 contains 192.168.2.30", "fail"},
+		{"arp h1 lacks 192.168.2.30", "ok"},
+		{"arp nope contains 192.168.1.1", "fail"},
+		{"arp h1 contains", "usage"},
+		{"mac s1 contains 02:00:00:00:01:10", "ok"},
+		{"mac h1 contains 02:00:00:00:01:10", "fail"},
+		{"route h1 192.168.2.30 via 192.168.1.1", "ok"},
+		{"route h1 192.168.1.20 direct", "ok"},
+		{"route h1 192.168.2.30 direct", "fail"},
+		{"tcp h3 ESTABLISHED 1", "ok"},
+		{"tcp h3 listen", "ok"},
+		{"tcp h1 ESTABLISHED", "fail"},
+		{"capture h3 count tcp.flags==SYN 1", "ok"},
+		{"capture h3 count icmp >=1", "ok"},
+		{"capture h3 count icmp 0", "fail"},
+		{"capture link 4 count ip >0", "ok"},
+		{"capture h2 count ip 1", "fail"},
+		{"capture h3 ip 1", "usage"},
+		{"capture link", "usage"},
+		{"capture link 4", "usage"},
+		{"clock < 100ms", "ok"},
+		{"clock >1h", "fail"},
+		{"clock soon", "usage"},
+		{"weather sunny", "usage"},
+	}
+	for _, tt := range tests {
+		err := checkExpectation(parseFields(tt.args))
+		got := "ok"
+		if errors.As(err, new(usageError)) {
+			got = "usage"
+		} else if err != nil {
+			got = "fail"
+		}
+		if got != tt.want {
+			t.Errorf("expect %s: %s (%v), want %s", tt.args, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,809 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"osiweb-go/host"
+	"osiweb-go/i18n"
+	"osiweb-go/level"
+)
+
+// Topology 拓扑文件, 扩展名为 .yaml/.yml 时按YAML读写, 否则按JSON读写
+type Topology struct {
+	// 主机
+	Hosts []TopoNode `json:"hosts,omitempty"`
+	// 路由器
+	Routers []TopoNode `json:"routers,omitempty"`
+	// 交换机
+	Switches []TopoSwitch `json:"switches,omitempty"`
+	// 链路
+	Links []TopoLink `json:"links,omitempty"`
+}
+
+// TopoNode 主机或路由器
+type TopoNode struct {
+	// 设备名称
+	Name string `json:"name"`
+	// 接口, 依次为 eth0、eth1 ...
+	Interfaces []TopoInterface `json:"interfaces,omitempty"`
+	// 默认网关, 等同于 to 为 default 的路由
+	Gateway string `json:"gateway,omitempty"`
+	// 静态路由
+	Routes []TopoRoute `json:"routes,omitempty"`
+	// 加载后启动的应用
+	Apps []TopoApp `json:"apps,omitempty"`
+}
+
+// TopoInterface 接口设置
+type TopoInterface struct {
+	// 接口名称, 可省略, 填写时必须与位置一致
+	Name string `json:"name,omitempty"`
+	// 地址 ip/prefix, 主机的 eth0 省略时从地址池分配
+	Address string `json:"address,omitempty"`
+	// MAC地址, 省略时自动分配
+	MAC string `json:"mac,omitempty"`
+	// MTU, 省略时使用 simulator.mtu
+	MTU int `json:"mtu,omitempty"`
+	// VLAN子接口, 名称为 接口名.VLAN编号
+	Subinterfaces []TopoSubinterface `json:"subinterfaces,omitempty"`
+}
+
+// TopoSubinterface VLAN子接口
+type TopoSubinterface struct {
+	// VLAN编号 1~4094
+	VLAN uint16 `json:"vlan"`
+	// 地址 ip/prefix
+	Address string `json:"address,omitempty"`
+}
+
+// TopoRoute 静态路由
+type TopoRoute struct {
+	// 目的网络 prefix/len 或 default
+	To string `json:"to"`
+	// 下一跳
+	Via string `json:"via"`
+}
+
+// TopoApp 加载后启动的应用
+type TopoApp struct {
+	// tcp-server 监听端口, ping/udp/tcp 向 to 发送一次
+	Type string `json:"type"`
+	// 端口
+	Port uint16 `json:"port,omitempty"`
+	// 目的地址
+	To string `json:"to,omitempty"`
+	// udp/tcp 发送的数据
+	Data string `json:"data,omitempty"`
+}
+
+// TopoSwitch 交换机
+type TopoSwitch struct {
+	// 交换机名称
+	Name string `json:"name"`
+	// 端口数, 省略时为8
+	Ports int `json:"ports,omitempty"`
+	// 端口的VLAN设置, 未列出的端口属于VLAN 1
+	VLANs []TopoSwitchPort `json:"vlans,omitempty"`
+	// 生成树, 省略时不运行
+	STP *TopoSTP `json:"stp,omitempty"`
+}
+
+// TopoSTP 交换机的生成树设置
+type TopoSTP struct {
+	// stp 或 rstp
+	Mode string `json:"mode"`
+	// 网桥优先级, 省略时为32768
+	Priority *int `json:"priority,omitempty"`
+	// 端口设置, 未列出的端口使用默认值
+	Ports []TopoSTPPort `json:"ports,omitempty"`
+}
+
+// TopoSTPPort 交换机端口的生成树设置
+type TopoSTPPort struct {
+	// 端口 eth1 或 1
+	Port string `json:"port"`
+	// 路径开销, 省略时按链路带宽计算
+	Cost uint32 `json:"cost,omitempty"`
+	// 端口优先级, 省略时为128
+	Priority *int `json:"priority,omitempty"`
+	// 边缘端口
+	Edge bool `json:"edge,omitempty"`
+}
+
+// TopoSwitchPort 交换机端口的VLAN设置
+type TopoSwitchPort struct {
+	// 端口 eth1 或 1
+	Port string `json:"port"`
+	// access 或 trunk
+	Mode string `json:"mode"`
+	// access 端口所属的VLAN, trunk 端口的本征VLAN, 省略时为1
+	VLAN uint16 `json:"vlan,omitempty"`
+	// trunk 端口允许通过的VLAN, 省略时全部允许
+	Allowed []uint16 `json:"allowed,omitempty"`
+}
+
+// TopoLink 链路, 参数省略时使用 simulator.link 中的默认值
+type TopoLink struct {
+	// 端点 <dev>[:port], 省略端口时使用第一个空闲端口
+	A string `json:"a"`
+	B string `json:"b"`
+	// 传播时延
+	Delay *Duration `json:"delay,omitempty"`
+	// 带宽
+	Bandwidth *Bandwidth `json:"bandwidth,omitempty"`
+	// 丢包率 0~1
+	Loss *float64 `json:"loss,omitempty"`
+	// 误码率 0~1
+	BER *float64 `json:"ber,omitempty"`
+	// 线路编码, 省略时按带宽选择
+	Code string `json:"code,omitempty"`
+}
+
+// defaultSwitchPorts 未指定端口数的交换机
+const defaultSwitchPorts = 8
+
+// cmdTopology 拓扑文件命令
+// @param args []string 子命令与文件名
+func cmdTopology(args []string) {
+	if len(args) != 2 {
+		printUsage("topology")
+		return
+	}
+	switch args[0] {
+	case "load":
+		t, err := loadTopology(args[1])
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Printf(i18n.T("OK 主机 %d, 路由器 %d, 交换机 %d, 链路 %d\n"),
+			len(t.Hosts), len(t.Routers), len(t.Switches), len(t.Links))
+	case "check":
+		t, err := readTopology(args[1])
+		if err == nil {
+			err = t.validate()
+		}
+		if err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK")
+	case "save":
+		if err := writeTopology(args[1], currentTopology()); err != nil {
+			printError(err)
+			return
+		}
+		fmt.Println("OK", args[1])
+	default:
+		printUsage("topology")
+	}
+}
+
+// isYAML 按扩展名判断文件格式
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readTopology 读取拓扑文件, 未知字段视为错误
+func readTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isYAML(path) {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	t := &Topology{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(t); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// writeTopology 写入拓扑文件
+func writeTopology(path string, t *Topology) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if isYAML(path) {
+		if data, err = jsonToYAML(data); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadTopology 读取、校验并创建拓扑, 校验失败时不创建任何设备
+// @param path 拓扑文件
+// @return *Topology, error
+func loadTopology(path string) (*Topology, error) {
+	t, err := readTopology(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, t.build()
+}
+
+// topoPort 校验时记录的设备端口, 子接口带VLAN编号
+type topoPort struct {
+	dev  string
+	port int
+	vlan uint16
+}
+
+// String 返回 设备:端口 形式的名称
+func (p topoPort) String() string {
+	if p.vlan != 0 {
+		return fmt.Sprintf("%s:eth%d.%d", p.dev, p.port, p.vlan)
+	}
+	return fmt.Sprintf("%s:eth%d", p.dev, p.port)
+}
+
+// topoAddr 校验时记录的接口地址
+type topoAddr struct {
+	at        topoPort
+	ip        [4]byte
+	prefixLen int
+}
+
+// network 接口所在网段
+func (a topoAddr) network() [4]byte {
+	return maskIPv4(a.ip, a.prefixLen)
+}
+
+// maskIPv4 按前缀长度取网络地址
+func maskIPv4(ip [4]byte, prefixLen int) [4]byte {
+	mask := level.PrefixMask(prefixLen)
+	for i := range ip {
+		ip[i] &= mask[i]
+	}
+	return ip
+}
+
+// validate 检查拓扑, 一次报告所有错误
+func (t *Topology) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, errors.New(i18n.T(format, args...)))
+		}
+	}
+
+	// 设备名称, 同时记录每个设备的端口数
+	ports := make(map[string]int)
+	kinds := make(map[string]string)
+	addDevice := func(kind, name string, n int) {
+		check(name != "" && !strings.ContainsAny(name, ": \t"), "%s名称不能为空或包含冒号、空白: %q", i18n.T(kind), name)
+		_, dup := ports[name]
+		check(!dup, "设备名称重复: %s", name)
+		check(host.FindDevice(name) == nil, "设备已存在: %s", name)
+		ports[name], kinds[name] = n, kind
+	}
+	for _, h := range t.Hosts {
+		addDevice("主机", h.Name, max(len(h.Interfaces), 1))
+	}
+	for _, r := range t.Routers {
+		addDevice("路由器", r.Name, len(r.Interfaces))
+	}
+	for _, sw := range t.Switches {
+		check(sw.Ports >= 0, "交换机 %s 的端口数不能为负: %d", sw.Name, sw.Ports)
+		n := sw.Ports
+		if n == 0 {
+			n = defaultSwitchPorts
+		}
+		addDevice("交换机", sw.Name, n)
+		configured := make(map[int]bool)
+		for _, v := range sw.VLANs {
+			port := parseTopoPort(v.Port)
+			check(port >= 0 && port < n, "交换机 %s 没有端口 %s", sw.Name, v.Port)
+			check(!configured[port], "交换机 %s 的端口 %s 重复设置VLAN", sw.Name, v.Port)
+			configured[port] = true
+			check(v.Mode == "access" || v.Mode == "trunk", "交换机 %s 端口 %s 的模式应为 access/trunk: %q", sw.Name, v.Port, v.Mode)
+			check(v.Mode == "trunk" || len(v.Allowed) == 0, "交换机 %s 端口 %s: 只有 trunk 端口可以设置 allowed", sw.Name, v.Port)
+			for _, vlan := range append([]uint16{max(v.VLAN, host.DefaultVLAN)}, v.Allowed...) {
+				check(vlan >= 1 && vlan <= level.MaxVLAN, "交换机 %s 端口 %s: VLAN编号应在1~%d之间: %d", sw.Name, v.Port, level.MaxVLAN, vlan)
+			}
+		}
+		if sw.STP != nil {
+			_, ok := host.ParseSTPMode(sw.STP.Mode)
+			check(ok, "交换机 %s 的生成树协议应为 stp/rstp: %q", sw.Name, sw.STP.Mode)
+			if p := sw.STP.Priority; p != nil {
+				check(*p >= 0 && *p <= 61440 && *p%4096 == 0, "交换机 %s 的网桥优先级应为0~61440之间4096的倍数: %d", sw.Name, *p)
+			}
+			configured := make(map[int]bool)
+			for _, sp := range sw.STP.Ports {
+				port := parseTopoPort(sp.Port)
+				check(port >= 0 && port < n, "交换机 %s 没有端口 %s", sw.Name, sp.Port)
+				check(!configured[port], "交换机 %s 的端口 %s 重复设置生成树", sw.Name, sp.Port)
+				configured[port] = true
+				if p := sp.Priority; p != nil {
+					check(*p >= 0 && *p <= 240 && *p%16 == 0, "交换机 %s 端口 %s: 端口优先级应为0~240之间16的倍数: %d", sw.Name, sp.Port, *p)
+				}
+			}
+		}
+	}
+
+	// 接口地址、路由与应用
+	var addrs []topoAddr
+	macs := make(map[[6]byte]topoPort)
+	nodes := append(slices.Clone(t.Hosts), t.Routers...)
+	for _, n := range nodes {
+		var own []topoAddr
+		addAddress := func(at topoPort, address string) {
+			ip, prefixLen, err := level.ParseIPv4Prefix(address)
+			if err != nil {
+				check(false, "%s: 地址格式错误: %q", at, address)
+				return
+			}
+			a := topoAddr{at: at, ip: ip, prefixLen: prefixLen}
+			if prefixLen <= 30 {
+				broadcast := a.network()
+				for j, m := range level.PrefixMask(prefixLen) {
+					broadcast[j] |= ^m
+				}
+				check(ip != a.network() && ip != broadcast, "%s: %s 是网络地址或广播地址", at, address)
+			}
+			for _, b := range own {
+				check(!subnetsOverlap(a, b), "%s 与 %s 的网段重叠: %s, %s", b.at, at,
+					formatPrefix(b.network(), b.prefixLen), formatPrefix(a.network(), a.prefixLen))
+			}
+			own = append(own, a)
+			for _, b := range addrs {
+				check(a.ip != b.ip, "IP地址重复: %s (%s, %s)", level.FormatIPv4(ip), b.at, at)
+			}
+			addrs = append(addrs, a)
+		}
+		for i, iface := range n.Interfaces {
+			at := topoPort{n.Name, i, 0}
+			check(iface.Name == "" || iface.Name == fmt.Sprintf("eth%d", i),
+				"%s: 第%d个接口的名称应为 eth%d: %q", n.Name, i+1, i, iface.Name)
+			check(iface.MTU == 0 || (iface.MTU >= 68 && iface.MTU <= level.MaxDataSize),
+				"%s: MTU应在68~%d之间: %d", at, level.MaxDataSize, iface.MTU)
+			if iface.MAC != "" {
+				mac, err := level.ParseMAC(iface.MAC)
+				check(err == nil, "%s: MAC地址格式错误: %q", at, iface.MAC)
+				check(err != nil || mac[0]&0x01 == 0, "%s: MAC地址不能是组播地址: %s", at, iface.MAC)
+				if prev, dup := macs[mac]; dup && err == nil {
+					check(false, "MAC地址重复: %s (%s, %s)", level.FormatMAC(mac), prev, at)
+				} else if err == nil {
+					macs[mac] = at
+				}
+			}
+			if iface.Address != "" {
+				addAddress(at, iface.Address)
+			}
+			vlans := make(map[uint16]bool)
+			for _, sub := range iface.Subinterfaces {
+				subAt := topoPort{n.Name, i, sub.VLAN}
+				check(sub.VLAN >= 1 && sub.VLAN <= level.MaxVLAN, "%s: VLAN编号应在1~%d之间: %d", subAt, level.MaxVLAN, sub.VLAN)
+				check(!vlans[sub.VLAN], "%s: 子接口重复", subAt)
+				vlans[sub.VLAN] = true
+				if sub.Address != "" {
+					addAddress(subAt, sub.Address)
+				}
+			}
+		}
+		direct := func(ip [4]byte) bool {
+			for _, a := range own {
+				if a.network() == maskIPv4(ip, a.prefixLen) {
+					return true
+				}
+			}
+			// 主机的 eth0 未指定地址时由地址池分配, 无法预先检查
+			return kinds[n.Name] == "主机" && (len(n.Interfaces) == 0 || n.Interfaces[0].Address == "")
+		}
+		routes := slices.Clone(n.Routes)
+		if n.Gateway != "" {
+			routes = append(routes, TopoRoute{To: "default", Via: n.Gateway})
+		}
+		seen := make(map[string]bool)
+		for _, r := range routes {
+			prefix, prefixLen := [4]byte{}, 0
+			if r.To != "default" {
+				var err error
+				if prefix, prefixLen, err = level.ParseIPv4Prefix(r.To); err != nil {
+					check(false, "%s: 路由目的网络格式错误: %q", n.Name, r.To)
+					continue
+				}
+			}
+			key := formatPrefix(maskIPv4(prefix, prefixLen), prefixLen)
+			check(!seen[key], "%s: 路由重复: %s", n.Name, key)
+			seen[key] = true
+			via, err := level.ParseIPv4(r.Via)
+			if err != nil {
+				check(false, "%s: 路由 %s 的下一跳格式错误: %q", n.Name, r.To, r.Via)
+				continue
+			}
+			check(via == [4]byte{} || direct(via), "%s: 路由 %s 的下一跳 %s 不在直连网段中", n.Name, r.To, r.Via)
+		}
+		for _, app := range n.Apps {
+			switch app.Type {
+			case "tcp-server":
+				check(app.Port > 0, "%s: tcp-server 需要 port", n.Name)
+			case "ping", "udp", "tcp":
+				_, err := level.ParseIPv4(app.To)
+				check(err == nil, "%s: %s 的目的地址格式错误: %q", n.Name, app.Type, app.To)
+				check(app.Type == "ping" || app.Port > 0, "%s: %s 需要 port", n.Name, app.Type)
+			default:
+				check(false, "%s: 应用类型应为 tcp-server/ping/udp/tcp: %q", n.Name, app.Type)
+			}
+		}
+	}
+
+	// 链路: 端点必须指向文件中的设备与端口, 每个端口只能连接一条链路
+	used := make(map[topoPort]int)
+	segments := newTopoSegments()
+	for i, l := range t.Links {
+		var ends []topoPort
+		for _, ep := range []string{l.A, l.B} {
+			name, portName, hasPort := strings.Cut(ep, ":")
+			n, ok := ports[name]
+			if !ok {
+				check(false, "链路 %d: 端点 %s 指向不存在的设备", i+1, ep)
+				continue
+			}
+			port := -1
+			if hasPort {
+				port = parseTopoPort(portName)
+				if port < 0 || port >= n {
+					check(false, "链路 %d: 端点 %s 指向不存在的端口", i+1, ep)
+					continue
+				}
+			} else {
+				// 与 host.FreePort 相同: 第一个空闲端口, 没有则新增
+				port = 0
+				for port < n && used[topoPort{name, port, 0}] > 0 {
+					port++
+				}
+				if port == n {
+					ports[name]++
+				}
+			}
+			at := topoPort{name, port, 0}
+			if prev := used[at]; prev > 0 {
+				check(false, "链路 %d: 端口 %s 已连接到链路 %d", i+1, at, prev)
+				continue
+			}
+			used[at] = i + 1
+			ends = append(ends, at)
+		}
+		if len(ends) == 2 {
+			check(ends[0] != ends[1], "链路 %d: 不能将端口连接到自身", i+1)
+			segments.union(ends[0], ends[1])
+		}
+		check(l.Delay == nil || *l.Delay >= 0, "链路 %d: 时延不能为负", i+1)
+		check(l.Bandwidth == nil || *l.Bandwidth >= 0, "链路 %d: 带宽不能为负", i+1)
+		check(l.Loss == nil || (*l.Loss >= 0 && *l.Loss <= 1), "链路 %d: 丢包率应在0~1之间", i+1)
+		check(l.BER == nil || (*l.BER >= 0 && *l.BER <= 1), "链路 %d: 误码率应在0~1之间", i+1)
+		if l.Code != "" {
+			_, err := level.ParseLineCode(l.Code)
+			check(err == nil, "链路 %d: %v", i+1, err)
+		}
+	}
+
+	// 交换机的所有端口属于同一个二层网段, 不同网段的地址不能重叠
+	for _, sw := range t.Switches {
+		for p := 1; p < ports[sw.Name]; p++ {
+			segments.union(topoPort{sw.Name, 0, 0}, topoPort{sw.Name, p, 0})
+		}
+	}
+	reported := make(map[string]bool)
+	for i, a := range addrs {
+		if used[a.at] == 0 {
+			continue
+		}
+		for _, b := range addrs[:i] {
+			if used[b.at] == 0 || segments.find(a.at) == segments.find(b.at) || !subnetsOverlap(a, b) {
+				continue
+			}
+			na, nb := formatPrefix(a.network(), a.prefixLen), formatPrefix(b.network(), b.prefixLen)
+			if !reported[nb+" "+na] {
+				reported[nb+" "+na] = true
+				check(false, "网段重叠: %s (%s) 与 %s (%s) 不在同一个二层网段", nb, b.at, na, a.at)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s\n%w", i18n.T("拓扑错误:"), errors.Join(errs...))
+	}
+	return nil
+}
+
+// parseTopoPort 解析 eth1 或 1 形式的端口, 格式错误返回-1
+func parseTopoPort(s string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "eth"))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// subnetsOverlap 两个接口的网段是否有交集
+func subnetsOverlap(a, b topoAddr) bool {
+	shorter := min(a.prefixLen, b.prefixLen)
+	return maskIPv4(a.ip, shorter) == maskIPv4(b.ip, shorter)
+}
+
+// formatPrefix 格式化 prefix/len
+func formatPrefix(ip [4]byte, prefixLen int) string {
+	return fmt.Sprintf("%s/%d", level.FormatIPv4(ip), prefixLen)
+}
+
+// topoSegments 用并查集划分二层网段
+type topoSegments map[topoPort]topoPort
+
+func newTopoSegments() topoSegments {
+	return make(topoSegments)
+}
+
+func (s topoSegments) find(p topoPort) topoPort {
+	for {
+		parent, ok := s[p]
+		if !ok || parent == p {
+			return p
+		}
+		p = parent
+	}
+}
+
+func (s topoSegments) union(a, b topoPort) {
+	if ra, rb := s.find(a), s.find(b); ra != rb {
+		s[ra] = rb
+	}
+}
+
+// build 创建设备、链路、路由并启动应用, 调用前必须先通过 validate
+func (t *Topology) build() error {
+	for _, n := range t.Hosts {
+		h := host.NewHost(n.Name)
+		for len(h.Interfaces) < len(n.Interfaces) {
+			h.AddPort()
+		}
+		configureInterfaces(h, n.Interfaces)
+	}
+	for _, n := range t.Routers {
+		r := host.NewRouter(n.Name)
+		for range n.Interfaces {
+			r.AddPort()
+		}
+		configureInterfaces(r, n.Interfaces)
+	}
+	for _, sw := range t.Switches {
+		ports := sw.Ports
+		if ports == 0 {
+			ports = defaultSwitchPorts
+		}
+		s := host.NewSwitch(sw.Name, ports)
+		for _, v := range sw.VLANs {
+			port, vlan := parseTopoPort(v.Port), max(v.VLAN, host.DefaultVLAN)
+			if v.Mode == "trunk" {
+				s.SetTrunk(port, vlan, v.Allowed)
+			} else {
+				s.SetAccess(port, vlan)
+			}
+		}
+	}
+	for i, l := range t.Links {
+		link, err := addLink(l.A, l.B, nil)
+		if err != nil {
+			return fmt.Errorf(i18n.T("链路 %d: %v"), i+1, err)
+		}
+		if l.Delay != nil {
+			link.Delay = time.Duration(*l.Delay)
+		}
+		if l.Bandwidth != nil {
+			link.Bandwidth = int64(*l.Bandwidth)
+		}
+		if l.Loss != nil {
+			link.Loss = *l.Loss
+		}
+		if l.BER != nil {
+			link.BER = *l.BER
+		}
+		if l.Code != "" {
+			link.LineCode, _ = level.ParseLineCode(l.Code)
+		}
+	}
+	// 链路就绪后再启用生成树, 端口从阻塞开始
+	for _, sw := range t.Switches {
+		if sw.STP == nil {
+			continue
+		}
+		s := host.FindDevice(sw.Name).(*host.Switch)
+		if sw.STP.Priority != nil {
+			s.SetBridgePriority(*sw.STP.Priority)
+		}
+		for _, sp := range sw.STP.Ports {
+			port := parseTopoPort(sp.Port)
+			s.SetPortCost(port, sp.Cost)
+			if sp.Priority != nil {
+				s.SetPortPriority(port, *sp.Priority)
+			}
+			s.SetEdge(port, sp.Edge)
+		}
+		mode, _ := host.ParseSTPMode(sw.STP.Mode)
+		s.EnableSTP(mode)
+	}
+	nodes := append(slices.Clone(t.Hosts), t.Routers...)
+	for _, n := range nodes {
+		h := host.FindHost(n.Name)
+		for _, r := range n.Routes {
+			prefix, prefixLen := [4]byte{}, 0
+			if r.To != "default" {
+				prefix, prefixLen, _ = level.ParseIPv4Prefix(r.To)
+			}
+			via, _ := level.ParseIPv4(r.Via)
+			h.AddRoute(prefix, prefixLen, via)
+		}
+		if n.Gateway != "" {
+			gateway, _ := level.ParseIPv4(n.Gateway)
+			h.SetGateway(gateway)
+		}
+	}
+	// 所有设备与路由就绪后再启动应用
+	for _, n := range nodes {
+		h := host.FindHost(n.Name)
+		for _, app := range n.Apps {
+			var err error
+			switch app.Type {
+			case "tcp-server":
+				h.Listen(app.Port)
+			case "ping":
+				err = sendPacket(n.Name, app.To, "icmp", 0, "")
+			default:
+				err = sendPacket(n.Name, app.To, app.Type, app.Port, app.Data)
+			}
+			if err != nil {
+				return fmt.Errorf(i18n.T("%s: 启动 %s 失败: %v"), n.Name, app.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configureInterfaces 按拓扑文件设置接口地址、MAC与MTU, 再创建子接口
+func configureInterfaces(h *host.BaseHost, ifaces []TopoInterface) {
+	for i, spec := range ifaces {
+		iface := h.Interfaces[i]
+		if spec.Address != "" {
+			ip, prefixLen, _ := level.ParseIPv4Prefix(spec.Address)
+			h.SetAddress(i, ip, prefixLen)
+		}
+		if spec.MAC != "" {
+			iface.MACAddress, _ = level.ParseMAC(spec.MAC)
+		}
+		if spec.MTU != 0 {
+			iface.MTU = spec.MTU
+		}
+	}
+	// 子接口排在所有物理接口之后, 物理接口的端口号与文件中的位置一致
+	for i, spec := range ifaces {
+		for _, sub := range spec.Subinterfaces {
+			port, _ := h.AddSubinterface(i, sub.VLAN)
+			if sub.Address != "" {
+				ip, prefixLen, _ := level.ParseIPv4Prefix(sub.Address)
+				h.SetAddress(port, ip, prefixLen)
+			}
+		}
+	}
+}
+
+// currentTopology 导出当前的设备、链路与路由, 再次加载可以得到相同的拓扑
+// @return *Topology
+func currentTopology() *Topology {
+	t := &Topology{}
+	for _, h := range host.HostList {
+		n := TopoNode{Name: h.Name}
+		// 物理接口在文件中的位置, 子接口写在其父接口下
+		specAt := make(map[int]int)
+		for i, iface := range h.Interfaces {
+			if iface.VLAN != 0 {
+				continue
+			}
+			specAt[i] = len(n.Interfaces)
+			spec := TopoInterface{Name: iface.Name, MAC: level.FormatMAC(iface.MACAddress)}
+			if iface.IPv4Address != ([4]byte{}) {
+				spec.Address = formatPrefix(iface.IPv4Address, iface.PrefixLen)
+			}
+			if iface.MTU != host.DefaultMTU {
+				spec.MTU = iface.MTU
+			}
+			n.Interfaces = append(n.Interfaces, spec)
+		}
+		for _, iface := range h.Interfaces {
+			if iface.VLAN == 0 {
+				continue
+			}
+			sub := TopoSubinterface{VLAN: iface.VLAN}
+			if iface.IPv4Address != ([4]byte{}) {
+				sub.Address = formatPrefix(iface.IPv4Address, iface.PrefixLen)
+			}
+			parent := &n.Interfaces[specAt[iface.Parent]]
+			parent.Subinterfaces = append(parent.Subinterfaces, sub)
+		}
+		for _, r := range h.Routes {
+			via := level.FormatIPv4(r.NextHop)
+			if r.PrefixLen == 0 && !h.Forwarding {
+				n.Gateway = via
+				continue
+			}
+			to := "default"
+			if r.PrefixLen > 0 {
+				to = formatPrefix(r.Prefix, r.PrefixLen)
+			}
+			n.Routes = append(n.Routes, TopoRoute{To: to, Via: via})
+		}
+		for _, port := range slices.Sorted(maps.Keys(h.Listening)) {
+			n.Apps = append(n.Apps, TopoApp{Type: "tcp-server", Port: port})
+		}
+		if h.Forwarding {
+			t.Routers = append(t.Routers, n)
+		} else {
+			t.Hosts = append(t.Hosts, n)
+		}
+	}
+	for _, sw := range host.SwitchList {
+		ts := TopoSwitch{Name: sw.Name, Ports: len(sw.NetChannel)}
+		for i, cfg := range sw.PortConfig {
+			if cfg.Mode == host.PortAccess && cfg.VLAN == host.DefaultVLAN {
+				continue
+			}
+			ts.VLANs = append(ts.VLANs, TopoSwitchPort{Port: sw.PortName(i), Mode: cfg.Mode.String(),
+				VLAN: cfg.VLAN, Allowed: cfg.Allowed})
+		}
+		if sw.STP.Mode != host.STPOff {
+			ts.STP = &TopoSTP{Mode: sw.STP.Mode.String()}
+			if sw.STP.Priority != host.DefaultBridgePriority {
+				priority := int(sw.STP.Priority)
+				ts.STP.Priority = &priority
+			}
+			for i, p := range sw.STP.Ports {
+				if p.Cost == 0 && p.Priority == host.DefaultPortPriority && !p.Edge {
+					continue
+				}
+				sp := TopoSTPPort{Port: sw.PortName(i), Cost: p.Cost, Edge: p.Edge}
+				if p.Priority != host.DefaultPortPriority {
+					priority := int(p.Priority)
+					sp.Priority = &priority
+				}
+				ts.STP.Ports = append(ts.STP.Ports, sp)
+			}
+		}
+		t.Switches = append(t.Switches, ts)
+	}
+	for _, l := range host.LinkList {
+		delay, bw, loss := Duration(l.Delay), Bandwidth(l.Bandwidth), l.Loss
+		tl := TopoLink{A: l.A.String(), B: l.B.String(), Delay: &delay, Bandwidth: &bw, Loss: &loss}
+		if l.BER > 0 {
+			ber := l.BER
+			tl.BER = &ber
+		}
+		if l.LineCode != level.LineAuto {
+			tl.Code = l.LineCode.String()
+		}
+		t.Links = append(t.Links, tl)
+	}
+	return t
+}
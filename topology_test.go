@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"osiweb-go/host"
+)
+
+// resetSimulator 清空全部设备、链路、事件与抓包
+func resetSimulator(t *testing.T) {
+	t.Helper()
+	for _, s := range captures {
+		if s.tapID != 0 {
+			s.stop()
+		}
+	}
+	captures = make(map[string]*captureSession)
+	if err := host.RestoreSnapshot(&host.Snapshot{RandSeed: 1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// parseTopology 解析 YAML 格式的拓扑
+func parseTopology(t *testing.T, doc string) *Topology {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "topo.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	topo, err := readTopology(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return topo
+}
+
+func TestTopologyValidate(t *testing.T) {
+	resetSimulator(t)
+	tests := []struct {
+		name string
+		doc  string
+		// 错误信息中应出现的内容, 为空表示应通过检查
+		want []string
+	}{
+		{"valid", `
+hosts:
+  - {name: a, interfaces: [{address: 10.0.0.1/24}]}
+  - {name: b, interfaces: [{address: 10.0.0.2/24}], gateway: 10.0.0.1}
+switches:
+  - {name: s, ports: 2}
+links:
+  - {a: a, b: s}
+  - {a: b, b: s}
+`, nil},
+		{"duplicate names", `
+hosts: [{name: a}, {name: a}]
+switches: [{name: "x:y"}]
+`, []string{"设备名称重复: a", `"x:y"`}},
+		{"bad interfaces", `
+hosts:
+  - name: a
+    interfaces:
+      - {name: eth1, address: 10.0.0.0/24, mtu: 20, mac: 01:00:00:00:00:01}
+      - {address: 10.0.0.5/16, mac: 02:00:00:00:00:01}
+      - {address: nonsense, mac: 02:00:00:00:00:01, mtu: 9000}
+`, []string{"应为 eth0", "eth0: MTU", "eth2: MTU", "组播", "网络地址或广播地址", "网段重叠", "MAC地址重复", `"nonsense"`}},
+		{"routes and apps", `
+hosts:
+  - name: a
+    interfaces: [{address: 10.0.0.1/24}]
+    gateway: 10.9.9.9
+    routes: [{to: 10.1.0.0/16, via: 10.0.0.2}, {to: 10.1.0.0/16, via: 10.0.0.3}]
+    apps: [{type: tcp-server}, {type: udp, to: 10.0.0.2}, {type: dns}]
+`, []string{"不在直连网段中", "路由重复", "tcp-server 需要 port", "udp 需要 port", "dns"}},
+		{"links", `
+hosts: [{name: a}, {name: b}]
+switches: [{name: s, ports: 1}]
+links:
+  - {a: a, b: nowhere}
+  - {a: a:eth0, b: s:eth5}
+  - {a: b, b: s:x}
+  - {a: s, b: a:eth0, loss: 2}
+`, []string{"不存在的设备", "不存在的端口", "s:x", "已连接到链路 1", "丢包率"}},
+		{"overlapping segments", `
+hosts:
+  - {name: a, interfaces: [{address: 10.0.0.1/24}]}
+  - {name: b, interfaces: [{address: 10.0.0.2/24}]}
+  - {name: c, interfaces: [{address: 10.0.0.3/24}]}
+  - {name: d, interfaces: [{address: 10.0.0.4/24}]}
+links:
+  - {a: a, b: b}
+  - {a: c, b: d}
+`, []string{"网段重叠: 10.0.0.0/24"}},
+	}
+	for _, tt := range tests {
+		err := parseTopology(t, tt.doc).validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: validate succeeded", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error does not mention %q:\n%v", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestTopologyLoadAndExport(t *testing.T) {
+	resetSimulator(t)
+	if _, err := loadTopology("examples/lab.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if h := host.FindHost("h1"); h == nil || h.Interfaces[0].MACAddress != [6]byte{2, 0, 0, 0, 1, 0x10} {
+		t.Fatalf("h1 not built with the MAC from the file: %+v", h)
+	}
+	if _, err := loadTopology("examples/lab.yaml"); err == nil || !strings.Contains(err.Error(), "设备已存在") {
+		t.Errorf("loading twice: %v, want 设备已存在", err)
+	}
+
+	topo := currentTopology()
+	if len(topo.Hosts) != 3 || len(topo.Routers) != 1 || len(topo.Switches) != 1 || len(topo.Links) != 4 {
+		t.Fatalf("exported %d hosts, %d routers, %d switches, %d links",
+			len(topo.Hosts), len(topo.Routers), len(topo.Switches), len(topo.Links))
+	}
+	if topo.Hosts[0].Gateway != "192.168.1.1" || topo.Links[3].A != "r1:eth1" || *topo.Links[3].Bandwidth != 10_000_000 {
+		t.Errorf("exported h1 gateway %q, link 4 %s bandwidth %d",
+			topo.Hosts[0].Gateway, topo.Links[3].A, *topo.Links[3].Bandwidth)
+	}
+	if apps := topo.Hosts[2].Apps; len(apps) != 1 || apps[0] != (TopoApp{Type: "tcp-server", Port: 80}) {
+		t.Errorf("exported h3 apps %+v", apps)
+	}
+
+	// 导出的 JSON 与 YAML 文件读回后与导出时相同
+	for _, name := range []string{"topo.json", "topo.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := writeTopology(path, topo); err != nil {
+			t.Fatal(err)
+		}
+		back, err := readTopology(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(back, topo) {
+			t.Errorf("%s: read back %+v, want %+v", name, back, topo)
+		}
+	}
+
+	// 重新加载导出的拓扑得到相同的设备与链路
+	path := filepath.Join(t.TempDir(), "topo.yaml")
+	if err := writeTopology(path, topo); err != nil {
+		t.Fatal(err)
+	}
+	resetSimulator(t)
+	if _, err := loadTopology(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTopology(); !reflect.DeepEqual(got, topo) {
+		t.Errorf("reloaded %+v, want %+v", got, topo)
+	}
+}
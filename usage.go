@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// commandLine 正在执行的命令行, 用于在参数错误时指出出错的参数
+var commandLine string
+
+// usageElement 用法中的一个参数位置, 如 <host>、[port] 或 [field=value ...]
+type usageElement struct {
+	// 写法
+	text string
+	// 是否可省略
+	optional bool
+	// 是否可重复
+	variadic bool
+}
+
+// findCommand 按名称或别名查找命令, 不区分大小写
+// @param name string 命令名称或别名
+// @return *Command 不存在时为nil
+func findCommand(name string) *Command {
+	name = strings.ToLower(name)
+	for i := range Commands {
+		if Commands[i].Name == name || slices.Contains(Commands[i].Aliases, name) {
+			return &Commands[i]
+		}
+	}
+	return nil
+}
+
+// diagnoseArgs 对照用法检查子命令与参数个数
+// @param cmd *Command 命令
+// @param args []string 命令名之后的参数
+// @return int 出错的词在命令行中的位置, 命令名为0, 缺少参数时为行尾
+// @return string 错误原因, 看不出错误时为空
+// @return string 对应子命令的用法
+func diagnoseArgs(cmd *Command, args []string) (int, string, string) {
+	prefix := cmd.Name
+	skip := 0
+	if subs, ok := subcommands[cmd.Name]; ok {
+		if len(args) == 0 {
+			return 1, i18n.T("缺少子命令, 应为 %s", strings.Join(subs, "/")), cmd.Usage
+		}
+		if !slices.Contains(subs, args[0]) {
+			reason := i18n.T("未知的子命令 %q", args[0])
+			if s := closestWord(args[0], subs); s != "" {
+				reason += i18n.T(", 您是否想输入 %s?", s)
+			}
+			return 1, reason, cmd.Usage
+		}
+		prefix += " " + args[0]
+		args, skip = args[1:], 1
+	}
+	line, elements, ok := usageLine(cmd, prefix)
+	if !ok {
+		return 0, "", ""
+	}
+	minArgs, maxArgs := 0, 0
+	for _, e := range elements {
+		if !e.optional {
+			minArgs++
+		}
+		if e.variadic {
+			maxArgs = -1
+		} else if maxArgs >= 0 {
+			maxArgs++
+		}
+	}
+	switch {
+	case len(args) < minArgs:
+		var required []string
+		for _, e := range elements {
+			if !e.optional {
+				required = append(required, e.text)
+			}
+		}
+		return 1 + skip + len(args), i18n.T("缺少参数 %s", required[min(len(args), len(required)-1)]), line
+	case maxArgs >= 0 && len(args) > maxArgs:
+		return 1 + skip + maxArgs, i18n.T("多余的参数 %q", args[maxArgs]), line
+	}
+	return 0, "", ""
+}
+
+// usageFor 与命令行的子命令对应的用法, 找不到时为完整用法
+func usageFor(cmd *Command, fields []string) string {
+	if _, ok := subcommands[cmd.Name]; ok && len(fields) > 1 {
+		if line, _, ok := usageLine(cmd, cmd.Name+" "+fields[1]); ok {
+			return line
+		}
+	}
+	return cmd.Usage
+}
+
+// usageLine 找到以 prefix 开头的一行用法, 并拆出其后的参数位置
+// @return string 这一行用法
+// @return []usageElement 参数位置
+// @return bool 是否找到
+func usageLine(cmd *Command, prefix string) (string, []usageElement, bool) {
+	for _, line := range strings.Split(cmd.Usage, "\n") {
+		line = strings.TrimSpace(line)
+		if line != prefix && !strings.HasPrefix(line, prefix+" ") {
+			continue
+		}
+		return line, parseUsageElements(strings.TrimPrefix(line, prefix)), true
+	}
+	return "", nil, false
+}
+
+// parseUsageElements 按空格拆分用法中的参数, 方括号内的空格不拆分
+// <dev>|link <id> 这样带关键字的选择项, 其后的参数只在选了关键字时需要, 视为可省略
+func parseUsageElements(s string) []usageElement {
+	var elements []usageElement
+	depth := 0
+	for _, word := range strings.Fields(s) {
+		if depth == 0 {
+			elements = append(elements, usageElement{text: word, optional: strings.HasPrefix(word, "[")})
+		} else {
+			elements[len(elements)-1].text += " " + word
+		}
+		depth += strings.Count(word, "[") - strings.Count(word, "]")
+	}
+	for i := range elements {
+		e := &elements[i]
+		e.variadic = strings.Contains(e.text, "...")
+		if i > 0 && hasKeywordChoice(elements[i-1].text) && strings.HasPrefix(e.text, "<") {
+			e.optional = true
+		}
+	}
+	return elements
+}
+
+// hasKeywordChoice 选择项中既有 <参数> 又有关键字, 如 <dev>|link
+func hasKeywordChoice(text string) bool {
+	if !strings.Contains(text, "|") || strings.HasPrefix(text, "[") {
+		return false
+	}
+	placeholder, keyword := false, false
+	for _, alt := range strings.Split(text, "|") {
+		if strings.ContainsAny(alt, "<>[]") {
+			placeholder = true
+		} else {
+			keyword = true
+		}
+	}
+	return placeholder && keyword
+}
+
+// commandErr 当前命令的第一个错误, 批量执行时写入结果的 error 字段, 为空表示命令成功
+var commandErr string
+
+// failCommand 把当前命令记为失败, 只保留第一个错误
+func failCommand(reason string) {
+	if commandErr == "" {
+		commandErr = reason
+	}
+}
+
+// printError 像 fmt.Println 一样打印命令的错误, 并把当前命令记为失败
+func printError(a ...any) {
+	msg := fmt.Sprintln(a...)
+	fmt.Print(msg)
+	failCommand(strings.TrimSuffix(msg, "\n"))
+}
+
+// printArgErrorLine 打印错误原因, 并在命令行下方用 ^ 标出出错的词
+// @param index int 词在命令行中的位置, 超出时标在行尾
+// @param reason string 错误原因
+func printArgErrorLine(index int, reason string) {
+	fmt.Printf(i18n.T("参数错误: %s\n"), reason)
+	failCommand(reason)
+	if commandLine == "" {
+		return
+	}
+	spans := fieldSpans(commandLine)
+	start, width := len(commandLine), 1
+	if index < len(spans) {
+		start = spans[index][0]
+		width = max(displayWidth(commandLine[spans[index][0]:spans[index][1]]), 1)
+	}
+	fmt.Printf("  %s\n", commandLine)
+	fmt.Printf("  %s%s\n", strings.Repeat(" ", displayWidth(commandLine[:start])), strings.Repeat("^", width))
+}
+
+// fieldSpans 各个词在命令行中的起止位置, 拆分规则与 parseFields 相同
+func fieldSpans(line string) [][2]int {
+	var spans [][2]int
+	start := -1
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ' ' && !inQuotes {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(line)})
+	}
+	return spans
+}
+
+// closestWord 与输入编辑距离最小且不超过2的候选, 没有时为空
+func closestWord(input string, words []string) string {
+	best, bestDist := "", 3
+	for _, w := range words {
+		if d := editDistance(strings.ToLower(input), w); d < bestDist {
+			best, bestDist = w, d
+		}
+	}
+	return best
+}
+
+// editDistance Damerau–Levenshtein 距离: 插入、删除、替换与相邻交换各算一次
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	inf := len(s) + len(t)
+	// d[i+1][j+1] 为 s[:i] 与 t[:j] 的距离, 第0行与第0列为哨兵
+	d := make([][]int, len(s)+2)
+	for i := range d {
+		d[i] = make([]int, len(t)+2)
+	}
+	d[0][0] = inf
+	for i := 0; i <= len(s); i++ {
+		d[i+1][0], d[i+1][1] = inf, i
+	}
+	for j := 0; j <= len(t); j++ {
+		d[0][j+1], d[1][j+1] = inf, j
+	}
+	// 字符在 s 中最后出现的行
+	last := make(map[rune]int)
+	for i := 1; i <= len(s); i++ {
+		// 本行中最后一个与 s[i-1] 相同的列
+		match := 0
+		for j := 1; j <= len(t); j++ {
+			i1, j1 := last[t[j-1]], match
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost, match = 0, j
+			}
+			d[i+1][j+1] = min(d[i][j]+cost, d[i+1][j]+1, d[i][j+1]+1,
+				d[i1][j1]+(i-i1-1)+1+(j-j1-1))
+		}
+		last[s[i-1]] = i
+	}
+	return d[len(s)+1][len(t)+1]
+}
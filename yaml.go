@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"osiweb-go/i18n"
+)
+
+// 拓扑文件支持的YAML子集:
+//   - 块格式的映射与序列, 序列可以与上级键同缩进, 也可以写成 "- key: value" 的紧凑形式
+//   - 单行的流格式 [a, b] 与 {k: v}
+//   - 普通、单引号、双引号标量, # 注释, 文档标记 --- 与 ...
+//
+// 不支持锚点、别名、标签与多行块标量 | >.
+// YAML 先转换为JSON, 再由 encoding/json 解码, 两种格式共用同一套字段与校验.
+
+// const YAML 节点类型
+const (
+	// yamlScalar 标量, value 为JSON字面量
+	yamlScalar = iota
+	// yamlMap 映射, 保留键的顺序
+	yamlMap
+	// yamlSeq 序列
+	yamlSeq
+)
+
+// yamlNode YAML/JSON 节点
+type yamlNode struct {
+	// 节点类型
+	kind int
+	// 标量的JSON字面量, 如 "\"h1\"", 1, true, null
+	value string
+	// 映射的键
+	keys []string
+	// 映射的值或序列的元素
+	items []*yamlNode
+}
+
+// yamlLine 去掉注释后的非空行
+type yamlLine struct {
+	// 行号, 从1开始
+	num int
+	// 缩进空格数
+	indent int
+	// 去掉缩进与注释后的内容
+	text string
+}
+
+// yamlParser 按缩进解析块格式
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// yamlToJSON 把YAML文档转换为JSON
+// @param data YAML文档
+// @return []byte JSON, 映射的键保持原有顺序
+func yamlToJSON(data []byte) ([]byte, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf(i18n.T("第%d行: 缩进不能使用制表符"), i+1)
+		}
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		if text == "" || (indent == 0 && (text == "---" || text == "...")) {
+			continue
+		}
+		if text == "|" || text == ">" || strings.HasSuffix(text, ": |") || strings.HasSuffix(text, ": >") {
+			return nil, fmt.Errorf(i18n.T("第%d行: 不支持多行块标量"), i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: indent, text: text})
+	}
+	if len(p.lines) == 0 {
+		return []byte("null"), nil
+	}
+	node, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf(i18n.T("第%d行: 缩进错误"), p.lines[p.pos].num)
+	}
+	var b bytes.Buffer
+	node.writeJSON(&b)
+	return b.Bytes(), nil
+}
+
+// stripYAMLComment 去掉引号以外、行首或空白之后的 # 注释
+func stripYAMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// isSeqItem 判断一行是否为序列元素 "- ..."
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseBlock 解析从当前行开始、缩进为 indent 的块
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+// parseSeq 解析块格式序列
+func (p *yamlParser) parseSeq(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlSeq}
+	for p.pos < len(p.lines) {
+		line := &p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isSeqItem(line.text)) {
+			// 与上级键同缩进的序列到此结束
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf(i18n.T("第%d行: 缩进错误"), line.num)
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var item *yamlNode
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			item, err = p.parseChild(indent, false)
+		case rest[0] != '[' && rest[0] != '{' && yamlKeyEnd(rest) >= 0,
+			isSeqItem(rest):
+			// 紧凑形式: 把 "- " 之后的内容当作更深缩进的一行
+			line.indent += len(line.text) - len(rest)
+			line.text = rest
+			item, err = p.parseBlock(line.indent)
+		default:
+			item, err = parseYAMLInline(rest, line.num)
+			p.pos++
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+	}
+	return node, nil
+}
+
+// parseMap 解析块格式映射
+func (p *yamlParser) parseMap(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlMap}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isSeqItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf(i18n.T("第%d行: 缩进错误"), line.num)
+		}
+		end := yamlKeyEnd(line.text)
+		if end < 0 {
+			return nil, fmt.Errorf(i18n.T("第%d行: 应为 key: value"), line.num)
+		}
+		key, err := parseYAMLKey(line.text[:end], line.num)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range node.keys {
+			if k == key {
+				return nil, fmt.Errorf(i18n.T("第%d行: 重复的键 %q"), line.num, key)
+			}
+		}
+		p.pos++
+		var value *yamlNode
+		if rest := strings.TrimSpace(line.text[end+1:]); rest != "" {
+			value, err = parseYAMLInline(rest, line.num)
+		} else {
+			value, err = p.parseChild(indent, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.keys = append(node.keys, key)
+		node.items = append(node.items, value)
+	}
+	return node, nil
+}
+
+// parseChild 解析值为空的键或序列元素下面的块
+// @param sameIndentSeq 映射的值可以是与键同缩进的序列
+func (p *yamlParser) parseChild(indent int, sameIndentSeq bool) (*yamlNode, error) {
+	if p.pos < len(p.lines) {
+		next := p.lines[p.pos]
+		if next.indent > indent || (sameIndentSeq && next.indent == indent && isSeqItem(next.text)) {
+			return p.parseBlock(next.indent)
+		}
+	}
+	return &yamlNode{kind: yamlScalar, value: "null"}, nil
+}
+
+// yamlKeyEnd 返回引号以外第一个 ": " 或行尾 ":" 的位置, 不是映射时返回-1
+func yamlKeyEnd(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case i == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			return i
+		}
+	}
+	return -1
+}
+
+// parseYAMLKey 解析映射的键, 可以加引号
+func parseYAMLKey(s string, num int) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf(i18n.T("第%d行: 键不能为空"), num)
+	}
+	if s[0] != '"' && s[0] != '\'' {
+		return s, nil
+	}
+	node, err := parseYAMLInline(s, num)
+	if err != nil {
+		return "", err
+	}
+	var key string
+	if err := json.Unmarshal([]byte(node.value), &key); err != nil {
+		return "", fmt.Errorf(i18n.T("第%d行: 键格式错误: %s"), num, s)
+	}
+	return key, nil
+}
+
+// parseYAMLInline 解析一行中的标量或流格式集合
+func parseYAMLInline(s string, num int) (*yamlNode, error) {
+	f := &yamlFlow{s: s}
+	node, err := f.value(false)
+	if err == nil {
+		f.skipSpace()
+		if f.pos < len(f.s) {
+			err = fmt.Errorf(i18n.T("多余的内容 %q"), f.s[f.pos:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("第%d行: %v"), num, err)
+	}
+	return node, nil
+}
+
+// yamlFlow 解析单行内容
+type yamlFlow struct {
+	s   string
+	pos int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.s) && (f.s[f.pos] == ' ' || f.s[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+// value 解析一个值
+// @param inFlow 在 [] 或 {} 之内, 普通标量遇到 , ] } 结束
+func (f *yamlFlow) value(inFlow bool) (*yamlNode, error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return &yamlNode{kind: yamlScalar, value: "null"}, nil
+	}
+	switch f.s[f.pos] {
+	case '[':
+		return f.collection(']')
+	case '{':
+		return f.collection('}')
+	case '"', '\'':
+		str, err := f.quoted()
+		if err != nil {
+			return nil, err
+		}
+		data, _ := json.Marshal(str)
+		return &yamlNode{kind: yamlScalar, value: string(data)}, nil
+	case '&', '*', '!', '|', '>', '@', '`':
+		return nil, fmt.Errorf(i18n.T("不支持的YAML语法 %q"), f.s[f.pos:])
+	}
+	start := f.pos
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if inFlow && c == ':' && (f.pos+1 == len(f.s) || f.s[f.pos+1] == ' ') {
+			break
+		}
+		f.pos++
+	}
+	return &yamlNode{kind: yamlScalar, value: plainScalarJSON(strings.TrimSpace(f.s[start:f.pos]))}, nil
+}
+
+// collection 解析流格式序列或映射
+func (f *yamlFlow) collection(end byte) (*yamlNode, error) {
+	f.pos++
+	node := &yamlNode{kind: yamlSeq}
+	if end == '}' {
+		node.kind = yamlMap
+	}
+	for {
+		f.skipSpace()
+		if f.pos >= len(f.s) {
+			return nil, fmt.Errorf(i18n.T("缺少 %q"), end)
+		}
+		if f.s[f.pos] == end {
+			f.pos++
+			return node, nil
+		}
+		if node.kind == yamlMap {
+			key, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			var k string
+			if key.kind != yamlScalar || json.Unmarshal([]byte(key.value), &k) != nil {
+				k = strings.Trim(key.value, "\"")
+			}
+			f.skipSpace()
+			if f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, errors.New(i18n.T("应为 key: value"))
+			}
+			f.pos++
+			node.keys = append(node.keys, k)
+		}
+		item, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+		f.skipSpace()
+		if f.pos < len(f.s) && f.s[f.pos] == ',' {
+			f.pos++
+		} else if f.pos < len(f.s) && f.s[f.pos] != end {
+			return nil, fmt.Errorf(i18n.T("应为 , 或 %q"), end)
+		}
+	}
+}
+
+// quoted 解析单引号或双引号字符串
+func (f *yamlFlow) quoted() (string, error) {
+	q := f.s[f.pos]
+	start := f.pos
+	for f.pos++; f.pos < len(f.s); f.pos++ {
+		c := f.s[f.pos]
+		if q == '"' && c == '\\' {
+			f.pos++
+			continue
+		}
+		if c != q {
+			continue
+		}
+		if q == '\'' && f.pos+1 < len(f.s) && f.s[f.pos+1] == '\'' {
+			// 单引号内 '' 表示一个单引号
+			f.pos++
+			continue
+		}
+		f.pos++
+		raw := f.s[start:f.pos]
+		if q == '\'' {
+			return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+		}
+		str, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf(i18n.T("字符串格式错误: %s"), raw)
+		}
+		return str, nil
+	}
+	return "", fmt.Errorf(i18n.T("字符串缺少结束引号: %s"), f.s[start:])
+}
+
+// yamlNumber YAML 1.2 核心模式的十进制数
+var yamlNumber = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+
+// plainScalarJSON 按 YAML 1.2 核心模式推断普通标量的类型
+func plainScalarJSON(s string) string {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return "null"
+	case "true", "True", "TRUE":
+		return "true"
+	case "false", "False", "FALSE":
+		return "false"
+	}
+	if yamlNumber.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// writeJSON 输出JSON
+func (n *yamlNode) writeJSON(b *bytes.Buffer) {
+	switch n.kind {
+	case yamlScalar:
+		b.WriteString(n.value)
+	case yamlSeq:
+		b.WriteByte('[')
+		for i, item := range n.items {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			item.writeJSON(b)
+		}
+		b.WriteByte(']')
+	case yamlMap:
+		b.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			data, _ := json.Marshal(key)
+			b.Write(data)
+			b.WriteByte(':')
+			n.items[i].writeJSON(b)
+		}
+		b.WriteByte('}')
+	}
+}
+
+// jsonToYAML 把JSON转换为块格式的YAML
+// @param data JSON, 映射的键保持原有顺序
+// @return []byte YAML文档
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := readJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	switch {
+	case node.kind == yamlScalar || len(node.items) == 0:
+		b.WriteString(node.scalarYAML() + "\n")
+	case node.kind == yamlMap:
+		node.writeMapYAML(&b, 0)
+	default:
+		node.writeSeqYAML(&b, 0)
+	}
+	return b.Bytes(), nil
+}
+
+// readJSONNode 按顺序读取一个JSON值
+func readJSONNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yamlNode{kind: yamlSeq}
+		if t == '{' {
+			node.kind = yamlMap
+		}
+		for dec.More() {
+			if node.kind == yamlMap {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			item, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+		}
+		_, err := dec.Token()
+		return node, err
+	case nil:
+		return &yamlNode{kind: yamlScalar, value: "null"}, nil
+	default:
+		data, err := json.Marshal(t)
+		return &yamlNode{kind: yamlScalar, value: string(data)}, err
+	}
+}
+
+// scalarYAML 标量或空集合的YAML写法, 不会被误读为其他类型的字符串不加引号
+func (n *yamlNode) scalarYAML() string {
+	switch {
+	case n.kind == yamlMap:
+		return "{}"
+	case n.kind == yamlSeq:
+		return "[]"
+	case !strings.HasPrefix(n.value, `"`):
+		return n.value
+	}
+	var s string
+	json.Unmarshal([]byte(n.value), &s)
+	if s == "" || plainScalarJSON(s) != n.value || strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@` ") ||
+		strings.HasSuffix(s, " ") || strings.HasSuffix(s, ":") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsFunc(s, func(r rune) bool { return r < ' ' }) {
+		return n.value
+	}
+	return s
+}
+
+// isBlock 是否需要另起一行输出
+func (n *yamlNode) isBlock() bool {
+	return n.kind != yamlScalar && len(n.items) > 0
+}
+
+// writeMapYAML 输出块格式映射, 第一行的缩进由调用者写出
+func (n *yamlNode) writeMapYAML(b *bytes.Buffer, indent int) {
+	for i, key := range n.keys {
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		k := (&yamlNode{kind: yamlScalar, value: mustJSON(key)}).scalarYAML()
+		value := n.items[i]
+		if !value.isBlock() {
+			fmt.Fprintf(b, "%s: %s\n", k, value.scalarYAML())
+			continue
+		}
+		b.WriteString(k + ":\n")
+		b.WriteString(strings.Repeat(" ", indent+2))
+		if value.kind == yamlMap {
+			value.writeMapYAML(b, indent+2)
+		} else {
+			value.writeSeqYAML(b, indent+2)
+		}
+	}
+}
+
+// writeSeqYAML 输出块格式序列, 第一行的缩进由调用者写出
+func (n *yamlNode) writeSeqYAML(b *bytes.Buffer, indent int) {
+	for i, item := range n.items {
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		switch {
+		case !item.isBlock():
+			b.WriteString("- " + item.scalarYAML() + "\n")
+		case item.kind == yamlMap:
+			b.WriteString("- ")
+			item.writeMapYAML(b, indent+2)
+		default:
+			b.WriteString("- ")
+			item.writeSeqYAML(b, indent+2)
+		}
+	}
+}
+
+// mustJSON 字符串的JSON字面量
+func mustJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"empty", "# only a comment\n", `null`},
+		{"scalars", "a: 1\nb: -2.5\nc: true\nd: ~\ne: null\nf: hello world\ng: 10.0.0.1/24\nh: 1e3\n",
+			`{"a":1,"b":-2.5,"c":true,"d":null,"e":null,"f":"hello world","g":"10.0.0.1/24","h":1000}`},
+		{"quoted", "a: \"x: y # z\"\nb: 'it''s'\nc: \"tab\\there\"\nd: '1'\n",
+			`{"a":"x: y # z","b":"it's","c":"tab\there","d":"1"}`},
+		{"comments and markers", "---\n# c\na: 1 # trailing\nb: x#y\n...\n", `{"a":1,"b":"x#y"}`},
+		{"nested map", "a:\n  b:\n    c: 1\n  d: 2\ne: 3\n", `{"a":{"b":{"c":1},"d":2},"e":3}`},
+		{"sequence under key", "a:\n  - 1\n  - two\n", `{"a":[1,"two"]}`},
+		{"sequence at key indent", "a:\n- 1\n- 2\nb: 3\n", `{"a":[1,2],"b":3}`},
+		{"compact maps", "hosts:\n  - name: h1\n    gateway: 10.0.0.1\n  - name: h2\n",
+			`{"hosts":[{"name":"h1","gateway":"10.0.0.1"},{"name":"h2"}]}`},
+		{"nested sequences", "- - 1\n  - 2\n- - 3\n", `[[1,2],[3]]`},
+		{"item on its own line", "-\n  a: 1\n- \n", `[{"a":1},null]`},
+		{"empty value", "a:\nb: 1\n", `{"a":null,"b":1}`},
+		{"flow", "a: {x: 1, y: [b, \"c, d\"]}\nb: []\nc: {}\n", `{"a":{"x":1,"y":["b","c, d"]},"b":[],"c":{}}`},
+		{"quoted key", "\"a b\": 1\n'c': 2\n", `{"a b":1,"c":2}`},
+		{"quoted key in compact map", "hosts:\n  - \"name\": h1\n    'gateway': 10.0.0.1\n  - \"a: b\"\n",
+			`{"hosts":[{"name":"h1","gateway":"10.0.0.1"},"a: b"]}`},
+		{"windows line endings", "a: 1\r\nb: 2\r\n", `{"a":1,"b":2}`},
+	}
+	for _, tt := range tests {
+		got, err := yamlToJSON([]byte(tt.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestYAMLToJSONErrors(t *testing.T) {
+	tests := map[string]string{
+		"tab indent":         "a:\n\tb: 1\n",
+		"block scalar":       "a: |\n  text\n",
+		"duplicate key":      "a: 1\na: 2\n",
+		"deeper indent":      "a: 1\n  b: 2\n",
+		"not a mapping":      "a: 1\nplain\n",
+		"unterminated":       "a: \"open\n",
+		"anchor":             "a: &x 1\n",
+		"alias":              "a: *x\n",
+		"flow missing end":   "a: [1, 2\n",
+		"flow missing colon": "a: {x}\n",
+		"trailing content":   "a: [1] 2\n",
+		"empty key":          ": 1\n",
+	}
+	for name, doc := range tests {
+		if got, err := yamlToJSON([]byte(doc)); err == nil {
+			t.Errorf("%s: accepted as %s", name, got)
+		}
+	}
+}
+
+// JSON 转为 YAML 再转回, 值与键的顺序不变, 容易被误读的字符串加引号
+func TestJSONToYAMLRoundTrip(t *testing.T) {
+	docs := []string{
+		`{"name":"h1","ports":4,"up":true,"note":null,"ratio":0.25}`,
+		`{"strings":["yes","123","","a: b","- x","#c"," lead","trail ","true","null","x #y","line\nbreak","it's","1e3","[x]"]}`,
+		`{"hosts":[{"name":"h1","interfaces":[{"address":"10.0.0.1/24"},{}]},{"name":"h2","apps":[]}]}`,
+		`[[1,2],[],{"a":{}},"s"]`,
+		`{"nested":{"deeper":{"list":[{"a":1,"b":[true,false]}]}}}`,
+	}
+	for _, doc := range docs {
+		y, err := jsonToYAML([]byte(doc))
+		if err != nil {
+			t.Errorf("jsonToYAML(%s): %v", doc, err)
+			continue
+		}
+		back, err := yamlToJSON(y)
+		if err != nil {
+			t.Errorf("yamlToJSON of\n%s: %v", y, err)
+			continue
+		}
+		var want bytes.Buffer
+		json.Compact(&want, []byte(doc))
+		if string(back) != want.String() {
+			t.Errorf("round trip of %s via\n%s\ngave %s", doc, y, back)
+		}
+	}
+}